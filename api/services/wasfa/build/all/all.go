@@ -0,0 +1,67 @@
+// Package all binds all the routes into the specified app.
+package all
+
+import (
+	"lobbyte.com/alkeepy/api/services/wasfa/mux"
+	"lobbyte.com/alkeepy/app/domain/auditapp"
+	"lobbyte.com/alkeepy/app/domain/checkapp"
+	"lobbyte.com/alkeepy/app/domain/recipeapp"
+	"lobbyte.com/alkeepy/foundation/web"
+)
+
+// Routes constructs the add value which provides the implementation of
+// of RouteAdder for specifying what routes to bind to this instance.
+func Routes() add {
+	return add{}
+}
+
+type add struct{}
+
+// Add implements the RouterAdder interface. Each API version binds its
+// routes under its own group so a new version can be introduced alongside
+// the current one.
+func (add) Add(app *web.App, cfg mux.Config) {
+	checkapp.Routes(app, checkapp.Config{
+		Build:    cfg.Build,
+		Draining: cfg.Draining,
+	})
+
+	recipeapp.Routes(app, recipeapp.Config{
+		Log:       cfg.Log,
+		RecipeBus: cfg.RecipeBus,
+		AuditBus:  cfg.AuditBus,
+		Events:    cfg.RecipeEvents,
+		Beginner:  cfg.Beginner,
+	})
+}
+
+// =============================================================================
+
+// InternalRoutes constructs the internal value which provides the
+// implementation of RouteAdder for the routes bound to the internal listener.
+func InternalRoutes() internal {
+	return internal{}
+}
+
+type internal struct{}
+
+// Add implements the RouterAdder interface.
+func (internal) Add(app *web.App, cfg mux.Config) {
+	checkapp.Routes(app, checkapp.Config{
+		Build:    cfg.Build,
+		Draining: cfg.Draining,
+	})
+
+	auditapp.Routes(app, auditapp.Config{
+		AuditBus: cfg.AuditBus,
+	})
+
+	recipeapp.Routes(app, recipeapp.Config{
+		Log:       cfg.Log,
+		RecipeBus: cfg.RecipeBus,
+		AuditBus:  cfg.AuditBus,
+		Events:    cfg.RecipeEvents,
+		Beginner:  cfg.Beginner,
+		Admin:     true,
+	})
+}
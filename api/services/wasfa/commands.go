@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"text/tabwriter"
+	"time"
+
+	"lobbyte.com/alkeepy/business/sdk/migrate"
+	"lobbyte.com/alkeepy/business/sdk/sqldb"
+	"lobbyte.com/alkeepy/foundation/buildinfo"
+)
+
+// version prints the build of the binary.
+func version(w io.Writer, bi buildinfo.Info) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(bi)
+}
+
+// migrateDB brings the schema of the database up to date. The configured
+// user is used rather than Vault credentials, which usually aren't allowed
+// to change the schema.
+func migrateDB(ctx context.Context, log *slog.Logger, cfg sqldb.Config) error {
+	db, err := sqldb.Open(cfg)
+	if err != nil {
+		return fmt.Errorf("connecting to db: %w", err)
+	}
+	defer db.Close()
+
+	if err := migrate.Migrate(ctx, log, db); err != nil {
+		return fmt.Errorf("migrate: %w", err)
+	}
+
+	return nil
+}
+
+// migrateStatus prints the state of every migration in the database, which
+// tells whether a deploy needs the migrate command run first or whether it
+// has been run. The database is only read. It fails when an applied
+// migration was changed or is unknown to this build, since the migrate
+// command would refuse to run.
+func migrateStatus(ctx context.Context, w io.Writer, cfg sqldb.Config) error {
+	db, err := sqldb.Open(cfg)
+	if err != nil {
+		return fmt.Errorf("connecting to db: %w", err)
+	}
+	defer db.Close()
+
+	status, err := migrate.Report(ctx, db)
+	if err != nil {
+		return fmt.Errorf("migrate status: %w", err)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tSTATE\tAPPLIED AT\tTOOK\tDESCRIPTION")
+
+	for _, ms := range status.Migrations {
+		appliedAt := "-"
+		if ms.AppliedAt != nil {
+			appliedAt = ms.AppliedAt.Format(time.RFC3339)
+		}
+
+		took := ms.ExecutionTime
+		if took == "" {
+			took = "-"
+		}
+
+		fmt.Fprintf(tw, "%v\t%s\t%s\t%s\t%s\n", ms.Version, ms.State, appliedAt, took, ms.Description)
+	}
+
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "\ncurrent version %v, %d applied, %d pending, %d drifted\n", status.Current, status.Applied, status.Pending, status.Drifted)
+
+	if status.Drifted > 0 {
+		return fmt.Errorf("%d applied migrations were changed or are unknown to this build", status.Drifted)
+	}
+
+	return nil
+}
+
+// seedDB loads the baseline dataset into the database. The schema is brought
+// up to date first so a new environment is usable after a single command.
+func seedDB(ctx context.Context, log *slog.Logger, cfg sqldb.Config) error {
+	db, err := sqldb.Open(cfg)
+	if err != nil {
+		return fmt.Errorf("connecting to db: %w", err)
+	}
+	defer db.Close()
+
+	if err := migrate.Migrate(ctx, log, db); err != nil {
+		return fmt.Errorf("migrate: %w", err)
+	}
+
+	if err := migrate.Seed(ctx, log, db); err != nil {
+		return fmt.Errorf("seed: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,439 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"lobbyte.com/alkeepy/business/domain/orderbus"
+	"lobbyte.com/alkeepy/business/domain/orderbus/stores/orderdb"
+	"lobbyte.com/alkeepy/business/domain/recipebus"
+	"lobbyte.com/alkeepy/business/domain/recipebus/stores/recipedb"
+	"lobbyte.com/alkeepy/business/sdk/order"
+	"lobbyte.com/alkeepy/business/sdk/page"
+	"lobbyte.com/alkeepy/business/sdk/sqldb"
+	"lobbyte.com/alkeepy/business/sdk/tenant"
+)
+
+// Set of formats the export command writes.
+const (
+	formatCSV    = "csv"
+	formatNDJSON = "ndjson"
+)
+
+// Set of domains the export command exports.
+const (
+	exportRecipesDomain = "recipes"
+	exportOrdersDomain  = "orders"
+)
+
+// exportRows is the number of rows read from the database at a time.
+const exportRows = page.MaxRows
+
+// exportFilter selects the rows to export. The times bound when the rows
+// were created, From included and To excluded. Only recipes are deleted, so
+// IncludeDeleted doesn't apply to orders.
+type exportFilter struct {
+	Tenant         uuid.UUID
+	From           *time.Time
+	To             *time.Time
+	IncludeDeleted bool
+}
+
+// parseExportFilter parses the filter flags of the export command. An empty
+// tenant selects the default tenant. The times are RFC 3339 timestamps or
+// dates, taken as midnight UTC.
+func parseExportFilter(tenantID string, defaultTenant string, from string, to string, includeDeleted bool) (exportFilter, error) {
+	if tenantID == "" {
+		tenantID = defaultTenant
+	}
+
+	id, err := uuid.Parse(tenantID)
+	if err != nil {
+		return exportFilter{}, fmt.Errorf("parsing tenant: %w", err)
+	}
+
+	filter := exportFilter{
+		Tenant:         id,
+		IncludeDeleted: includeDeleted,
+	}
+
+	if filter.From, err = parseExportTime(from); err != nil {
+		return exportFilter{}, fmt.Errorf("parsing from: %w", err)
+	}
+
+	if filter.To, err = parseExportTime(to); err != nil {
+		return exportFilter{}, fmt.Errorf("parsing to: %w", err)
+	}
+
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		return exportFilter{}, errors.New("from must be before to")
+	}
+
+	return filter, nil
+}
+
+// parseExportTime parses a timestamp or a date.
+func parseExportTime(s string) (*time.Time, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return &t, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%q must be a RFC 3339 timestamp or a date like 2006-01-02", s)
+}
+
+// exportTo runs the export into the file at path, or to stdout when the
+// path is empty, and logs how many rows were written.
+func exportTo(ctx context.Context, log *slog.Logger, path string, cfg sqldb.Config, domain string, format string, filter exportFilter) error {
+	if path == "" {
+		return logExport(ctx, log, os.Stdout, cfg, domain, format, filter)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+	defer f.Close()
+
+	if err := logExport(ctx, log, f, cfg, domain, format, filter); err != nil {
+		return err
+	}
+
+	// The file is closed here too so an error flushing it isn't lost.
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+
+	return nil
+}
+
+func logExport(ctx context.Context, log *slog.Logger, w io.Writer, cfg sqldb.Config, domain string, format string, filter exportFilter) error {
+	n, err := exportData(ctx, log, w, cfg, domain, format, filter)
+	if err != nil {
+		return fmt.Errorf("export: %s: %w", domain, err)
+	}
+
+	log.InfoContext(ctx, "export", "status", "complete", "domain", domain, "format", format, "tenant", filter.Tenant, "count", n)
+
+	return nil
+}
+
+// exportData streams the rows of the domain that match the filter to the
+// writer, oldest first, and returns how many were written. The rows are
+// read a page at a time so the export holds little in memory however large
+// it is. The command is run by an operator holding the database settings,
+// so analysts and backups get the files it writes without being given
+// access to the database.
+func exportData(ctx context.Context, log *slog.Logger, w io.Writer, cfg sqldb.Config, domain string, format string, filter exportFilter) (int, error) {
+	if domain != exportRecipesDomain && domain != exportOrdersDomain {
+		return 0, fmt.Errorf("unknown export %q, expected %s or %s", domain, exportRecipesDomain, exportOrdersDomain)
+	}
+
+	db, err := sqldb.Open(cfg)
+	if err != nil {
+		return 0, fmt.Errorf("connecting to db: %w", err)
+	}
+	defer db.Close()
+
+	ctx = tenant.With(ctx, filter.Tenant)
+	cluster := sqldb.NewCluster(db, nil)
+
+	var ew *exportWriter
+	var n int
+
+	switch domain {
+	case exportRecipesDomain:
+		recipeBus := recipebus.NewBusiness(log, recipedb.NewStore(log, cluster))
+
+		ew = newExportWriter(w, format, recipeColumns)
+		n, err = exportRecipes(ctx, recipeBus, ew, filter)
+
+	case exportOrdersDomain:
+		orderBus := orderbus.NewBusiness(log, orderdb.NewStore(log, cluster))
+
+		ew = newExportWriter(w, format, orderColumns)
+		n, err = exportOrders(ctx, orderBus, ew, filter)
+	}
+	if err != nil {
+		return n, err
+	}
+
+	if err := ew.flush(); err != nil {
+		return n, fmt.Errorf("writing: %w", err)
+	}
+
+	return n, nil
+}
+
+// exportRecipes writes the recipes page by page, each page starting after
+// the last recipe of the one before.
+func exportRecipes(ctx context.Context, recipeBus *recipebus.Business, ew *exportWriter, filter exportFilter) (int, error) {
+	busFilter := recipebus.QueryFilter{
+		StartCreatedDate: filter.From,
+		EndCreatedDate:   filter.To,
+		IncludeDeleted:   filter.IncludeDeleted,
+	}
+
+	orderBy := order.NewBy(recipebus.OrderByDateCreated, order.ASC)
+	rows := strconv.Itoa(exportRows)
+
+	pg, err := page.Parse("", rows)
+	if err != nil {
+		return 0, err
+	}
+
+	var n int
+	for {
+		recipes, err := recipeBus.Query(ctx, busFilter, orderBy, pg)
+		if err != nil {
+			return n, fmt.Errorf("query: %w", err)
+		}
+
+		for _, r := range recipes {
+			rec := toRecipeRecord(r)
+			if err := ew.write(rec.row(), rec); err != nil {
+				return n, fmt.Errorf("writing: %w", err)
+			}
+			n++
+		}
+
+		if len(recipes) < exportRows {
+			return n, nil
+		}
+
+		last := recipes[len(recipes)-1]
+		if pg, err = page.ParseCursor(page.Cursor{DateCreated: last.DateCreated, ID: last.ID}.Encode(), rows); err != nil {
+			return n, err
+		}
+	}
+}
+
+// exportOrders writes the orders page by page. Orders can't be paged by
+// cursor, so they're paged by number in the order they were created.
+func exportOrders(ctx context.Context, orderBus *orderbus.Business, ew *exportWriter, filter exportFilter) (int, error) {
+	busFilter := orderbus.QueryFilter{
+		StartCreatedDate: filter.From,
+		EndCreatedDate:   filter.To,
+	}
+
+	orderBy := order.NewBy(orderbus.OrderByDateCreated, order.ASC)
+	rows := strconv.Itoa(exportRows)
+
+	var n int
+	for number := 1; ; number++ {
+		pg, err := page.Parse(strconv.Itoa(number), rows)
+		if err != nil {
+			return n, err
+		}
+
+		orders, err := orderBus.Query(ctx, busFilter, orderBy, pg)
+		if err != nil {
+			return n, fmt.Errorf("query: %w", err)
+		}
+
+		for _, o := range orders {
+			rec := toOrderRecord(o)
+			if err := ew.write(rec.row(), rec); err != nil {
+				return n, fmt.Errorf("writing: %w", err)
+			}
+			n++
+		}
+
+		if len(orders) < exportRows {
+			return n, nil
+		}
+	}
+}
+
+// =============================================================================
+
+// exportWriter writes the rows as CSV, with a header, or as one JSON object
+// per line.
+type exportWriter struct {
+	csv  *csv.Writer
+	json *json.Encoder
+}
+
+func newExportWriter(w io.Writer, format string, columns []string) *exportWriter {
+	if format == formatNDJSON {
+		return &exportWriter{json: json.NewEncoder(w)}
+	}
+
+	ew := exportWriter{csv: csv.NewWriter(w)}
+	ew.csv.Write(columns)
+
+	return &ew
+}
+
+// write writes a row, using the fields for CSV and the value for JSON.
+func (ew *exportWriter) write(fields []string, v any) error {
+	if ew.json != nil {
+		return ew.json.Encode(v)
+	}
+
+	return ew.csv.Write(fields)
+}
+
+// flush writes what the CSV writer buffers.
+func (ew *exportWriter) flush() error {
+	if ew.csv == nil {
+		return nil
+	}
+
+	ew.csv.Flush()
+
+	return ew.csv.Error()
+}
+
+// =============================================================================
+
+// recipeColumns are the CSV columns of a recipe. The tags are joined with a
+// semicolon and the metadata is a JSON object.
+var recipeColumns = []string{"id", "tenant_id", "user_id", "name", "description", "prep_minutes", "tags", "metadata", "version", "date_created", "date_updated", "date_deleted"}
+
+// recipeRecord is a recipe as it's exported.
+type recipeRecord struct {
+	ID          string         `json:"id"`
+	TenantID    string         `json:"tenantID"`
+	UserID      string         `json:"userID"`
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	PrepMinutes int            `json:"prepMinutes"`
+	Tags        []string       `json:"tags"`
+	Metadata    map[string]any `json:"metadata"`
+	Version     int            `json:"version"`
+	DateCreated string         `json:"dateCreated"`
+	DateUpdated string         `json:"dateUpdated"`
+	DateDeleted string         `json:"dateDeleted,omitempty"`
+}
+
+func toRecipeRecord(r recipebus.Recipe) recipeRecord {
+	tags := r.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+
+	metadata := r.Metadata
+	if metadata == nil {
+		metadata = map[string]any{}
+	}
+
+	rec := recipeRecord{
+		ID:          r.ID.String(),
+		TenantID:    r.TenantID.String(),
+		UserID:      r.UserID.String(),
+		Name:        r.Name,
+		Description: r.Description,
+		PrepMinutes: r.PrepMinutes,
+		Tags:        tags,
+		Metadata:    metadata,
+		Version:     r.Version,
+		DateCreated: r.DateCreated.UTC().Format(time.RFC3339Nano),
+		DateUpdated: r.DateUpdated.UTC().Format(time.RFC3339Nano),
+	}
+
+	if r.Deleted() {
+		rec.DateDeleted = r.DateDeleted.UTC().Format(time.RFC3339Nano)
+	}
+
+	return rec
+}
+
+// row returns the CSV fields of the recipe in the order of recipeColumns.
+func (rec recipeRecord) row() []string {
+	metadata, _ := json.Marshal(rec.Metadata)
+
+	return []string{
+		rec.ID,
+		rec.TenantID,
+		rec.UserID,
+		rec.Name,
+		rec.Description,
+		strconv.Itoa(rec.PrepMinutes),
+		strings.Join(rec.Tags, ";"),
+		string(metadata),
+		strconv.Itoa(rec.Version),
+		rec.DateCreated,
+		rec.DateUpdated,
+		rec.DateDeleted,
+	}
+}
+
+// =============================================================================
+
+// orderColumns are the CSV columns of an order. The items are a JSON array.
+var orderColumns = []string{"id", "tenant_id", "user_id", "items", "status", "total_cents", "date_created", "date_updated"}
+
+// orderRecord is an order as it's exported.
+type orderRecord struct {
+	ID          string            `json:"id"`
+	TenantID    string            `json:"tenantID"`
+	UserID      string            `json:"userID"`
+	Items       []orderItemRecord `json:"items"`
+	Status      string            `json:"status"`
+	TotalCents  int               `json:"totalCents"`
+	DateCreated string            `json:"dateCreated"`
+	DateUpdated string            `json:"dateUpdated"`
+}
+
+// orderItemRecord is an item of an order as it's exported.
+type orderItemRecord struct {
+	PastryID   string `json:"pastryID"`
+	Quantity   int    `json:"quantity"`
+	PriceCents int    `json:"priceCents"`
+}
+
+func toOrderRecord(o orderbus.Order) orderRecord {
+	items := make([]orderItemRecord, len(o.Items))
+	for i, it := range o.Items {
+		items[i] = orderItemRecord{
+			PastryID:   it.PastryID.String(),
+			Quantity:   it.Quantity,
+			PriceCents: it.PriceCents,
+		}
+	}
+
+	rec := orderRecord{
+		ID:          o.ID.String(),
+		TenantID:    o.TenantID.String(),
+		UserID:      o.UserID.String(),
+		Items:       items,
+		Status:      o.Status,
+		TotalCents:  o.TotalCents,
+		DateCreated: o.DateCreated.UTC().Format(time.RFC3339Nano),
+		DateUpdated: o.DateUpdated.UTC().Format(time.RFC3339Nano),
+	}
+
+	return rec
+}
+
+// row returns the CSV fields of the order in the order of orderColumns.
+func (rec orderRecord) row() []string {
+	items, _ := json.Marshal(rec.Items)
+
+	return []string{
+		rec.ID,
+		rec.TenantID,
+		rec.UserID,
+		string(items),
+		rec.Status,
+		strconv.Itoa(rec.TotalCents),
+		rec.DateCreated,
+		rec.DateUpdated,
+	}
+}
@@ -2,33 +2,126 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/base64"
 	"errors"
 	"expvar"
 	"fmt"
+	"io"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/ardanlabs/conf/v3"
-	"github.com/lmittmann/tint"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"golang.org/x/sync/errgroup"
+	"lobbyte.com/alkeepy/api/services/wasfa/build/all"
+	"lobbyte.com/alkeepy/api/services/wasfa/mux"
+	"lobbyte.com/alkeepy/app/api/auth"
+	"lobbyte.com/alkeepy/app/api/capture"
+	"lobbyte.com/alkeepy/app/api/debug"
+	"lobbyte.com/alkeepy/app/api/featureflag"
+	"lobbyte.com/alkeepy/app/api/metrics"
+	"lobbyte.com/alkeepy/app/api/mid"
+	"lobbyte.com/alkeepy/business/domain/auditbus"
+	"lobbyte.com/alkeepy/business/domain/auditbus/stores/auditdb"
+	"lobbyte.com/alkeepy/business/domain/auditbus/stores/auditmem"
+	"lobbyte.com/alkeepy/business/domain/orderbus"
+	"lobbyte.com/alkeepy/business/domain/orderbus/stores/orderdb"
+	"lobbyte.com/alkeepy/business/domain/recipebus"
+	"lobbyte.com/alkeepy/business/domain/recipebus/stores/recipedb"
+	"lobbyte.com/alkeepy/business/domain/recipebus/stores/recipemem"
+	"lobbyte.com/alkeepy/business/domain/userbus"
+	"lobbyte.com/alkeepy/business/domain/userbus/stores/userdb"
+	"lobbyte.com/alkeepy/business/domain/userbus/stores/usermem"
+	"lobbyte.com/alkeepy/business/sdk/migrate"
+	"lobbyte.com/alkeepy/business/sdk/outbox"
+	"lobbyte.com/alkeepy/business/sdk/retention"
+	"lobbyte.com/alkeepy/business/sdk/sqldb"
+	"lobbyte.com/alkeepy/foundation/buildinfo"
+	"lobbyte.com/alkeepy/foundation/config"
+	"lobbyte.com/alkeepy/foundation/envelope"
+	"lobbyte.com/alkeepy/foundation/errreport"
+	"lobbyte.com/alkeepy/foundation/health"
+	"lobbyte.com/alkeepy/foundation/limits"
+	"lobbyte.com/alkeepy/foundation/logger"
+	"lobbyte.com/alkeepy/foundation/otel"
+	"lobbyte.com/alkeepy/foundation/profiling"
+	"lobbyte.com/alkeepy/foundation/pubsub"
+	"lobbyte.com/alkeepy/foundation/statsd"
+	"lobbyte.com/alkeepy/foundation/tlscert"
+	"lobbyte.com/alkeepy/foundation/vault"
+	"lobbyte.com/alkeepy/foundation/web"
+	"lobbyte.com/alkeepy/foundation/worker"
 )
 
 var build = "develop"
 
+// defaultEnvironment is the profile used when no environment is selected.
+const defaultEnvironment = "development"
+
+// Set of backends the stores can be built on.
+const (
+	backendPostgres = "postgres"
+	backendMemory   = "memory"
+)
+
+// profiles holds the defaults of each deployment environment. Production and
+// staging log JSON, require TLS to the database and only expose the debug
+// host on the loopback interface. Development loads the seed data and seals
+// the users with a fixed key that mustn't be used anywhere else.
+var profiles = map[string]config.Profile{
+	"development": {
+		"LOG_FORMAT":            logger.FormatTint,
+		"DB_DISABLE_TLS":        "true",
+		"DB_SEED":               "true",
+		"WEB_DEBUG_HOST":        "0.0.0.0:3010",
+		"USERS_KEYRING":         "dev:VdFIbWhdhBFm1Eb0/ryFpDhCTd0k9T+/tw8MVkXUDSI=",
+		"USERS_KEYRING_PRIMARY": "dev",
+		"SENTRY_ENVIRONMENT":    "development",
+	},
+	"staging": {
+		"LOG_FORMAT":         logger.FormatJSON,
+		"DB_DISABLE_TLS":     "false",
+		"DB_SEED":            "false",
+		"WEB_DEBUG_HOST":     "127.0.0.1:3010",
+		"SENTRY_ENVIRONMENT": "staging",
+	},
+	"production": {
+		"LOG_FORMAT":         logger.FormatJSON,
+		"LOG_LEVEL":          "INFO",
+		"DB_DISABLE_TLS":     "false",
+		"DB_SEED":            "false",
+		"WEB_DEBUG_HOST":     "127.0.0.1:3010",
+		"SENTRY_ENVIRONMENT": "production",
+	},
+}
+
 func main() {
-	logger := slog.New(tint.NewHandler(os.Stderr, &tint.Options{
-		AddSource:  true,
-		Level:      slog.LevelDebug,
-		TimeFormat: time.DateTime,
-	})).With("service", "sales")
+
+	// This logger is used until the configuration is parsed and the
+	// configured logger can be constructed.
+	log, err := logger.New(os.Stderr, logger.FormatTint, slog.LevelDebug, "sales", nil)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
 
 	ctx := context.Background()
-	if err := run(ctx, logger); err != nil {
-		logger.ErrorContext(ctx, "startup", "msg", err)
+	if err := run(ctx, log); err != nil {
+		log.ErrorContext(ctx, "startup", "msg", err)
 		os.Exit(1)
 	}
 }
@@ -36,37 +129,195 @@ func main() {
 func run(ctx context.Context, log *slog.Logger) error {
 
 	// =========================================================================
-	// GOMAXPROCS
+	// Build Information
 
-	log.InfoContext(ctx, "startup", "GOMAXPROCS", runtime.GOMAXPROCS(0))
+	// The build set with ldflags is merged with the version control details
+	// so the running commit is always known.
+	bi := buildinfo.Read(build)
+
+	expvar.Publish("buildinfo", expvar.Func(func() any { return bi }))
 
 	// =========================================================================
 	// Configuration
 
 	cfg := struct {
 		conf.Version
-		Web struct {
-			ReadTimeout        time.Duration `conf:"default:5s"`
-			WriteTimeOut       time.Duration `conf:"default:10s"`
-			IdleTimeout        time.Duration `conf:"default:120s"`
-			ShutdownTimeout    time.Duration `conf:"default:20s"`
-			APIHost            string        `conf:"default:0.0.0.0:3000"`
-			DebugHost          string        `conf:"default:0.0.0.0:3010"`
-			CORSAllowedOrigins []string      `conf:"default:*"`
+		Args        conf.Args
+		Environment string          `conf:"default:development,env:ENVIRONMENT,flag:environment,help:development, staging or production"`
+		ConfigFile  string          `conf:"env:CONFIG_FILE,flag:config,help:path to a YAML or TOML config file"`
+		Features    map[string]bool `conf:"help:feature flag overrides like recipe-history:true"`
+		Runtime     struct {
+			MaxProcs      int     `conf:"default:0,help:overrides the CPU quota of the container"`
+			MemLimitMB    int     `conf:"default:0,help:overrides the memory limit of the container"`
+			MemLimitRatio float64 `conf:"default:0.9"`
+		}
+		DryRun bool `conf:"default:false,env:DRY_RUN,flag:dry-run,help:check the config and dependencies then exit"`
+		Web    struct {
+			ReadTimeout          time.Duration `conf:"default:5s"`
+			WriteTimeOut         time.Duration `conf:"default:10s"`
+			IdleTimeout          time.Duration `conf:"default:120s"`
+			ShutdownTimeout      time.Duration `conf:"default:20s"`
+			DrainDelay           time.Duration `conf:"default:5s"`
+			APIHost              string        `conf:"default:0.0.0.0:3000"`
+			DebugHost            string        `conf:"default:0.0.0.0:3010"`
+			InternalHost         string
+			CORSAllowedOrigins   []string      `conf:"default:*"`
+			CORSAllowCredentials bool          `conf:"default:false"`
+			MaxBodyBytes         int64         `conf:"default:1048576"`
+			CompressThreshold    int           `conf:"default:1024"`
+			RequestTimeout       time.Duration `conf:"default:8s"`
+			MetricsInterval      time.Duration `conf:"default:10s"`
+			EnableH2C            bool          `conf:"default:false,env:WEB_ENABLE_H2C,flag:web-enable-h2c"`
+			TrustedProxies       []string
+			TLS                  struct {
+				CertFile          string
+				KeyFile           string
+				ClientCAFile      string
+				RequireClientCert bool          `conf:"default:false"`
+				WatchInterval     time.Duration `conf:"default:1m"`
+			}
 		}
 		DB struct {
-			MaxIdleConns int  `conf:"default:0"`
-			MaxOpenConns int  `conf:"default:0"`
-			DisableTLS   bool `conf:"default:true"`
+			Backend                string        `conf:"default:postgres,help:postgres or memory to run without a database"`
+			User                   string        `conf:"default:postgres"`
+			Password               string        `conf:"default:postgres,mask"`
+			Host                   string        `conf:"default:localhost"`
+			Port                   int           `conf:"default:5432"`
+			Name                   string        `conf:"default:postgres"`
+			SSLMode                string        `conf:"default:require"`
+			MaxIdleConns           int           `conf:"default:2"`
+			MaxOpenConns           int           `conf:"default:0"`
+			DisableTLS             bool          `conf:"help:set by the profile of the environment"`
+			SlowQueryThreshold     time.Duration `conf:"default:200ms"`
+			QueryTimeout           time.Duration `conf:"default:5s,help:how long a query may run when its request or job doesn't end sooner - 0 disables the limit"`
+			Migrate                bool          `conf:"default:false,help:apply the pending migrations at startup"`
+			Seed                   bool          `conf:"help:apply the pending migrations and load the baseline dataset at startup"`
+			ReplicaHost            string        `conf:"help:read replica host used by the queries that only read"`
+			ReplicaPort            int           `conf:"default:5432"`
+			Driver                 string        `conf:"default:stdlib,help:stdlib for the database/sql pool or pgxpool for the native pgx pool"`
+			QueryExecMode          string        `conf:"default:cache_statement,help:how pgx prepares statements: cache_statement or cache_describe or describe_exec or exec or simple_protocol"`
+			StatementCacheCapacity int           `conf:"default:512,help:prepared statements cached per connection"`
+		}
+		Log struct {
+			Format     string     `conf:"default:tint"`
+			Level      slog.Level `conf:"default:INFO"`
+			SampleRate int64      `conf:"default:1"`
+			File       struct {
+				Path       string
+				MaxSizeMB  int  `conf:"default:100"`
+				MaxAgeDays int  `conf:"default:7"`
+				MaxBackups int  `conf:"default:5"`
+				Compress   bool `conf:"default:true"`
+			}
+		}
+		Debug struct {
+			Token       string `conf:"mask"`
+			DumpDir     string
+			CaptureSize int `conf:"default:100"`
+		}
+		Vault struct {
+			Address   string
+			Token     string `conf:"mask"`
+			Namespace string
+			Mount     string `conf:"default:database"`
+			Role      string
+		}
+		Sentry struct {
+			DSN         string  `conf:"mask"`
+			Environment string  `conf:"default:development"`
+			SampleRate  float64 `conf:"default:1"`
+		}
+		Pyroscope struct {
+			ServerAddress     string
+			ApplicationName   string `conf:"default:wasfa"`
+			BasicAuthUser     string
+			BasicAuthPassword string `conf:"mask"`
+			TenantID          string
+			UploadRate        time.Duration `conf:"default:15s"`
+		}
+		Recipe struct {
+			PurgeInterval  time.Duration `conf:"default:1h"`
+			PurgeRetention time.Duration `conf:"default:720h,help:how long a deleted recipe can be restored before it's purged"`
+		}
+		Users struct {
+			KeyringPrimary  string        `conf:"help:id of the key the phone and address of new users are sealed with"`
+			Keyring         []string      `conf:"mask,help:keys of the keyring as id:base64 separated by ; - random keys are used with the memory backend when empty"`
+			RewrapInterval  time.Duration `conf:"default:1h,help:how often the users sealed with a key that's no longer primary are sealed again"`
+			RewrapBatchSize int           `conf:"default:100"`
+		}
+		Tenant struct {
+			Default string `conf:"default:e7a1c2d4-5b6f-4a8e-9c0d-1f2a3b4c5d6e,help:tenant of the requests that don't name one - the nil uuid makes every request name one"`
+		}
+		Auth struct {
+			KeysFolder string `conf:"help:folder of the <kid>.pem keys bearer tokens are verified with - bearer tokens are refused when empty"`
+			Issuer     string `conf:"default:alkeepy,help:issuer the bearer tokens must come from"`
+			Audience   string `conf:"help:audience the bearer tokens must be meant for when set"`
+		}
+		Export struct {
+			Format         string `conf:"default:ndjson,help:csv or ndjson"`
+			Tenant         string `conf:"help:tenant to export - the default tenant when empty"`
+			From           string `conf:"help:exports the rows created at or after this RFC 3339 time or date"`
+			To             string `conf:"help:exports the rows created before this RFC 3339 time or date"`
+			IncludeDeleted bool   `conf:"help:exports the deleted recipes too"`
+			Output         string `conf:"help:file the rows are written to - stdout when empty"`
+		}
+		Retention struct {
+			Interval  time.Duration `conf:"default:1h"`
+			Audits    time.Duration `conf:"default:8760h,help:how long audit rows stay in the audits table before they're archived"`
+			Outbox    time.Duration `conf:"default:168h,help:how long published outbox messages are kept to trace what was sent"`
+			Orders    time.Duration `conf:"default:17520h,help:how long fulfilled and cancelled orders are kept before they're purged"`
+			BatchSize int           `conf:"default:1000"`
+		}
+		Outbox struct {
+			URL       string        `conf:"mask,help:endpoint of the message bus the outbox is published to - the messages wait in the outbox until it's set"`
+			Interval  time.Duration `conf:"default:1s"`
+			BatchSize int           `conf:"default:100"`
+			Timeout   time.Duration `conf:"default:5s"`
+		}
+		Alert struct {
+			Window      time.Duration `conf:"default:1m"`
+			Threshold   float64       `conf:"default:0.05"`
+			MinRequests int64         `conf:"default:20"`
+			WebhookURL  string        `conf:"mask"`
+		}
+		StatsD struct {
+			Host   string
+			Prefix string `conf:"default:wasfa"`
+			Tags   []string
+		}
+		Tempo struct {
+			Host        string
+			ServiceName string  `conf:"default:wasfa"`
+			Probability float64 `conf:"default:0.05"`
 		}
 	}{
 		Version: conf.Version{
-			Build: build,
+			Build: bi.Build,
 			Desc:  "pastry",
 		},
 	}
 
-	if help, err := conf.Parse("", &cfg); err != nil {
+	// The environment selects a profile of defaults so a single variable puts
+	// the service in its production posture.
+	environment := config.Environment(os.Args[1:])
+	if environment == "" {
+		environment = defaultEnvironment
+	}
+
+	profile, err := config.SelectProfile(profiles, environment)
+	if err != nil {
+		return fmt.Errorf("selecting profile: %w", err)
+	}
+
+	// A copy of the unparsed configuration is kept so a reload starts from
+	// the same defaults.
+	defaults := cfg
+
+	// Deployments can mount the configuration as a file and secrets can be
+	// read from mounted files with the _FILE variant of their environment
+	// variable. The environment and flags take precedence. Values like
+	// aws-sm://wasfa/db-password are fetched from AWS.
+	if help, err := config.Parse(ctx, "", &cfg, profile); err != nil {
 		if errors.Is(err, conf.ErrHelpWanted) {
 			fmt.Println(help)
 			return nil
@@ -74,23 +325,595 @@ func run(ctx context.Context, log *slog.Logger) error {
 		return fmt.Errorf("parsing config: %w", err)
 	}
 
+	// The first argument selects the command. The service is run when none
+	// is given.
+	command := cfg.Args.Num(0)
+	switch command {
+	case "", "serve":
+	case "version":
+		return version(os.Stdout, bi)
+	case "migrate":
+		if sub := cfg.Args.Num(1); sub != "" && sub != "status" {
+			return fmt.Errorf("unknown migrate command %q, expected status", sub)
+		}
+	case "seed":
+	case "export":
+		if sub := cfg.Args.Num(1); sub != exportRecipesDomain && sub != exportOrdersDomain {
+			return fmt.Errorf("unknown export %q, expected %s or %s", sub, exportRecipesDomain, exportOrdersDomain)
+		}
+		if f := cfg.Export.Format; f != formatCSV && f != formatNDJSON {
+			return fmt.Errorf("unknown export format %q, expected %s or %s", f, formatCSV, formatNDJSON)
+		}
+	default:
+		return fmt.Errorf("unknown command %q, expected one of serve, migrate, migrate status, seed, export or version", command)
+	}
+
+	// The connection URL is built from the discrete fields so each one can be
+	// set and validated on its own.
+	dbCfg := sqldb.Config{
+		User:         cfg.DB.User,
+		Password:     cfg.DB.Password,
+		Host:         cfg.DB.Host,
+		Port:         cfg.DB.Port,
+		Name:         cfg.DB.Name,
+		SSLMode:      cfg.DB.SSLMode,
+		DisableTLS:   cfg.DB.DisableTLS,
+		MaxIdleConns: cfg.DB.MaxIdleConns,
+		MaxOpenConns: cfg.DB.MaxOpenConns,
+
+		Driver:                 cfg.DB.Driver,
+		QueryExecMode:          cfg.DB.QueryExecMode,
+		StatementCacheCapacity: cfg.DB.StatementCacheCapacity,
+	}
+
+	// Constraints conf can't express are checked before anything is started
+	// and every violation is reported at once.
+	var violations config.Violations
+
+	violations.Check(cfg.Web.ShutdownTimeout > cfg.Web.WriteTimeOut, "WEB_SHUTDOWN_TIMEOUT", "must be longer than WEB_WRITE_TIME_OUT (%s)", cfg.Web.WriteTimeOut)
+	violations.Check(cfg.Web.RequestTimeout < cfg.Web.WriteTimeOut, "WEB_REQUEST_TIMEOUT", "must be shorter than WEB_WRITE_TIME_OUT (%s) so the timeout response can be written", cfg.Web.WriteTimeOut)
+	violations.Check(cfg.Web.MaxBodyBytes > 0, "WEB_MAX_BODY_BYTES", "must be positive")
+	violations.Check(cfg.Web.MetricsInterval > 0, "WEB_METRICS_INTERVAL", "must be positive")
+	violations.CheckErr(web.ValidateAddr(cfg.Web.APIHost), "WEB_API_HOST")
+	violations.CheckErr(web.ValidateAddr(cfg.Web.DebugHost), "WEB_DEBUG_HOST")
+	violations.Check(cfg.Web.APIHost != cfg.Web.DebugHost, "WEB_DEBUG_HOST", "must differ from WEB_API_HOST")
+	if cfg.Web.InternalHost != "" {
+		violations.CheckErr(web.ValidateAddr(cfg.Web.InternalHost), "WEB_INTERNAL_HOST")
+		violations.Check(cfg.Web.InternalHost != cfg.Web.APIHost && cfg.Web.InternalHost != cfg.Web.DebugHost, "WEB_INTERNAL_HOST", "must differ from WEB_API_HOST and WEB_DEBUG_HOST")
+	}
+	violations.CheckErr(mid.ValidateOrigins(cfg.Web.CORSAllowedOrigins, cfg.Web.CORSAllowCredentials), "WEB_CORS_ALLOWED_ORIGINS")
+	_, err = mid.ParseTrustedProxies(cfg.Web.TrustedProxies)
+	violations.CheckErr(err, "WEB_TRUSTED_PROXIES")
+	violations.Check((cfg.Web.TLS.CertFile == "") == (cfg.Web.TLS.KeyFile == ""), "WEB_TLS_KEY_FILE", "must be set together with WEB_TLS_CERT_FILE")
+	violations.Check(!cfg.Web.TLS.RequireClientCert || cfg.Web.TLS.ClientCAFile != "", "WEB_TLS_CLIENT_CA_FILE", "is required when WEB_TLS_REQUIRE_CLIENT_CERT is set")
+	violations.Check(cfg.DB.Backend == backendPostgres || cfg.DB.Backend == backendMemory, "DB_BACKEND", "must be %q or %q", backendPostgres, backendMemory)
+	violations.CheckErr(dbCfg.Validate(), "DB")
+	violations.Check(cfg.DB.ReplicaPort > 0 && cfg.DB.ReplicaPort <= 65535, "DB_REPLICA_PORT", "port %d is out of range", cfg.DB.ReplicaPort)
+	violations.Check(cfg.DB.QueryTimeout >= 0, "DB_QUERY_TIMEOUT", "must not be negative")
+	violations.Check(cfg.DB.QueryTimeout < cfg.Web.ShutdownTimeout, "DB_QUERY_TIMEOUT", "must be shorter than WEB_SHUTDOWN_TIMEOUT (%s)", cfg.Web.ShutdownTimeout)
+	violations.Check(cfg.DB.MaxOpenConns >= 0, "DB_MAX_OPEN_CONNS", "must not be negative")
+	violations.Check(cfg.DB.MaxIdleConns >= 0, "DB_MAX_IDLE_CONNS", "must not be negative")
+	violations.Check(cfg.DB.MaxOpenConns == 0 || cfg.DB.MaxIdleConns <= cfg.DB.MaxOpenConns, "DB_MAX_IDLE_CONNS", "must not exceed DB_MAX_OPEN_CONNS (%d)", cfg.DB.MaxOpenConns)
+	violations.Check(cfg.Recipe.PurgeInterval > 0, "RECIPE_PURGE_INTERVAL", "must be positive")
+	violations.Check(cfg.Recipe.PurgeRetention >= 0, "RECIPE_PURGE_RETENTION", "must not be negative")
+	_, err = uuid.Parse(cfg.Tenant.Default)
+	violations.CheckErr(err, "TENANT_DEFAULT")
+	violations.Check(cfg.Log.Format == logger.FormatTint || cfg.Log.Format == logger.FormatJSON, "LOG_FORMAT", "must be %q or %q", logger.FormatTint, logger.FormatJSON)
+	violations.Check(cfg.Log.SampleRate >= 1, "LOG_SAMPLE_RATE", "must be 1 or more")
+	violations.Check(cfg.Runtime.MaxProcs >= 0, "RUNTIME_MAX_PROCS", "must not be negative")
+	violations.Check(cfg.Runtime.MemLimitMB >= 0, "RUNTIME_MEM_LIMIT_MB", "must not be negative")
+	violations.Check(cfg.Runtime.MemLimitRatio > 0 && cfg.Runtime.MemLimitRatio <= 1, "RUNTIME_MEM_LIMIT_RATIO", "must be above 0 and at most 1")
+	violations.Check(cfg.Debug.CaptureSize >= 0, "DEBUG_CAPTURE_SIZE", "must not be negative")
+	violations.Check(cfg.Vault.Address == "" || cfg.Vault.Role != "", "VAULT_ROLE", "is required when VAULT_ADDRESS is set")
+	violations.Check(cfg.Sentry.SampleRate >= 0 && cfg.Sentry.SampleRate <= 1, "SENTRY_SAMPLE_RATE", "must be between 0 and 1")
+	violations.Check(cfg.Retention.Interval > 0, "RETENTION_INTERVAL", "must be positive")
+	violations.Check(cfg.Retention.Audits > 0, "RETENTION_AUDITS", "must be positive")
+	violations.Check(cfg.Retention.Outbox > 0, "RETENTION_OUTBOX", "must be positive")
+	violations.Check(cfg.Retention.Orders > 0, "RETENTION_ORDERS", "must be positive")
+	violations.Check(cfg.Retention.BatchSize > 0, "RETENTION_BATCH_SIZE", "must be positive")
+	violations.Check(cfg.Outbox.Interval > 0, "OUTBOX_INTERVAL", "must be positive")
+	violations.Check(cfg.Outbox.BatchSize > 0, "OUTBOX_BATCH_SIZE", "must be positive")
+	violations.Check(cfg.Alert.Window > 0, "ALERT_WINDOW", "must be positive")
+	violations.Check(cfg.Alert.Threshold > 0 && cfg.Alert.Threshold <= 1, "ALERT_THRESHOLD", "must be above 0 and at most 1")
+	violations.Check(cfg.Tempo.Probability >= 0 && cfg.Tempo.Probability <= 1, "TEMPO_PROBABILITY", "must be between 0 and 1")
+
+	if err := violations.Err(); err != nil {
+		return err
+	}
+
+	// =========================================================================
+	// Logging
+
+	// The level can be changed while the service is running.
+	level := new(slog.LevelVar)
+	level.Set(cfg.Log.Level)
+
+	// Deployments without a log collector capturing stderr can also write
+	// the logs to a file that is rotated.
+	var logWriter io.Writer = os.Stderr
+
+	if cfg.Log.File.Path != "" {
+		logFile := logger.NewFile(logger.FileConfig{
+			Path:       cfg.Log.File.Path,
+			MaxSizeMB:  cfg.Log.File.MaxSizeMB,
+			MaxAgeDays: cfg.Log.File.MaxAgeDays,
+			MaxBackups: cfg.Log.File.MaxBackups,
+			Compress:   cfg.Log.File.Compress,
+		})
+		defer logFile.Close()
+
+		logWriter = io.MultiWriter(os.Stderr, logFile)
+	}
+
+	// Records logged with the context of a request carry its trace id.
+	traceIDFn := func(ctx context.Context) string {
+		traceID, _ := web.LookupTraceID(ctx)
+		return traceID
+	}
+
+	log, err = logger.New(logWriter, cfg.Log.Format, level, "sales", traceIDFn)
+	if err != nil {
+		return fmt.Errorf("constructing logger: %w", err)
+	}
+
+	// =========================================================================
+	// Runtime Limits
+
+	// The CPU and memory limits of the container are applied to the runtime
+	// so the service isn't throttled or killed under Kubernetes limits.
+	err = limits.Set(log, limits.Config{
+		MaxProcs:      cfg.Runtime.MaxProcs,
+		MemLimitMB:    cfg.Runtime.MemLimitMB,
+		MemLimitRatio: cfg.Runtime.MemLimitRatio,
+	})
+	if err != nil {
+		return fmt.Errorf("setting runtime limits: %w", err)
+	}
+
+	log.InfoContext(ctx, "startup", "GOMAXPROCS", runtime.GOMAXPROCS(0), "GOMEMLIMIT", limits.MemLimit())
+
 	// =========================================================================
 	// App Starting
 
 	log.InfoContext(ctx, "starting service", "version", cfg.Build)
 	defer log.InfoContext(ctx, "shutdown complete")
 
-	log.InfoContext(ctx, "startup", "conf", cfg)
+	// Secrets are masked so the configuration can be logged as a whole.
+	log.InfoContext(ctx, "startup", "conf", config.Sanitize(cfg))
 
-	expvar.NewString("build").Set(cfg.Build)
+	// =========================================================================
+	// Dry Run
 
-	// -------------------------------------------------------------------------
-	// Start Debug Service
+	// A dry run stops once the configuration is valid and the dependencies
+	// can be reached, so a deployment can be checked before it's rolled out.
+	if cfg.DryRun {
+		checks := health.New(5 * time.Second)
+
+		checks.Register("config", 0, func(ctx context.Context) error {
+			return nil
+		})
+
+		if cfg.DB.Backend == backendPostgres {
+			checks.Register("db", 0, func(ctx context.Context) error {
+				return dial(ctx, net.JoinHostPort(dbCfg.Host, strconv.Itoa(dbCfg.Port)))
+			})
+		}
 
-	go func() {
-		log.InfoContext(ctx, "startup", "status", "debug v1 router started", "host", cfg.Web.DebugHost)
+		if cfg.Vault.Address != "" {
+			checks.Register("vault", 0, func(ctx context.Context) error {
+				vc, err := vault.New(vault.Config{
+					Address:   cfg.Vault.Address,
+					Token:     cfg.Vault.Token,
+					Namespace: cfg.Vault.Namespace,
+					Mount:     cfg.Vault.Mount,
+					Role:      cfg.Vault.Role,
+				})
+				if err != nil {
+					return err
+				}
+				return vc.Check(ctx)
+			})
+		}
 
-	}()
+		if cfg.Tempo.Host != "" {
+			checks.Register("tempo", 0, func(ctx context.Context) error {
+				return dial(ctx, cfg.Tempo.Host)
+			})
+		}
+
+		report := checks.Run(ctx)
+		if err := report.Print(os.Stdout); err != nil {
+			return fmt.Errorf("printing report: %w", err)
+		}
+
+		if !report.Healthy() {
+			return errors.New("dry run failed")
+		}
+
+		return nil
+	}
+
+	// =========================================================================
+	// Commands
+
+	if (command == "migrate" || command == "seed" || command == "export") && cfg.DB.Backend != backendPostgres {
+		return fmt.Errorf("the %s command requires the %s backend", command, backendPostgres)
+	}
+
+	switch command {
+	case "migrate":
+		if cfg.Args.Num(1) == "status" {
+			return migrateStatus(ctx, os.Stdout, dbCfg)
+		}
+		return migrateDB(ctx, log, dbCfg)
+	case "seed":
+		return seedDB(ctx, log, dbCfg)
+	case "export":
+		filter, err := parseExportFilter(cfg.Export.Tenant, cfg.Tenant.Default, cfg.Export.From, cfg.Export.To, cfg.Export.IncludeDeleted)
+		if err != nil {
+			return fmt.Errorf("export: %w", err)
+		}
+		return exportTo(ctx, log, cfg.Export.Output, dbCfg, cfg.Args.Num(1), cfg.Export.Format, filter)
+	}
+
+	// Background workers are tracked so the ones that don't stop when the
+	// background context is canceled are reported at shutdown.
+	workers := worker.New()
+
+	bgCtx, cancelBackground := context.WithCancel(ctx)
+	defer cancelBackground()
+
+	workers.Go("metrics sampler", func() {
+		metrics.Sample(bgCtx, cfg.Web.MetricsInterval)
+	})
+
+	// A cheap built-in alarm until alerting is in place.
+	workers.Go("error rate watcher", func() {
+		metrics.WatchErrorRate(bgCtx, log, metrics.WatchConfig{
+			Window:      cfg.Alert.Window,
+			Threshold:   cfg.Alert.Threshold,
+			MinRequests: cfg.Alert.MinRequests,
+			WebhookURL:  cfg.Alert.WebhookURL,
+			Client:      otel.NewClient(5 * time.Second),
+		})
+	})
+
+	// Teams running a Datadog agent receive the metrics over StatsD.
+	if cfg.StatsD.Host != "" {
+		sd, err := statsd.New(statsd.Config{
+			Host:   cfg.StatsD.Host,
+			Prefix: cfg.StatsD.Prefix,
+			Tags:   append(cfg.StatsD.Tags, "version:"+bi.Build),
+		})
+		if err != nil {
+			return fmt.Errorf("starting statsd: %w", err)
+		}
+		defer sd.Close()
+
+		metrics.SetSink(sd)
+
+		log.InfoContext(ctx, "startup", "status", "statsd sink started", "host", cfg.StatsD.Host)
+	}
+
+	// =========================================================================
+	// Start Tracing Support
+
+	log.InfoContext(ctx, "startup", "status", "initializing tracing support")
+
+	traceProvider, teardown, err := otel.InitTracing(log, otel.Config{
+		ServiceName: cfg.Tempo.ServiceName,
+		Host:        cfg.Tempo.Host,
+		Probability: cfg.Tempo.Probability,
+	})
+	if err != nil {
+		return fmt.Errorf("starting tracing: %w", err)
+	}
+
+	defer teardown(context.Background())
+
+	tracer := traceProvider.Tracer(cfg.Tempo.ServiceName)
+
+	// =========================================================================
+	// Database Support
+
+	sqldb.SetSlowQueryThreshold(cfg.DB.SlowQueryThreshold)
+	sqldb.SetQueryTimeout(cfg.DB.QueryTimeout)
+
+	// The memory backend lets the API run without Postgres during
+	// development. There's no schema to migrate and the data is lost when
+	// the process exits.
+	var db *sqlx.DB
+	var cluster *sqldb.Cluster
+
+	if cfg.DB.Backend == backendPostgres {
+		// Dynamic credentials are generated by Vault when it's configured. The
+		// lease is renewed in the background and the credentials are replaced
+		// before the lease reaches its maximum TTL.
+		var vc *vault.Client
+		var dbCreds atomic.Pointer[vault.Credentials]
+
+		if cfg.Vault.Address != "" {
+			vc, err = vault.New(vault.Config{
+				Address:   cfg.Vault.Address,
+				Token:     cfg.Vault.Token,
+				Namespace: cfg.Vault.Namespace,
+				Mount:     cfg.Vault.Mount,
+				Role:      cfg.Vault.Role,
+			})
+			if err != nil {
+				return fmt.Errorf("constructing vault client: %w", err)
+			}
+
+			creds, err := vc.Credentials(ctx)
+			if err != nil {
+				return fmt.Errorf("fetching database credentials: %w", err)
+			}
+			dbCreds.Store(&creds)
+
+			// New connections always use the latest credentials.
+			dbCfg.Credentials = func() (string, string) {
+				creds := dbCreds.Load()
+				return creds.Username, creds.Password
+			}
+
+			log.InfoContext(ctx, "startup", "status", "database credentials fetched", "vault", cfg.Vault.Address, "username", creds.Username, "lease_duration", creds.LeaseDuration)
+		}
+
+		log.InfoContext(ctx, "startup", "status", "initializing database support", "host", dbCfg.Host, "name", dbCfg.Name, "driver", dbCfg.Driver)
+
+		db, err = sqldb.Open(dbCfg)
+		if err != nil {
+			return fmt.Errorf("connecting to db: %w", err)
+		}
+
+		defer db.Close()
+
+		// Reads are routed to the replica while it's healthy. It's reached with
+		// the same credentials as the primary, which are rotated for both.
+		var replica *sqlx.DB
+
+		if cfg.DB.ReplicaHost != "" {
+			replicaCfg := dbCfg
+			replicaCfg.Host, replicaCfg.Port = cfg.DB.ReplicaHost, cfg.DB.ReplicaPort
+
+			log.InfoContext(ctx, "startup", "status", "initializing database replica support", "host", replicaCfg.Host)
+
+			replica, err = sqldb.Open(replicaCfg)
+			if err != nil {
+				return fmt.Errorf("connecting to db replica: %w", err)
+			}
+
+			defer replica.Close()
+		}
+
+		cluster = sqldb.NewCluster(db, replica)
+
+		// The pool statistics tell whether MaxOpenConns is holding requests
+		// back.
+		workers.Go("db stats sampler", func() {
+			metrics.SampleDB(bgCtx, cfg.Web.MetricsInterval, "primary", db.Stats)
+		})
+
+		if replica != nil {
+			workers.Go("replica monitor", func() {
+				cluster.Monitor(bgCtx, log, 5*time.Second)
+			})
+
+			workers.Go("db replica stats sampler", func() {
+				metrics.SampleDB(bgCtx, cfg.Web.MetricsInterval, "replica", replica.Stats)
+			})
+		}
+
+		// Small deployments can apply the migrations as the service starts
+		// instead of running the migrate command before rolling it out.
+		// Seeding needs the schema, so like the seed command it migrates too.
+		if cfg.DB.Migrate || cfg.DB.Seed {
+			if err := migrate.Migrate(ctx, log, db); err != nil {
+				return fmt.Errorf("migrating db: %w", err)
+			}
+		}
+
+		if cfg.DB.Seed {
+			if err := migrate.Seed(ctx, log, db); err != nil {
+				return fmt.Errorf("seeding db: %w", err)
+			}
+		}
+
+		if vc != nil {
+			// The primary and the replica open new connections with the latest
+			// credentials and both drivers discard the connections still using
+			// the previous user as they're reused.
+			rotate := func(ctx context.Context, creds vault.Credentials) error {
+				dbCreds.Store(&creds)
+				return nil
+			}
+
+			creds := *dbCreds.Load()
+
+			workers.Go("vault lease keeper", func() {
+				vc.Keep(bgCtx, log, creds, rotate)
+			})
+		}
+	}
+
+	// =========================================================================
+	// Health Support
+
+	// Subsystems register a named check as they're started so readiness
+	// reports which dependency is failing.
+	healthChecks := health.New(time.Second)
+
+	if db != nil {
+		healthChecks.Register("db", 0, func(ctx context.Context) error {
+			return sqldb.StatusCheck(ctx, db)
+		})
+	}
+
+	// =========================================================================
+	// Start Error Reporting Support
+
+	reporter, err := errreport.New(errreport.Config{
+		DSN:         cfg.Sentry.DSN,
+		Environment: cfg.Sentry.Environment,
+		Release:     bi.Build,
+		SampleRate:  cfg.Sentry.SampleRate,
+	})
+	if err != nil {
+		return fmt.Errorf("starting error reporting: %w", err)
+	}
+
+	defer reporter.Flush(2 * time.Second)
+
+	// =========================================================================
+	// Start Profiling Support
+
+	stopProfiling, err := profiling.Start(log, profiling.Config{
+		ServerAddress:     cfg.Pyroscope.ServerAddress,
+		ApplicationName:   cfg.Pyroscope.ApplicationName,
+		BasicAuthUser:     cfg.Pyroscope.BasicAuthUser,
+		BasicAuthPassword: cfg.Pyroscope.BasicAuthPassword,
+		TenantID:          cfg.Pyroscope.TenantID,
+		UploadRate:        cfg.Pyroscope.UploadRate,
+		Tags: map[string]string{
+			"version": bi.Build,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("starting profiling: %w", err)
+	}
+
+	defer stopProfiling()
+
+	// =========================================================================
+	// Create Business Packages
+
+	// Audit records are written to their own logger so they can be routed
+	// separately from the application logs.
+	var auditStore auditbus.Storer = auditmem.NewStore()
+	if cluster != nil {
+		auditStore = auditdb.NewStore(log, cluster)
+	}
+
+	auditBus := auditbus.NewBusiness(log.With("log", "audit"), auditStore)
+
+	// The phone and address of the users are sealed with the keyring. A key
+	// is rotated by adding a new one as primary, then removing the old one
+	// once the users sealed with it have all been rewrapped.
+	keys, err := newKeyring(cfg.Users.KeyringPrimary, cfg.Users.Keyring, cluster != nil)
+	if err != nil {
+		return fmt.Errorf("users keyring: %w", err)
+	}
+
+	var userStore userbus.Storer
+	if cluster != nil {
+		userStore = userdb.NewStore(log, cluster, keys)
+	} else {
+		if userStore, err = usermem.NewStore(keys); err != nil {
+			return fmt.Errorf("users store: %w", err)
+		}
+	}
+
+	userBus := userbus.NewBusiness(log, auditBus, userStore)
+
+	workers.Go("user rewrapper", func() {
+		userBus.RewrapEvery(bgCtx, cfg.Users.RewrapInterval, cfg.Users.RewrapBatchSize)
+	})
+
+	// Every change made to the recipes is fanned out to the clients following
+	// them. The database notifies the changes, whichever instance made them,
+	// and without one the store kept in memory reports the changes of this
+	// instance. The streams are the only subscribers, there's no cache of the
+	// recipes to invalidate.
+	recipeEvents := pubsub.New[recipebus.Event]()
+
+	memStore := recipemem.NewStore()
+	memStore.OnChange(recipeEvents.Publish)
+
+	var recipeStore recipebus.Storer = memStore
+	if cluster != nil {
+		recipeStore = recipedb.NewStore(log, cluster)
+	}
+
+	recipeBus := recipebus.NewBusiness(log, recipeStore)
+
+	workers.Go("recipe purger", func() {
+		recipeBus.PurgeEvery(bgCtx, cfg.Recipe.PurgeInterval, cfg.Recipe.PurgeRetention)
+	})
+
+	// The events written to the outbox with the changes are published to
+	// the message bus by a relay on every instance. The relays share the
+	// work, and a bus that's down only delays the events.
+	if cluster != nil && cfg.Outbox.URL != "" {
+		relay := outbox.NewRelay(log, db, outbox.NewHTTPPublisher(cfg.Outbox.URL, otel.NewClient(cfg.Outbox.Timeout)), cfg.Outbox.BatchSize)
+
+		workers.Go("outbox relay", func() {
+			relay.Run(bgCtx, cfg.Outbox.Interval)
+		})
+	}
+
+	// Expired rows are removed from the primary tables in the background.
+	// Audits, written to the database by the audit store, are moved to an
+	// archive. Published outbox messages are never sent again, so they're
+	// only kept to trace what was sent. Orders are purged once they're
+	// fulfilled or cancelled, those in progress are kept however old. The
+	// service doesn't store idempotency keys yet, so their policy is added
+	// along with the table that keeps them.
+	if cluster != nil {
+		orderBus := orderbus.NewBusiness(log, orderdb.NewStore(log, cluster))
+
+		policies := []retention.Policy{
+			{
+				Name:      "audits",
+				Retention: cfg.Retention.Audits,
+				Remove: retention.Rows(log, db, retention.Table{
+					Name:      "audits",
+					Column:    "timestamp",
+					ArchiveTo: "audits_archive",
+					BatchSize: cfg.Retention.BatchSize,
+				}),
+			},
+			{
+				Name:      "outbox",
+				Retention: cfg.Retention.Outbox,
+				Remove: retention.Rows(log, db, retention.Table{
+					Name:      "outbox",
+					Column:    "date_published",
+					BatchSize: cfg.Retention.BatchSize,
+				}),
+			},
+			{
+				Name:      "orders",
+				Retention: cfg.Retention.Orders,
+				Remove:    orderBus.Purge,
+			},
+		}
+
+		workers.Go("retention", func() {
+			retention.Every(bgCtx, log, cfg.Retention.Interval, policies)
+		})
+	}
+
+	// The notifications of the database are published to the recipe events.
+	// A resync is published after a reconnect since the ones sent while
+	// disconnected are lost.
+	if cluster != nil {
+		workers.Go("db listener", func() {
+			sqldb.Listen(bgCtx, log, dbCfg, []string{recipebus.EventChannel}, func(ctx context.Context, n *sqldb.Notification) {
+				if n == nil {
+					recipeEvents.Publish(recipebus.Event{Op: recipebus.EventResync})
+					return
+				}
+
+				ev, err := recipebus.ParseEvent(n.Payload)
+				if err != nil {
+					log.ErrorContext(ctx, "database.listen", "channel", n.Channel, "msg", err)
+					return
+				}
+
+				recipeEvents.Publish(ev)
+			})
+		})
+	}
 
 	// =========================================================================
 	// Start API Service
@@ -102,49 +925,488 @@ func run(ctx context.Context, log *slog.Logger) error {
 	shutdown := make(chan os.Signal, 1)
 	signal.Notify(shutdown, syscall.SIGINT, syscall.SIGTERM)
 
+	trustedProxies, err := mid.ParseTrustedProxies(cfg.Web.TrustedProxies)
+	if err != nil {
+		return fmt.Errorf("parsing trusted proxies: %w", err)
+	}
+
+	defaultTenant, err := uuid.Parse(cfg.Tenant.Default)
+	if err != nil {
+		return fmt.Errorf("parsing default tenant: %w", err)
+	}
+
+	// Draining is flipped when shutdown starts so readiness checks fail and
+	// traffic is routed elsewhere before the listener is closed.
+	var draining atomic.Bool
+
+	// The request log sample rate can be changed on the debug host.
+	var logSampleRate atomic.Int64
+	logSampleRate.Store(cfg.Log.SampleRate)
+
+	// Body capture is off until it's enabled on the debug host.
+	captures := capture.NewBuffer(cfg.Debug.CaptureSize)
+
+	// Features are defined in code with a default. The config overrides the
+	// defaults and the debug host can toggle them at runtime.
+	flags := featureflag.New(featureflag.Defined()...)
+	if err := flags.Override(cfg.Features); err != nil {
+		return fmt.Errorf("overriding feature flags: %w", err)
+	}
+
+	// Bearer tokens are only accepted once the keys they're signed with are
+	// configured. A key is rotated by adding its file and restarting.
+	var tokenAuth *auth.Auth
+	if cfg.Auth.KeysFolder != "" {
+		keys, err := auth.LoadKeys(os.DirFS(cfg.Auth.KeysFolder))
+		if err != nil {
+			return fmt.Errorf("loading auth keys: %w", err)
+		}
+
+		tokenAuth, err = auth.New(auth.Config{
+			KeyLookup: keys,
+			Issuer:    cfg.Auth.Issuer,
+			Audience:  cfg.Auth.Audience,
+		})
+		if err != nil {
+			return fmt.Errorf("constructing auth: %w", err)
+		}
+	}
+
+	corsPolicy := mid.NewCorsPolicy(cfg.Web.CORSAllowedOrigins, cfg.Web.CORSAllowCredentials)
+
+	// The effective configuration is the startup configuration with the
+	// values applied by a reload, and it's what the debug host reports.
+	var (
+		effectiveMu sync.Mutex
+		effective   = cfg
+	)
+
+	// Reloading parses the configuration again and applies the values that
+	// can change without restarting the listeners. Everything else keeps the
+	// value it had at startup.
+	reloadConfig := func(ctx context.Context) error {
+		effectiveMu.Lock()
+		defer effectiveMu.Unlock()
+
+		fresh := defaults
+		if _, err := config.Parse(ctx, "", &fresh, profile); err != nil {
+			return fmt.Errorf("parsing config: %w", err)
+		}
+
+		var violations config.Violations
+		violations.CheckErr(mid.ValidateOrigins(fresh.Web.CORSAllowedOrigins, fresh.Web.CORSAllowCredentials), "WEB_CORS_ALLOWED_ORIGINS")
+		violations.Check(fresh.Log.SampleRate >= 1, "LOG_SAMPLE_RATE", "must be 1 or more")
+
+		if err := violations.Err(); err != nil {
+			return err
+		}
+
+		level.Set(fresh.Log.Level)
+		logSampleRate.Store(fresh.Log.SampleRate)
+		corsPolicy.Set(fresh.Web.CORSAllowedOrigins, fresh.Web.CORSAllowCredentials)
+
+		effective.Log.Level = fresh.Log.Level
+		effective.Log.SampleRate = fresh.Log.SampleRate
+		effective.Web.CORSAllowedOrigins = fresh.Web.CORSAllowedOrigins
+		effective.Web.CORSAllowCredentials = fresh.Web.CORSAllowCredentials
+
+		log.InfoContext(ctx, "reload", "status", "config reloaded",
+			"log_level", fresh.Log.Level,
+			"log_sample_rate", fresh.Log.SampleRate,
+			"cors_allowed_origins", fresh.Web.CORSAllowedOrigins,
+			"cors_allow_credentials", fresh.Web.CORSAllowCredentials,
+		)
+
+		return nil
+	}
+
+	effectiveConfig := func() map[string]any {
+		effectiveMu.Lock()
+		defer effectiveMu.Unlock()
+
+		return config.Sanitized(effective)
+	}
+
+	cfgMux := mux.Config{
+		Build:             bi.Build,
+		Shutdown:          shutdown,
+		Log:               log,
+		CORS:              corsPolicy,
+		MaxBodyBytes:      cfg.Web.MaxBodyBytes,
+		CompressThreshold: cfg.Web.CompressThreshold,
+		RequestTimeout:    cfg.Web.RequestTimeout,
+		TrustedProxies:    trustedProxies,
+		Draining:          &draining,
+		Tracer:            tracer,
+		LogSampleRate:     &logSampleRate,
+		AuditBus:          auditBus,
+		RecipeBus:         recipeBus,
+		RecipeEvents:      recipeEvents,
+		Reporter:          reporter,
+		Capture:           captures,
+		Flags:             flags,
+		Auth:              tokenAuth,
+		DefaultTenant:     defaultTenant,
+	}
+
+	// A nil cluster mustn't become a non-nil Beginner, the routes run without
+	// a transaction when the stores are kept in memory.
+	if cluster != nil {
+		cfgMux.Beginner = cluster
+	}
+
+	webAPI := mux.WebAPI(cfgMux, all.Routes())
+
+	var handler http.Handler = webAPI
+
+	// HTTP/2 is negotiated automatically when serving TLS. Cleartext HTTP/2
+	// is only safe behind a trusted load balancer so it must be enabled.
+	h2s := http2.Server{
+		IdleTimeout: cfg.Web.IdleTimeout,
+	}
+
+	if cfg.Web.EnableH2C {
+		handler = h2c.NewHandler(handler, &h2s)
+	}
+
 	// Construct a server to service the request against the mux.
 	api := http.Server{
 		Addr:         cfg.Web.APIHost,
+		Handler:      handler,
 		ReadTimeout:  cfg.Web.ReadTimeout,
 		WriteTimeout: cfg.Web.WriteTimeOut,
 		IdleTimeout:  cfg.Web.IdleTimeout,
 		ErrorLog:     slog.NewLogLogger(log.Handler(), slog.LevelError),
 	}
 
-	// Make a channel to listen for errors coming from the listener. Use a
-	// buffered channel so the goroutine can exit if we don't collect this
-	// error.
+	// -------------------------------------------------------------------------
+	// TLS Support
+
+	var certs *tlscert.Store
+
+	if cfg.Web.TLS.CertFile != "" {
+		log.InfoContext(ctx, "startup", "status", "initializing TLS support")
+
+		certs, err = tlscert.New(tlscert.Config{
+			CertFile:          cfg.Web.TLS.CertFile,
+			KeyFile:           cfg.Web.TLS.KeyFile,
+			ClientCAFile:      cfg.Web.TLS.ClientCAFile,
+			RequireClientCert: cfg.Web.TLS.RequireClientCert,
+		})
+		if err != nil {
+			return fmt.Errorf("loading certificates: %w", err)
+		}
+
+		api.TLSConfig = certs.TLSConfig()
+
+		workers.Go("certificate watcher", func() {
+			certs.Watch(bgCtx, log, cfg.Web.TLS.WatchInterval)
+		})
+	}
+
+	// Hijacked websocket connections aren't tracked by the server and the
+	// event streams only end when the client goes away, so both are closed
+	// explicitly when shutdown starts.
+	api.RegisterOnShutdown(webAPI.CloseWebSockets)
+	api.RegisterOnShutdown(webAPI.CloseEventStreams)
+
+	if err := http2.ConfigureServer(&api, &h2s); err != nil {
+		return fmt.Errorf("configuring http2: %w", err)
+	}
+
+	// The API host may be a unix domain socket for sidecar deployments.
+	apiListener, err := web.Listen(cfg.Web.APIHost)
+	if err != nil {
+		return fmt.Errorf("listening on api host: %w", err)
+	}
+
+	// -------------------------------------------------------------------------
+	// Internal API Support
+
+	var internal *http.Server
+	var internalAPI *web.App
+	var internalListener net.Listener
+
+	if cfg.Web.InternalHost != "" {
+		internalAPI = mux.InternalAPI(cfgMux, all.InternalRoutes())
+
+		internal = &http.Server{
+			Addr:         cfg.Web.InternalHost,
+			Handler:      internalAPI,
+			ReadTimeout:  cfg.Web.ReadTimeout,
+			WriteTimeout: cfg.Web.WriteTimeOut,
+			IdleTimeout:  cfg.Web.IdleTimeout,
+			ErrorLog:     slog.NewLogLogger(log.Handler(), slog.LevelError),
+		}
+
+		if certs != nil {
+			internal.TLSConfig = certs.TLSConfig()
+		}
+
+		internal.RegisterOnShutdown(internalAPI.CloseWebSockets)
+		internal.RegisterOnShutdown(internalAPI.CloseEventStreams)
+
+		internalListener, err = web.Listen(cfg.Web.InternalHost)
+		if err != nil {
+			return fmt.Errorf("listening on internal host: %w", err)
+		}
+	}
+
+	// -------------------------------------------------------------------------
+	// Debug Support
+
+	apps := map[string]debug.RouteLister{
+		"api": webAPI,
+	}
+	if internalAPI != nil {
+		apps["internal"] = internalAPI
+	}
+
+	// The schema is reported from the primary since a replica can lag
+	// behind a migration.
+	var migrationStatus func(ctx context.Context) (migrate.Status, error)
+	if db != nil {
+		migrationStatus = func(ctx context.Context) (migrate.Status, error) {
+			return migrate.Report(ctx, db)
+		}
+	}
+
+	dbg := http.Server{
+		Addr: cfg.Web.DebugHost,
+		Handler: debug.Mux(debug.Config{
+			Build:      bi,
+			Log:        log,
+			Health:     healthChecks,
+			LogLevel:   level,
+			LogRate:    &logSampleRate,
+			Capture:    captures,
+			Flags:      flags,
+			Draining:   &draining,
+			Apps:       apps,
+			Token:      cfg.Debug.Token,
+			DumpDir:    cfg.Debug.DumpDir,
+			Reload:     reloadConfig,
+			Config:     effectiveConfig,
+			Migrations: migrationStatus,
+		}),
+		ReadTimeout: cfg.Web.ReadTimeout,
+		IdleTimeout: cfg.Web.IdleTimeout,
+		ErrorLog:    slog.NewLogLogger(log.Handler(), slog.LevelError),
+	}
 
-	serveErrors := make(chan error, 1)
+	dbgListener, err := web.Listen(cfg.Web.DebugHost)
+	if err != nil {
+		return fmt.Errorf("listening on debug host: %w", err)
+	}
+
+	// =========================================================================
+	// Run
 
-	// Start the service listening for api requests.
-	go func() {
-		log.InfoContext(ctx, "startup", "status", "api router started", "host", api.Addr)
-		serveErrors <- api.ListenAndServe()
-	}()
+	// Every component runs in the group. The first one to fail cancels the
+	// group context which starts the shutdown of all the others, and its
+	// error is the one returned. A clean shutdown cancels it once every
+	// server is stopped so the remaining components return.
+	runCtx, stopRun := context.WithCancel(ctx)
+	defer stopRun()
+
+	g, gctx := errgroup.WithContext(runCtx)
+
+	g.Go(func() error {
+		log.InfoContext(ctx, "startup", "status", "api router started", "host", api.Addr, "tls", certs != nil)
+
+		if err := serve(&api, apiListener, certs != nil); err != nil {
+			return fmt.Errorf("api server: %w", err)
+		}
+		return nil
+	})
+
+	if internal != nil {
+		g.Go(func() error {
+			log.InfoContext(ctx, "startup", "status", "internal router started", "host", internal.Addr, "tls", certs != nil)
+
+			if err := serve(internal, internalListener, certs != nil); err != nil {
+				return fmt.Errorf("internal server: %w", err)
+			}
+			return nil
+		})
+	}
+
+	g.Go(func() error {
+		log.InfoContext(ctx, "startup", "status", "debug v1 router started", "host", dbg.Addr)
+
+		if err := serve(&dbg, dbgListener, false); err != nil {
+			return fmt.Errorf("debug server: %w", err)
+		}
+		return nil
+	})
+
+	// A hangup signal from the OS reloads resources without restarting the
+	// process.
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+
+	g.Go(func() error {
+		defer signal.Stop(reload)
+
+		for {
+			select {
+			case <-gctx.Done():
+				return nil
+
+			case <-reload:
+			}
+
+			log.InfoContext(ctx, "reload", "status", "reload requested")
+
+			if err := reloadConfig(ctx); err != nil {
+				log.ErrorContext(ctx, "reload", "status", "reloading config", "msg", err)
+			}
+
+			if certs != nil {
+				if err := certs.Reload(); err != nil {
+					log.ErrorContext(ctx, "reload", "status", "reloading certificates", "msg", err)
+				}
+			}
+		}
+	})
 
 	// =========================================================================
 	// Shutdown
 
-	// Blocking main and waiting for shutdown
-	select {
-	case err := <-serveErrors:
-		return fmt.Errorf("server error: %w", err)
+	// The servers are always stopped in the same order: the API first, then
+	// the internal API and the background workers, and the debug server last
+	// so profiles and metrics remain available while the API drains.
+	g.Go(func() error {
+		defer stopRun()
 
-	case sig := <-shutdown:
-		log.InfoContext(ctx, "shutdown", "status", "shutdown started", "signal", sig)
-		defer log.InfoContext(ctx, "shutdown", "status", "shutdown complete", "signal", sig)
+		select {
+		case sig := <-shutdown:
+			log.InfoContext(ctx, "shutdown", "status", "shutdown started", "signal", sig)
+			defer log.InfoContext(ctx, "shutdown", "status", "shutdown complete", "signal", sig)
+
+			// Report the service as not ready and give the load balancers time
+			// to stop sending new requests.
+			draining.Store(true)
+
+			log.InfoContext(ctx, "shutdown", "status", "draining", "delay", cfg.Web.DrainDelay)
+			time.Sleep(cfg.Web.DrainDelay)
+
+		case <-gctx.Done():
+			log.ErrorContext(ctx, "shutdown", "status", "component failed, shutdown started")
+			defer log.InfoContext(ctx, "shutdown", "status", "shutdown complete")
+
+			draining.Store(true)
+		}
 
 		// give outstanding requests a deadline for completion.
 		ctx, cancel := context.WithTimeout(context.Background(), cfg.Web.ShutdownTimeout)
 		defer cancel()
 
+		var errs []error
+
 		// Asking listener to shut down and shed load.
 		if err := api.Shutdown(ctx); err != nil {
 			api.Close()
-			return fmt.Errorf("could not stop server gracefully: %w", err)
+			errs = append(errs, fmt.Errorf("could not stop server gracefully: %w", err))
+		}
+
+		if internal != nil {
+			if err := internal.Shutdown(ctx); err != nil {
+				internal.Close()
+				errs = append(errs, fmt.Errorf("could not stop internal server gracefully: %w", err))
+			}
+		}
+
+		// Once the listeners are stopped, report what is still running so
+		// leaks introduced by new subsystems are caught.
+		cancelBackground()
+		workers.Wait(time.Second)
+
+		inFlight := webAPI.InFlight()
+		if internalAPI != nil {
+			inFlight += internalAPI.InFlight()
 		}
+
+		log.InfoContext(ctx, "shutdown", "status", "shutdown report",
+			"goroutines", runtime.NumGoroutine(),
+			"inflight_requests", inFlight,
+			"running_workers", workers.Running(),
+			"goroutines_by_creator", worker.Goroutines())
+
+		if err := dbg.Shutdown(ctx); err != nil {
+			dbg.Close()
+			errs = append(errs, fmt.Errorf("could not stop debug server gracefully: %w", err))
+		}
+
+		return errors.Join(errs...)
+	})
+
+	return g.Wait()
+}
+
+// serve accepts connections on the listener until the server is shut down,
+// which isn't reported as an error.
+func serve(srv *http.Server, l net.Listener, useTLS bool) error {
+	var err error
+	switch {
+	case useTLS:
+		err = srv.ServeTLS(l, "", "")
+	default:
+		err = srv.Serve(l)
+	}
+
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+
+	return err
+}
+
+// newKeyring constructs the keyring from the keys given as id:base64. With
+// no keys, a random key is used unless the users are stored, since the users
+// sealed with it couldn't be opened after a restart.
+func newKeyring(primary string, keys []string, stored bool) (*envelope.Keyring, error) {
+	if len(keys) == 0 {
+		if stored {
+			return nil, errors.New("USERS_KEYRING and USERS_KEYRING_PRIMARY must be set when the users are stored in the database, like USERS_KEYRING=key-1:<base64 of 32 random bytes> and USERS_KEYRING_PRIMARY=key-1")
+		}
+
+		key := make([]byte, envelope.KeySize)
+		if _, err := rand.Read(key); err != nil {
+			return nil, fmt.Errorf("generating key: %w", err)
+		}
+
+		return envelope.New("random", map[string][]byte{"random": key})
+	}
+
+	// The entries hold the key material, so an entry that can't be parsed
+	// is reported by its position.
+	byID := make(map[string][]byte, len(keys))
+	for i, k := range keys {
+		id, encoded, found := strings.Cut(strings.TrimSpace(k), ":")
+		if !found {
+			return nil, fmt.Errorf("key %d must be given as id:base64", i+1)
+		}
+
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("decoding key %d: %w", i+1, err)
+		}
+
+		byID[id] = key
+	}
+
+	return envelope.New(primary, byID)
+}
+
+// dial checks a TCP address can be reached.
+func dial(ctx context.Context, addr string) error {
+	var d net.Dialer
+
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
 	}
 
-	return nil
+	return conn.Close()
 }
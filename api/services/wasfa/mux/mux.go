@@ -0,0 +1,145 @@
+// Package mux provides support to bind domain level routes
+// to the application mux.
+package mux
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/netip"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+	"lobbyte.com/alkeepy/app/api/auth"
+	"lobbyte.com/alkeepy/app/api/capture"
+	"lobbyte.com/alkeepy/app/api/errs"
+	"lobbyte.com/alkeepy/app/api/featureflag"
+	"lobbyte.com/alkeepy/app/api/mid"
+	"lobbyte.com/alkeepy/business/domain/auditbus"
+	"lobbyte.com/alkeepy/business/domain/recipebus"
+	"lobbyte.com/alkeepy/business/sdk/sqldb"
+	"lobbyte.com/alkeepy/foundation/errreport"
+	"lobbyte.com/alkeepy/foundation/pubsub"
+	"lobbyte.com/alkeepy/foundation/web"
+)
+
+// Config contains all the mandatory systems required by handlers.
+type Config struct {
+	Build             string
+	Shutdown          chan os.Signal
+	Log               *slog.Logger
+	CORS              *mid.CorsPolicy
+	MaxBodyBytes      int64
+	CompressThreshold int
+	RequestTimeout    time.Duration
+	TrustedProxies    []netip.Prefix
+	Draining          *atomic.Bool
+	Tracer            trace.Tracer
+	LogSampleRate     *atomic.Int64
+	AuditBus          *auditbus.Business
+	RecipeBus         *recipebus.Business
+	RecipeEvents      *pubsub.Broker[recipebus.Event]
+	Reporter          *errreport.Reporter
+	Capture           *capture.Buffer
+	Flags             *featureflag.Flags
+	Auth              *auth.Auth
+	DefaultTenant     uuid.UUID
+	Beginner          sqldb.Beginner
+}
+
+// RouteAdder defines behavior that sets the routes to bind for an instance
+// of the service.
+type RouteAdder interface {
+	Add(app *web.App, cfg Config)
+}
+
+// WebAPI constructs a web.App with all application routes bound. The App is
+// returned so the caller can close websockets during shutdown. CORS runs
+// ahead of the error handling so error responses carry its headers too.
+func WebAPI(cfg Config, routeAdder RouteAdder) *web.App {
+	logger := func(ctx context.Context, msg string, args ...any) {
+		cfg.Log.InfoContext(ctx, msg, args...)
+	}
+
+	app := web.NewApp(
+		logger,
+		cfg.Shutdown,
+		mid.Otel(cfg.Tracer),
+		mid.RequestID(),
+		mid.RealIP(cfg.TrustedProxies),
+		mid.Cors(cfg.CORS),
+		mid.Logger(cfg.Log, cfg.LogSampleRate),
+		mid.Metrics(),
+		mid.Compress(cfg.CompressThreshold),
+		mid.ETag(),
+		mid.Capture(cfg.Capture),
+		mid.Errors(cfg.Log, cfg.Reporter),
+		mid.Panics(cfg.Log, cfg.Reporter),
+		mid.ClientCert(),
+		mid.Authenticate(cfg.Auth),
+		mid.Actor(),
+		mid.Tenant(cfg.DefaultTenant),
+		mid.FeatureFlags(cfg.Flags),
+		mid.BodyLimit(cfg.MaxBodyBytes),
+	)
+
+	app.SetPolicy(mid.Policy(cfg.RequestTimeout))
+	app.NotFound(notFound)
+	app.MethodNotAllowed(methodNotAllowed)
+
+	routeAdder.Add(app, cfg)
+
+	return app
+}
+
+// InternalAPI constructs a http.Handler for the internal listener. It has its
+// own middleware stack and routes so admin only endpoints are never bound to
+// the public listener.
+func InternalAPI(cfg Config, routeAdder RouteAdder) *web.App {
+	logger := func(ctx context.Context, msg string, args ...any) {
+		cfg.Log.InfoContext(ctx, msg, args...)
+	}
+
+	app := web.NewApp(
+		logger,
+		cfg.Shutdown,
+		mid.Otel(cfg.Tracer),
+		mid.RequestID(),
+		mid.RealIP(cfg.TrustedProxies),
+		mid.Logger(cfg.Log, cfg.LogSampleRate),
+		mid.Metrics(),
+		mid.Errors(cfg.Log, cfg.Reporter),
+		mid.Panics(cfg.Log, cfg.Reporter),
+		mid.ClientCert(),
+		mid.Authenticate(cfg.Auth),
+		mid.Actor(),
+		mid.Tenant(cfg.DefaultTenant),
+		mid.FeatureFlags(cfg.Flags),
+		mid.BodyLimit(cfg.MaxBodyBytes),
+	)
+
+	app.SetPolicy(mid.Policy(cfg.RequestTimeout))
+	app.NotFound(notFound)
+	app.MethodNotAllowed(methodNotAllowed)
+
+	routeAdder.Add(app, cfg)
+
+	return app
+}
+
+// =============================================================================
+
+// notFound is the handler used for requests that don't match any route so
+// the client receives the standard JSON error envelope.
+func notFound(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	return errs.Newf(errs.NotFound, "no route for %s %s", r.Method, r.URL.Path)
+}
+
+// methodNotAllowed is the handler used for requests that match a route but not
+// its method so the client receives the standard JSON error envelope.
+func methodNotAllowed(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	return errs.Newf(errs.MethodNotAllowed, "method %s is not allowed for %s", r.Method, r.URL.Path)
+}
@@ -0,0 +1,66 @@
+// This program encrypts a config value with the master key in
+// CONFIG_MASTER_KEY so it can be stored in a config file as an enc:// value.
+// The plaintext is read from stdin so it doesn't end up in the shell history.
+//
+//	printf 'secret' | go run ./api/tooling/encrypt
+//
+// A new master key is printed with the -genkey flag.
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"lobbyte.com/alkeepy/foundation/config"
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, "encrypt:", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	genKey := flag.Bool("genkey", false, "print a new base64 encoded master key")
+	flag.Parse()
+
+	if *genKey {
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return fmt.Errorf("generating key: %w", err)
+		}
+
+		fmt.Println(base64.StdEncoding.EncodeToString(key))
+		return nil
+	}
+
+	key, err := config.MasterKey()
+	if err != nil {
+		return err
+	}
+
+	if key == nil {
+		return errors.New("CONFIG_MASTER_KEY or CONFIG_MASTER_KEY_FILE must be set")
+	}
+
+	plaintext, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return fmt.Errorf("reading stdin: %w", err)
+	}
+
+	value, err := config.Encrypt(key, strings.TrimRight(string(plaintext), "\r\n"))
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(value)
+
+	return nil
+}
@@ -0,0 +1,383 @@
+// Package auth provides support for authenticating the requests with JSON web
+// tokens. Tokens are signed with RS256 and name the key they were signed
+// with, so the keys can be rotated: a token is verified with the public key
+// of that name for as long as it's kept.
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Set of error variables for validating tokens.
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrUnknownKey   = errors.New("unknown key")
+)
+
+// algorithm is the only signing algorithm tokens are accepted with, so a
+// token can't pick a weaker one like none or an HMAC keyed with the public
+// key.
+const algorithm = "RS256"
+
+// ClockSkew is how far the clocks of the issuer and the service may drift
+// apart before a token is refused for being expired or not valid yet.
+const ClockSkew = time.Minute
+
+// KeyLookup declares the behavior needed to find the public key a token was
+// signed with by the id in its header.
+type KeyLookup interface {
+	PublicKey(kid string) (*rsa.PublicKey, error)
+}
+
+// Claims represents what a token says about its bearer. TenantID is the
+// bakery the bearer acts for and is nil when the token doesn't name one.
+type Claims struct {
+	ID        string
+	Issuer    string
+	Subject   string
+	Audience  []string
+	TenantID  uuid.UUID
+	Roles     []string
+	IssuedAt  time.Time
+	NotBefore time.Time
+	ExpiresAt time.Time
+}
+
+// HasRole reports whether the claims hold any of the roles.
+func (c Claims) HasRole(roles ...string) bool {
+	for _, role := range roles {
+		if slices.Contains(c.Roles, role) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Config represents what's needed to issue and validate tokens. Only the
+// tokens from the issuer are accepted and, when Audience is set, only the
+// ones meant for it. SigningKey and KID are only needed to issue tokens.
+type Config struct {
+	KeyLookup  KeyLookup
+	Issuer     string
+	Audience   string
+	SigningKey *rsa.PrivateKey
+	KID        string
+}
+
+// Auth issues and validates tokens.
+type Auth struct {
+	keyLookup  KeyLookup
+	issuer     string
+	audience   string
+	signingKey *rsa.PrivateKey
+	kid        string
+}
+
+// New constructs an Auth for use.
+func New(cfg Config) (*Auth, error) {
+	if cfg.KeyLookup == nil {
+		return nil, errors.New("key lookup is required")
+	}
+
+	if cfg.Issuer == "" {
+		return nil, errors.New("issuer is required")
+	}
+
+	if cfg.SigningKey != nil && cfg.KID == "" {
+		return nil, errors.New("the kid of the signing key is required")
+	}
+
+	a := Auth{
+		keyLookup:  cfg.KeyLookup,
+		issuer:     cfg.Issuer,
+		audience:   cfg.Audience,
+		signingKey: cfg.SigningKey,
+		kid:        cfg.KID,
+	}
+
+	return &a, nil
+}
+
+// GenerateToken signs a token holding the claims. The issuer and the time
+// the token is issued at are set when the claims don't, and so is an id.
+// The claims must say when the token expires.
+func (a *Auth) GenerateToken(claims Claims) (string, error) {
+	if a.signingKey == nil {
+		return "", errors.New("no signing key configured")
+	}
+
+	if claims.ExpiresAt.IsZero() {
+		return "", errors.New("the expiration of the token is required")
+	}
+
+	if claims.ID == "" {
+		claims.ID = uuid.NewString()
+	}
+
+	if claims.Issuer == "" {
+		claims.Issuer = a.issuer
+	}
+
+	if claims.IssuedAt.IsZero() {
+		claims.IssuedAt = time.Now()
+	}
+
+	header, err := encodeSegment(tokenHeader{Alg: algorithm, Typ: "JWT", Kid: a.kid})
+	if err != nil {
+		return "", fmt.Errorf("encoding header: %w", err)
+	}
+
+	payload, err := encodeSegment(toTokenClaims(claims))
+	if err != nil {
+		return "", fmt.Errorf("encoding claims: %w", err)
+	}
+
+	signed := header + "." + payload
+	digest := sha256.Sum256([]byte(signed))
+
+	sig, err := rsa.SignPKCS1v15(rand.Reader, a.signingKey, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("signing: %w", err)
+	}
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// Authenticate validates the bearer token of an Authorization header and
+// returns its claims.
+func (a *Auth) Authenticate(authorization string) (Claims, error) {
+	scheme, token, found := strings.Cut(authorization, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return Claims{}, fmt.Errorf("%w: expected authorization header format: Bearer <token>", ErrInvalidToken)
+	}
+
+	return a.ValidateToken(strings.TrimSpace(token))
+}
+
+// ValidateToken verifies the signature of the token and checks it's from the
+// issuer, for the audience and valid now. It returns the claims of the
+// token. Every error wraps ErrInvalidToken.
+func (a *Auth) ValidateToken(token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Claims{}, fmt.Errorf("%w: malformed", ErrInvalidToken)
+	}
+
+	var header tokenHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return Claims{}, fmt.Errorf("%w: header: %w", ErrInvalidToken, err)
+	}
+
+	if header.Alg != algorithm {
+		return Claims{}, fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidToken, header.Alg)
+	}
+
+	key, err := a.keyLookup.PublicKey(header.Kid)
+	if err != nil {
+		return Claims{}, fmt.Errorf("%w: kid[%s]: %w", ErrInvalidToken, header.Kid, err)
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Claims{}, fmt.Errorf("%w: signature: %w", ErrInvalidToken, err)
+	}
+
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig); err != nil {
+		return Claims{}, fmt.Errorf("%w: signature: %w", ErrInvalidToken, err)
+	}
+
+	var tc tokenClaims
+	if err := decodeSegment(parts[1], &tc); err != nil {
+		return Claims{}, fmt.Errorf("%w: claims: %w", ErrInvalidToken, err)
+	}
+
+	claims, err := tc.toClaims()
+	if err != nil {
+		return Claims{}, fmt.Errorf("%w: claims: %w", ErrInvalidToken, err)
+	}
+
+	if err := a.check(claims, time.Now()); err != nil {
+		return Claims{}, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+
+	return claims, nil
+}
+
+// check makes sure the claims of a token are acceptable at the time.
+func (a *Auth) check(claims Claims, now time.Time) error {
+	if claims.Issuer != a.issuer {
+		return fmt.Errorf("issued by %q", claims.Issuer)
+	}
+
+	if a.audience != "" && !slices.Contains(claims.Audience, a.audience) {
+		return fmt.Errorf("not meant for %q", a.audience)
+	}
+
+	if claims.ExpiresAt.IsZero() {
+		return errors.New("doesn't expire")
+	}
+
+	if now.After(claims.ExpiresAt.Add(ClockSkew)) {
+		return fmt.Errorf("expired at %s", claims.ExpiresAt.Format(time.RFC3339))
+	}
+
+	if !claims.NotBefore.IsZero() && now.Add(ClockSkew).Before(claims.NotBefore) {
+		return fmt.Errorf("not valid before %s", claims.NotBefore.Format(time.RFC3339))
+	}
+
+	return nil
+}
+
+// =============================================================================
+
+type ctxKey int
+
+const claimsKey ctxKey = 1
+
+// SetClaims returns a context holding the claims of the authenticated
+// request.
+func SetClaims(ctx context.Context, claims Claims) context.Context {
+	return context.WithValue(ctx, claimsKey, claims)
+}
+
+// GetClaims returns the claims of the authenticated request. The second
+// value is false when the request wasn't authenticated with a token.
+func GetClaims(ctx context.Context) (Claims, bool) {
+	v, ok := ctx.Value(claimsKey).(Claims)
+	return v, ok
+}
+
+// =============================================================================
+
+// tokenHeader is the header of a token.
+type tokenHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ,omitempty"`
+	Kid string `json:"kid,omitempty"`
+}
+
+// tokenClaims is the payload of a token, the registered claims with the
+// times in seconds since the epoch plus the claims of the service.
+type tokenClaims struct {
+	ID        string   `json:"jti,omitempty"`
+	Issuer    string   `json:"iss,omitempty"`
+	Subject   string   `json:"sub,omitempty"`
+	Audience  audience `json:"aud,omitempty"`
+	TenantID  string   `json:"tenant_id,omitempty"`
+	Roles     []string `json:"roles,omitempty"`
+	IssuedAt  int64    `json:"iat,omitempty"`
+	NotBefore int64    `json:"nbf,omitempty"`
+	ExpiresAt int64    `json:"exp,omitempty"`
+}
+
+func toTokenClaims(c Claims) tokenClaims {
+	tc := tokenClaims{
+		ID:        c.ID,
+		Issuer:    c.Issuer,
+		Subject:   c.Subject,
+		Audience:  c.Audience,
+		Roles:     c.Roles,
+		IssuedAt:  unix(c.IssuedAt),
+		NotBefore: unix(c.NotBefore),
+		ExpiresAt: unix(c.ExpiresAt),
+	}
+
+	if c.TenantID != uuid.Nil {
+		tc.TenantID = c.TenantID.String()
+	}
+
+	return tc
+}
+
+func (tc tokenClaims) toClaims() (Claims, error) {
+	c := Claims{
+		ID:        tc.ID,
+		Issuer:    tc.Issuer,
+		Subject:   tc.Subject,
+		Audience:  tc.Audience,
+		Roles:     tc.Roles,
+		IssuedAt:  fromUnix(tc.IssuedAt),
+		NotBefore: fromUnix(tc.NotBefore),
+		ExpiresAt: fromUnix(tc.ExpiresAt),
+	}
+
+	if tc.TenantID != "" {
+		id, err := uuid.Parse(tc.TenantID)
+		if err != nil {
+			return Claims{}, fmt.Errorf("tenant_id: %w", err)
+		}
+		c.TenantID = id
+	}
+
+	return c, nil
+}
+
+// audience is the aud claim, which is either a single string or a list of
+// them.
+type audience []string
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (aud *audience) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*aud = audience{s}
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return errors.New("aud must be a string or a list of strings")
+	}
+	*aud = list
+
+	return nil
+}
+
+func unix(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+
+	return t.Unix()
+}
+
+func fromUnix(sec int64) time.Time {
+	if sec == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(sec, 0)
+}
+
+func encodeSegment(v any) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
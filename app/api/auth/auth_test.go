@@ -0,0 +1,265 @@
+package auth_test
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"lobbyte.com/alkeepy/app/api/auth"
+)
+
+const (
+	kid      = "s4sKIjD9kIRjxs2tulPqGLdxSfgPErRN1Mu3Hd9k9NQ"
+	issuer   = "alkeepy"
+	audience = "wasfa"
+)
+
+func Test_ValidateToken(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Should be able to generate a key: %s", err)
+	}
+
+	ks := auth.NewKeyStore()
+	ks.AddPrivateKey(kid, key)
+
+	a, err := auth.New(auth.Config{
+		KeyLookup:  ks,
+		Issuer:     issuer,
+		Audience:   audience,
+		SigningKey: key,
+		KID:        kid,
+	})
+	if err != nil {
+		t.Fatalf("Should be able to construct auth: %s", err)
+	}
+
+	now := time.Now()
+	tenantID := uuid.New()
+
+	claims := func(mod func(c map[string]any)) map[string]any {
+		c := map[string]any{
+			"jti":       uuid.NewString(),
+			"iss":       issuer,
+			"sub":       "baker@example.com",
+			"aud":       audience,
+			"tenant_id": tenantID.String(),
+			"roles":     []string{"ADMIN"},
+			"iat":       now.Unix(),
+			"exp":       now.Add(time.Hour).Unix(),
+		}
+		if mod != nil {
+			mod(c)
+		}
+		return c
+	}
+
+	header := map[string]any{"alg": "RS256", "typ": "JWT", "kid": kid}
+
+	tests := []struct {
+		name  string
+		token string
+		valid bool
+	}{
+		{
+			name:  "valid",
+			token: signRS256(t, key, header, claims(nil)),
+			valid: true,
+		},
+		{
+			name:  "audience list",
+			token: signRS256(t, key, header, claims(func(c map[string]any) { c["aud"] = []string{"other", audience} })),
+			valid: true,
+		},
+		{
+			name:  "audience list without the audience",
+			token: signRS256(t, key, header, claims(func(c map[string]any) { c["aud"] = []string{"other"} })),
+		},
+		{
+			name:  "other audience",
+			token: signRS256(t, key, header, claims(func(c map[string]any) { c["aud"] = "other" })),
+		},
+		{
+			name:  "other issuer",
+			token: signRS256(t, key, header, claims(func(c map[string]any) { c["iss"] = "other" })),
+		},
+		{
+			name:  "expired within the clock skew",
+			token: signRS256(t, key, header, claims(func(c map[string]any) { c["exp"] = now.Add(-auth.ClockSkew / 2).Unix() })),
+			valid: true,
+		},
+		{
+			name:  "expired beyond the clock skew",
+			token: signRS256(t, key, header, claims(func(c map[string]any) { c["exp"] = now.Add(-2 * auth.ClockSkew).Unix() })),
+		},
+		{
+			name:  "not valid yet within the clock skew",
+			token: signRS256(t, key, header, claims(func(c map[string]any) { c["nbf"] = now.Add(auth.ClockSkew / 2).Unix() })),
+			valid: true,
+		},
+		{
+			name:  "not valid yet beyond the clock skew",
+			token: signRS256(t, key, header, claims(func(c map[string]any) { c["nbf"] = now.Add(2 * auth.ClockSkew).Unix() })),
+		},
+		{
+			name:  "no expiration",
+			token: signRS256(t, key, header, claims(func(c map[string]any) { delete(c, "exp") })),
+		},
+		{
+			name:  "invalid tenant",
+			token: signRS256(t, key, header, claims(func(c map[string]any) { c["tenant_id"] = "bakery-1" })),
+		},
+		{
+			name:  "unknown kid",
+			token: signRS256(t, key, map[string]any{"alg": "RS256", "kid": "other"}, claims(nil)),
+		},
+		{
+			name:  "signed with another key",
+			token: signRS256(t, otherKey(t), header, claims(nil)),
+		},
+		{
+			name:  "tampered payload",
+			token: tamper(t, signRS256(t, key, header, claims(nil)), claims(func(c map[string]any) { c["roles"] = []string{"ADMIN", "OWNER"} })),
+		},
+		{
+			name:  "alg none",
+			token: encode(t, map[string]any{"alg": "none", "kid": kid}) + "." + encode(t, claims(nil)) + ".",
+		},
+		{
+			name:  "alg HS256 keyed with the public key",
+			token: signHS256(t, &key.PublicKey, map[string]any{"alg": "HS256", "kid": kid}, claims(nil)),
+		},
+		{
+			name:  "malformed",
+			token: "not.a-token",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := a.ValidateToken(tt.token)
+
+			if !tt.valid {
+				if !errors.Is(err, auth.ErrInvalidToken) {
+					t.Fatalf("Should refuse the token with ErrInvalidToken, got %v", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Should accept the token: %s", err)
+			}
+
+			if got.Subject != "baker@example.com" || got.TenantID != tenantID || !got.HasRole("ADMIN") {
+				t.Fatalf("Should get the claims of the token, got %+v", got)
+			}
+		})
+	}
+}
+
+func Test_GenerateToken(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Should be able to generate a key: %s", err)
+	}
+
+	ks := auth.NewKeyStore()
+	ks.AddPrivateKey(kid, key)
+
+	a, err := auth.New(auth.Config{
+		KeyLookup:  ks,
+		Issuer:     issuer,
+		Audience:   audience,
+		SigningKey: key,
+		KID:        kid,
+	})
+	if err != nil {
+		t.Fatalf("Should be able to construct auth: %s", err)
+	}
+
+	claims := auth.Claims{
+		Subject:   "baker@example.com",
+		Audience:  []string{audience},
+		TenantID:  uuid.New(),
+		Roles:     []string{"USER"},
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	token, err := a.GenerateToken(claims)
+	if err != nil {
+		t.Fatalf("Should be able to generate a token: %s", err)
+	}
+
+	got, err := a.Authenticate("Bearer " + token)
+	if err != nil {
+		t.Fatalf("Should be able to authenticate with the token: %s", err)
+	}
+
+	if got.Issuer != issuer || got.Subject != claims.Subject || got.TenantID != claims.TenantID || got.ID == "" {
+		t.Fatalf("Should get the claims back, got %+v", got)
+	}
+
+	if _, err := a.Authenticate("Basic " + token); !errors.Is(err, auth.ErrInvalidToken) {
+		t.Fatalf("Should refuse a header that isn't a bearer token, got %v", err)
+	}
+}
+
+// =============================================================================
+
+func otherKey(t *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Should be able to generate a key: %s", err)
+	}
+
+	return key
+}
+
+func encode(t *testing.T, v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("Should be able to marshal the segment: %s", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func signRS256(t *testing.T, key *rsa.PrivateKey, header map[string]any, claims map[string]any) string {
+	signed := encode(t, header) + "." + encode(t, claims)
+	digest := sha256.Sum256([]byte(signed))
+
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatalf("Should be able to sign the token: %s", err)
+	}
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+// signHS256 signs the token with an HMAC keyed with the public key, the way
+// an attacker would try to pass a key that's public as a shared secret.
+func signHS256(t *testing.T, key *rsa.PublicKey, header map[string]any, claims map[string]any) string {
+	signed := encode(t, header) + "." + encode(t, claims)
+
+	mac := hmac.New(sha256.New, key.N.Bytes())
+	mac.Write([]byte(signed))
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// tamper replaces the payload of the token keeping its signature.
+func tamper(t *testing.T, token string, claims map[string]any) string {
+	parts := strings.Split(token, ".")
+	parts[1] = encode(t, claims)
+
+	return strings.Join(parts, ".")
+}
@@ -0,0 +1,149 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+	"sync"
+)
+
+// KeyStore holds the keys tokens are signed with by kid. It implements
+// KeyLookup. A key can be added with its private half, to issue tokens, or
+// only with the public one, to validate the tokens issued elsewhere.
+type KeyStore struct {
+	mu      sync.RWMutex
+	private map[string]*rsa.PrivateKey
+	public  map[string]*rsa.PublicKey
+}
+
+// NewKeyStore constructs an empty key store.
+func NewKeyStore() *KeyStore {
+	return &KeyStore{
+		private: make(map[string]*rsa.PrivateKey),
+		public:  make(map[string]*rsa.PublicKey),
+	}
+}
+
+// LoadKeys constructs a key store holding the keys of the .pem files of the
+// file system. The name of a file without the extension is the kid of its
+// key, so a key is rotated by adding the file of the new key and removing
+// the old one once the tokens signed with it have expired.
+func LoadKeys(fsys fs.FS) (*KeyStore, error) {
+	ks := NewKeyStore()
+
+	files, err := fs.Glob(fsys, "*.pem")
+	if err != nil {
+		return nil, fmt.Errorf("listing keys: %w", err)
+	}
+
+	for _, file := range files {
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, fmt.Errorf("reading key %s: %w", file, err)
+		}
+
+		kid := strings.TrimSuffix(path.Base(file), ".pem")
+		if err := ks.AddPEM(kid, data); err != nil {
+			return nil, fmt.Errorf("key %s: %w", file, err)
+		}
+	}
+
+	if len(files) == 0 {
+		return nil, errors.New("no .pem files found")
+	}
+
+	return ks, nil
+}
+
+// AddPrivateKey adds the key to issue and validate tokens with.
+func (ks *KeyStore) AddPrivateKey(kid string, key *rsa.PrivateKey) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	ks.private[kid] = key
+	ks.public[kid] = &key.PublicKey
+}
+
+// AddPublicKey adds the key to validate tokens with.
+func (ks *KeyStore) AddPublicKey(kid string, key *rsa.PublicKey) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	ks.public[kid] = key
+}
+
+// AddPEM adds the RSA key encoded in PEM. It's either a private key, in
+// PKCS #1 or PKCS #8, or a public key in PKIX.
+func (ks *KeyStore) AddPEM(kid string, data []byte) error {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return errors.New("no PEM block found")
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return fmt.Errorf("parsing private key: %w", err)
+		}
+		ks.AddPrivateKey(kid, key)
+
+	case "PRIVATE KEY":
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return fmt.Errorf("parsing private key: %w", err)
+		}
+		key, ok := parsed.(*rsa.PrivateKey)
+		if !ok {
+			return fmt.Errorf("private key is a %T, not an RSA key", parsed)
+		}
+		ks.AddPrivateKey(kid, key)
+
+	case "PUBLIC KEY":
+		parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return fmt.Errorf("parsing public key: %w", err)
+		}
+		key, ok := parsed.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("public key is a %T, not an RSA key", parsed)
+		}
+		ks.AddPublicKey(kid, key)
+
+	default:
+		return fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+
+	return nil
+}
+
+// PrivateKey returns the private key of the kid.
+func (ks *KeyStore) PrivateKey(kid string) (*rsa.PrivateKey, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	key, exists := ks.private[kid]
+	if !exists {
+		return nil, fmt.Errorf("private key %q: %w", kid, ErrUnknownKey)
+	}
+
+	return key, nil
+}
+
+// PublicKey implements the KeyLookup interface.
+func (ks *KeyStore) PublicKey(kid string) (*rsa.PublicKey, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	key, exists := ks.public[kid]
+	if !exists {
+		return nil, fmt.Errorf("public key %q: %w", kid, ErrUnknownKey)
+	}
+
+	return key, nil
+}
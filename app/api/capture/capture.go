@@ -0,0 +1,228 @@
+// Package capture keeps a sample of sanitized request and response bodies in
+// memory so client reported issues can be reproduced.
+package capture
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// MaxBodyBytes is the most of a request or response body that is kept.
+const MaxBodyBytes = 64 << 10
+
+// redacted replaces sensitive values.
+const redacted = "[REDACTED]"
+
+// sensitiveHeaders are never captured.
+var sensitiveHeaders = map[string]bool{
+	"Authorization":       true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+	"Proxy-Authorization": true,
+	"X-Api-Key":           true,
+}
+
+// sensitiveFields are the JSON keys whose values are redacted. Keys are
+// matched case insensitively and when they contain one of these words.
+var sensitiveFields = []string{"password", "secret", "token", "apikey", "api_key", "card", "ssn"}
+
+// Entry is a captured request and response.
+type Entry struct {
+	Time            time.Time   `json:"time"`
+	TraceID         string      `json:"traceID"`
+	Method          string      `json:"method"`
+	Path            string      `json:"path"`
+	StatusCode      int         `json:"statusCode"`
+	Duration        string      `json:"duration"`
+	RequestHeaders  http.Header `json:"requestHeaders"`
+	RequestBody     string      `json:"requestBody,omitempty"`
+	ResponseHeaders http.Header `json:"responseHeaders"`
+	ResponseBody    string      `json:"responseBody,omitempty"`
+	Truncated       bool        `json:"truncated,omitempty"`
+}
+
+// Settings are the runtime controls of the buffer.
+type Settings struct {
+	Enabled bool    `json:"enabled"`
+	Rate    float64 `json:"rate"`
+}
+
+// Buffer is a fixed size ring of captured entries. Capturing is off until it's
+// enabled.
+type Buffer struct {
+	enabled atomic.Bool
+	rate    atomic.Uint64
+
+	mu      sync.Mutex
+	entries []Entry
+	next    int
+	full    bool
+}
+
+// NewBuffer constructs a buffer holding the most recent size entries.
+func NewBuffer(size int) *Buffer {
+	if size < 1 {
+		size = 1
+	}
+
+	b := Buffer{
+		entries: make([]Entry, size),
+	}
+	b.rate.Store(math.Float64bits(1))
+
+	return &b
+}
+
+// Settings returns the current controls.
+func (b *Buffer) Settings() Settings {
+	return Settings{
+		Enabled: b.enabled.Load(),
+		Rate:    math.Float64frombits(b.rate.Load()),
+	}
+}
+
+// Set changes the controls. The rate is the fraction of requests captured
+// and is clamped between 0 and 1.
+func (b *Buffer) Set(s Settings) {
+	rate := min(max(s.Rate, 0), 1)
+
+	b.rate.Store(math.Float64bits(rate))
+	b.enabled.Store(s.Enabled)
+}
+
+// Sample reports whether the current request should be captured.
+func (b *Buffer) Sample() bool {
+	if b == nil || !b.enabled.Load() {
+		return false
+	}
+
+	return rand.Float64() < math.Float64frombits(b.rate.Load())
+}
+
+// Add sanitizes the entry and stores it, replacing the oldest entry when the
+// buffer is full.
+func (b *Buffer) Add(e Entry) {
+	e.RequestHeaders = sanitizeHeaders(e.RequestHeaders)
+	e.ResponseHeaders = sanitizeHeaders(e.ResponseHeaders)
+	e.RequestBody = sanitizeBody(e.RequestBody)
+	e.ResponseBody = sanitizeBody(e.ResponseBody)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.entries[b.next] = e
+	b.next = (b.next + 1) % len(b.entries)
+	if b.next == 0 {
+		b.full = true
+	}
+}
+
+// List returns the captured entries, newest first.
+func (b *Buffer) List() []Entry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	n := b.next
+	if b.full {
+		n = len(b.entries)
+	}
+
+	list := make([]Entry, 0, n)
+	for i := 1; i <= n; i++ {
+		idx := (b.next - i + len(b.entries)) % len(b.entries)
+		list = append(list, b.entries[idx])
+	}
+
+	return list
+}
+
+// Reset discards every captured entry.
+func (b *Buffer) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	clear(b.entries)
+	b.next = 0
+	b.full = false
+}
+
+// =============================================================================
+
+func sanitizeHeaders(h http.Header) http.Header {
+	if h == nil {
+		return nil
+	}
+
+	out := h.Clone()
+	for key := range out {
+		if sensitiveHeaders[http.CanonicalHeaderKey(key)] {
+			out[key] = []string{redacted}
+		}
+	}
+
+	return out
+}
+
+// sanitizeBody redacts sensitive values from JSON bodies. A body that can't
+// be parsed, like a form or a JSON body cut at the capture limit, can't be
+// redacted either, so only its size is kept.
+func sanitizeBody(body string) string {
+	if body == "" {
+		return ""
+	}
+
+	var v any
+	if err := json.Unmarshal([]byte(body), &v); err != nil {
+		return unparsed(body)
+	}
+
+	data, err := json.Marshal(redact(v))
+	if err != nil {
+		return unparsed(body)
+	}
+
+	return string(data)
+}
+
+// unparsed is what's kept of a body that can't be redacted.
+func unparsed(body string) string {
+	return fmt.Sprintf("[unparsed %d bytes]", len(body))
+}
+
+func redact(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, val := range v {
+			if isSensitive(key) {
+				v[key] = redacted
+				continue
+			}
+			v[key] = redact(val)
+		}
+
+	case []any:
+		for i := range v {
+			v[i] = redact(v[i])
+		}
+	}
+
+	return v
+}
+
+func isSensitive(key string) bool {
+	key = strings.ToLower(key)
+	for _, f := range sensitiveFields {
+		if strings.Contains(key, f) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,69 @@
+package capture_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"lobbyte.com/alkeepy/app/api/capture"
+)
+
+func Test_AddSanitizes(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{name: "empty", body: "", want: ""},
+		{name: "no secrets", body: `{"name":"bread"}`, want: `{"name":"bread"}`},
+		{name: "sensitive key", body: `{"password":"hunter2","name":"bread"}`, want: `{"name":"bread","password":"[REDACTED]"}`},
+		{name: "key containing a sensitive word", body: `{"AccessToken":"abc"}`, want: `{"AccessToken":"[REDACTED]"}`},
+		{name: "nested", body: `{"user":{"card_number":"4242"},"items":[{"api_key":"k","qty":2}]}`, want: `{"items":[{"api_key":"[REDACTED]","qty":2}],"user":{"card_number":"[REDACTED]"}}`},
+		{name: "form", body: "password=hunter2&name=bread", want: "[unparsed 27 bytes]"},
+		{name: "truncated", body: `{"password":"hunt`, want: "[unparsed 17 bytes]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := capture.NewBuffer(1)
+			b.Add(capture.Entry{RequestBody: tt.body, ResponseBody: tt.body})
+
+			e := b.List()[0]
+
+			if e.RequestBody != tt.want {
+				t.Fatalf("Should keep the request body %s, got %s", tt.want, e.RequestBody)
+			}
+
+			if e.ResponseBody != tt.want {
+				t.Fatalf("Should keep the response body %s, got %s", tt.want, e.ResponseBody)
+			}
+
+			if strings.Contains(e.RequestBody, "hunter2") {
+				t.Fatalf("Should never keep a secret, got %s", e.RequestBody)
+			}
+		})
+	}
+}
+
+func Test_AddSanitizesHeaders(t *testing.T) {
+	h := http.Header{}
+	h.Set("Authorization", "Bearer abc")
+	h.Set("Content-Type", "application/json")
+
+	b := capture.NewBuffer(1)
+	b.Add(capture.Entry{RequestHeaders: h})
+
+	got := b.List()[0].RequestHeaders
+
+	if v := got.Get("Authorization"); v != "[REDACTED]" {
+		t.Fatalf("Should redact the authorization header, got %q", v)
+	}
+
+	if v := got.Get("Content-Type"); v != "application/json" {
+		t.Fatalf("Should keep the content type header, got %q", v)
+	}
+
+	if v := h.Get("Authorization"); v != "Bearer abc" {
+		t.Fatalf("Should leave the headers of the caller untouched, got %q", v)
+	}
+}
@@ -0,0 +1,68 @@
+package debug
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"lobbyte.com/alkeepy/app/api/capture"
+)
+
+// captures returns the captured requests, newest first. The bodies may
+// contain customer data so requests must be authenticated with the debug
+// token.
+func captures(token string, buf *capture.Buffer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(r, token) {
+			writeJSON(w, Info{Status: "unauthorized"}, http.StatusUnauthorized)
+			return
+		}
+
+		if buf == nil {
+			writeJSON(w, Info{Status: "capture is not available"}, http.StatusNotFound)
+			return
+		}
+
+		data := struct {
+			Settings capture.Settings `json:"settings"`
+			Entries  []capture.Entry  `json:"entries"`
+		}{
+			Settings: buf.Settings(),
+			Entries:  buf.List(),
+		}
+
+		writeJSON(w, data, http.StatusOK)
+	}
+}
+
+// setCapture turns capturing on or off and sets the fraction of requests that
+// are captured. Turning capturing off discards the captured requests.
+// Requests must be authenticated with the debug token.
+func setCapture(log *slog.Logger, token string, buf *capture.Buffer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(r, token) {
+			writeJSON(w, Info{Status: "unauthorized"}, http.StatusUnauthorized)
+			return
+		}
+
+		if buf == nil {
+			writeJSON(w, Info{Status: "capture is not available"}, http.StatusNotFound)
+			return
+		}
+
+		var s capture.Settings
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1024)).Decode(&s); err != nil {
+			writeJSON(w, Info{Status: "invalid request body"}, http.StatusBadRequest)
+			return
+		}
+
+		buf.Set(s)
+		if !s.Enabled {
+			buf.Reset()
+		}
+
+		log.InfoContext(r.Context(), "capture settings changed", "enabled", s.Enabled, "rate", buf.Settings().Rate)
+
+		writeJSON(w, buf.Settings(), http.StatusOK)
+	}
+}
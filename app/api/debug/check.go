@@ -0,0 +1,91 @@
+package debug
+
+import (
+	"log/slog"
+	"net/http"
+	"os"
+	"runtime"
+	"sync/atomic"
+	"time"
+
+	"lobbyte.com/alkeepy/foundation/buildinfo"
+	"lobbyte.com/alkeepy/foundation/health"
+)
+
+// started records when the process started for reporting uptime.
+var started = time.Now()
+
+// Info represents information about the service.
+type Info struct {
+	Status     string `json:"status,omitempty"`
+	Build      string `json:"build,omitempty"`
+	Revision   string `json:"revision,omitempty"`
+	VCSTime    string `json:"vcsTime,omitempty"`
+	Modified   bool   `json:"modified,omitempty"`
+	GoVersion  string `json:"goVersion,omitempty"`
+	Host       string `json:"host,omitempty"`
+	Name       string `json:"name,omitempty"`
+	PodIP      string `json:"podIP,omitempty"`
+	Node       string `json:"node,omitempty"`
+	Namespace  string `json:"namespace,omitempty"`
+	GOMAXPROCS int    `json:"GOMAXPROCS,omitempty"`
+	Uptime     string `json:"uptime,omitempty"`
+}
+
+// liveness returns simple status info if the service is alive. If the
+// app is deployed to a Kubernetes cluster, it will also return pod, node, and
+// namespace details via the Downward API. The Kubernetes environment variables
+// need to be set within your Pod/Deployment manifest.
+func liveness(bi buildinfo.Info) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		host, err := os.Hostname()
+		if err != nil {
+			host = "unavailable"
+		}
+
+		info := Info{
+			Status:     "up",
+			Build:      bi.Build,
+			Revision:   bi.Revision,
+			VCSTime:    bi.Time,
+			Modified:   bi.Modified,
+			GoVersion:  bi.GoVersion,
+			Host:       host,
+			Name:       os.Getenv("KUBERNETES_NAME"),
+			PodIP:      os.Getenv("KUBERNETES_POD_IP"),
+			Node:       os.Getenv("KUBERNETES_NODE_NAME"),
+			Namespace:  os.Getenv("KUBERNETES_NAMESPACE"),
+			GOMAXPROCS: runtime.GOMAXPROCS(0),
+			Uptime:     time.Since(started).Round(time.Second).String(),
+		}
+
+		writeJSON(w, info, http.StatusOK)
+	}
+}
+
+// readiness runs the registered health checks and returns a 500 status with
+// a breakdown of the failing dependencies if any of them are unhealthy. Once
+// shutdown has started a 503 is returned so the load balancers stop routing
+// new requests to the service.
+func readiness(log *slog.Logger, checks *health.Registry, draining *atomic.Bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if draining != nil && draining.Load() {
+			writeJSON(w, Info{Status: "draining"}, http.StatusServiceUnavailable)
+			return
+		}
+
+		if checks == nil {
+			writeJSON(w, health.Report{Status: health.StatusOK}, http.StatusOK)
+			return
+		}
+
+		report := checks.Run(r.Context())
+		if !report.Healthy() {
+			log.InfoContext(r.Context(), "readiness failure", "checks", report.Checks)
+			writeJSON(w, report, http.StatusInternalServerError)
+			return
+		}
+
+		writeJSON(w, report, http.StatusOK)
+	}
+}
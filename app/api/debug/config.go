@@ -0,0 +1,24 @@
+package debug
+
+import (
+	"net/http"
+)
+
+// config returns the configuration the process is running with, including
+// the values changed by a reload, with the secrets masked. Requests must be
+// authenticated with the debug token.
+func config(token string, fn func() map[string]any) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(r, token) {
+			writeJSON(w, Info{Status: "unauthorized"}, http.StatusUnauthorized)
+			return
+		}
+
+		if fn == nil {
+			writeJSON(w, Info{Status: "config is not available"}, http.StatusNotFound)
+			return
+		}
+
+		writeJSON(w, fn(), http.StatusOK)
+	}
+}
@@ -0,0 +1,100 @@
+// Package debug provides the handlers bound to the debug host.
+package debug
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"log/slog"
+	"net/http"
+	"net/http/pprof"
+	"sync/atomic"
+
+	"lobbyte.com/alkeepy/app/api/capture"
+	"lobbyte.com/alkeepy/app/api/featureflag"
+	"lobbyte.com/alkeepy/app/api/metrics"
+	"lobbyte.com/alkeepy/business/sdk/migrate"
+	"lobbyte.com/alkeepy/foundation/buildinfo"
+	"lobbyte.com/alkeepy/foundation/health"
+	"lobbyte.com/alkeepy/foundation/web"
+)
+
+// RouteLister represents an App whose routes can be introspected.
+type RouteLister interface {
+	Routes() []web.Route
+}
+
+// Config contains all the mandatory systems required by the debug handlers.
+// Migrations is nil when the service runs without a database.
+type Config struct {
+	Build      buildinfo.Info
+	Log        *slog.Logger
+	LogLevel   *slog.LevelVar
+	LogRate    *atomic.Int64
+	Capture    *capture.Buffer
+	Flags      *featureflag.Flags
+	Health     *health.Registry
+	Draining   *atomic.Bool
+	Apps       map[string]RouteLister
+	Token      string
+	DumpDir    string
+	Reload     func(ctx context.Context) error
+	Config     func() map[string]any
+	Migrations func(ctx context.Context) (migrate.Status, error)
+}
+
+// Mux registers all the debug routes from the standard library into a new mux
+// bypassing the use of the DefaultServerMux. Using the DefaultServerMux would
+// be a security risk since a dependency could inject a handler into our
+// service without us knowing it. The debug host is never exposed to the
+// public so these routes bypass the application middleware.
+func Mux(cfg Config) *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+	mux.Handle("GET /metrics", metrics.Handler())
+
+	mux.HandleFunc("GET /debug/readiness", readiness(cfg.Log, cfg.Health, cfg.Draining))
+	mux.HandleFunc("GET /debug/liveness", liveness(cfg.Build))
+	mux.HandleFunc("GET /debug/routes", routes(cfg.Apps))
+	mux.HandleFunc("GET /debug/runtime", runtimeStats())
+	mux.HandleFunc("POST /debug/dump", dump(cfg.Log, cfg.Token, cfg.DumpDir))
+	mux.HandleFunc("GET /debug/loglevel", getLogLevel(cfg.LogLevel))
+	mux.HandleFunc("PUT /debug/loglevel", setLogLevel(cfg.Log, cfg.Token, cfg.LogLevel))
+	mux.HandleFunc("GET /debug/logsample", getLogSample(cfg.LogRate))
+	mux.HandleFunc("PUT /debug/logsample", setLogSample(cfg.Log, cfg.Token, cfg.LogRate))
+	mux.HandleFunc("GET /debug/captures", captures(cfg.Token, cfg.Capture))
+	mux.HandleFunc("PUT /debug/captures", setCapture(cfg.Log, cfg.Token, cfg.Capture))
+	mux.HandleFunc("GET /debug/flags", featureFlags(cfg.Flags))
+	mux.HandleFunc("PUT /debug/flags", setFeatureFlag(cfg.Log, cfg.Token, cfg.Flags))
+	mux.HandleFunc("GET /debug/config", config(cfg.Token, cfg.Config))
+	mux.HandleFunc("POST /debug/config/reload", reload(cfg.Log, cfg.Token, cfg.Reload))
+	mux.HandleFunc("GET /debug/migrations", migrations(cfg.Log, cfg.Token, cfg.Migrations))
+
+	return mux
+}
+
+// routes returns the routes bound to each of the apps served by the process.
+func routes(apps map[string]RouteLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		data := make(map[string][]web.Route, len(apps))
+		for name, app := range apps {
+			data[name] = app.Routes()
+		}
+
+		writeJSON(w, data, http.StatusOK)
+	}
+}
+
+// writeJSON sends the data to the client as JSON.
+func writeJSON(w http.ResponseWriter, data any, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	json.NewEncoder(w).Encode(data)
+}
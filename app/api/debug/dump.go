@@ -0,0 +1,101 @@
+package debug
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"strings"
+	"time"
+)
+
+// authorized reports whether the request carries the bearer token. An empty
+// token disables the protected endpoints.
+func authorized(r *http.Request, token string) bool {
+	if token == "" {
+		return false
+	}
+
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
+
+// dump captures a heap profile or a full goroutine stack dump. The dump is
+// streamed back to the client unless a dump directory is configured, in which
+// case it's written to a file in that directory and the path is returned.
+// Requests must be authenticated with the debug token.
+func dump(log *slog.Logger, token string, dir string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(r, token) {
+			writeJSON(w, Info{Status: "unauthorized"}, http.StatusUnauthorized)
+			return
+		}
+
+		kind := r.URL.Query().Get("type")
+		if kind == "" {
+			kind = "goroutine"
+		}
+
+		var write func(f *os.File) error
+		var stream func(w http.ResponseWriter) error
+
+		switch kind {
+		case "heap":
+			runtime.GC()
+			write = func(f *os.File) error { return pprof.WriteHeapProfile(f) }
+			stream = func(w http.ResponseWriter) error {
+				w.Header().Set("Content-Type", "application/octet-stream")
+				w.Header().Set("Content-Disposition", `attachment; filename="heap.pprof"`)
+				return pprof.WriteHeapProfile(w)
+			}
+
+		case "goroutine":
+			write = func(f *os.File) error { return pprof.Lookup("goroutine").WriteTo(f, 2) }
+			stream = func(w http.ResponseWriter) error {
+				w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+				return pprof.Lookup("goroutine").WriteTo(w, 2)
+			}
+
+		default:
+			writeJSON(w, Info{Status: fmt.Sprintf("unknown dump type %q", kind)}, http.StatusBadRequest)
+			return
+		}
+
+		if dir == "" {
+			if err := stream(w); err != nil {
+				log.ErrorContext(r.Context(), "debug dump", "type", kind, "msg", err)
+			}
+			return
+		}
+
+		name := filepath.Join(dir, fmt.Sprintf("%s-%s.dump", kind, time.Now().UTC().Format("20060102T150405Z")))
+
+		f, err := os.Create(name)
+		if err != nil {
+			log.ErrorContext(r.Context(), "debug dump", "type", kind, "msg", err)
+			writeJSON(w, Info{Status: "creating dump file failed"}, http.StatusInternalServerError)
+			return
+		}
+		defer f.Close()
+
+		if err := write(f); err != nil {
+			log.ErrorContext(r.Context(), "debug dump", "type", kind, "msg", err)
+			writeJSON(w, Info{Status: "writing dump failed"}, http.StatusInternalServerError)
+			return
+		}
+
+		log.InfoContext(r.Context(), "debug dump", "type", kind, "path", name)
+
+		writeJSON(w, struct {
+			Path string `json:"path"`
+		}{Path: name}, http.StatusOK)
+	}
+}
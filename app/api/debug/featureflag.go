@@ -0,0 +1,73 @@
+package debug
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"lobbyte.com/alkeepy/app/api/featureflag"
+)
+
+// FeatureFlag represents a change to a feature flag. A nil Enabled puts the
+// flag back to its default.
+type FeatureFlag struct {
+	Name    string `json:"name"`
+	Enabled *bool  `json:"enabled"`
+}
+
+// featureFlags returns the state of every feature flag.
+func featureFlags(flags *featureflag.Flags) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if flags == nil {
+			writeJSON(w, Info{Status: "feature flags are not available"}, http.StatusNotFound)
+			return
+		}
+
+		writeJSON(w, flags.List(), http.StatusOK)
+	}
+}
+
+// setFeatureFlag turns a feature flag on or off without a restart. Requests
+// must be authenticated with the debug token.
+func setFeatureFlag(log *slog.Logger, token string, flags *featureflag.Flags) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(r, token) {
+			writeJSON(w, Info{Status: "unauthorized"}, http.StatusUnauthorized)
+			return
+		}
+
+		if flags == nil {
+			writeJSON(w, Info{Status: "feature flags are not available"}, http.StatusNotFound)
+			return
+		}
+
+		var ff FeatureFlag
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1024)).Decode(&ff); err != nil {
+			writeJSON(w, Info{Status: "invalid request body"}, http.StatusBadRequest)
+			return
+		}
+
+		var err error
+		switch ff.Enabled {
+		case nil:
+			err = flags.Reset(ff.Name)
+		default:
+			err = flags.Set(ff.Name, *ff.Enabled)
+		}
+
+		if err != nil {
+			status := http.StatusBadRequest
+			if errors.Is(err, featureflag.ErrUnknown) {
+				status = http.StatusNotFound
+			}
+
+			writeJSON(w, Info{Status: err.Error()}, status)
+			return
+		}
+
+		log.InfoContext(r.Context(), "feature flag changed", "name", ff.Name, "enabled", flags.Enabled(ff.Name), "default", ff.Enabled == nil)
+
+		writeJSON(w, flags.List(), http.StatusOK)
+	}
+}
@@ -0,0 +1,49 @@
+package debug
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+)
+
+// LogLevel represents the level of the logger.
+type LogLevel struct {
+	Level string `json:"level"`
+}
+
+// getLogLevel returns the current level of the logger.
+func getLogLevel(level *slog.LevelVar) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, LogLevel{Level: level.Level().String()}, http.StatusOK)
+	}
+}
+
+// setLogLevel changes the level of the logger without a restart. Requests
+// must be authenticated with the debug token.
+func setLogLevel(log *slog.Logger, token string, level *slog.LevelVar) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(r, token) {
+			writeJSON(w, Info{Status: "unauthorized"}, http.StatusUnauthorized)
+			return
+		}
+
+		var ll LogLevel
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1024)).Decode(&ll); err != nil {
+			writeJSON(w, Info{Status: "invalid request body"}, http.StatusBadRequest)
+			return
+		}
+
+		var newLevel slog.Level
+		if err := newLevel.UnmarshalText([]byte(ll.Level)); err != nil {
+			writeJSON(w, Info{Status: err.Error()}, http.StatusBadRequest)
+			return
+		}
+
+		old := level.Level()
+		level.Set(newLevel)
+
+		log.InfoContext(r.Context(), "log level changed", "from", old, "to", newLevel)
+
+		writeJSON(w, LogLevel{Level: newLevel.String()}, http.StatusOK)
+	}
+}
@@ -0,0 +1,49 @@
+package debug
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+)
+
+// LogSample represents the sample rate of the request logs.
+type LogSample struct {
+	Rate int64 `json:"rate"`
+}
+
+// getLogSample returns the current sample rate of the request logs.
+func getLogSample(rate *atomic.Int64) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, LogSample{Rate: rate.Load()}, http.StatusOK)
+	}
+}
+
+// setLogSample changes the sample rate of the request logs without a restart.
+// A rate of 1 logs every request. Requests must be authenticated with the
+// debug token.
+func setLogSample(log *slog.Logger, token string, rate *atomic.Int64) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(r, token) {
+			writeJSON(w, Info{Status: "unauthorized"}, http.StatusUnauthorized)
+			return
+		}
+
+		var ls LogSample
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1024)).Decode(&ls); err != nil {
+			writeJSON(w, Info{Status: "invalid request body"}, http.StatusBadRequest)
+			return
+		}
+
+		if ls.Rate < 1 {
+			writeJSON(w, Info{Status: "rate must be 1 or more"}, http.StatusBadRequest)
+			return
+		}
+
+		old := rate.Swap(ls.Rate)
+
+		log.InfoContext(r.Context(), "log sample rate changed", "from", old, "to", ls.Rate)
+
+		writeJSON(w, ls, http.StatusOK)
+	}
+}
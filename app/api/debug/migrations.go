@@ -0,0 +1,35 @@
+package debug
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"lobbyte.com/alkeepy/business/sdk/migrate"
+)
+
+// migrations returns the applied and pending migrations of the database the
+// process is connected to. Requests must be authenticated with the debug
+// token.
+func migrations(log *slog.Logger, token string, fn func(ctx context.Context) (migrate.Status, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(r, token) {
+			writeJSON(w, Info{Status: "unauthorized"}, http.StatusUnauthorized)
+			return
+		}
+
+		if fn == nil {
+			writeJSON(w, Info{Status: "migrations are not available without a database"}, http.StatusNotFound)
+			return
+		}
+
+		status, err := fn(r.Context())
+		if err != nil {
+			log.ErrorContext(r.Context(), "migration status", "msg", err)
+			writeJSON(w, Info{Status: err.Error()}, http.StatusInternalServerError)
+			return
+		}
+
+		writeJSON(w, status, http.StatusOK)
+	}
+}
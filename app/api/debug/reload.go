@@ -0,0 +1,31 @@
+package debug
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+)
+
+// reload re-reads the configuration and applies the values that can change
+// without a restart. Requests must be authenticated with the debug token.
+func reload(log *slog.Logger, token string, fn func(ctx context.Context) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(r, token) {
+			writeJSON(w, Info{Status: "unauthorized"}, http.StatusUnauthorized)
+			return
+		}
+
+		if fn == nil {
+			writeJSON(w, Info{Status: "reload not supported"}, http.StatusNotImplemented)
+			return
+		}
+
+		if err := fn(r.Context()); err != nil {
+			log.ErrorContext(r.Context(), "config reload", "msg", err)
+			writeJSON(w, Info{Status: err.Error()}, http.StatusBadRequest)
+			return
+		}
+
+		writeJSON(w, Info{Status: "reloaded"}, http.StatusOK)
+	}
+}
@@ -0,0 +1,142 @@
+package debug
+
+import (
+	"math"
+	"net/http"
+	"runtime"
+	rdebug "runtime/debug"
+	"runtime/metrics"
+	"time"
+)
+
+// gcPauseHistory is the number of recent GC pauses reported.
+const gcPauseHistory = 32
+
+// GCStats represents the garbage collector history.
+type GCStats struct {
+	NumGC          int64           `json:"numGC"`
+	LastGC         time.Time       `json:"lastGC"`
+	PauseTotal     string          `json:"pauseTotal"`
+	Pauses         []time.Duration `json:"pausesNS"`
+	PauseQuantiles []time.Duration `json:"pauseQuantilesNS"`
+}
+
+// SchedStats represents the state of the scheduler.
+type SchedStats struct {
+	GOMAXPROCS  int               `json:"GOMAXPROCS"`
+	NumCPU      int               `json:"numCPU"`
+	Goroutines  int               `json:"goroutines"`
+	NumCgoCall  int64             `json:"numCgoCall"`
+	LatencyP50  float64           `json:"latencyP50Seconds"`
+	LatencyP99  float64           `json:"latencyP99Seconds"`
+	RuntimeVals map[string]uint64 `json:"runtime"`
+}
+
+// RuntimeStats is a snapshot of the runtime statistics of the process.
+type RuntimeStats struct {
+	MemStats runtime.MemStats `json:"memStats"`
+	GC       GCStats          `json:"gc"`
+	Sched    SchedStats       `json:"sched"`
+}
+
+// runtimeStats returns a snapshot of the memory, GC and scheduler statistics
+// so memory growth can be diagnosed without attaching a profiler.
+func runtimeStats() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var stats RuntimeStats
+
+		runtime.ReadMemStats(&stats.MemStats)
+
+		gc := rdebug.GCStats{
+			PauseQuantiles: make([]time.Duration, 5),
+		}
+		rdebug.ReadGCStats(&gc)
+
+		pauses := gc.Pause
+		if len(pauses) > gcPauseHistory {
+			pauses = pauses[:gcPauseHistory]
+		}
+
+		stats.GC = GCStats{
+			NumGC:          gc.NumGC,
+			LastGC:         gc.LastGC,
+			PauseTotal:     gc.PauseTotal.String(),
+			Pauses:         pauses,
+			PauseQuantiles: gc.PauseQuantiles,
+		}
+
+		stats.Sched = schedStats()
+
+		writeJSON(w, stats, http.StatusOK)
+	}
+}
+
+// schedStats reads the scheduler metrics from the runtime.
+func schedStats() SchedStats {
+	samples := []metrics.Sample{
+		{Name: "/sched/latencies:seconds"},
+		{Name: "/sched/goroutines:goroutines"},
+		{Name: "/sched/gomaxprocs:threads"},
+	}
+	metrics.Read(samples)
+
+	ss := SchedStats{
+		GOMAXPROCS:  runtime.GOMAXPROCS(0),
+		NumCPU:      runtime.NumCPU(),
+		Goroutines:  runtime.NumGoroutine(),
+		NumCgoCall:  runtime.NumCgoCall(),
+		RuntimeVals: make(map[string]uint64),
+	}
+
+	for _, s := range samples {
+		switch s.Value.Kind() {
+		case metrics.KindUint64:
+			ss.RuntimeVals[s.Name] = s.Value.Uint64()
+
+		case metrics.KindFloat64Histogram:
+			h := s.Value.Float64Histogram()
+			ss.LatencyP50 = quantile(h, 0.50)
+			ss.LatencyP99 = quantile(h, 0.99)
+		}
+	}
+
+	return ss
+}
+
+// quantile estimates the quantile of the histogram using the upper bound of
+// the bucket the quantile falls into. The lower bound is used for the open
+// ended buckets since infinity can't be encoded as JSON.
+func quantile(h *metrics.Float64Histogram, q float64) float64 {
+	var total uint64
+	for _, c := range h.Counts {
+		total += c
+	}
+
+	if total == 0 {
+		return 0
+	}
+
+	target := uint64(float64(total) * q)
+
+	var seen uint64
+	for i, c := range h.Counts {
+		seen += c
+		if seen >= target {
+			return finite(h.Buckets[i+1], h.Buckets[i])
+		}
+	}
+
+	return 0
+}
+
+// finite returns v, or the fallback when v is infinite.
+func finite(v float64, fallback float64) float64 {
+	if math.IsInf(v, 0) {
+		if math.IsInf(fallback, 0) {
+			return 0
+		}
+		return fallback
+	}
+
+	return v
+}
@@ -0,0 +1,152 @@
+// Package errs provides types and support related to web error functionality.
+package errs
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime"
+
+	"lobbyte.com/alkeepy/foundation/validate"
+)
+
+// ErrCode represents an error code in the system.
+type ErrCode struct {
+	value int
+}
+
+// Value returns the integer value of the error code.
+func (ec ErrCode) Value() int {
+	return ec.value
+}
+
+// String returns the string representation of the error code.
+func (ec ErrCode) String() string {
+	return codeNames[ec]
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (ec ErrCode) MarshalText() ([]byte, error) {
+	return []byte(ec.String()), nil
+}
+
+// HTTPStatus returns the http status code associated with the error code.
+func (ec ErrCode) HTTPStatus() int {
+	return httpStatus[ec]
+}
+
+// The set of error codes that can be returned to a client.
+var (
+	InvalidArgument    = ErrCode{value: 1}
+	Unauthenticated    = ErrCode{value: 2}
+	NotFound           = ErrCode{value: 3}
+	Internal           = ErrCode{value: 4}
+	PayloadTooLarge    = ErrCode{value: 5}
+	DeadlineExceeded   = ErrCode{value: 6}
+	PreconditionFailed = ErrCode{value: 7}
+	MethodNotAllowed   = ErrCode{value: 8}
+	Conflict           = ErrCode{value: 9}
+	PermissionDenied   = ErrCode{value: 10}
+)
+
+var codeNames = map[ErrCode]string{
+	InvalidArgument:    "invalid_argument",
+	Unauthenticated:    "unauthenticated",
+	NotFound:           "not_found",
+	Internal:           "internal",
+	PayloadTooLarge:    "payload_too_large",
+	DeadlineExceeded:   "deadline_exceeded",
+	PreconditionFailed: "precondition_failed",
+	MethodNotAllowed:   "method_not_allowed",
+	Conflict:           "conflict",
+	PermissionDenied:   "permission_denied",
+}
+
+var httpStatus = map[ErrCode]int{
+	InvalidArgument:    http.StatusBadRequest,
+	Unauthenticated:    http.StatusUnauthorized,
+	NotFound:           http.StatusNotFound,
+	Internal:           http.StatusInternalServerError,
+	PayloadTooLarge:    http.StatusRequestEntityTooLarge,
+	DeadlineExceeded:   http.StatusGatewayTimeout,
+	PreconditionFailed: http.StatusPreconditionFailed,
+	MethodNotAllowed:   http.StatusMethodNotAllowed,
+	Conflict:           http.StatusConflict,
+	PermissionDenied:   http.StatusForbidden,
+}
+
+// =============================================================================
+
+// Error represents an error in the system that is trusted to be returned
+// to the client.
+type Error struct {
+	Code     ErrCode           `json:"code"`
+	Message  string            `json:"message"`
+	Fields   map[string]string `json:"fields,omitempty"`
+	FuncName string            `json:"-"`
+	FileName string            `json:"-"`
+	err      error
+}
+
+// New constructs an error based on an app error. Field level validation
+// errors are preserved so they can be reported back to the client.
+func New(code ErrCode, err error) *Error {
+	pc, filename, line, _ := runtime.Caller(1)
+
+	e := Error{
+		Code:     code,
+		Message:  err.Error(),
+		FuncName: runtime.FuncForPC(pc).Name(),
+		FileName: fmt.Sprintf("%s:%d", filename, line),
+		err:      err,
+	}
+
+	if fe := validate.GetFieldErrors(err); fe != nil {
+		e.Message = "data validation error"
+		e.Fields = fe.Fields()
+	}
+
+	return &e
+}
+
+// Newf constructs an error based on a error message.
+func Newf(code ErrCode, format string, v ...any) *Error {
+	pc, filename, line, _ := runtime.Caller(1)
+
+	return &Error{
+		Code:     code,
+		Message:  fmt.Sprintf(format, v...),
+		FuncName: runtime.FuncForPC(pc).Name(),
+		FileName: fmt.Sprintf("%s:%d", filename, line),
+	}
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Unwrap provides access to the error the app error was constructed from.
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+// HTTPStatus returns the http status code for the error.
+func (e *Error) HTTPStatus() int {
+	return e.Code.HTTPStatus()
+}
+
+// IsError tests the concrete error is of the Error type.
+func IsError(err error) bool {
+	var er *Error
+	return errors.As(err, &er)
+}
+
+// GetError returns a copy of the Error pointer.
+func GetError(err error) *Error {
+	var er *Error
+	if !errors.As(err, &er) {
+		return nil
+	}
+	return er
+}
@@ -0,0 +1,162 @@
+// Package featureflag provides flags that turn features on and off while the
+// service is running so they can be launched dark and enabled safely.
+package featureflag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// ErrUnknown is returned when a flag isn't defined.
+var ErrUnknown = errors.New("unknown feature flag")
+
+// Flag describes a feature that can be turned on and off.
+type Flag struct {
+	Name        string
+	Description string
+	Default     bool
+}
+
+// Status is the current state of a flag.
+type Status struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Default     bool   `json:"default"`
+	Enabled     bool   `json:"enabled"`
+	Overridden  bool   `json:"overridden"`
+}
+
+// Flags holds the state of every defined flag. The flags are defined in code
+// and can be overridden by the config and at runtime.
+type Flags struct {
+	mu        sync.RWMutex
+	defined   map[string]Flag
+	overrides map[string]bool
+}
+
+// New constructs the set of defined flags.
+func New(flags ...Flag) *Flags {
+	f := Flags{
+		defined:   make(map[string]Flag, len(flags)),
+		overrides: make(map[string]bool),
+	}
+
+	for _, flag := range flags {
+		f.defined[flag.Name] = flag
+	}
+
+	return &f
+}
+
+// Enabled reports whether the flag is on. Flags that aren't defined are
+// always off.
+func (f *Flags) Enabled(name string) bool {
+	if f == nil {
+		return false
+	}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if enabled, exists := f.overrides[name]; exists {
+		return enabled
+	}
+
+	return f.defined[name].Default
+}
+
+// Set overrides the default of the flag.
+func (f *Flags) Set(name string, enabled bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, exists := f.defined[name]; !exists {
+		return fmt.Errorf("%w: %s", ErrUnknown, name)
+	}
+
+	f.overrides[name] = enabled
+
+	return nil
+}
+
+// Reset puts the flag back to its default.
+func (f *Flags) Reset(name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, exists := f.defined[name]; !exists {
+		return fmt.Errorf("%w: %s", ErrUnknown, name)
+	}
+
+	delete(f.overrides, name)
+
+	return nil
+}
+
+// Override applies the overrides from the config. Every flag is checked so
+// a misspelled name is reported at startup.
+func (f *Flags) Override(overrides map[string]bool) error {
+	var unknown []string
+	for name, enabled := range overrides {
+		if err := f.Set(name, enabled); err != nil {
+			unknown = append(unknown, name)
+		}
+	}
+
+	if len(unknown) > 0 {
+		slices.Sort(unknown)
+		return fmt.Errorf("%w: %s", ErrUnknown, strings.Join(unknown, ", "))
+	}
+
+	return nil
+}
+
+// List returns the state of every defined flag ordered by name.
+func (f *Flags) List() []Status {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	list := make([]Status, 0, len(f.defined))
+	for _, flag := range f.defined {
+		enabled, overridden := f.overrides[flag.Name]
+		if !overridden {
+			enabled = flag.Default
+		}
+
+		list = append(list, Status{
+			Name:        flag.Name,
+			Description: flag.Description,
+			Default:     flag.Default,
+			Enabled:     enabled,
+			Overridden:  overridden,
+		})
+	}
+
+	slices.SortFunc(list, func(a, b Status) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return list
+}
+
+// =============================================================================
+
+type ctxKey int
+
+const key ctxKey = 1
+
+// WithFlags returns a context holding the flags.
+func WithFlags(ctx context.Context, f *Flags) context.Context {
+	return context.WithValue(ctx, key, f)
+}
+
+// Enabled reports whether the flag is on for the flags in the context. Every
+// flag is off when the context doesn't hold any flags.
+func Enabled(ctx context.Context, name string) bool {
+	f, _ := ctx.Value(key).(*Flags)
+	return f.Enabled(name)
+}
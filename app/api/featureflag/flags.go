@@ -0,0 +1,17 @@
+package featureflag
+
+// The names of the flags defined by the service.
+const (
+	RecipeHistory = "recipe-history"
+)
+
+// Defined returns the flags of the service with their defaults.
+func Defined() []Flag {
+	return []Flag{
+		{
+			Name:        RecipeHistory,
+			Description: "serve the revision history of recipes and revert them to previous versions",
+			Default:     false,
+		},
+	}
+}
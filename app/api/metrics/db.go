@@ -0,0 +1,131 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"expvar"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// dbPools holds the expvar view of the last statistics sampled for each
+// database pool, one map per pool.
+var dbPools = expvar.NewMap("db_pools")
+
+var (
+	dbMu      sync.Mutex
+	dbSamples = make(map[string]sql.DBStats)
+)
+
+func init() {
+	registry.MustRegister(dbCollector{})
+}
+
+// SampleDB records the statistics of the database pool every interval until
+// the context is canceled. The pool name tells the pools apart, like primary
+// and replica. It's meant to be run in its own goroutine.
+func SampleDB(ctx context.Context, interval time.Duration, pool string, stats func() sql.DBStats) {
+	sampleDB(pool, stats())
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-ticker.C:
+			sampleDB(pool, stats())
+		}
+	}
+}
+
+func sampleDB(pool string, st sql.DBStats) {
+	dbMu.Lock()
+	dbSamples[pool] = st
+	dbMu.Unlock()
+
+	v := new(expvar.Map).Init()
+	for name, n := range dbValues(st) {
+		v.Set(name, intVar(n))
+	}
+	v.Set("wait_duration_ms", intVar(st.WaitDuration.Milliseconds()))
+
+	dbPools.Set(pool, v)
+
+	if s := currentSink(); s != nil {
+		tag := "pool:" + pool
+		for name, n := range dbValues(st) {
+			s.Gauge("db."+name, float64(n), tag)
+		}
+		s.Gauge("db.wait_duration_ms", float64(st.WaitDuration.Milliseconds()), tag)
+	}
+}
+
+// dbValues returns the counts of the statistics by name.
+func dbValues(st sql.DBStats) map[string]int64 {
+	return map[string]int64{
+		"max_open_connections": int64(st.MaxOpenConnections),
+		"open_connections":     int64(st.OpenConnections),
+		"in_use":               int64(st.InUse),
+		"idle":                 int64(st.Idle),
+		"wait_count":           st.WaitCount,
+		"max_idle_closed":      st.MaxIdleClosed,
+		"max_idle_time_closed": st.MaxIdleTimeClosed,
+		"max_lifetime_closed":  st.MaxLifetimeClosed,
+	}
+}
+
+// intVar returns an expvar integer holding the value.
+func intVar(n int64) *expvar.Int {
+	i := new(expvar.Int)
+	i.Set(n)
+	return i
+}
+
+// =============================================================================
+
+var (
+	dbMaxOpenDesc     = prometheus.NewDesc("wasfa_db_max_open_connections", "Maximum number of open connections to the database, 0 for unlimited.", []string{"pool"}, nil)
+	dbOpenDesc        = prometheus.NewDesc("wasfa_db_open_connections", "Number of established connections both in use and idle.", []string{"pool"}, nil)
+	dbInUseDesc       = prometheus.NewDesc("wasfa_db_in_use_connections", "Number of connections currently in use.", []string{"pool"}, nil)
+	dbIdleDesc        = prometheus.NewDesc("wasfa_db_idle_connections", "Number of idle connections.", []string{"pool"}, nil)
+	dbWaitCountDesc   = prometheus.NewDesc("wasfa_db_wait_count_total", "Number of connections waited for because the pool was at its maximum.", []string{"pool"}, nil)
+	dbWaitSecondsDesc = prometheus.NewDesc("wasfa_db_wait_duration_seconds_total", "Time spent waiting for a connection.", []string{"pool"}, nil)
+	dbClosedDesc      = prometheus.NewDesc("wasfa_db_closed_connections_total", "Number of connections closed by the pool by reason.", []string{"pool", "reason"}, nil)
+)
+
+// dbCollector reports the last sampled statistics of each database pool.
+type dbCollector struct{}
+
+// Describe implements the prometheus.Collector interface.
+func (dbCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- dbMaxOpenDesc
+	ch <- dbOpenDesc
+	ch <- dbInUseDesc
+	ch <- dbIdleDesc
+	ch <- dbWaitCountDesc
+	ch <- dbWaitSecondsDesc
+	ch <- dbClosedDesc
+}
+
+// Collect implements the prometheus.Collector interface.
+func (dbCollector) Collect(ch chan<- prometheus.Metric) {
+	dbMu.Lock()
+	defer dbMu.Unlock()
+
+	for pool, st := range dbSamples {
+		ch <- prometheus.MustNewConstMetric(dbMaxOpenDesc, prometheus.GaugeValue, float64(st.MaxOpenConnections), pool)
+		ch <- prometheus.MustNewConstMetric(dbOpenDesc, prometheus.GaugeValue, float64(st.OpenConnections), pool)
+		ch <- prometheus.MustNewConstMetric(dbInUseDesc, prometheus.GaugeValue, float64(st.InUse), pool)
+		ch <- prometheus.MustNewConstMetric(dbIdleDesc, prometheus.GaugeValue, float64(st.Idle), pool)
+		ch <- prometheus.MustNewConstMetric(dbWaitCountDesc, prometheus.CounterValue, float64(st.WaitCount), pool)
+		ch <- prometheus.MustNewConstMetric(dbWaitSecondsDesc, prometheus.CounterValue, st.WaitDuration.Seconds(), pool)
+		ch <- prometheus.MustNewConstMetric(dbClosedDesc, prometheus.CounterValue, float64(st.MaxIdleClosed), pool, "max_idle")
+		ch <- prometheus.MustNewConstMetric(dbClosedDesc, prometheus.CounterValue, float64(st.MaxIdleTimeClosed), pool, "max_idle_time")
+		ch <- prometheus.MustNewConstMetric(dbClosedDesc, prometheus.CounterValue, float64(st.MaxLifetimeClosed), pool, "max_lifetime")
+	}
+}
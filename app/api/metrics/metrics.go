@@ -0,0 +1,98 @@
+// Package metrics constructs the metrics the application will track.
+package metrics
+
+import (
+	"context"
+	"expvar"
+	"runtime"
+	"time"
+)
+
+// This holds the single instance of the metrics value needed for
+// collecting metrics. The expvar package is already based on a singleton
+// for the different metrics that are registered with the package so there
+// isn't much choice here.
+var m metrics
+
+// metrics represents the set of metrics we gather. These fields are
+// safe to be accessed concurrently thanks to expvar. No extra abstraction is
+// required.
+type metrics struct {
+	goroutines *expvar.Int
+	requests   *expvar.Int
+	errors     *expvar.Int
+	panics     *expvar.Int
+}
+
+// init constructs the metrics value that will be used to capture metrics.
+// The metrics value is stored in a package level variable since everything
+// inside of expvar is registered as a singleton.
+func init() {
+	m = metrics{
+		goroutines: expvar.NewInt("goroutines"),
+		requests:   expvar.NewInt("requests"),
+		errors:     expvar.NewInt("errors"),
+		panics:     expvar.NewInt("panics"),
+	}
+}
+
+// Sample records the number of goroutines every interval until the context
+// is canceled. It's meant to be run in its own goroutine.
+func Sample(ctx context.Context, interval time.Duration) {
+	sampleGoroutines()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-ticker.C:
+			sampleGoroutines()
+		}
+	}
+}
+
+func sampleGoroutines() {
+	n := runtime.NumGoroutine()
+	m.goroutines.Set(int64(n))
+
+	if s := currentSink(); s != nil {
+		s.Gauge("goroutines", float64(n))
+	}
+}
+
+// AddRequests increments the request metric by 1.
+func AddRequests(ctx context.Context) int64 {
+	m.requests.Add(1)
+
+	if s := currentSink(); s != nil {
+		s.Count("requests", 1)
+	}
+
+	return m.requests.Value()
+}
+
+// AddErrors increments the errors metric by 1.
+func AddErrors(ctx context.Context) int64 {
+	m.errors.Add(1)
+
+	if s := currentSink(); s != nil {
+		s.Count("errors", 1)
+	}
+
+	return m.errors.Value()
+}
+
+// AddPanics increments the panics metric by 1.
+func AddPanics(ctx context.Context) int64 {
+	m.panics.Add(1)
+
+	if s := currentSink(); s != nil {
+		s.Count("panics", 1)
+	}
+
+	return m.panics.Value()
+}
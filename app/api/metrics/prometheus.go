@@ -0,0 +1,35 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// registry holds the collectors exposed in the Prometheus exposition format.
+// A dedicated registry is used so dependencies can't register collectors
+// without us knowing about it.
+var registry = prometheus.NewRegistry()
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		collectors.NewExpvarCollector(map[string]*prometheus.Desc{
+			"goroutines":     prometheus.NewDesc("wasfa_goroutines_sampled", "Number of goroutines at the last sample.", nil, nil),
+			"requests":       prometheus.NewDesc("wasfa_requests_total", "Number of requests handled by the API.", nil, nil),
+			"errors":         prometheus.NewDesc("wasfa_errors_total", "Number of requests that failed with a server error.", nil, nil),
+			"panics":         prometheus.NewDesc("wasfa_panics_total", "Number of panics recovered by the API.", nil, nil),
+			"slow_queries":   prometheus.NewDesc("wasfa_slow_queries_total", "Number of database queries above the slow query threshold.", nil, nil),
+			"retention_rows": prometheus.NewDesc("wasfa_retention_rows_total", "Number of expired rows archived or purged by retention policy.", []string{"policy"}, nil),
+		}),
+	)
+}
+
+// Handler returns a handler that serves the metrics in the Prometheus
+// exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
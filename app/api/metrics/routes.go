@@ -0,0 +1,120 @@
+package metrics
+
+import (
+	"context"
+	"expvar"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// latencyBuckets are the upper bounds, in seconds, of the request latency
+// histogram.
+var latencyBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+var (
+	routeRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "wasfa_http_requests_total",
+		Help: "Number of requests handled by route and status code.",
+	}, []string{"route", "code"})
+
+	routeErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "wasfa_http_errors_total",
+		Help: "Number of requests by route that failed with a server error.",
+	}, []string{"route"})
+
+	routeLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "wasfa_http_request_duration_seconds",
+		Help:    "Latency of requests by route.",
+		Buckets: latencyBuckets,
+	}, []string{"route"})
+)
+
+func init() {
+	registry.MustRegister(routeRequests, routeErrors, routeLatency)
+}
+
+// =============================================================================
+
+// routes holds the expvar view of the per route metrics, one map per route.
+var routes = expvar.NewMap("routes")
+
+// routeStats is the expvar view of the metrics for a single route.
+type routeStats struct {
+	requests *expvar.Int
+	errors   *expvar.Int
+	latency  *expvar.Map
+}
+
+var (
+	statsMu sync.Mutex
+	stats   = make(map[string]*routeStats)
+)
+
+// lookupRoute returns the stats for the route, creating them on first use.
+func lookupRoute(route string) *routeStats {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+
+	if rs, exists := stats[route]; exists {
+		return rs
+	}
+
+	rs := routeStats{
+		requests: new(expvar.Int),
+		errors:   new(expvar.Int),
+		latency:  new(expvar.Map).Init(),
+	}
+
+	v := new(expvar.Map).Init()
+	v.Set("requests", rs.requests)
+	v.Set("errors", rs.errors)
+	v.Set("latency", rs.latency)
+
+	routes.Set(route, v)
+	stats[route] = &rs
+
+	return &rs
+}
+
+// AddRoute records a request handled by the specified route. A status code of
+// 500 or above is counted as an error.
+func AddRoute(ctx context.Context, route string, statusCode int, since time.Duration) {
+	isError := statusCode >= 500
+
+	routeRequests.WithLabelValues(route, strconv.Itoa(statusCode)).Inc()
+	routeLatency.WithLabelValues(route).Observe(since.Seconds())
+	if isError {
+		routeErrors.WithLabelValues(route).Inc()
+	}
+
+	rs := lookupRoute(route)
+	rs.requests.Add(1)
+	if isError {
+		rs.errors.Add(1)
+	}
+	rs.latency.Add(bucketFor(since), 1)
+
+	if s := currentSink(); s != nil {
+		tag := "route:" + strings.ReplaceAll(route, " ", "_")
+		s.Count("http.requests", 1, tag, "code:"+strconv.Itoa(statusCode))
+		s.Timing("http.latency", since, tag)
+		if isError {
+			s.Count("http.errors", 1, tag)
+		}
+	}
+}
+
+// bucketFor returns the name of the latency bucket the duration falls into.
+func bucketFor(since time.Duration) string {
+	for _, le := range latencyBuckets {
+		if since.Seconds() <= le {
+			return "le_" + strconv.FormatFloat(le, 'f', -1, 64)
+		}
+	}
+
+	return "le_inf"
+}
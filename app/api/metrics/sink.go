@@ -0,0 +1,41 @@
+package metrics
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Sink represents a metrics backend the counters and timings are forwarded
+// to in addition to expvar, like StatsD.
+type Sink interface {
+	Count(name string, value int64, tags ...string)
+	Gauge(name string, value float64, tags ...string)
+	Timing(name string, d time.Duration, tags ...string)
+}
+
+// sink holds the configured sink, if any.
+var sink atomic.Pointer[sinkHolder]
+
+type sinkHolder struct {
+	Sink
+}
+
+// SetSink forwards every metric to the specified sink from now on. A nil
+// sink stops forwarding.
+func SetSink(s Sink) {
+	if s == nil {
+		sink.Store(nil)
+		return
+	}
+
+	sink.Store(&sinkHolder{s})
+}
+
+// currentSink returns the configured sink or nil.
+func currentSink() Sink {
+	if h := sink.Load(); h != nil {
+		return h.Sink
+	}
+
+	return nil
+}
@@ -0,0 +1,129 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// WatchConfig defines when the error rate raises an alert.
+type WatchConfig struct {
+	Window      time.Duration
+	Threshold   float64
+	MinRequests int64
+	WebhookURL  string
+	Client      *http.Client
+}
+
+// Alert is the payload sent to the webhook.
+type Alert struct {
+	Status    string    `json:"status"`
+	ErrorRate float64   `json:"errorRate"`
+	Threshold float64   `json:"threshold"`
+	Requests  int64     `json:"requests"`
+	Errors    int64     `json:"errors"`
+	Window    string    `json:"window"`
+	Time      time.Time `json:"time"`
+}
+
+// WatchErrorRate compares the errors and requests counters every window and
+// raises an alert when the fraction of requests that failed is above the
+// threshold. Windows with fewer than the minimum number of requests don't
+// raise an alert so a single failure on an idle service doesn't alert, but
+// they do resolve one, so an alert doesn't stay firing once traffic stops.
+// Only the changes are reported: the alert is logged at error level and
+// posted to the webhook when one is configured as it starts firing, and a
+// resolved alert is sent once the rate drops below the threshold again. It's
+// meant to be run in its own goroutine.
+func WatchErrorRate(ctx context.Context, log *slog.Logger, cfg WatchConfig) {
+	client := cfg.Client
+	if client == nil {
+		client = &http.Client{Timeout: 5 * time.Second}
+	}
+
+	ticker := time.NewTicker(cfg.Window)
+	defer ticker.Stop()
+
+	lastRequests := m.requests.Value()
+	lastErrors := m.errors.Value()
+	firing := false
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-ticker.C:
+		}
+
+		requests, errors := m.requests.Value(), m.errors.Value()
+		dr, de := requests-lastRequests, errors-lastErrors
+		lastRequests, lastErrors = requests, errors
+
+		alert := Alert{
+			Threshold: cfg.Threshold,
+			Requests:  dr,
+			Errors:    de,
+			Window:    cfg.Window.String(),
+			Time:      time.Now().UTC(),
+		}
+
+		if dr > 0 {
+			alert.ErrorRate = float64(de) / float64(dr)
+		}
+
+		above := alert.ErrorRate > cfg.Threshold
+		quiet := dr < max(cfg.MinRequests, 1)
+
+		switch {
+		case !firing && above && !quiet:
+			alert.Status = "firing"
+			firing = true
+			log.ErrorContext(ctx, "error rate above threshold", "rate", alert.ErrorRate, "threshold", cfg.Threshold, "requests", dr, "errors", de, "window", cfg.Window)
+
+		case firing && !above:
+			alert.Status = "resolved"
+			firing = false
+			log.InfoContext(ctx, "error rate back below threshold", "rate", alert.ErrorRate, "threshold", cfg.Threshold, "requests", dr, "errors", de, "window", cfg.Window)
+
+		default:
+			continue
+		}
+
+		if cfg.WebhookURL != "" {
+			if err := postAlert(ctx, client, cfg.WebhookURL, alert); err != nil {
+				log.ErrorContext(ctx, "error rate webhook", "msg", err)
+			}
+		}
+	}
+}
+
+// postAlert sends the alert to the webhook as JSON.
+func postAlert(ctx context.Context, client *http.Client, url string, alert Alert) error {
+	data, err := json.Marshal(alert)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("new request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("post: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("post: unexpected status %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,41 @@
+package mid
+
+import (
+	"context"
+	"net/http"
+
+	"lobbyte.com/alkeepy/app/api/auth"
+	"lobbyte.com/alkeepy/business/sdk/sqldb"
+	"lobbyte.com/alkeepy/foundation/web"
+)
+
+// AnonymousActor is recorded as the actor of the changes made by requests
+// that don't identify the client.
+const AnonymousActor = "anonymous"
+
+// Actor records who is making the request so the rows it changes can be
+// stamped with it. The subject of the bearer token is used, otherwise the
+// common name of a verified client certificate, so it must come after the
+// ClientCert and Authenticate middleware.
+func Actor() web.Middleware {
+	m := func(next web.Handler) web.Handler {
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			actor := AnonymousActor
+			if id, ok := GetClientIdentity(ctx); ok && id.CommonName != "" {
+				actor = id.CommonName
+			}
+
+			if claims, ok := auth.GetClaims(ctx); ok && claims.Subject != "" {
+				actor = claims.Subject
+			}
+
+			ctx = sqldb.WithActor(ctx, actor)
+
+			return next(ctx, w, r)
+		}
+
+		return h
+	}
+
+	return m
+}
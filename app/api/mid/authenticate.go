@@ -0,0 +1,39 @@
+package mid
+
+import (
+	"context"
+	"net/http"
+
+	"lobbyte.com/alkeepy/app/api/auth"
+	"lobbyte.com/alkeepy/app/api/errs"
+	"lobbyte.com/alkeepy/foundation/web"
+)
+
+// Authenticate validates the bearer token of the request and places its
+// claims in the context for handlers. Requests without an Authorization
+// header pass through untouched, like the ones without a client
+// certificate, but a token that isn't valid is refused. Every request
+// passes through when a is nil.
+func Authenticate(a *auth.Auth) web.Middleware {
+	m := func(next web.Handler) web.Handler {
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			authorization := r.Header.Get("Authorization")
+			if a == nil || authorization == "" {
+				return next(ctx, w, r)
+			}
+
+			claims, err := a.Authenticate(authorization)
+			if err != nil {
+				return errs.New(errs.Unauthenticated, err)
+			}
+
+			ctx = auth.SetClaims(ctx, claims)
+
+			return next(ctx, w, r)
+		}
+
+		return h
+	}
+
+	return m
+}
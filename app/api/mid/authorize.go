@@ -0,0 +1,60 @@
+package mid
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"lobbyte.com/alkeepy/app/api/auth"
+	"lobbyte.com/alkeepy/app/api/errs"
+	"lobbyte.com/alkeepy/foundation/web"
+)
+
+// Authorize refuses the requests that don't carry the claims of a bearer
+// token with a 401, and the ones whose claims hold none of the roles with a
+// 403. Any authenticated caller is let through when no roles are given. It
+// must come after the Authenticate middleware.
+func Authorize(roles ...string) web.Middleware {
+	m := func(next web.Handler) web.Handler {
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			claims, ok := auth.GetClaims(ctx)
+			if !ok {
+				return errs.Newf(errs.Unauthenticated, "a bearer token is required")
+			}
+
+			if len(roles) > 0 && !claims.HasRole(roles...) {
+				return errs.Newf(errs.PermissionDenied, "one of the roles [%s] is required", strings.Join(roles, ", "))
+			}
+
+			return next(ctx, w, r)
+		}
+
+		return h
+	}
+
+	return m
+}
+
+// Policy returns the policy enforcing the metadata of the routes: each route
+// runs within its timeout, or the default timeout when it doesn't set one,
+// and the routes that require authentication are authorized for their
+// roles.
+func Policy(defaultTimeout time.Duration) web.Policy {
+	p := func(meta web.RouteMeta) []web.Middleware {
+		timeout := defaultTimeout
+		if meta.Timeout > 0 {
+			timeout = meta.Timeout
+		}
+
+		mw := []web.Middleware{Timeout(timeout)}
+
+		if meta.Auth {
+			mw = append(mw, Authorize(meta.Roles...))
+		}
+
+		return mw
+	}
+
+	return p
+}
@@ -0,0 +1,123 @@
+package mid_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"lobbyte.com/alkeepy/app/api/auth"
+	"lobbyte.com/alkeepy/app/api/mid"
+	"lobbyte.com/alkeepy/foundation/web"
+)
+
+func Test_Policy(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	// The claims of the test are placed in the context the way Authenticate
+	// does for a valid token.
+	claimsFrom := func(next web.Handler) web.Handler {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			if role := r.Header.Get("X-Test-Role"); role != "" {
+				ctx = auth.SetClaims(ctx, auth.Claims{Subject: "baker", Roles: []string{role}})
+			}
+			return next(ctx, w, r)
+		}
+	}
+
+	app := web.NewApp(func(context.Context, string, ...any) {}, nil, mid.Errors(log, nil), claimsFrom)
+	app.SetPolicy(mid.Policy(0))
+
+	ok := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return web.Respond(ctx, w, nil, http.StatusNoContent)
+	}
+
+	app.HandleMeta(web.RouteMeta{}, http.MethodGet, "", "/open", ok)
+	app.HandleMeta(web.RouteMeta{Auth: true}, http.MethodGet, "", "/any", ok)
+	app.HandleMeta(web.RouteMeta{Auth: true, Roles: []string{"ADMIN"}}, http.MethodGet, "", "/admin", ok)
+
+	tests := []struct {
+		name   string
+		path   string
+		role   string
+		status int
+	}{
+		{name: "open route anonymous", path: "/open", status: http.StatusNoContent},
+		{name: "auth route anonymous", path: "/any", status: http.StatusUnauthorized},
+		{name: "auth route any role", path: "/any", role: "USER", status: http.StatusNoContent},
+		{name: "role route anonymous", path: "/admin", status: http.StatusUnauthorized},
+		{name: "role route other role", path: "/admin", role: "USER", status: http.StatusForbidden},
+		{name: "role route with the role", path: "/admin", role: "ADMIN", status: http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.role != "" {
+				r.Header.Set("X-Test-Role", tt.role)
+			}
+			w := httptest.NewRecorder()
+
+			app.ServeHTTP(w, r)
+
+			if w.Code != tt.status {
+				t.Fatalf("Should respond with %d, got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func Test_PolicyTimeout(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	app := web.NewApp(func(context.Context, string, ...any) {}, nil, mid.Errors(log, nil))
+	app.SetPolicy(mid.Policy(time.Minute))
+
+	// The handler reports how long it was given to run, or waits for the
+	// deadline when it's short.
+	wait := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		deadline, ok := ctx.Deadline()
+		if !ok {
+			return web.Respond(ctx, w, nil, http.StatusNoContent)
+		}
+
+		if time.Until(deadline) > time.Second {
+			w.Header().Set("X-Test-Timeout", "default")
+			return web.Respond(ctx, w, nil, http.StatusNoContent)
+		}
+
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	app.HandleMeta(web.RouteMeta{}, http.MethodGet, "", "/default", wait)
+	app.HandleMeta(web.RouteMeta{Timeout: 10 * time.Millisecond}, http.MethodGet, "", "/short", wait)
+
+	tests := []struct {
+		name    string
+		path    string
+		status  int
+		timeout string
+	}{
+		{name: "default timeout", path: "/default", status: http.StatusNoContent, timeout: "default"},
+		{name: "route timeout", path: "/short", status: http.StatusGatewayTimeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if w.Code != tt.status {
+				t.Fatalf("Should respond with %d, got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+
+			if got := w.Header().Get("X-Test-Timeout"); got != tt.timeout {
+				t.Fatalf("Should run with the %q timeout, got %q", tt.timeout, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package mid
+
+import (
+	"context"
+	"net/http"
+
+	"lobbyte.com/alkeepy/app/api/errs"
+	"lobbyte.com/alkeepy/foundation/web"
+)
+
+// BodyLimit restricts the size of a request body to the specified number of
+// bytes. Requests that declare a larger body are rejected up front, others
+// fail with a 413 once the handler reads past the limit.
+func BodyLimit(maxBytes int64) web.Middleware {
+	m := func(next web.Handler) web.Handler {
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			if r.ContentLength > maxBytes {
+				return errs.Newf(errs.PayloadTooLarge, "request body exceeds the %d byte limit", maxBytes)
+			}
+
+			r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+
+			return next(ctx, w, r)
+		}
+
+		return h
+	}
+
+	return m
+}
@@ -0,0 +1,115 @@
+package mid
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"lobbyte.com/alkeepy/app/api/capture"
+	"lobbyte.com/alkeepy/foundation/web"
+)
+
+// Capture records the sanitized request and response bodies of a sampled
+// fraction of requests into the buffer. Capturing is off until it's enabled
+// on the buffer. Streaming and upgraded requests are skipped.
+func Capture(buf *capture.Buffer) web.Middleware {
+	m := func(next web.Handler) web.Handler {
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			if !buf.Sample() || isUpgrade(r) || isStream(r) {
+				return next(ctx, w, r)
+			}
+
+			var reqBody []byte
+			var truncated bool
+
+			if r.Body != nil {
+				read, err := io.ReadAll(io.LimitReader(r.Body, capture.MaxBodyBytes+1))
+				if err != nil {
+					return err
+				}
+
+				// The handler still needs to read the full body, so everything
+				// read is replayed and only the captured copy is truncated.
+				r.Body = readCloser{io.MultiReader(bytes.NewReader(read), r.Body), r.Body}
+
+				reqBody = read
+				if len(reqBody) > capture.MaxBodyBytes {
+					reqBody = reqBody[:capture.MaxBodyBytes]
+					truncated = true
+				}
+			}
+
+			reqHeaders := r.Header.Clone()
+
+			cw := captureWriter{
+				ResponseWriter: w,
+			}
+
+			err := next(ctx, &cw, r)
+
+			buf.Add(capture.Entry{
+				Time:            web.GetTime(ctx),
+				TraceID:         web.GetTraceID(ctx),
+				Method:          r.Method,
+				Path:            r.URL.RequestURI(),
+				StatusCode:      web.GetStatusCode(ctx),
+				Duration:        time.Since(web.GetTime(ctx)).String(),
+				RequestHeaders:  reqHeaders,
+				RequestBody:     string(reqBody),
+				ResponseHeaders: w.Header(),
+				ResponseBody:    cw.body.String(),
+				Truncated:       truncated || cw.truncated,
+			})
+
+			return err
+		}
+
+		return h
+	}
+
+	return m
+}
+
+// readCloser replays the part of the body read for the capture before the
+// rest of it.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// captureWriter keeps a copy of the response body up to the capture limit.
+type captureWriter struct {
+	http.ResponseWriter
+	body      bytes.Buffer
+	truncated bool
+}
+
+// Write copies the data before passing it on.
+func (cw *captureWriter) Write(p []byte) (int, error) {
+	if room := capture.MaxBodyBytes - cw.body.Len(); room > 0 {
+		if len(p) > room {
+			cw.body.Write(p[:room])
+			cw.truncated = true
+		} else {
+			cw.body.Write(p)
+		}
+	} else if len(p) > 0 {
+		cw.truncated = true
+	}
+
+	return cw.ResponseWriter.Write(p)
+}
+
+// Flush passes the flush on to the underlying writer.
+func (cw *captureWriter) Flush() {
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying writer for use by http.ResponseController.
+func (cw *captureWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
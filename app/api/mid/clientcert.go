@@ -0,0 +1,42 @@
+package mid
+
+import (
+	"context"
+	"net/http"
+
+	"lobbyte.com/alkeepy/foundation/web"
+)
+
+// ClientCert extracts the identity of a verified client certificate and
+// places it in the context for handlers. Requests without a verified
+// certificate pass through untouched.
+func ClientCert() web.Middleware {
+	m := func(next web.Handler) web.Handler {
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+				return next(ctx, w, r)
+			}
+
+			cert := r.TLS.VerifiedChains[0][0]
+
+			id := ClientIdentity{
+				CommonName:   cert.Subject.CommonName,
+				Organization: cert.Subject.Organization,
+				DNSNames:     cert.DNSNames,
+				SerialNumber: cert.SerialNumber.String(),
+			}
+
+			for _, uri := range cert.URIs {
+				id.URIs = append(id.URIs, uri.String())
+			}
+
+			ctx = setClientIdentity(ctx, id)
+
+			return next(ctx, w, r)
+		}
+
+		return h
+	}
+
+	return m
+}
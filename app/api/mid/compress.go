@@ -0,0 +1,243 @@
+package mid
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"lobbyte.com/alkeepy/foundation/web"
+)
+
+// Compress compresses JSON responses that are at least threshold bytes in
+// size using the best encoding the client accepts. Brotli is preferred over
+// gzip when the client supports both.
+func Compress(threshold int) web.Middleware {
+	m := func(next web.Handler) web.Handler {
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+			if encoding == "" || isUpgrade(r) {
+				return next(ctx, w, r)
+			}
+
+			w.Header().Add("Vary", "Accept-Encoding")
+
+			cw := compressWriter{
+				ResponseWriter: w,
+				encoding:       encoding,
+				threshold:      threshold,
+				status:         http.StatusOK,
+			}
+			defer cw.Close()
+
+			return next(ctx, &cw, r)
+		}
+
+		return h
+	}
+
+	return m
+}
+
+// negotiateEncoding returns the supported encoding to use based on the
+// Accept-Encoding header or an empty string if none is acceptable.
+func negotiateEncoding(header string) string {
+	var gz bool
+
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if v, err := strconv.ParseFloat(q, 64); err == nil && v == 0 {
+				continue
+			}
+		}
+
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "br":
+			return "br"
+		case "gzip":
+			gz = true
+		}
+	}
+
+	if gz {
+		return "gzip"
+	}
+
+	return ""
+}
+
+// =============================================================================
+
+var gzipPool = sync.Pool{
+	New: func() any {
+		return gzip.NewWriter(io.Discard)
+	},
+}
+
+var brotliPool = sync.Pool{
+	New: func() any {
+		return brotli.NewWriter(io.Discard)
+	},
+}
+
+// compressWriter buffers the response until it knows if the response is worth
+// compressing, then either compresses or passes through the data.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding    string
+	threshold   int
+	status      int
+	wroteHeader bool
+	decided     bool
+	buf         []byte
+	enc         io.WriteCloser
+}
+
+// WriteHeader records the status code. The header is written once the
+// compression decision has been made.
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.wroteHeader {
+		return
+	}
+
+	cw.status = status
+	cw.wroteHeader = true
+}
+
+// Write buffers data until the threshold is reached.
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if cw.decided {
+		if cw.enc != nil {
+			return cw.enc.Write(p)
+		}
+		return cw.ResponseWriter.Write(p)
+	}
+
+	cw.buf = append(cw.buf, p...)
+	if len(cw.buf) >= cw.threshold {
+		if err := cw.decide(); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(p), nil
+}
+
+// Flush sends any buffered data to the client, which supports streaming
+// responses through the middleware.
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		if err := cw.decide(); err != nil {
+			return
+		}
+	}
+
+	switch enc := cw.enc.(type) {
+	case *gzip.Writer:
+		enc.Flush()
+	case *brotli.Writer:
+		enc.Flush()
+	}
+
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying writer for use by http.ResponseController.
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// Close completes the response, writing any buffered data.
+func (cw *compressWriter) Close() error {
+	if !cw.decided {
+		if err := cw.decide(); err != nil {
+			return err
+		}
+	}
+
+	if cw.enc == nil {
+		return nil
+	}
+
+	err := cw.enc.Close()
+
+	switch enc := cw.enc.(type) {
+	case *gzip.Writer:
+		gzipPool.Put(enc)
+	case *brotli.Writer:
+		brotliPool.Put(enc)
+	}
+	cw.enc = nil
+
+	return err
+}
+
+// decide determines if the response is compressed, writes the header and
+// any buffered data.
+func (cw *compressWriter) decide() error {
+	cw.decided = true
+
+	if cw.compressible() {
+		hdr := cw.ResponseWriter.Header()
+		hdr.Del("Content-Length")
+		hdr.Set("Content-Encoding", cw.encoding)
+
+		switch cw.encoding {
+		case "br":
+			enc := brotliPool.Get().(*brotli.Writer)
+			enc.Reset(cw.ResponseWriter)
+			cw.enc = enc
+
+		default:
+			enc := gzipPool.Get().(*gzip.Writer)
+			enc.Reset(cw.ResponseWriter)
+			cw.enc = enc
+		}
+	}
+
+	cw.ResponseWriter.WriteHeader(cw.status)
+
+	if len(cw.buf) == 0 {
+		return nil
+	}
+
+	buf := cw.buf
+	cw.buf = nil
+
+	var err error
+	switch {
+	case cw.enc != nil:
+		_, err = cw.enc.Write(buf)
+	default:
+		_, err = cw.ResponseWriter.Write(buf)
+	}
+
+	return err
+}
+
+// compressible reports if the buffered response should be compressed.
+func (cw *compressWriter) compressible() bool {
+	if len(cw.buf) < cw.threshold {
+		return false
+	}
+
+	switch cw.status {
+	case http.StatusNoContent, http.StatusNotModified:
+		return false
+	}
+
+	hdr := cw.ResponseWriter.Header()
+	if hdr.Get("Content-Encoding") != "" {
+		return false
+	}
+
+	return strings.HasPrefix(hdr.Get("Content-Type"), "application/json")
+}
@@ -0,0 +1,68 @@
+package mid
+
+import (
+	"context"
+	"errors"
+
+	"lobbyte.com/alkeepy/business/sdk/sqldb"
+)
+
+type ctxKey int
+
+const (
+	clientIdentityKey ctxKey = iota + 1
+	clientIPKey
+	trKey
+)
+
+// ClientIdentity represents the identity presented by a verified client
+// certificate.
+type ClientIdentity struct {
+	CommonName   string
+	Organization []string
+	DNSNames     []string
+	URIs         []string
+	SerialNumber string
+}
+
+func setClientIdentity(ctx context.Context, id ClientIdentity) context.Context {
+	return context.WithValue(ctx, clientIdentityKey, id)
+}
+
+// GetClientIdentity returns the identity of the client certificate used for
+// the request. The second value is false when the request was not made with
+// a verified client certificate.
+func GetClientIdentity(ctx context.Context) (ClientIdentity, bool) {
+	v, ok := ctx.Value(clientIdentityKey).(ClientIdentity)
+	return v, ok
+}
+
+func setClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey, ip)
+}
+
+// GetClientIP returns the address of the client that made the request as
+// determined by the RealIP middleware.
+func GetClientIP(ctx context.Context) string {
+	v, ok := ctx.Value(clientIPKey).(string)
+	if !ok {
+		return ""
+	}
+
+	return v
+}
+
+func setTran(ctx context.Context, tx sqldb.CommitRollbacker) context.Context {
+	return context.WithValue(ctx, trKey, tx)
+}
+
+// GetTran returns the transaction started by the BeginCommitRollback
+// middleware for the route.
+func GetTran(ctx context.Context) (sqldb.CommitRollbacker, error) {
+	v, ok := ctx.Value(trKey).(sqldb.CommitRollbacker)
+	if !ok {
+		return nil, errors.New("transaction not found in context")
+	}
+
+	return v, nil
+}
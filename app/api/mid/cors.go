@@ -0,0 +1,141 @@
+package mid
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"lobbyte.com/alkeepy/foundation/web"
+)
+
+// The set of methods and headers allowed on cross origin requests.
+var (
+	corsAllowedMethods = []string{
+		http.MethodGet,
+		http.MethodHead,
+		http.MethodPost,
+		http.MethodPut,
+		http.MethodPatch,
+		http.MethodDelete,
+		http.MethodOptions,
+	}
+
+	corsAllowedHeaders = []string{
+		"Accept",
+		"Authorization",
+		"Content-Type",
+		"If-Match",
+		"If-None-Match",
+		"X-Request-ID",
+	}
+)
+
+// ValidateOrigins reports the first origin that isn't "*" or a scheme, host
+// and optional port, which is the only form browsers send. The wildcard is
+// refused when credentials are allowed, since it would let any site make
+// credentialed requests.
+func ValidateOrigins(origins []string, allowCredentials bool) error {
+	for _, origin := range origins {
+		if origin == "*" {
+			if allowCredentials {
+				return errors.New("origin \"*\" can't be allowed with credentials, the origins must be listed")
+			}
+			continue
+		}
+
+		u, err := url.Parse(origin)
+		if err != nil {
+			return fmt.Errorf("parsing origin %q: %w", origin, err)
+		}
+
+		if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.User != nil || u.Path != "" || u.RawQuery != "" || u.Fragment != "" {
+			return fmt.Errorf("origin %q must be a scheme and host like https://example.com", origin)
+		}
+	}
+
+	return nil
+}
+
+// CorsPolicy holds the origins allowed to make cross origin requests. The
+// policy can be replaced while the service is running.
+type CorsPolicy struct {
+	state atomic.Pointer[corsState]
+}
+
+type corsState struct {
+	origins          []string
+	wildcard         bool
+	allowCredentials bool
+}
+
+// NewCorsPolicy constructs a policy for the specified set of origins.
+func NewCorsPolicy(origins []string, allowCredentials bool) *CorsPolicy {
+	var p CorsPolicy
+	p.Set(origins, allowCredentials)
+
+	return &p
+}
+
+// Set replaces the origins allowed by the policy.
+func (p *CorsPolicy) Set(origins []string, allowCredentials bool) {
+	p.state.Store(&corsState{
+		origins:          slices.Clone(origins),
+		wildcard:         slices.Contains(origins, "*"),
+		allowCredentials: allowCredentials,
+	})
+}
+
+// Cors sets the response headers needed for Cross-Origin Resource Sharing
+// for the origins allowed by the policy. Preflight requests are answered
+// directly and never reach the route handler.
+func Cors(policy *CorsPolicy) web.Middleware {
+	m := func(next web.Handler) web.Handler {
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			origin := r.Header.Get("Origin")
+			if origin == "" {
+				return next(ctx, w, r)
+			}
+
+			w.Header().Add("Vary", "Origin")
+
+			p := policy.state.Load()
+
+			if !p.wildcard && !slices.Contains(p.origins, origin) {
+				return next(ctx, w, r)
+			}
+
+			// Credentials are only allowed for the listed origins, which
+			// ValidateOrigins enforces, so a wildcard never carries them.
+			switch {
+			case p.wildcard:
+				w.Header().Set("Access-Control-Allow-Origin", "*")
+			default:
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+				if p.allowCredentials {
+					w.Header().Set("Access-Control-Allow-Credentials", "true")
+				}
+			}
+
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				w.Header().Add("Vary", "Access-Control-Request-Method")
+				w.Header().Add("Vary", "Access-Control-Request-Headers")
+				w.Header().Set("Access-Control-Allow-Methods", strings.Join(corsAllowedMethods, ", "))
+				w.Header().Set("Access-Control-Allow-Headers", strings.Join(corsAllowedHeaders, ", "))
+				w.Header().Set("Access-Control-Max-Age", "86400")
+
+				return web.Respond(ctx, w, nil, http.StatusNoContent)
+			}
+
+			return next(ctx, w, r)
+		}
+
+		return h
+	}
+
+	return m
+}
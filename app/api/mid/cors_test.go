@@ -0,0 +1,104 @@
+package mid_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"lobbyte.com/alkeepy/app/api/mid"
+	"lobbyte.com/alkeepy/foundation/web"
+)
+
+func Test_ValidateOrigins(t *testing.T) {
+	tests := []struct {
+		name             string
+		origins          []string
+		allowCredentials bool
+		valid            bool
+	}{
+		{name: "wildcard", origins: []string{"*"}, valid: true},
+		{name: "listed origins", origins: []string{"https://example.com", "http://localhost:3000"}, valid: true},
+		{name: "listed origins with credentials", origins: []string{"https://example.com"}, allowCredentials: true, valid: true},
+		{name: "wildcard with credentials", origins: []string{"*"}, allowCredentials: true},
+		{name: "wildcard among listed origins with credentials", origins: []string{"https://example.com", "*"}, allowCredentials: true},
+		{name: "path", origins: []string{"https://example.com/app"}},
+		{name: "no scheme", origins: []string{"example.com"}},
+		{name: "other scheme", origins: []string{"ftp://example.com"}},
+		{name: "user info", origins: []string{"https://user@example.com"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := mid.ValidateOrigins(tt.origins, tt.allowCredentials)
+
+			if tt.valid && err != nil {
+				t.Fatalf("Should accept the origins: %s", err)
+			}
+
+			if !tt.valid && err == nil {
+				t.Fatalf("Should refuse the origins")
+			}
+		})
+	}
+}
+
+func Test_Cors(t *testing.T) {
+	tests := []struct {
+		name             string
+		origins          []string
+		allowCredentials bool
+		origin           string
+		preflight        bool
+		wantOrigin       string
+		wantCredentials  string
+		wantStatus       int
+	}{
+		{name: "no origin", origins: []string{"*"}, wantStatus: http.StatusOK},
+		{name: "wildcard", origins: []string{"*"}, origin: "https://evil.example", wantOrigin: "*", wantStatus: http.StatusOK},
+		{name: "wildcard never carries credentials", origins: []string{"*"}, allowCredentials: true, origin: "https://evil.example", wantOrigin: "*", wantStatus: http.StatusOK},
+		{name: "listed origin", origins: []string{"https://example.com"}, origin: "https://example.com", wantOrigin: "https://example.com", wantStatus: http.StatusOK},
+		{name: "listed origin with credentials", origins: []string{"https://example.com"}, allowCredentials: true, origin: "https://example.com", wantOrigin: "https://example.com", wantCredentials: "true", wantStatus: http.StatusOK},
+		{name: "unlisted origin", origins: []string{"https://example.com"}, allowCredentials: true, origin: "https://evil.example", wantStatus: http.StatusOK},
+		{name: "preflight", origins: []string{"https://example.com"}, origin: "https://example.com", preflight: true, wantOrigin: "https://example.com", wantStatus: http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := mid.NewCorsPolicy(tt.origins, tt.allowCredentials)
+
+			app := web.NewApp(func(context.Context, string, ...any) {}, nil, mid.Cors(policy))
+			app.Handle(http.MethodGet, "v1", "/recipes", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				return web.Respond(ctx, w, nil, http.StatusOK)
+			})
+
+			method := http.MethodGet
+			if tt.preflight {
+				method = http.MethodOptions
+			}
+
+			r := httptest.NewRequest(method, "/v1/recipes", nil)
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+			if tt.preflight {
+				r.Header.Set("Access-Control-Request-Method", http.MethodPut)
+			}
+			w := httptest.NewRecorder()
+
+			app.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("Should respond with %d, got %d", tt.wantStatus, w.Code)
+			}
+
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Fatalf("Should allow the origin %q, got %q", tt.wantOrigin, got)
+			}
+
+			if got := w.Header().Get("Access-Control-Allow-Credentials"); got != tt.wantCredentials {
+				t.Fatalf("Should allow credentials %q, got %q", tt.wantCredentials, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package mid
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"lobbyte.com/alkeepy/app/api/errs"
+	"lobbyte.com/alkeepy/foundation/errreport"
+	"lobbyte.com/alkeepy/foundation/validate"
+	"lobbyte.com/alkeepy/foundation/web"
+)
+
+// Errors handles errors coming out of the call chain. It detects normal
+// application errors which are used to respond to the client in a uniform way.
+// Unexpected and internal errors are logged, sent to the error reporter and
+// the details are hidden from the client.
+func Errors(log *slog.Logger, reporter *errreport.Reporter) web.Middleware {
+	m := func(next web.Handler) web.Handler {
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			err := next(ctx, w, r)
+			if err == nil {
+				return nil
+			}
+
+			var appErr *errs.Error
+			var maxBytesErr *http.MaxBytesError
+
+			switch {
+			case errors.As(err, &maxBytesErr):
+				log.ErrorContext(ctx, "request body too large", "err", err)
+				appErr = errs.Newf(errs.PayloadTooLarge, "request body exceeds the %d byte limit", maxBytesErr.Limit)
+
+			case errs.IsError(err):
+				appErr = errs.GetError(err)
+				log.ErrorContext(ctx, "handled error during request",
+					"err", err,
+					"source_err_file", appErr.FileName,
+					"source_err_func", appErr.FuncName)
+
+				// Internal errors are built from the errors of the stores, so
+				// their details only go to the logs and the reporter.
+				if appErr.Code == errs.Internal {
+					reporter.Report(ctx, err, r, map[string]string{"trace_id": web.GetTraceID(ctx), "source_err_func": appErr.FuncName})
+					appErr = errs.Newf(errs.Internal, "%s", http.StatusText(http.StatusInternalServerError))
+				}
+
+			case validate.IsFieldErrors(err):
+				log.ErrorContext(ctx, "validation error during request", "err", err)
+				appErr = errs.New(errs.InvalidArgument, err)
+
+			default:
+				log.ErrorContext(ctx, "unexpected error during request", "err", err)
+
+				var pe *panicError
+				if !errors.As(err, &pe) {
+					reporter.Report(ctx, err, r, map[string]string{"trace_id": web.GetTraceID(ctx)})
+				}
+				appErr = errs.Newf(errs.Internal, "%s", http.StatusText(http.StatusInternalServerError))
+			}
+
+			if err := web.RespondError(ctx, w, appErr, appErr.HTTPStatus()); err != nil {
+				return err
+			}
+
+			// If we receive the shutdown err we need to return it
+			// back to the base handler to shut down the service.
+			if web.IsShutdown(err) {
+				return err
+			}
+
+			return nil
+		}
+
+		return h
+	}
+
+	return m
+}
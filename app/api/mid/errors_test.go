@@ -0,0 +1,66 @@
+package mid_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"lobbyte.com/alkeepy/app/api/errs"
+	"lobbyte.com/alkeepy/app/api/mid"
+	"lobbyte.com/alkeepy/foundation/web"
+)
+
+func Test_Errors(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tests := []struct {
+		name        string
+		err         error
+		wantStatus  int
+		wantMessage string
+	}{
+		{
+			name:        "not found",
+			err:         errs.Newf(errs.NotFound, "recipe 42 not found"),
+			wantStatus:  http.StatusNotFound,
+			wantMessage: "recipe 42 not found",
+		},
+		{
+			name:        "internal",
+			err:         errs.Newf(errs.Internal, "query: pq: relation \"recipes\" does not exist"),
+			wantStatus:  http.StatusInternalServerError,
+			wantMessage: http.StatusText(http.StatusInternalServerError),
+		},
+		{
+			name:        "unexpected",
+			err:         errors.New("dial tcp 10.0.0.5:5432: connection refused"),
+			wantStatus:  http.StatusInternalServerError,
+			wantMessage: http.StatusText(http.StatusInternalServerError),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := web.NewApp(func(context.Context, string, ...any) {}, nil, mid.Errors(log, nil))
+			app.Handle(http.MethodGet, "", "/fail", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				return tt.err
+			})
+
+			w := httptest.NewRecorder()
+			app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/fail", nil))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("Should respond with %d, got %d", tt.wantStatus, w.Code)
+			}
+
+			if body := w.Body.String(); !strings.Contains(body, `"message":"`+tt.wantMessage+`"`) {
+				t.Fatalf("Should respond with the message %q, got %s", tt.wantMessage, body)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package mid
+
+import (
+	"context"
+	"net/http"
+
+	"lobbyte.com/alkeepy/foundation/web"
+)
+
+// ETag computes an entity tag for successful GET and HEAD responses and
+// answers with a 304 when the client's If-None-Match header matches it.
+// Streaming responses that flush are passed through untouched.
+func ETag() web.Middleware {
+	m := func(next web.Handler) web.Handler {
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			if (r.Method != http.MethodGet && r.Method != http.MethodHead) || isUpgrade(r) {
+				return next(ctx, w, r)
+			}
+
+			ew := etagWriter{
+				ResponseWriter: w,
+				status:         http.StatusOK,
+			}
+
+			err := next(ctx, &ew, r)
+
+			ew.finish(ctx, r)
+
+			return err
+		}
+
+		return h
+	}
+
+	return m
+}
+
+// etagWriter buffers a successful response so the entity tag can be computed
+// before anything is sent to the client.
+type etagWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	passthrough bool
+	buf         []byte
+}
+
+// WriteHeader records the status code. Responses other than a 200 are sent
+// straight through since they don't get an entity tag.
+func (ew *etagWriter) WriteHeader(status int) {
+	if ew.wroteHeader {
+		return
+	}
+
+	ew.status = status
+	ew.wroteHeader = true
+
+	if status != http.StatusOK {
+		ew.passthrough = true
+		ew.ResponseWriter.WriteHeader(status)
+	}
+}
+
+// Write buffers the response body.
+func (ew *etagWriter) Write(p []byte) (int, error) {
+	if ew.passthrough {
+		return ew.ResponseWriter.Write(p)
+	}
+
+	ew.buf = append(ew.buf, p...)
+
+	return len(p), nil
+}
+
+// Flush switches the writer to pass through mode for streaming responses.
+func (ew *etagWriter) Flush() {
+	if !ew.passthrough {
+		ew.passthrough = true
+		ew.ResponseWriter.WriteHeader(ew.status)
+		ew.ResponseWriter.Write(ew.buf)
+		ew.buf = nil
+	}
+
+	if f, ok := ew.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying writer for use by http.ResponseController.
+func (ew *etagWriter) Unwrap() http.ResponseWriter {
+	return ew.ResponseWriter
+}
+
+// finish computes the entity tag and writes the buffered response.
+func (ew *etagWriter) finish(ctx context.Context, r *http.Request) {
+	if ew.passthrough {
+		return
+	}
+
+	hdr := ew.ResponseWriter.Header()
+
+	etag := hdr.Get("ETag")
+	if etag == "" {
+		etag = web.ETag(ew.buf)
+		hdr.Set("ETag", etag)
+	}
+
+	if web.IfNoneMatch(r, etag) {
+		hdr.Del("Content-Type")
+		hdr.Del("Content-Length")
+		web.SetStatusCode(ctx, http.StatusNotModified)
+		ew.ResponseWriter.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	ew.ResponseWriter.WriteHeader(ew.status)
+	ew.ResponseWriter.Write(ew.buf)
+}
@@ -0,0 +1,23 @@
+package mid
+
+import (
+	"context"
+	"net/http"
+
+	"lobbyte.com/alkeepy/app/api/featureflag"
+	"lobbyte.com/alkeepy/foundation/web"
+)
+
+// FeatureFlags places the feature flags in the context so handlers can check
+// them with featureflag.Enabled.
+func FeatureFlags(flags *featureflag.Flags) web.Middleware {
+	m := func(next web.Handler) web.Handler {
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			return next(featureflag.WithFlags(ctx, flags), w, r)
+		}
+
+		return h
+	}
+
+	return m
+}
@@ -0,0 +1,63 @@
+package mid
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"lobbyte.com/alkeepy/foundation/web"
+)
+
+// Logger writes information about the request to the logs. When the sample
+// rate is above 1 only 1 in rate requests is logged, but every request that
+// completes with a status code of 400 or above is. The rate can be changed
+// while the service is running. A nil rate logs every request.
+func Logger(log *slog.Logger, sampleRate *atomic.Int64) web.Middleware {
+	var counter atomic.Uint64
+
+	m := func(next web.Handler) web.Handler {
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			now := web.GetTime(ctx)
+
+			sampled := true
+			if sampleRate != nil {
+				if rate := sampleRate.Load(); rate > 1 {
+					sampled = counter.Add(1)%uint64(rate) == 0
+				}
+			}
+
+			path := r.URL.Path
+			if r.URL.RawQuery != "" {
+				path = fmt.Sprintf("%s?%s", path, r.URL.RawQuery)
+			}
+
+			remoteAddr := GetClientIP(ctx)
+			if remoteAddr == "" {
+				remoteAddr = r.RemoteAddr
+			}
+
+			if sampled {
+				log.InfoContext(ctx, "request started", "method", r.Method, "path", path, "remoteaddr", remoteAddr)
+			}
+
+			err := next(ctx, w, r)
+
+			statusCode := web.GetStatusCode(ctx)
+			if !sampled && err == nil && statusCode < http.StatusBadRequest {
+				return nil
+			}
+
+			log.InfoContext(ctx, "request completed", "method", r.Method, "path", path, "remoteaddr", remoteAddr,
+				"statuscode", statusCode, "since", time.Since(now).String())
+
+			return err
+		}
+
+		return h
+	}
+
+	return m
+}
@@ -0,0 +1,52 @@
+package mid
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"lobbyte.com/alkeepy/app/api/metrics"
+	"lobbyte.com/alkeepy/foundation/web"
+)
+
+// unmatchedRoute is the route name used for requests that aren't bound to a
+// route so unknown paths can't create an unbounded number of metrics.
+const unmatchedRoute = "unmatched"
+
+// Metrics updates the application counters and records the request count,
+// error count and latency of each route.
+// It must run before the Errors middleware so the final status code is known.
+func Metrics() web.Middleware {
+	m := func(next web.Handler) web.Handler {
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			err := next(ctx, w, r)
+
+			route := r.Pattern
+			if route == "" {
+				route = unmatchedRoute
+			}
+
+			// Handlers that stream the response write the status themselves.
+			statusCode := web.GetStatusCode(ctx)
+			switch {
+			case err != nil:
+				statusCode = http.StatusInternalServerError
+			case statusCode == 0:
+				statusCode = http.StatusOK
+			}
+
+			metrics.AddRequests(ctx)
+			if statusCode >= http.StatusInternalServerError {
+				metrics.AddErrors(ctx)
+			}
+
+			metrics.AddRoute(ctx, route, statusCode, time.Since(web.GetTime(ctx)))
+
+			return err
+		}
+
+		return h
+	}
+
+	return m
+}
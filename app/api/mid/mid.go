@@ -0,0 +1,14 @@
+// Package mid contains the set of middleware functions.
+package mid
+
+import (
+	"net/http"
+	"strings"
+)
+
+// isUpgrade reports whether the request is asking to switch protocols, such
+// as a websocket handshake. These requests need the original response writer
+// so it can be hijacked.
+func isUpgrade(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
+}
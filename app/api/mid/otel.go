@@ -0,0 +1,63 @@
+package mid
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"lobbyte.com/alkeepy/foundation/otel"
+	"lobbyte.com/alkeepy/foundation/web"
+)
+
+// Otel starts a span for the request and places it in the context so the
+// business and data layers can add child spans. When the request is sampled
+// the otel trace id replaces the generated trace id so logs and traces can be
+// correlated. A traceparent header sent by the caller makes the request span
+// a child of the caller's span. It should be the first middleware so the span
+// covers the whole request.
+func Otel(tracer trace.Tracer) web.Middleware {
+	m := func(next web.Handler) web.Handler {
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			name := r.Method
+			if r.Pattern != "" {
+				name = r.Pattern
+			}
+
+			ctx = otel.Extract(ctx, r.Header)
+
+			ctx, span := tracer.Start(ctx, name,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					attribute.String("http.request.method", r.Method),
+					attribute.String("url.path", r.URL.Path),
+				),
+			)
+			defer span.End()
+
+			if traceID := otel.GetTraceID(ctx); traceID != "" {
+				web.SetTraceID(ctx, traceID)
+			}
+
+			err := next(ctx, w, r)
+
+			status := web.GetStatusCode(ctx)
+			span.SetAttributes(attribute.Int("http.response.status_code", status))
+
+			if status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(status))
+			}
+
+			if err != nil {
+				span.RecordError(err)
+			}
+
+			return err
+		}
+
+		return h
+	}
+
+	return m
+}
@@ -0,0 +1,54 @@
+package mid
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	"lobbyte.com/alkeepy/app/api/metrics"
+	"lobbyte.com/alkeepy/foundation/errreport"
+	"lobbyte.com/alkeepy/foundation/web"
+)
+
+// Panics recovers from panics and converts the panic to an error so it is
+// reported in Metrics and handled in Errors. The panic is sent to the error
+// reporter with the stack trace of where it happened.
+func Panics(log *slog.Logger, reporter *errreport.Reporter) web.Middleware {
+	m := func(next web.Handler) web.Handler {
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) (err error) {
+
+			// Defer a function to recover from a panic and set the err return
+			// variable after the fact.
+			defer func() {
+				if rec := recover(); rec != nil {
+					trace := debug.Stack()
+
+					log.ErrorContext(ctx, "panic recovered", "panic", rec, "trace", string(trace))
+					metrics.AddPanics(ctx)
+					reporter.ReportPanic(ctx, rec, r, map[string]string{"trace_id": web.GetTraceID(ctx)})
+
+					err = &panicError{fmt.Sprintf("PANIC [%v]", rec)}
+				}
+			}()
+
+			return next(ctx, w, r)
+		}
+
+		return h
+	}
+
+	return m
+}
+
+// panicError is the error a recovered panic is converted to. It's used so the
+// panic isn't reported a second time by the Errors middleware.
+type panicError struct {
+	msg string
+}
+
+// Error implements the error interface.
+func (pe *panicError) Error() string {
+	return pe.msg
+}
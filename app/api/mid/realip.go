@@ -0,0 +1,121 @@
+package mid
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"lobbyte.com/alkeepy/foundation/web"
+)
+
+// ParseTrustedProxies converts the set of addresses and CIDR ranges into
+// prefixes for use with RealIP. A bare address is treated as a single host.
+func ParseTrustedProxies(proxies []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(proxies))
+
+	for _, proxy := range proxies {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+
+		if !strings.Contains(proxy, "/") {
+			addr, err := netip.ParseAddr(proxy)
+			if err != nil {
+				return nil, fmt.Errorf("parsing trusted proxy %q: %w", proxy, err)
+			}
+
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("parsing trusted proxy %q: %w", proxy, err)
+		}
+
+		prefixes = append(prefixes, prefix.Masked())
+	}
+
+	return prefixes, nil
+}
+
+// RealIP determines the address of the client that made the request. The
+// X-Forwarded-For and X-Real-IP headers are only honored when the request
+// arrives from one of the trusted proxies, otherwise the peer address is used.
+func RealIP(trusted []netip.Prefix) web.Middleware {
+	m := func(next web.Handler) web.Handler {
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			ctx = setClientIP(ctx, clientIP(r, trusted))
+
+			return next(ctx, w, r)
+		}
+
+		return h
+	}
+
+	return m
+}
+
+// clientIP walks the forwarding chain from the closest hop backwards and
+// returns the first address that isn't a trusted proxy. A hop that isn't an
+// address can't have been added by a proxy, so the chain is given up on and
+// the peer is used rather than letting the client name itself with any text.
+func clientIP(r *http.Request, trusted []netip.Prefix) string {
+	peer := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(peer); err == nil {
+		peer = host
+	}
+
+	if !isTrusted(peer, trusted) {
+		return peer
+	}
+
+	if xff := r.Header.Values("X-Forwarded-For"); len(xff) > 0 {
+		hops := strings.Split(strings.Join(xff, ","), ",")
+
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if hop == "" {
+				continue
+			}
+
+			addr, err := netip.ParseAddr(hop)
+			if err != nil {
+				return peer
+			}
+
+			if !isTrusted(hop, trusted) {
+				return addr.Unmap().String()
+			}
+		}
+	}
+
+	if xrip := strings.TrimSpace(r.Header.Get("X-Real-IP")); xrip != "" {
+		if _, err := netip.ParseAddr(xrip); err == nil {
+			return xrip
+		}
+	}
+
+	return peer
+}
+
+// isTrusted reports whether the address falls in any of the trusted prefixes.
+func isTrusted(ip string, trusted []netip.Prefix) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+
+	for _, prefix := range trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
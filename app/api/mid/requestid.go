@@ -0,0 +1,55 @@
+package mid
+
+import (
+	"context"
+	"net/http"
+
+	"lobbyte.com/alkeepy/foundation/web"
+)
+
+// requestIDHeader is the header used to accept and return the request id.
+const requestIDHeader = "X-Request-ID"
+
+// maxRequestIDLen is the longest request id accepted from a client.
+const maxRequestIDLen = 128
+
+// RequestID uses the X-Request-ID header provided by the client as the trace
+// id for the request when it's well formed, otherwise the generated trace id
+// is kept. The id is returned in the response headers so it can be quoted in
+// bug reports.
+func RequestID() web.Middleware {
+	m := func(next web.Handler) web.Handler {
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			if id := r.Header.Get(requestIDHeader); validRequestID(id) {
+				web.SetTraceID(ctx, id)
+			}
+
+			w.Header().Set(requestIDHeader, web.GetTraceID(ctx))
+
+			return next(ctx, w, r)
+		}
+
+		return h
+	}
+
+	return m
+}
+
+// validRequestID checks the id is a reasonable length and only contains
+// characters that are safe to log and echo back.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
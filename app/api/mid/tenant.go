@@ -0,0 +1,92 @@
+package mid
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"lobbyte.com/alkeepy/app/api/auth"
+	"lobbyte.com/alkeepy/app/api/errs"
+	"lobbyte.com/alkeepy/business/sdk/tenant"
+	"lobbyte.com/alkeepy/foundation/web"
+)
+
+// TenantURIPrefix prefixes the URI of a client certificate naming the
+// tenant the client acts for, like urn:alkeepy:tenant:<uuid>.
+const TenantURIPrefix = "urn:alkeepy:tenant:"
+
+// Tenant scopes the request to the bakery it acts for so the data layer only
+// reaches the rows of that tenant. The tenant named by the bearer token is
+// used, then the one named by a verified client certificate. A caller that
+// authenticated with either without naming a tenant gets the default one,
+// while anonymous requests are left unscoped so they can't reach any tenant
+// data. A nil default leaves every request that doesn't name a tenant
+// unscoped. It must come after the ClientCert and Authenticate middleware.
+func Tenant(defaultTenant uuid.UUID) web.Middleware {
+	m := func(next web.Handler) web.Handler {
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			var tenantID uuid.UUID
+			var authenticated bool
+
+			if id, ok := GetClientIdentity(ctx); ok {
+				authenticated = true
+
+				for _, uri := range id.URIs {
+					v, found := strings.CutPrefix(uri, TenantURIPrefix)
+					if !found {
+						continue
+					}
+
+					tid, err := uuid.Parse(v)
+					if err != nil {
+						return errs.Newf(errs.Unauthenticated, "client certificate names an invalid tenant %q", v)
+					}
+					tenantID = tid
+					break
+				}
+			}
+
+			if claims, ok := auth.GetClaims(ctx); ok {
+				authenticated = true
+
+				if claims.TenantID != uuid.Nil {
+					tenantID = claims.TenantID
+				}
+			}
+
+			if tenantID == uuid.Nil && authenticated {
+				tenantID = defaultTenant
+			}
+
+			if tenantID != uuid.Nil {
+				ctx = tenant.With(ctx, tenantID)
+			}
+
+			return next(ctx, w, r)
+		}
+
+		return h
+	}
+
+	return m
+}
+
+// RequireTenant refuses the requests that aren't scoped to a tenant. It's
+// set on the routes reaching tenant data so the client gets a clear error
+// instead of the data layer refusing the query.
+func RequireTenant() web.Middleware {
+	m := func(next web.Handler) web.Handler {
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			if _, err := tenant.Get(ctx); err != nil {
+				return errs.New(errs.Unauthenticated, err)
+			}
+
+			return next(ctx, w, r)
+		}
+
+		return h
+	}
+
+	return m
+}
@@ -0,0 +1,115 @@
+package mid_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/google/uuid"
+	"lobbyte.com/alkeepy/app/api/auth"
+	"lobbyte.com/alkeepy/app/api/mid"
+	"lobbyte.com/alkeepy/business/sdk/tenant"
+	"lobbyte.com/alkeepy/foundation/web"
+)
+
+func Test_Tenant(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	defaultTenant := uuid.MustParse("e7a1c2d4-5b6f-4a8e-9c0d-1f2a3b4c5d6e")
+	tokenTenant := uuid.MustParse("0b6c1a4e-2f3d-4c5b-8a9e-7d6f5e4c3b2a")
+	certTenant := uuid.MustParse("5a4b3c2d-1e0f-4a9b-8c7d-6e5f4a3b2c1d")
+
+	// The claims of the test are placed in the context the way Authenticate
+	// does for a valid token.
+	claimsFrom := func(next web.Handler) web.Handler {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			switch r.Header.Get("X-Test-Token") {
+			case "tenant":
+				ctx = auth.SetClaims(ctx, auth.Claims{Subject: "baker", TenantID: tokenTenant})
+			case "no tenant":
+				ctx = auth.SetClaims(ctx, auth.Claims{Subject: "baker"})
+			}
+			return next(ctx, w, r)
+		}
+	}
+
+	app := web.NewApp(func(context.Context, string, ...any) {}, nil, mid.Errors(log, nil), mid.ClientCert(), claimsFrom, mid.Tenant(defaultTenant))
+
+	app.Handle(http.MethodGet, "", "/tenant", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		tenantID, err := tenant.Get(ctx)
+		if err != nil {
+			return web.Respond(ctx, w, nil, http.StatusNoContent)
+		}
+		w.Write([]byte(tenantID.String()))
+		return nil
+	})
+
+	app.Handle(http.MethodGet, "", "/scoped", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return web.Respond(ctx, w, nil, http.StatusNoContent)
+	}, mid.RequireTenant())
+
+	tests := []struct {
+		name       string
+		path       string
+		token      string
+		certTenant string
+		wantStatus int
+		wantTenant uuid.UUID
+	}{
+		{name: "anonymous is unscoped", path: "/tenant", wantStatus: http.StatusNoContent},
+		{name: "token naming a tenant", path: "/tenant", token: "tenant", wantStatus: http.StatusOK, wantTenant: tokenTenant},
+		{name: "token without a tenant gets the default", path: "/tenant", token: "no tenant", wantStatus: http.StatusOK, wantTenant: defaultTenant},
+		{name: "certificate naming a tenant", path: "/tenant", certTenant: certTenant.String(), wantStatus: http.StatusOK, wantTenant: certTenant},
+		{name: "certificate without a tenant gets the default", path: "/tenant", certTenant: "-", wantStatus: http.StatusOK, wantTenant: defaultTenant},
+		{name: "token wins over certificate", path: "/tenant", token: "tenant", certTenant: certTenant.String(), wantStatus: http.StatusOK, wantTenant: tokenTenant},
+		{name: "certificate naming an invalid tenant", path: "/tenant", certTenant: "bakery", wantStatus: http.StatusUnauthorized},
+		{name: "required tenant anonymous", path: "/scoped", wantStatus: http.StatusUnauthorized},
+		{name: "required tenant with token", path: "/scoped", token: "no tenant", wantStatus: http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.token != "" {
+				r.Header.Set("X-Test-Token", tt.token)
+			}
+			if tt.certTenant != "" {
+				r.TLS = verifiedCert(tt.certTenant)
+			}
+			w := httptest.NewRecorder()
+
+			app.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("Should respond with %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+
+			if tt.wantTenant != uuid.Nil && w.Body.String() != tt.wantTenant.String() {
+				t.Fatalf("Should scope the request to %s, got %q", tt.wantTenant, w.Body.String())
+			}
+		})
+	}
+}
+
+// verifiedCert returns the state of a connection made with a verified client
+// certificate. The certificate names the tenant unless it's "-".
+func verifiedCert(tenantID string) *tls.ConnectionState {
+	cert := x509.Certificate{
+		Subject:      pkix.Name{CommonName: "bakery"},
+		SerialNumber: big.NewInt(1),
+	}
+
+	if tenantID != "-" {
+		cert.URIs = []*url.URL{{Scheme: "urn", Opaque: "alkeepy:tenant:" + tenantID}}
+	}
+
+	return &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{&cert}}}
+}
@@ -0,0 +1,47 @@
+package mid
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"lobbyte.com/alkeepy/app/api/errs"
+	"lobbyte.com/alkeepy/foundation/web"
+)
+
+// Timeout places a deadline on the request context so downstream calls are
+// cancelled once the duration elapses. Requests that fail because the deadline
+// was reached are reported as a 504. It's applied to every route by Policy
+// with the timeout of the route. Event streams and websockets are long lived
+// by design and are not given a deadline.
+func Timeout(d time.Duration) web.Middleware {
+	m := func(next web.Handler) web.Handler {
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			if d <= 0 || isStream(r) || isUpgrade(r) {
+				return next(ctx, w, r)
+			}
+
+			ctx, cancel := context.WithTimeout(ctx, d)
+			defer cancel()
+
+			err := next(ctx, w, r.WithContext(ctx))
+
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) && web.GetStatusCode(ctx) == 0 {
+				return errs.Newf(errs.DeadlineExceeded, "request did not complete within %s", d)
+			}
+
+			return err
+		}
+
+		return h
+	}
+
+	return m
+}
+
+// isStream reports whether the request is for a long lived event stream.
+func isStream(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
@@ -0,0 +1,63 @@
+package mid
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"lobbyte.com/alkeepy/app/api/errs"
+	"lobbyte.com/alkeepy/business/sdk/sqldb"
+	"lobbyte.com/alkeepy/foundation/web"
+)
+
+// BeginCommitRollback starts a transaction for the route and places it in the
+// context for the handler to pass to the ExecuteUnderTransaction method of
+// every business package it uses. The transaction is committed when the
+// handler succeeds and rolled back when it returns an error or panics, so
+// the changes made across the stores land together or not at all. Every
+// request passes through without a transaction when bgn is nil, like it
+// does when the stores are kept in memory.
+func BeginCommitRollback(log *slog.Logger, bgn sqldb.Beginner) web.Middleware {
+	m := func(next web.Handler) web.Handler {
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			if bgn == nil {
+				return next(ctx, w, r)
+			}
+
+			tx, err := bgn.Begin()
+			if err != nil {
+				return errs.Newf(errs.Internal, "begin transaction: %s", err)
+			}
+
+			committed := false
+			defer func() {
+				if committed {
+					return
+				}
+
+				log.InfoContext(ctx, "rollback transaction")
+				if err := tx.Rollback(); err != nil {
+					log.ErrorContext(ctx, "rollback transaction", "msg", err)
+				}
+			}()
+
+			ctx = setTran(ctx, tx)
+
+			if err := next(ctx, w, r); err != nil {
+				return err
+			}
+
+			log.InfoContext(ctx, "commit transaction")
+			if err := tx.Commit(); err != nil {
+				return errs.Newf(errs.Internal, "commit transaction: %s", err)
+			}
+			committed = true
+
+			return nil
+		}
+
+		return h
+	}
+
+	return m
+}
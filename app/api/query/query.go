@@ -0,0 +1,26 @@
+// Package query provides support for the response of list endpoints.
+package query
+
+import "lobbyte.com/alkeepy/business/sdk/page"
+
+// Result is the envelope every list endpoint responds with. The total is the
+// number of items matching the query across all pages. Page is left out
+// when the page started after a cursor, and Next is the cursor of the
+// following page when the endpoint supports paging by cursor.
+type Result[T any] struct {
+	Items       []T    `json:"items"`
+	Total       int    `json:"total"`
+	Page        int    `json:"page,omitempty"`
+	RowsPerPage int    `json:"rowsPerPage"`
+	Next        string `json:"next,omitempty"`
+}
+
+// NewResult constructs a result value to return query results.
+func NewResult[T any](items []T, total int, pg page.Page) Result[T] {
+	return Result[T]{
+		Items:       items,
+		Total:       total,
+		Page:        pg.Number(),
+		RowsPerPage: pg.RowsPerPage(),
+	}
+}
@@ -0,0 +1,43 @@
+// Package auditapp maintains the app layer api for the audit domain.
+package auditapp
+
+import (
+	"context"
+	"net/http"
+
+	"lobbyte.com/alkeepy/app/api/errs"
+	"lobbyte.com/alkeepy/app/api/query"
+	"lobbyte.com/alkeepy/business/domain/auditbus"
+	"lobbyte.com/alkeepy/foundation/web"
+)
+
+type app struct {
+	auditBus *auditbus.Business
+}
+
+func newApp(auditBus *auditbus.Business) *app {
+	return &app{
+		auditBus: auditBus,
+	}
+}
+
+// query returns the page of audit records matching the query string, newest
+// first.
+func (a *app) query(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	filter, pg, err := parseFilter(parseQueryParams(r))
+	if err != nil {
+		return err
+	}
+
+	audits, err := a.auditBus.Query(ctx, filter, pg)
+	if err != nil {
+		return errs.Newf(errs.Internal, "query: %s", err)
+	}
+
+	total, err := a.auditBus.Count(ctx, filter)
+	if err != nil {
+		return errs.Newf(errs.Internal, "count: %s", err)
+	}
+
+	return web.Respond(ctx, w, query.NewResult(toAppAudits(audits), total, pg), http.StatusOK)
+}
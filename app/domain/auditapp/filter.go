@@ -0,0 +1,89 @@
+package auditapp
+
+import (
+	"net/http"
+	"time"
+
+	"lobbyte.com/alkeepy/app/api/errs"
+	"lobbyte.com/alkeepy/business/domain/auditbus"
+	"lobbyte.com/alkeepy/business/sdk/page"
+	"lobbyte.com/alkeepy/foundation/validate"
+)
+
+type queryParams struct {
+	Actor        string
+	Action       string
+	ResourceType string
+	ResourceID   string
+	StartDate    string
+	EndDate      string
+	Page         string
+	Rows         string
+}
+
+func parseQueryParams(r *http.Request) queryParams {
+	values := r.URL.Query()
+
+	return queryParams{
+		Actor:        values.Get("actor"),
+		Action:       values.Get("action"),
+		ResourceType: values.Get("resource_type"),
+		ResourceID:   values.Get("resource_id"),
+		StartDate:    values.Get("start_date"),
+		EndDate:      values.Get("end_date"),
+		Page:         values.Get("page"),
+		Rows:         values.Get("rows"),
+	}
+}
+
+func parseFilter(qp queryParams) (auditbus.QueryFilter, page.Page, error) {
+	var fieldErrors validate.FieldErrors
+	var filter auditbus.QueryFilter
+
+	if qp.Actor != "" {
+		filter.Actor = &qp.Actor
+	}
+
+	if qp.Action != "" {
+		filter.Action = &qp.Action
+	}
+
+	if qp.ResourceType != "" {
+		filter.ResourceType = &qp.ResourceType
+	}
+
+	if qp.ResourceID != "" {
+		filter.ResourceID = &qp.ResourceID
+	}
+
+	if qp.StartDate != "" {
+		t, err := time.Parse(time.RFC3339, qp.StartDate)
+		switch err {
+		case nil:
+			filter.StartDate = &t
+		default:
+			fieldErrors = append(fieldErrors, validate.FieldError{Field: "start_date", Err: err.Error()})
+		}
+	}
+
+	if qp.EndDate != "" {
+		t, err := time.Parse(time.RFC3339, qp.EndDate)
+		switch err {
+		case nil:
+			filter.EndDate = &t
+		default:
+			fieldErrors = append(fieldErrors, validate.FieldError{Field: "end_date", Err: err.Error()})
+		}
+	}
+
+	pg, err := page.Parse(qp.Page, qp.Rows)
+	if err != nil {
+		fieldErrors = append(fieldErrors, validate.GetFieldErrors(err)...)
+	}
+
+	if fieldErrors != nil {
+		return auditbus.QueryFilter{}, page.Page{}, errs.New(errs.InvalidArgument, fieldErrors)
+	}
+
+	return filter, pg, nil
+}
@@ -0,0 +1,39 @@
+package auditapp
+
+import (
+	"time"
+
+	"lobbyte.com/alkeepy/business/domain/auditbus"
+)
+
+// Audit represents information about an individual audit record.
+type Audit struct {
+	ID           string `json:"id"`
+	Actor        string `json:"actor"`
+	Action       string `json:"action"`
+	ResourceType string `json:"resourceType"`
+	ResourceID   string `json:"resourceID"`
+	Message      string `json:"message"`
+	Timestamp    string `json:"timestamp"`
+}
+
+func toAppAudit(a auditbus.Audit) Audit {
+	return Audit{
+		ID:           a.ID.String(),
+		Actor:        a.Actor,
+		Action:       a.Action,
+		ResourceType: a.ResourceType,
+		ResourceID:   a.ResourceID,
+		Message:      a.Message,
+		Timestamp:    a.Timestamp.Format(time.RFC3339),
+	}
+}
+
+func toAppAudits(audits []auditbus.Audit) []Audit {
+	app := make([]Audit, len(audits))
+	for i, a := range audits {
+		app[i] = toAppAudit(a)
+	}
+
+	return app
+}
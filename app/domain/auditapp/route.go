@@ -0,0 +1,25 @@
+package auditapp
+
+import (
+	"net/http"
+
+	"lobbyte.com/alkeepy/business/domain/auditbus"
+	"lobbyte.com/alkeepy/business/domain/userbus"
+	"lobbyte.com/alkeepy/foundation/web"
+)
+
+// Config contains all the mandatory systems required by handlers.
+type Config struct {
+	AuditBus *auditbus.Business
+}
+
+// Routes adds specific routes for this group. The audit log is only for
+// admins so these routes are bound to the internal listener and require the
+// admin role.
+func Routes(app *web.App, cfg Config) {
+	const version = "v1"
+
+	api := newApp(cfg.AuditBus)
+
+	app.HandleMeta(web.RouteMeta{Summary: "Query the audit log", Auth: true, Roles: []string{userbus.RoleAdmin}}, http.MethodGet, version, "/audits", api.query)
+}
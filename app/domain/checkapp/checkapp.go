@@ -0,0 +1,41 @@
+// Package checkapp maintains the app layer api for the check domain.
+package checkapp
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+
+	"lobbyte.com/alkeepy/foundation/web"
+)
+
+type app struct {
+	build    string
+	draining *atomic.Bool
+}
+
+func newApp(build string, draining *atomic.Bool) *app {
+	return &app{
+		build:    build,
+		draining: draining,
+	}
+}
+
+// readiness checks if the service is ready to receive traffic. Once shutdown
+// has started the service reports it is unavailable so load balancers stop
+// routing new requests to it.
+func (a *app) readiness(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	if a.draining != nil && a.draining.Load() {
+		status := Status{
+			Status: "draining",
+		}
+
+		return web.Respond(ctx, w, status, http.StatusServiceUnavailable)
+	}
+
+	status := Status{
+		Status: "ok",
+	}
+
+	return web.Respond(ctx, w, status, http.StatusOK)
+}
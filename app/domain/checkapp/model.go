@@ -0,0 +1,6 @@
+package checkapp
+
+// Status represents the status of the service.
+type Status struct {
+	Status string `json:"status"`
+}
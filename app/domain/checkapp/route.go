@@ -0,0 +1,23 @@
+package checkapp
+
+import (
+	"net/http"
+	"sync/atomic"
+
+	"lobbyte.com/alkeepy/foundation/web"
+)
+
+// Config contains all the mandatory systems required by handlers.
+type Config struct {
+	Build    string
+	Draining *atomic.Bool
+}
+
+// Routes adds specific routes for this group.
+func Routes(app *web.App, cfg Config) {
+	const version = ""
+
+	api := newApp(cfg.Build, cfg.Draining)
+
+	app.Handle(http.MethodGet, version, "/readiness", api.readiness)
+}
@@ -0,0 +1,201 @@
+package recipeapp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"lobbyte.com/alkeepy/app/api/errs"
+	"lobbyte.com/alkeepy/business/domain/recipebus"
+	"lobbyte.com/alkeepy/business/sdk/order"
+	"lobbyte.com/alkeepy/business/sdk/page"
+	"lobbyte.com/alkeepy/foundation/validate"
+)
+
+type queryParams struct {
+	UserID           string
+	Name             string
+	Tag              string
+	MaxPrepMinutes   string
+	StartCreatedDate string
+	EndCreatedDate   string
+	Search           string
+	SearchLanguage   string
+	Metadata         string
+	IncludeDeleted   string
+	OrderBy          string
+	Page             string
+	Cursor           string
+	Rows             string
+}
+
+func parseQueryParams(r *http.Request) queryParams {
+	values := r.URL.Query()
+
+	return queryParams{
+		UserID:           values.Get("user_id"),
+		Name:             values.Get("name"),
+		Tag:              values.Get("tag"),
+		MaxPrepMinutes:   values.Get("max_prep_minutes"),
+		StartCreatedDate: values.Get("start_created_date"),
+		EndCreatedDate:   values.Get("end_created_date"),
+		Search:           values.Get("search"),
+		SearchLanguage:   values.Get("search_language"),
+		Metadata:         values.Get("metadata"),
+		IncludeDeleted:   values.Get("include_deleted"),
+		OrderBy:          values.Get("orderBy"),
+		Page:             values.Get("page"),
+		Cursor:           values.Get("cursor"),
+		Rows:             values.Get("rows"),
+	}
+}
+
+// parseFilter builds the filter from the query string. Only admins can ask
+// for the deleted recipes. A search is ordered by rank unless the query
+// string says otherwise, and only a search can be. A cursor pages the
+// recipes by the date they were created instead of by page number.
+func parseFilter(qp queryParams, admin bool) (recipebus.QueryFilter, order.By, page.Page, error) {
+	var fieldErrors validate.FieldErrors
+	var filter recipebus.QueryFilter
+
+	if qp.UserID != "" {
+		id, err := uuid.Parse(qp.UserID)
+		switch err {
+		case nil:
+			filter.UserID = &id
+		default:
+			fieldErrors = append(fieldErrors, validate.FieldError{Field: "user_id", Err: err.Error()})
+		}
+	}
+
+	if qp.Name != "" {
+		filter.Name = &qp.Name
+	}
+
+	if qp.Tag != "" {
+		filter.Tag = &qp.Tag
+	}
+
+	if qp.MaxPrepMinutes != "" {
+		n, err := strconv.Atoi(qp.MaxPrepMinutes)
+		switch {
+		case err != nil:
+			fieldErrors = append(fieldErrors, validate.FieldError{Field: "max_prep_minutes", Err: err.Error()})
+		case n < 0:
+			fieldErrors = append(fieldErrors, validate.FieldError{Field: "max_prep_minutes", Err: "must not be negative"})
+		default:
+			filter.MaxPrepMinutes = &n
+		}
+	}
+
+	if qp.StartCreatedDate != "" {
+		t, err := time.Parse(time.RFC3339, qp.StartCreatedDate)
+		switch err {
+		case nil:
+			filter.StartCreatedDate = &t
+		default:
+			fieldErrors = append(fieldErrors, validate.FieldError{Field: "start_created_date", Err: err.Error()})
+		}
+	}
+
+	if qp.EndCreatedDate != "" {
+		t, err := time.Parse(time.RFC3339, qp.EndCreatedDate)
+		switch err {
+		case nil:
+			filter.EndCreatedDate = &t
+		default:
+			fieldErrors = append(fieldErrors, validate.FieldError{Field: "end_created_date", Err: err.Error()})
+		}
+	}
+
+	if qp.Search != "" {
+		filter.Search = &qp.Search
+	}
+
+	if qp.SearchLanguage != "" {
+		switch {
+		case qp.Search == "":
+			fieldErrors = append(fieldErrors, validate.FieldError{Field: "search_language", Err: "requires search"})
+		case !slices.Contains(recipebus.SearchLanguages, qp.SearchLanguage):
+			fieldErrors = append(fieldErrors, validate.FieldError{Field: "search_language", Err: fmt.Sprintf("must be one of %s", strings.Join(recipebus.SearchLanguages, ", "))})
+		default:
+			filter.SearchLanguage = &qp.SearchLanguage
+		}
+	}
+
+	if qp.Metadata != "" {
+		m, err := parseMetadata(qp.Metadata)
+		switch err {
+		case nil:
+			filter.Metadata = m
+		default:
+			fieldErrors = append(fieldErrors, validate.FieldError{Field: "metadata", Err: err.Error()})
+		}
+	}
+
+	if qp.IncludeDeleted != "" {
+		b, err := strconv.ParseBool(qp.IncludeDeleted)
+		switch {
+		case err != nil:
+			fieldErrors = append(fieldErrors, validate.FieldError{Field: "include_deleted", Err: err.Error()})
+		case !admin:
+			fieldErrors = append(fieldErrors, validate.FieldError{Field: "include_deleted", Err: "only available on the internal api"})
+		default:
+			filter.IncludeDeleted = b
+		}
+	}
+
+	defaultOrderBy := recipebus.DefaultOrderBy
+	if filter.Search != nil {
+		defaultOrderBy = recipebus.SearchOrderBy
+	}
+
+	orderBy, err := order.Parse(orderByFields, qp.OrderBy, defaultOrderBy)
+	switch {
+	case err != nil:
+		fieldErrors = append(fieldErrors, validate.GetFieldErrors(err)...)
+	case orderBy.Field == recipebus.OrderByRank && filter.Search == nil:
+		fieldErrors = append(fieldErrors, validate.FieldError{Field: "orderBy", Err: "rank requires search"})
+	}
+
+	var pg page.Page
+	switch {
+	case qp.Cursor == "":
+		pg, err = page.Parse(qp.Page, qp.Rows)
+	case qp.Page != "":
+		err = validate.FieldErrors{{Field: "page", Err: "can't be used with cursor"}}
+	case orderBy.Field != recipebus.OrderByDateCreated:
+		err = validate.FieldErrors{{Field: "cursor", Err: "requires ordering by date_created"}}
+	default:
+		pg, err = page.ParseCursor(qp.Cursor, qp.Rows)
+	}
+	if err != nil {
+		fieldErrors = append(fieldErrors, validate.GetFieldErrors(err)...)
+	}
+
+	if fieldErrors != nil {
+		return recipebus.QueryFilter{}, order.By{}, page.Page{}, errs.New(errs.InvalidArgument, fieldErrors)
+	}
+
+	return filter, orderBy, pg, nil
+}
+
+// parseMetadata decodes the JSON object the recipes' metadata must contain.
+func parseMetadata(s string) (recipebus.Metadata, error) {
+	var m recipebus.Metadata
+	if err := json.Unmarshal([]byte(s), &m); err != nil || m == nil {
+		return nil, errors.New("must be a JSON object")
+	}
+
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
@@ -0,0 +1,182 @@
+package recipeapp
+
+import (
+	"time"
+
+	"lobbyte.com/alkeepy/business/domain/recipebus"
+	"lobbyte.com/alkeepy/foundation/web"
+)
+
+// Recipe represents information about an individual recipe. Rank and
+// Headline are only returned by a search.
+type Recipe struct {
+	ID          string         `json:"id"`
+	UserID      string         `json:"userID"`
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	PrepMinutes int            `json:"prepMinutes"`
+	Tags        []string       `json:"tags"`
+	Metadata    map[string]any `json:"metadata"`
+	Version     int            `json:"version"`
+	DateCreated string         `json:"dateCreated"`
+	DateUpdated string         `json:"dateUpdated"`
+	DateDeleted string         `json:"dateDeleted,omitempty"`
+	Rank        float64        `json:"rank,omitempty"`
+	Headline    string         `json:"headline,omitempty"`
+}
+
+func toAppRecipe(r recipebus.Recipe) Recipe {
+	tags := r.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+
+	metadata := r.Metadata
+	if metadata == nil {
+		metadata = map[string]any{}
+	}
+
+	app := Recipe{
+		ID:          r.ID.String(),
+		UserID:      r.UserID.String(),
+		Name:        r.Name,
+		Description: r.Description,
+		PrepMinutes: r.PrepMinutes,
+		Tags:        tags,
+		Metadata:    metadata,
+		Version:     r.Version,
+		DateCreated: r.DateCreated.Format(time.RFC3339),
+		DateUpdated: r.DateUpdated.Format(time.RFC3339),
+		Rank:        r.Rank,
+		Headline:    r.Headline,
+	}
+
+	if r.Deleted() {
+		app.DateDeleted = r.DateDeleted.Format(time.RFC3339)
+	}
+
+	return app
+}
+
+func toAppRecipes(recipes []recipebus.Recipe) []Recipe {
+	app := make([]Recipe, len(recipes))
+	for i, r := range recipes {
+		app[i] = toAppRecipe(r)
+	}
+
+	return app
+}
+
+// =============================================================================
+
+// Event represents a change made to a recipe, sent to the clients following
+// the changes.
+type Event struct {
+	Op       string `json:"op"`
+	RecipeID string `json:"recipeID,omitempty"`
+	Version  int    `json:"version,omitempty"`
+}
+
+func toWebEvent(ev recipebus.Event) web.Event {
+	app := Event{
+		Op: ev.Op,
+	}
+
+	if ev.Op != recipebus.EventResync {
+		app.RecipeID = ev.RecipeID.String()
+		app.Version = ev.Version
+	}
+
+	return web.Event{
+		Event: ev.Op,
+		Data:  app,
+	}
+}
+
+// =============================================================================
+
+// UpdateRecipe defines the data needed to update a recipe. Version must be
+// the version of the recipe the changes were made to, as last returned by
+// the API. Metadata replaces the metadata of the recipe as a whole and can
+// only hold the keys listed by recipebus.MetadataKeys.
+type UpdateRecipe struct {
+	Name        *string        `json:"name" validate:"omitempty,min=1,max=200"`
+	Description *string        `json:"description" validate:"omitempty,max=5000"`
+	PrepMinutes *int           `json:"prepMinutes" validate:"omitempty,min=0"`
+	Tags        []string       `json:"tags" validate:"omitempty,dive,min=1,max=50"`
+	Metadata    map[string]any `json:"metadata"`
+	Version     int            `json:"version" validate:"required,min=1"`
+}
+
+func toBusUpdateRecipe(ur UpdateRecipe) recipebus.UpdateRecipe {
+	return recipebus.UpdateRecipe{
+		Name:        ur.Name,
+		Description: ur.Description,
+		PrepMinutes: ur.PrepMinutes,
+		Tags:        ur.Tags,
+		Metadata:    ur.Metadata,
+		Version:     ur.Version,
+	}
+}
+
+// =============================================================================
+
+// Revision represents a change made to a recipe, with the fields it changed
+// and the recipe before and after it.
+type Revision struct {
+	ID          string   `json:"id"`
+	Version     int      `json:"version"`
+	Op          string   `json:"op"`
+	Actor       string   `json:"actor"`
+	Changes     []Change `json:"changes"`
+	Old         Recipe   `json:"old"`
+	New         Recipe   `json:"new"`
+	DateCreated string   `json:"dateCreated"`
+}
+
+// Change represents a field a revision changed.
+type Change struct {
+	Field string `json:"field"`
+	Old   any    `json:"old"`
+	New   any    `json:"new"`
+}
+
+func toAppRevision(rev recipebus.Revision) Revision {
+	diff := rev.Diff()
+
+	changes := make([]Change, len(diff))
+	for i, c := range diff {
+		changes[i] = Change{
+			Field: c.Field,
+			Old:   c.Old,
+			New:   c.New,
+		}
+	}
+
+	return Revision{
+		ID:          rev.ID.String(),
+		Version:     rev.Version,
+		Op:          rev.Op,
+		Actor:       rev.Actor,
+		Changes:     changes,
+		Old:         toAppRecipe(rev.Old),
+		New:         toAppRecipe(rev.New),
+		DateCreated: rev.DateCreated.Format(time.RFC3339),
+	}
+}
+
+func toAppRevisions(revisions []recipebus.Revision) []Revision {
+	app := make([]Revision, len(revisions))
+	for i, rev := range revisions {
+		app[i] = toAppRevision(rev)
+	}
+
+	return app
+}
+
+// RevertRecipe defines the data needed to revert a recipe to a previous
+// version. Version must be the current version of the recipe, as last
+// returned by the API.
+type RevertRecipe struct {
+	Version int `json:"version" validate:"required,min=1"`
+}
@@ -0,0 +1,11 @@
+package recipeapp
+
+import "lobbyte.com/alkeepy/business/domain/recipebus"
+
+var orderByFields = map[string]string{
+	"recipe_id":    recipebus.OrderByID,
+	"name":         recipebus.OrderByName,
+	"prep_minutes": recipebus.OrderByPrepMinutes,
+	"date_created": recipebus.OrderByDateCreated,
+	"rank":         recipebus.OrderByRank,
+}
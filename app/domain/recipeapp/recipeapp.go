@@ -0,0 +1,409 @@
+// Package recipeapp maintains the app layer api for the recipe domain.
+package recipeapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"lobbyte.com/alkeepy/app/api/errs"
+	"lobbyte.com/alkeepy/app/api/featureflag"
+	"lobbyte.com/alkeepy/app/api/mid"
+	"lobbyte.com/alkeepy/app/api/query"
+	"lobbyte.com/alkeepy/business/domain/auditbus"
+	"lobbyte.com/alkeepy/business/domain/recipebus"
+	"lobbyte.com/alkeepy/business/sdk/page"
+	"lobbyte.com/alkeepy/business/sdk/sqldb"
+	"lobbyte.com/alkeepy/business/sdk/tenant"
+	"lobbyte.com/alkeepy/foundation/pubsub"
+	"lobbyte.com/alkeepy/foundation/validate"
+	"lobbyte.com/alkeepy/foundation/web"
+)
+
+// keepAlive is how long a stream of events stays idle before a comment is
+// sent to keep the connection open through proxies.
+const keepAlive = 15 * time.Second
+
+type app struct {
+	log       *slog.Logger
+	recipeBus *recipebus.Business
+	auditBus  *auditbus.Business
+	events    *pubsub.Broker[recipebus.Event]
+	admin     bool
+}
+
+func newApp(log *slog.Logger, recipeBus *recipebus.Business, auditBus *auditbus.Business, events *pubsub.Broker[recipebus.Event], admin bool) *app {
+	return &app{
+		log:       log,
+		recipeBus: recipeBus,
+		auditBus:  auditBus,
+		events:    events,
+		admin:     admin,
+	}
+}
+
+// query returns the page of recipes matching the query string.
+func (a *app) query(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	filter, orderBy, pg, err := parseFilter(parseQueryParams(r), a.admin)
+	if err != nil {
+		return err
+	}
+
+	recipes, err := a.recipeBus.Query(ctx, filter, orderBy, pg)
+	if err != nil {
+		return errs.Newf(errs.Internal, "query: %s", err)
+	}
+
+	total, err := a.recipeBus.Count(ctx, filter)
+	if err != nil {
+		return errs.Newf(errs.Internal, "count: %s", err)
+	}
+
+	result := query.NewResult(toAppRecipes(recipes), total, pg)
+
+	// A full page ordered by the date created can be followed by cursor.
+	if n := len(recipes); n > 0 && n == pg.RowsPerPage() && orderBy.Field == recipebus.OrderByDateCreated {
+		last := recipes[n-1]
+		result.Next = page.Cursor{DateCreated: last.DateCreated, ID: last.ID}.Encode()
+	}
+
+	return web.Respond(ctx, w, result, http.StatusOK)
+}
+
+// queryByID returns the recipe with the id in the path.
+func (a *app) queryByID(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	recipeID, err := uuid.Parse(r.PathValue("recipe_id"))
+	if err != nil {
+		return errs.Newf(errs.InvalidArgument, "recipe_id: %s", err)
+	}
+
+	recipe, err := a.recipeBus.QueryByID(ctx, recipeID)
+	if err != nil {
+		if errors.Is(err, recipebus.ErrNotFound) {
+			return errs.Newf(errs.NotFound, "recipe %s not found", recipeID)
+		}
+		return errs.Newf(errs.Internal, "querybyid: %s", err)
+	}
+
+	return web.Respond(ctx, w, toAppRecipe(recipe), http.StatusOK)
+}
+
+// update applies the changes in the body to the recipe with the id in the
+// path. A conflict is returned when the recipe has been changed since the
+// version the changes were made to, and a failed precondition when it no
+// longer has the entity tag of the If-Match header.
+func (a *app) update(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	a, err := a.inTran(ctx)
+	if err != nil {
+		return err
+	}
+
+	var ur UpdateRecipe
+	if err := web.Decode(r, &ur, web.Strict()); err != nil {
+		return errs.New(errs.InvalidArgument, err)
+	}
+
+	if ur.Metadata != nil {
+		if err := recipebus.Metadata(ur.Metadata).Validate(); err != nil {
+			return errs.New(errs.InvalidArgument, validate.FieldErrors{{Field: "metadata", Err: err.Error()}})
+		}
+	}
+
+	recipeID, err := uuid.Parse(r.PathValue("recipe_id"))
+	if err != nil {
+		return errs.Newf(errs.InvalidArgument, "recipe_id: %s", err)
+	}
+
+	recipe, err := a.recipeBus.QueryByID(ctx, recipeID)
+	if err != nil {
+		if errors.Is(err, recipebus.ErrNotFound) {
+			return errs.Newf(errs.NotFound, "recipe %s not found", recipeID)
+		}
+		return errs.Newf(errs.Internal, "querybyid: %s", err)
+	}
+
+	if err := checkIfMatch(r, recipe); err != nil {
+		return err
+	}
+
+	recipe, err = a.recipeBus.Update(ctx, recipe, toBusUpdateRecipe(ur))
+	if err != nil {
+		if errors.Is(err, recipebus.ErrVersionConflict) {
+			return errs.Newf(errs.Conflict, "recipe %s has been changed since version %d", recipeID, ur.Version)
+		}
+		return errs.Newf(errs.Internal, "update: %s", err)
+	}
+
+	if err := a.audit(ctx, auditbus.ActionUpdate, recipe.ID, fmt.Sprintf("updated to version %d", recipe.Version)); err != nil {
+		return err
+	}
+
+	return web.Respond(ctx, w, toAppRecipe(recipe), http.StatusOK)
+}
+
+// delete marks the recipe with the id in the path as deleted.
+func (a *app) delete(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	a, err := a.inTran(ctx)
+	if err != nil {
+		return err
+	}
+
+	recipeID, err := uuid.Parse(r.PathValue("recipe_id"))
+	if err != nil {
+		return errs.Newf(errs.InvalidArgument, "recipe_id: %s", err)
+	}
+
+	recipe, err := a.recipeBus.QueryByID(ctx, recipeID)
+	if err != nil {
+		if errors.Is(err, recipebus.ErrNotFound) {
+			return errs.Newf(errs.NotFound, "recipe %s not found", recipeID)
+		}
+		return errs.Newf(errs.Internal, "querybyid: %s", err)
+	}
+
+	if err := a.recipeBus.Delete(ctx, recipe); err != nil {
+		return errs.Newf(errs.Internal, "delete: %s", err)
+	}
+
+	if err := a.audit(ctx, auditbus.ActionDelete, recipe.ID, "deleted"); err != nil {
+		return err
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// restore brings back the deleted recipe with the id in the path.
+func (a *app) restore(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	a, err := a.inTran(ctx)
+	if err != nil {
+		return err
+	}
+
+	recipeID, err := uuid.Parse(r.PathValue("recipe_id"))
+	if err != nil {
+		return errs.Newf(errs.InvalidArgument, "recipe_id: %s", err)
+	}
+
+	recipe, err := a.recipeBus.Restore(ctx, recipeID)
+	if err != nil {
+		if errors.Is(err, recipebus.ErrNotFound) {
+			return errs.Newf(errs.NotFound, "deleted recipe %s not found", recipeID)
+		}
+		return errs.Newf(errs.Internal, "restore: %s", err)
+	}
+
+	if err := a.audit(ctx, auditbus.ActionRestore, recipe.ID, fmt.Sprintf("restored at version %d", recipe.Version)); err != nil {
+		return err
+	}
+
+	return web.Respond(ctx, w, toAppRecipe(recipe), http.StatusOK)
+}
+
+// history returns the page of the changes made to the recipe with the id in
+// the path, newest first.
+func (a *app) history(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	if !featureflag.Enabled(ctx, featureflag.RecipeHistory) {
+		return errs.Newf(errs.NotFound, "recipe history isn't enabled")
+	}
+
+	recipeID, err := uuid.Parse(r.PathValue("recipe_id"))
+	if err != nil {
+		return errs.Newf(errs.InvalidArgument, "recipe_id: %s", err)
+	}
+
+	values := r.URL.Query()
+
+	pg, err := page.Parse(values.Get("page"), values.Get("rows"))
+	if err != nil {
+		return errs.New(errs.InvalidArgument, err)
+	}
+
+	if _, err := a.recipeBus.QueryByID(ctx, recipeID); err != nil {
+		if errors.Is(err, recipebus.ErrNotFound) {
+			return errs.Newf(errs.NotFound, "recipe %s not found", recipeID)
+		}
+		return errs.Newf(errs.Internal, "querybyid: %s", err)
+	}
+
+	revisions, err := a.recipeBus.QueryHistory(ctx, recipeID, pg)
+	if err != nil {
+		return errs.Newf(errs.Internal, "queryhistory: %s", err)
+	}
+
+	total, err := a.recipeBus.CountHistory(ctx, recipeID)
+	if err != nil {
+		return errs.Newf(errs.Internal, "counthistory: %s", err)
+	}
+
+	return web.Respond(ctx, w, query.NewResult(toAppRevisions(revisions), total, pg), http.StatusOK)
+}
+
+// revert changes the recipe with the id in the path back to the version in
+// the path. A conflict is returned when the recipe has been changed since
+// the version in the body, and a failed precondition when it no longer has
+// the entity tag of the If-Match header.
+func (a *app) revert(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	if !featureflag.Enabled(ctx, featureflag.RecipeHistory) {
+		return errs.Newf(errs.NotFound, "recipe history isn't enabled")
+	}
+
+	a, err := a.inTran(ctx)
+	if err != nil {
+		return err
+	}
+
+	var rr RevertRecipe
+	if err := web.Decode(r, &rr, web.Strict()); err != nil {
+		return errs.New(errs.InvalidArgument, err)
+	}
+
+	recipeID, err := uuid.Parse(r.PathValue("recipe_id"))
+	if err != nil {
+		return errs.Newf(errs.InvalidArgument, "recipe_id: %s", err)
+	}
+
+	version, err := strconv.Atoi(r.PathValue("version"))
+	if err != nil || version < 1 {
+		return errs.Newf(errs.InvalidArgument, "version: must be a positive number")
+	}
+
+	recipe, err := a.recipeBus.QueryByID(ctx, recipeID)
+	if err != nil {
+		if errors.Is(err, recipebus.ErrNotFound) {
+			return errs.Newf(errs.NotFound, "recipe %s not found", recipeID)
+		}
+		return errs.Newf(errs.Internal, "querybyid: %s", err)
+	}
+
+	if err := checkIfMatch(r, recipe); err != nil {
+		return err
+	}
+
+	recipe, err = a.recipeBus.Revert(ctx, recipe, version, rr.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, recipebus.ErrVersionNotFound):
+			return errs.Newf(errs.NotFound, "version %d of recipe %s not found in its history", version, recipeID)
+		case errors.Is(err, recipebus.ErrVersionConflict):
+			return errs.Newf(errs.Conflict, "recipe %s has been changed since version %d", recipeID, rr.Version)
+		}
+		return errs.Newf(errs.Internal, "revert: %s", err)
+	}
+
+	if err := a.audit(ctx, auditbus.ActionRevert, recipe.ID, fmt.Sprintf("reverted to version %d at version %d", version, recipe.Version)); err != nil {
+		return err
+	}
+
+	return web.Respond(ctx, w, toAppRecipe(recipe), http.StatusOK)
+}
+
+// stream sends the changes made to the recipes of the tenant as server
+// sent events until the client goes away. A resync event tells the client
+// changes may have been missed and it should query the recipes again.
+func (a *app) stream(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	tenantID, err := tenant.Get(ctx)
+	if err != nil {
+		return errs.New(errs.Unauthenticated, err)
+	}
+
+	sub, unsubscribe := a.events.Subscribe(16)
+	defer unsubscribe()
+
+	sse, err := web.NewSSE(ctx, w)
+	if err != nil {
+		return errs.Newf(errs.Internal, "events: %s", err)
+	}
+
+	ch := make(chan web.Event)
+	go func() {
+		defer close(ch)
+
+		for ev := range sub {
+			if ev.Op != recipebus.EventResync && ev.TenantID != tenantID {
+				continue
+			}
+
+			select {
+			case ch <- toWebEvent(ev):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// The stream only fails to write once the client has gone away, and
+	// there's no one left to tell.
+	sse.Stream(ctx, ch, keepAlive)
+
+	return nil
+}
+
+// checkIfMatch makes sure the recipe still has the entity tag the client
+// sent in the If-Match header, the tag of the recipe as queryByID returns
+// it. Requests without the header only rely on the version in the body.
+func checkIfMatch(r *http.Request, recipe recipebus.Recipe) error {
+	if r.Header.Get("If-Match") == "" {
+		return nil
+	}
+
+	etag, err := web.ETagFor(toAppRecipe(recipe))
+	if err != nil {
+		return errs.Newf(errs.Internal, "etag: %s", err)
+	}
+
+	if !web.IfMatch(r, etag) {
+		return errs.Newf(errs.PreconditionFailed, "recipe %s has been changed, it no longer matches If-Match", recipe.ID)
+	}
+
+	return nil
+}
+
+// audit records the change made to the recipe. It's part of the
+// transaction of the route, so a record that can't be stored fails the
+// request and the change is rolled back with it.
+func (a *app) audit(ctx context.Context, action string, recipeID uuid.UUID, message string) error {
+	na := auditbus.NewAudit{
+		Actor:        sqldb.GetActor(ctx),
+		Action:       action,
+		ResourceType: "recipe",
+		ResourceID:   recipeID.String(),
+		Message:      message,
+	}
+
+	if _, err := a.auditBus.Create(ctx, na); err != nil {
+		return errs.Newf(errs.Internal, "audit: %s", err)
+	}
+
+	return nil
+}
+
+// inTran returns the app running its business calls in the transaction the
+// BeginCommitRollback middleware started for the route. Without one, as
+// when the stores are kept in memory, the app is returned as is.
+func (a *app) inTran(ctx context.Context) (*app, error) {
+	tx, err := mid.GetTran(ctx)
+	if err != nil {
+		return a, nil
+	}
+
+	recipeBus, err := a.recipeBus.ExecuteUnderTransaction(tx)
+	if err != nil {
+		return nil, errs.Newf(errs.Internal, "recipe transaction: %s", err)
+	}
+
+	auditBus, err := a.auditBus.ExecuteUnderTransaction(tx)
+	if err != nil {
+		return nil, errs.Newf(errs.Internal, "audit transaction: %s", err)
+	}
+
+	tran := *a
+	tran.recipeBus = recipeBus
+	tran.auditBus = auditBus
+
+	return &tran, nil
+}
@@ -0,0 +1,56 @@
+package recipeapp
+
+import (
+	"log/slog"
+	"net/http"
+
+	"lobbyte.com/alkeepy/app/api/mid"
+	"lobbyte.com/alkeepy/business/domain/auditbus"
+	"lobbyte.com/alkeepy/business/domain/recipebus"
+	"lobbyte.com/alkeepy/business/domain/userbus"
+	"lobbyte.com/alkeepy/business/sdk/sqldb"
+	"lobbyte.com/alkeepy/foundation/pubsub"
+	"lobbyte.com/alkeepy/foundation/web"
+)
+
+// Config contains all the mandatory systems required by handlers. The
+// changes made through the routes are recorded with AuditBus. Admin is
+// set for the routes bound to the internal listener, which can see and
+// restore the deleted recipes. The changes are only streamed when Events is
+// set. The history of the recipes is only served while the recipe-history
+// feature flag is on. The changing routes run in a transaction begun with
+// Beginner, and without a transaction when it isn't set.
+type Config struct {
+	Log       *slog.Logger
+	RecipeBus *recipebus.Business
+	AuditBus  *auditbus.Business
+	Events    *pubsub.Broker[recipebus.Event]
+	Beginner  sqldb.Beginner
+	Admin     bool
+}
+
+// Routes adds specific routes for this group. The routes changing recipes
+// require an authenticated caller with a role and run in a transaction, so
+// the change and its audit are committed or rolled back together.
+func Routes(app *web.App, cfg Config) {
+	const version = "v1"
+
+	api := newApp(cfg.Log, cfg.RecipeBus, cfg.AuditBus, cfg.Events, cfg.Admin)
+	scoped := mid.RequireTenant()
+	transaction := mid.BeginCommitRollback(cfg.Log, cfg.Beginner)
+
+	app.HandleMeta(web.RouteMeta{Summary: "Query recipes"}, http.MethodGet, version, "/recipes", api.query, scoped)
+	app.HandleMeta(web.RouteMeta{Summary: "Query a recipe by id"}, http.MethodGet, version, "/recipes/{recipe_id}", api.queryByID, scoped)
+	app.HandleMeta(web.RouteMeta{Summary: "Update a recipe", Auth: true, Roles: []string{userbus.RoleAdmin, userbus.RoleUser}}, http.MethodPut, version, "/recipes/{recipe_id}", api.update, scoped, transaction)
+	app.HandleMeta(web.RouteMeta{Summary: "Delete a recipe", Auth: true, Roles: []string{userbus.RoleAdmin}}, http.MethodDelete, version, "/recipes/{recipe_id}", api.delete, scoped, transaction)
+	app.HandleMeta(web.RouteMeta{Summary: "Query the history of a recipe"}, http.MethodGet, version, "/recipes/{recipe_id}/history", api.history, scoped)
+	app.HandleMeta(web.RouteMeta{Summary: "Revert a recipe to a previous version", Auth: true, Roles: []string{userbus.RoleAdmin}}, http.MethodPost, version, "/recipes/{recipe_id}/history/{version}/revert", api.revert, scoped, transaction)
+
+	if cfg.Events != nil {
+		app.HandleMeta(web.RouteMeta{Summary: "Stream the changes made to recipes"}, http.MethodGet, version, "/recipes/events", app.EventStream(api.stream), scoped)
+	}
+
+	if cfg.Admin {
+		app.HandleMeta(web.RouteMeta{Summary: "Restore a deleted recipe", Auth: true, Roles: []string{userbus.RoleAdmin}}, http.MethodPost, version, "/recipes/{recipe_id}/restore", api.restore, scoped, transaction)
+	}
+}
@@ -0,0 +1,100 @@
+// Package auditbus provides business access to the audit domain. Audit
+// records are append only, once written they can't be changed. They're only
+// removed once they're past their retention, when the retention worker
+// moves them to the archive.
+package auditbus
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"lobbyte.com/alkeepy/business/sdk/page"
+	"lobbyte.com/alkeepy/business/sdk/sqldb"
+)
+
+// Storer interface declares the behavior this package needs to persist and
+// retrieve data. There is deliberately no way to update or delete a record.
+type Storer interface {
+	ExecuteUnderTransaction(tx sqldb.CommitRollbacker) (Storer, error)
+	Create(ctx context.Context, audit Audit) error
+	Query(ctx context.Context, filter QueryFilter, pg page.Page) ([]Audit, error)
+	Count(ctx context.Context, filter QueryFilter) (int, error)
+}
+
+// Business manages the set of APIs for audit access.
+type Business struct {
+	log    *slog.Logger
+	storer Storer
+}
+
+// NewBusiness constructs an audit business API for use. Every record is also
+// written to the specified logger so the audit trail can be shipped with the
+// rest of the logs.
+func NewBusiness(log *slog.Logger, storer Storer) *Business {
+	return &Business{
+		log:    log,
+		storer: storer,
+	}
+}
+
+// ExecuteUnderTransaction constructs a new Business value that will use the
+// specified transaction in any store related calls.
+func (b *Business) ExecuteUnderTransaction(tx sqldb.CommitRollbacker) (*Business, error) {
+	storer, err := b.storer.ExecuteUnderTransaction(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	bus := Business{
+		log:    b.log,
+		storer: storer,
+	}
+
+	return &bus, nil
+}
+
+// Create records a new audit entry.
+func (b *Business) Create(ctx context.Context, na NewAudit) (Audit, error) {
+	audit := Audit{
+		ID:           uuid.New(),
+		Actor:        na.Actor,
+		Action:       na.Action,
+		ResourceType: na.ResourceType,
+		ResourceID:   na.ResourceID,
+		Message:      na.Message,
+		Timestamp:    time.Now().UTC(),
+	}
+
+	if err := b.storer.Create(ctx, audit); err != nil {
+		return Audit{}, fmt.Errorf("create: %w", err)
+	}
+
+	b.log.InfoContext(ctx, "audit", "id", audit.ID, "actor", audit.Actor, "action", audit.Action,
+		"resource_type", audit.ResourceType, "resource_id", audit.ResourceID, "message", audit.Message)
+
+	return audit, nil
+}
+
+// Query retrieves the page of audit records that match the filter, newest
+// first.
+func (b *Business) Query(ctx context.Context, filter QueryFilter, pg page.Page) ([]Audit, error) {
+	audits, err := b.storer.Query(ctx, filter, pg)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	return audits, nil
+}
+
+// Count returns the total number of audit records that match the filter.
+func (b *Business) Count(ctx context.Context, filter QueryFilter) (int, error) {
+	n, err := b.storer.Count(ctx, filter)
+	if err != nil {
+		return 0, fmt.Errorf("count: %w", err)
+	}
+
+	return n, nil
+}
@@ -0,0 +1,34 @@
+package auditbus
+
+import "time"
+
+// QueryFilter holds the available fields a query can be filtered on.
+// A nil field doesn't restrict the query.
+type QueryFilter struct {
+	Actor        *string
+	Action       *string
+	ResourceType *string
+	ResourceID   *string
+	StartDate    *time.Time
+	EndDate      *time.Time
+}
+
+// Match reports whether the audit record satisfies the filter.
+func (qf QueryFilter) Match(a Audit) bool {
+	switch {
+	case qf.Actor != nil && a.Actor != *qf.Actor:
+		return false
+	case qf.Action != nil && a.Action != *qf.Action:
+		return false
+	case qf.ResourceType != nil && a.ResourceType != *qf.ResourceType:
+		return false
+	case qf.ResourceID != nil && a.ResourceID != *qf.ResourceID:
+		return false
+	case qf.StartDate != nil && a.Timestamp.Before(*qf.StartDate):
+		return false
+	case qf.EndDate != nil && !a.Timestamp.Before(*qf.EndDate):
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,38 @@
+package auditbus
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Set of actions recorded in the audit log.
+const (
+	ActionLogin      = "login"
+	ActionCreate     = "create"
+	ActionUpdate     = "update"
+	ActionDelete     = "delete"
+	ActionRestore    = "restore"
+	ActionRevert     = "revert"
+	ActionRoleChange = "role_change"
+)
+
+// Audit represents who did what to which resource.
+type Audit struct {
+	ID           uuid.UUID
+	Actor        string
+	Action       string
+	ResourceType string
+	ResourceID   string
+	Message      string
+	Timestamp    time.Time
+}
+
+// NewAudit is what we require from clients when recording an audit entry.
+type NewAudit struct {
+	Actor        string
+	Action       string
+	ResourceType string
+	ResourceID   string
+	Message      string
+}
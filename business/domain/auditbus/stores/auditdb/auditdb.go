@@ -0,0 +1,139 @@
+// Package auditdb contains audit related CRUD functionality. The records
+// belong to no tenant, they're read by the operators of the service.
+package auditdb
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"lobbyte.com/alkeepy/business/domain/auditbus"
+	"lobbyte.com/alkeepy/business/sdk/page"
+	"lobbyte.com/alkeepy/business/sdk/sqldb"
+)
+
+// Store manages the set of APIs for audit database access.
+type Store struct {
+	log     *slog.Logger
+	cluster *sqldb.Cluster
+	tx      sqlx.ExtContext
+}
+
+// NewStore constructs the api for data access.
+func NewStore(log *slog.Logger, cluster *sqldb.Cluster) *Store {
+	return &Store{
+		log:     log,
+		cluster: cluster,
+	}
+}
+
+// ExecuteUnderTransaction constructs a new Store value that runs every query
+// inside the transaction, so a record is only kept when the change it
+// reports is.
+func (s *Store) ExecuteUnderTransaction(tx sqldb.CommitRollbacker) (auditbus.Storer, error) {
+	ec, err := sqldb.GetExtContext(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	store := Store{
+		log:     s.log,
+		cluster: s.cluster,
+		tx:      ec,
+	}
+
+	return &store, nil
+}
+
+// read runs a query that only reads data. Outside of a transaction it goes
+// to a replica when one is healthy and is retried after a transient failure.
+func (s *Store) read(ctx context.Context, fn func(ctx context.Context, db sqlx.ExtContext) error) error {
+	if s.tx != nil {
+		return fn(ctx, s.tx)
+	}
+
+	return sqldb.Retry(ctx, s.log, sqldb.DefaultBackoff, func(ctx context.Context) error {
+		return fn(ctx, s.cluster.Reader())
+	})
+}
+
+// primary returns the transaction when there is one, otherwise the primary.
+func (s *Store) primary() sqlx.ExtContext {
+	if s.tx != nil {
+		return s.tx
+	}
+
+	return s.cluster.Primary()
+}
+
+// Create inserts the audit record into the database.
+func (s *Store) Create(ctx context.Context, audit auditbus.Audit) error {
+	const q = `
+	INSERT INTO audits
+		(audit_id, actor, action, resource_type, resource_id, message, timestamp)
+	VALUES
+		(:audit_id, :actor, :action, :resource_type, :resource_id, :message, :timestamp)`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.primary(), q, toDBAudit(audit)); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// Query retrieves the page of audit records that match the filter, newest
+// first.
+func (s *Store) Query(ctx context.Context, filter auditbus.QueryFilter, pg page.Page) ([]auditbus.Audit, error) {
+	data := map[string]any{}
+
+	const q = `
+	SELECT
+		audit_id, actor, action, resource_type, resource_id, message, timestamp
+	FROM
+		audits`
+
+	var buf strings.Builder
+	buf.WriteString(q)
+	applyFilter(filter, data, &buf)
+	buf.WriteString(" ORDER BY timestamp DESC, audit_id DESC")
+	sqldb.AddPageClause(&buf, data, pg)
+
+	var dbAudits []audit
+	err := s.read(ctx, func(ctx context.Context, db sqlx.ExtContext) error {
+		return sqldb.NamedQuerySlice(ctx, s.log, db, buf.String(), data, &dbAudits)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return toBusAudits(dbAudits), nil
+}
+
+// Count returns the total number of audit records that match the filter.
+func (s *Store) Count(ctx context.Context, filter auditbus.QueryFilter) (int, error) {
+	data := map[string]any{}
+
+	const q = `
+	SELECT
+		count(1)
+	FROM
+		audits`
+
+	var buf strings.Builder
+	buf.WriteString(q)
+	applyFilter(filter, data, &buf)
+
+	var count struct {
+		Count int `db:"count"`
+	}
+	err := s.read(ctx, func(ctx context.Context, db sqlx.ExtContext) error {
+		return sqldb.NamedQueryStruct(ctx, s.log, db, buf.String(), data, &count)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return count.Count, nil
+}
@@ -0,0 +1,48 @@
+package auditdb
+
+import (
+	"strings"
+
+	"lobbyte.com/alkeepy/business/domain/auditbus"
+)
+
+// applyFilter adds the WHERE clause for the filter to the query. The values
+// are always bound as named parameters, never written into the query.
+func applyFilter(filter auditbus.QueryFilter, data map[string]any, buf *strings.Builder) {
+	var wc []string
+
+	if filter.Actor != nil {
+		data["actor"] = *filter.Actor
+		wc = append(wc, "actor = :actor")
+	}
+
+	if filter.Action != nil {
+		data["action"] = *filter.Action
+		wc = append(wc, "action = :action")
+	}
+
+	if filter.ResourceType != nil {
+		data["resource_type"] = *filter.ResourceType
+		wc = append(wc, "resource_type = :resource_type")
+	}
+
+	if filter.ResourceID != nil {
+		data["resource_id"] = *filter.ResourceID
+		wc = append(wc, "resource_id = :resource_id")
+	}
+
+	if filter.StartDate != nil {
+		data["start_date"] = filter.StartDate.UTC()
+		wc = append(wc, "timestamp >= :start_date")
+	}
+
+	if filter.EndDate != nil {
+		data["end_date"] = filter.EndDate.UTC()
+		wc = append(wc, "timestamp < :end_date")
+	}
+
+	if len(wc) > 0 {
+		buf.WriteString(" WHERE ")
+		buf.WriteString(strings.Join(wc, " AND "))
+	}
+}
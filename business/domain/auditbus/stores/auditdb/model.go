@@ -0,0 +1,51 @@
+package auditdb
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"lobbyte.com/alkeepy/business/domain/auditbus"
+)
+
+type audit struct {
+	ID           uuid.UUID `db:"audit_id"`
+	Actor        string    `db:"actor"`
+	Action       string    `db:"action"`
+	ResourceType string    `db:"resource_type"`
+	ResourceID   string    `db:"resource_id"`
+	Message      string    `db:"message"`
+	Timestamp    time.Time `db:"timestamp"`
+}
+
+func toDBAudit(bus auditbus.Audit) audit {
+	return audit{
+		ID:           bus.ID,
+		Actor:        bus.Actor,
+		Action:       bus.Action,
+		ResourceType: bus.ResourceType,
+		ResourceID:   bus.ResourceID,
+		Message:      bus.Message,
+		Timestamp:    bus.Timestamp.UTC(),
+	}
+}
+
+func toBusAudit(db audit) auditbus.Audit {
+	return auditbus.Audit{
+		ID:           db.ID,
+		Actor:        db.Actor,
+		Action:       db.Action,
+		ResourceType: db.ResourceType,
+		ResourceID:   db.ResourceID,
+		Message:      db.Message,
+		Timestamp:    db.Timestamp.UTC(),
+	}
+}
+
+func toBusAudits(dbs []audit) []auditbus.Audit {
+	audits := make([]auditbus.Audit, len(dbs))
+	for i, db := range dbs {
+		audits[i] = toBusAudit(db)
+	}
+
+	return audits
+}
@@ -0,0 +1,84 @@
+// Package auditmem contains audit related CRUD functionality backed by
+// memory. The records are lost when the process exits.
+package auditmem
+
+import (
+	"context"
+	"sync"
+
+	"lobbyte.com/alkeepy/business/domain/auditbus"
+	"lobbyte.com/alkeepy/business/sdk/page"
+	"lobbyte.com/alkeepy/business/sdk/sqldb"
+)
+
+// Store manages the set of APIs for audit in memory access.
+type Store struct {
+	mu     sync.RWMutex
+	audits []auditbus.Audit
+}
+
+// NewStore constructs the api for data access.
+func NewStore() *Store {
+	return &Store{}
+}
+
+// ExecuteUnderTransaction returns the store itself. Records kept in memory
+// can't take part in a database transaction, so they're written even when
+// the transaction is rolled back.
+func (s *Store) ExecuteUnderTransaction(tx sqldb.CommitRollbacker) (auditbus.Storer, error) {
+	return s, nil
+}
+
+// Create appends the audit record.
+func (s *Store) Create(ctx context.Context, audit auditbus.Audit) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.audits = append(s.audits, audit)
+
+	return nil
+}
+
+// Query retrieves the page of audit records that match the filter, newest
+// first.
+func (s *Store) Query(ctx context.Context, filter auditbus.QueryFilter, pg page.Page) ([]auditbus.Audit, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	skip := pg.Offset()
+
+	var audits []auditbus.Audit
+	for i := len(s.audits) - 1; i >= 0; i-- {
+		if !filter.Match(s.audits[i]) {
+			continue
+		}
+
+		if skip > 0 {
+			skip--
+			continue
+		}
+
+		audits = append(audits, s.audits[i])
+
+		if len(audits) == pg.RowsPerPage() {
+			break
+		}
+	}
+
+	return audits, nil
+}
+
+// Count returns the number of audit records that match the filter.
+func (s *Store) Count(ctx context.Context, filter auditbus.QueryFilter) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var n int
+	for _, a := range s.audits {
+		if filter.Match(a) {
+			n++
+		}
+	}
+
+	return n, nil
+}
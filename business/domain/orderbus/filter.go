@@ -0,0 +1,35 @@
+package orderbus
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// QueryFilter holds the available fields a query can be filtered on.
+// A nil field doesn't restrict the query.
+type QueryFilter struct {
+	ID               *uuid.UUID
+	UserID           *uuid.UUID
+	Status           *string
+	StartCreatedDate *time.Time
+	EndCreatedDate   *time.Time
+}
+
+// Match reports whether the order satisfies the filter.
+func (qf QueryFilter) Match(o Order) bool {
+	switch {
+	case qf.ID != nil && o.ID != *qf.ID:
+		return false
+	case qf.UserID != nil && o.UserID != *qf.UserID:
+		return false
+	case qf.Status != nil && o.Status != *qf.Status:
+		return false
+	case qf.StartCreatedDate != nil && o.DateCreated.Before(*qf.StartCreatedDate):
+		return false
+	case qf.EndCreatedDate != nil && !o.DateCreated.Before(*qf.EndCreatedDate):
+		return false
+	}
+
+	return true
+}
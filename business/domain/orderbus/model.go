@@ -0,0 +1,56 @@
+package orderbus
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Set of statuses an order moves through.
+const (
+	StatusPending   = "pending"
+	StatusConfirmed = "confirmed"
+	StatusFulfilled = "fulfilled"
+	StatusCancelled = "cancelled"
+)
+
+// transitions lists the statuses an order can move to from each status.
+// Fulfilled and cancelled orders are final.
+var transitions = map[string][]string{
+	StatusPending:   {StatusConfirmed, StatusCancelled},
+	StatusConfirmed: {StatusFulfilled, StatusCancelled},
+	StatusFulfilled: nil,
+	StatusCancelled: nil,
+}
+
+// Final reports whether the order can't move to another status anymore.
+func Final(status string) bool {
+	next, exists := transitions[status]
+	return exists && len(next) == 0
+}
+
+// Order represents an individual order of pastries placed by a user.
+// TenantID is the bakery the order was placed with.
+type Order struct {
+	ID          uuid.UUID
+	TenantID    uuid.UUID
+	UserID      uuid.UUID
+	Items       []Item
+	Status      string
+	TotalCents  int
+	DateCreated time.Time
+	DateUpdated time.Time
+}
+
+// Item is a quantity of a pastry at the price it was ordered for.
+type Item struct {
+	PastryID   uuid.UUID
+	Quantity   int
+	PriceCents int
+}
+
+// NewOrder contains information needed to place a new order.
+type NewOrder struct {
+	UserID uuid.UUID
+	Items  []Item
+}
@@ -0,0 +1,14 @@
+package orderbus
+
+import "lobbyte.com/alkeepy/business/sdk/order"
+
+// DefaultOrderBy represents the default way we sort.
+var DefaultOrderBy = order.NewBy(OrderByDateCreated, order.DESC)
+
+// Set of fields that the results can be ordered by.
+const (
+	OrderByID          = "a"
+	OrderByStatus      = "b"
+	OrderByTotal       = "c"
+	OrderByDateCreated = "d"
+)
@@ -0,0 +1,166 @@
+// Package orderbus provides business access to the order domain.
+package orderbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"lobbyte.com/alkeepy/business/sdk/order"
+	"lobbyte.com/alkeepy/business/sdk/page"
+	"lobbyte.com/alkeepy/business/sdk/sqldb"
+	"lobbyte.com/alkeepy/business/sdk/tenant"
+)
+
+// Set of error variables for CRUD operations.
+var (
+	ErrNotFound      = errors.New("order not found")
+	ErrNoItems       = errors.New("order has no items")
+	ErrInvalidItem   = errors.New("item quantity must be positive and price must not be negative")
+	ErrInvalidStatus = errors.New("status transition not allowed")
+)
+
+// Storer interface declares the behavior this package needs to persist and
+// retrieve data.
+type Storer interface {
+	ExecuteUnderTransaction(tx sqldb.CommitRollbacker) (Storer, error)
+	Create(ctx context.Context, ord Order) error
+	Update(ctx context.Context, ord Order) error
+	Query(ctx context.Context, filter QueryFilter, orderBy order.By, pg page.Page) ([]Order, error)
+	Count(ctx context.Context, filter QueryFilter) (int, error)
+	QueryByID(ctx context.Context, orderID uuid.UUID) (Order, error)
+	Purge(ctx context.Context, before time.Time) (int, error)
+}
+
+// Business manages the set of APIs for order access.
+type Business struct {
+	log    *slog.Logger
+	storer Storer
+}
+
+// NewBusiness constructs an order business API for use.
+func NewBusiness(log *slog.Logger, storer Storer) *Business {
+	return &Business{
+		log:    log,
+		storer: storer,
+	}
+}
+
+// ExecuteUnderTransaction constructs a new Business value that will use the
+// specified transaction in any store related calls.
+func (b *Business) ExecuteUnderTransaction(tx sqldb.CommitRollbacker) (*Business, error) {
+	storer, err := b.storer.ExecuteUnderTransaction(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	bus := Business{
+		log:    b.log,
+		storer: storer,
+	}
+
+	return &bus, nil
+}
+
+// Create places a new pending order. The total is computed from the items.
+func (b *Business) Create(ctx context.Context, no NewOrder) (Order, error) {
+	tenantID, err := tenant.Get(ctx)
+	if err != nil {
+		return Order{}, err
+	}
+
+	if len(no.Items) == 0 {
+		return Order{}, ErrNoItems
+	}
+
+	var total int
+	for _, item := range no.Items {
+		if item.Quantity <= 0 || item.PriceCents < 0 {
+			return Order{}, fmt.Errorf("pastryID[%s]: %w", item.PastryID, ErrInvalidItem)
+		}
+		total += item.Quantity * item.PriceCents
+	}
+
+	now := time.Now().UTC()
+
+	ord := Order{
+		ID:          uuid.New(),
+		TenantID:    tenantID,
+		UserID:      no.UserID,
+		Items:       no.Items,
+		Status:      StatusPending,
+		TotalCents:  total,
+		DateCreated: now,
+		DateUpdated: now,
+	}
+
+	if err := b.storer.Create(ctx, ord); err != nil {
+		return Order{}, fmt.Errorf("create: %w", err)
+	}
+
+	return ord, nil
+}
+
+// UpdateStatus moves the order to the specified status. ErrInvalidStatus is
+// returned when the order can't move there from its current status.
+func (b *Business) UpdateStatus(ctx context.Context, ord Order, status string) (Order, error) {
+	if !slices.Contains(transitions[ord.Status], status) {
+		return Order{}, fmt.Errorf("%s to %s: %w", ord.Status, status, ErrInvalidStatus)
+	}
+
+	ord.Status = status
+	ord.DateUpdated = time.Now().UTC()
+
+	if err := b.storer.Update(ctx, ord); err != nil {
+		return Order{}, fmt.Errorf("update: %w", err)
+	}
+
+	return ord, nil
+}
+
+// Query retrieves the page of orders that match the filter in the specified
+// order.
+func (b *Business) Query(ctx context.Context, filter QueryFilter, orderBy order.By, pg page.Page) ([]Order, error) {
+	orders, err := b.storer.Query(ctx, filter, orderBy, pg)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	return orders, nil
+}
+
+// Count returns the total number of orders that match the filter.
+func (b *Business) Count(ctx context.Context, filter QueryFilter) (int, error) {
+	n, err := b.storer.Count(ctx, filter)
+	if err != nil {
+		return 0, fmt.Errorf("count: %w", err)
+	}
+
+	return n, nil
+}
+
+// QueryByID finds the order by the specified ID.
+func (b *Business) QueryByID(ctx context.Context, orderID uuid.UUID) (Order, error) {
+	ord, err := b.storer.QueryByID(ctx, orderID)
+	if err != nil {
+		return Order{}, fmt.Errorf("query: orderID[%s]: %w", orderID, err)
+	}
+
+	return ord, nil
+}
+
+// Purge permanently removes the fulfilled and cancelled orders last updated
+// before the specified time and returns how many were removed. Orders still
+// in progress are kept however old they are.
+func (b *Business) Purge(ctx context.Context, before time.Time) (int, error) {
+	n, err := b.storer.Purge(ctx, before)
+	if err != nil {
+		return 0, fmt.Errorf("purge: %w", err)
+	}
+
+	return n, nil
+}
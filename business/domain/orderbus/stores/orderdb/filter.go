@@ -0,0 +1,43 @@
+package orderdb
+
+import (
+	"strings"
+
+	"lobbyte.com/alkeepy/business/domain/orderbus"
+	"lobbyte.com/alkeepy/business/sdk/sqldb"
+)
+
+// applyFilter adds the WHERE clause for the filter to the query. The values
+// are always bound as named parameters, never written into the query.
+// The query is always scoped to the tenant in the context.
+func applyFilter(filter orderbus.QueryFilter, data map[string]any, buf *strings.Builder) {
+	wc := []string{sqldb.TenantScope}
+
+	if filter.ID != nil {
+		data["order_id"] = *filter.ID
+		wc = append(wc, "order_id = :order_id")
+	}
+
+	if filter.UserID != nil {
+		data["user_id"] = *filter.UserID
+		wc = append(wc, "user_id = :user_id")
+	}
+
+	if filter.Status != nil {
+		data["status"] = *filter.Status
+		wc = append(wc, "status = :status")
+	}
+
+	if filter.StartCreatedDate != nil {
+		data["start_date_created"] = filter.StartCreatedDate.UTC()
+		wc = append(wc, "date_created >= :start_date_created")
+	}
+
+	if filter.EndCreatedDate != nil {
+		data["end_date_created"] = filter.EndCreatedDate.UTC()
+		wc = append(wc, "date_created < :end_date_created")
+	}
+
+	buf.WriteString(" WHERE ")
+	buf.WriteString(strings.Join(wc, " AND "))
+}
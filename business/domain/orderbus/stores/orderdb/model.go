@@ -0,0 +1,84 @@
+package orderdb
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"lobbyte.com/alkeepy/business/domain/orderbus"
+	"lobbyte.com/alkeepy/business/sdk/sqldb"
+)
+
+type dbOrder struct {
+	ID          uuid.UUID           `db:"order_id"`
+	TenantID    uuid.UUID           `db:"tenant_id"`
+	UserID      uuid.UUID           `db:"user_id"`
+	Items       sqldb.JSONB[[]item] `db:"items"`
+	Status      string              `db:"status"`
+	TotalCents  int                 `db:"total_cents"`
+	DateCreated time.Time           `db:"date_created"`
+	DateUpdated time.Time           `db:"date_updated"`
+}
+
+// item is how an item of the order is kept in the items column.
+type item struct {
+	PastryID   uuid.UUID `json:"pastry_id"`
+	Quantity   int       `json:"quantity"`
+	PriceCents int       `json:"price_cents"`
+}
+
+func toDBOrder(bus orderbus.Order) dbOrder {
+	items := make([]item, len(bus.Items))
+	for i, it := range bus.Items {
+		items[i] = item{
+			PastryID:   it.PastryID,
+			Quantity:   it.Quantity,
+			PriceCents: it.PriceCents,
+		}
+	}
+
+	db := dbOrder{
+		ID:          bus.ID,
+		TenantID:    bus.TenantID,
+		UserID:      bus.UserID,
+		Items:       sqldb.JSONB[[]item]{V: items},
+		Status:      bus.Status,
+		TotalCents:  bus.TotalCents,
+		DateCreated: bus.DateCreated.UTC(),
+		DateUpdated: bus.DateUpdated.UTC(),
+	}
+
+	return db
+}
+
+func toBusOrder(db dbOrder) orderbus.Order {
+	items := make([]orderbus.Item, len(db.Items.V))
+	for i, it := range db.Items.V {
+		items[i] = orderbus.Item{
+			PastryID:   it.PastryID,
+			Quantity:   it.Quantity,
+			PriceCents: it.PriceCents,
+		}
+	}
+
+	bus := orderbus.Order{
+		ID:          db.ID,
+		TenantID:    db.TenantID,
+		UserID:      db.UserID,
+		Items:       items,
+		Status:      db.Status,
+		TotalCents:  db.TotalCents,
+		DateCreated: db.DateCreated.In(time.Local),
+		DateUpdated: db.DateUpdated.In(time.Local),
+	}
+
+	return bus
+}
+
+func toBusOrders(dbs []dbOrder) []orderbus.Order {
+	bus := make([]orderbus.Order, len(dbs))
+	for i, db := range dbs {
+		bus[i] = toBusOrder(db)
+	}
+
+	return bus
+}
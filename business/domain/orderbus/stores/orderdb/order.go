@@ -0,0 +1,38 @@
+package orderdb
+
+import (
+	"fmt"
+
+	"lobbyte.com/alkeepy/business/domain/orderbus"
+	"lobbyte.com/alkeepy/business/sdk/order"
+)
+
+// orderByFields is the whitelist of columns a query can be ordered by. Only
+// these values are ever written into the query.
+var orderByFields = map[string]string{
+	orderbus.OrderByID:          "order_id",
+	orderbus.OrderByStatus:      "status",
+	orderbus.OrderByTotal:       "total_cents",
+	orderbus.OrderByDateCreated: "date_created",
+}
+
+// orderByClause returns the columns of the ORDER BY clause. The id breaks
+// ties in the same direction so the rows keep the same order from one page
+// to the next.
+func orderByClause(orderBy order.By) (string, error) {
+	by, exists := orderByFields[orderBy.Field]
+	if !exists {
+		return "", fmt.Errorf("field %q does not exist", orderBy.Field)
+	}
+
+	direction := order.ASC
+	if orderBy.Direction == order.DESC {
+		direction = order.DESC
+	}
+
+	if by == "order_id" {
+		return by + " " + direction, nil
+	}
+
+	return by + " " + direction + ", order_id " + direction, nil
+}
@@ -0,0 +1,256 @@
+// Package orderdb contains order related CRUD functionality.
+package orderdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"lobbyte.com/alkeepy/business/domain/orderbus"
+	"lobbyte.com/alkeepy/business/sdk/order"
+	"lobbyte.com/alkeepy/business/sdk/page"
+	"lobbyte.com/alkeepy/business/sdk/sqldb"
+	"lobbyte.com/alkeepy/business/sdk/tenant"
+)
+
+// Store manages the set of APIs for order database access.
+type Store struct {
+	log     *slog.Logger
+	cluster *sqldb.Cluster
+	tx      sqlx.ExtContext
+}
+
+// NewStore constructs the api for data access.
+func NewStore(log *slog.Logger, cluster *sqldb.Cluster) *Store {
+	return &Store{
+		log:     log,
+		cluster: cluster,
+	}
+}
+
+// ExecuteUnderTransaction constructs a new Store value that runs every query
+// inside the transaction, reads included.
+func (s *Store) ExecuteUnderTransaction(tx sqldb.CommitRollbacker) (orderbus.Storer, error) {
+	ec, err := sqldb.GetExtContext(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	store := Store{
+		log:     s.log,
+		cluster: s.cluster,
+		tx:      ec,
+	}
+
+	return &store, nil
+}
+
+// read runs a query that only reads data. Outside of a transaction it goes
+// to a replica when one is healthy and is retried after a transient failure,
+// picking the database again on each attempt. It's refused when the context
+// isn't scoped to a tenant.
+func (s *Store) read(ctx context.Context, fn func(ctx context.Context, db sqlx.ExtContext) error) error {
+	if _, err := tenant.Get(ctx); err != nil {
+		return err
+	}
+
+	if s.tx != nil {
+		return fn(ctx, s.tx)
+	}
+
+	return sqldb.Retry(ctx, s.log, sqldb.DefaultBackoff, func(ctx context.Context) error {
+		return fn(ctx, s.cluster.Reader())
+	})
+}
+
+// primary returns the transaction when there is one, otherwise the primary.
+func (s *Store) primary() sqlx.ExtContext {
+	if s.tx != nil {
+		return s.tx
+	}
+
+	return s.cluster.Primary()
+}
+
+// Create inserts a new order into the tenant in the context.
+func (s *Store) Create(ctx context.Context, ord orderbus.Order) error {
+	if _, err := tenant.Get(ctx); err != nil {
+		return err
+	}
+
+	const q = `
+	INSERT INTO orders
+		(order_id, tenant_id, user_id, items, status, total_cents, date_created, date_updated)
+	VALUES
+		(:order_id, :tenant_id, :user_id, :items, :status, :total_cents, :date_created, :date_updated)`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.primary(), q, toDBOrder(ord)); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// Update replaces an order of the tenant in the context.
+func (s *Store) Update(ctx context.Context, ord orderbus.Order) error {
+	if _, err := tenant.Get(ctx); err != nil {
+		return err
+	}
+
+	const q = `
+	UPDATE
+		orders
+	SET
+		items = :items,
+		status = :status,
+		total_cents = :total_cents,
+		date_updated = :date_updated
+	WHERE
+		order_id = :order_id AND tenant_id = :tenant_id
+	RETURNING
+		order_id`
+
+	var updated struct {
+		ID uuid.UUID `db:"order_id"`
+	}
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.primary(), q, toDBOrder(ord), &updated); err != nil {
+		if errors.Is(err, sqldb.ErrDBNotFound) {
+			return fmt.Errorf("namedquerystruct: %w", orderbus.ErrNotFound)
+		}
+		return fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return nil
+}
+
+// Query retrieves the page of orders that match the filter in the specified
+// order.
+func (s *Store) Query(ctx context.Context, filter orderbus.QueryFilter, orderBy order.By, pg page.Page) ([]orderbus.Order, error) {
+	data := map[string]any{}
+
+	const q = `
+	SELECT
+		order_id, tenant_id, user_id, items, status, total_cents, date_created, date_updated
+	FROM
+		orders`
+
+	orderClause, err := orderByClause(orderBy)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf strings.Builder
+	buf.WriteString(q)
+	applyFilter(filter, data, &buf)
+	buf.WriteString(" ORDER BY " + orderClause)
+	sqldb.AddPageClause(&buf, data, pg)
+
+	var dbOrders []dbOrder
+	err = s.read(ctx, func(ctx context.Context, db sqlx.ExtContext) error {
+		return sqldb.NamedQuerySlice(ctx, s.log, db, buf.String(), data, &dbOrders)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return toBusOrders(dbOrders), nil
+}
+
+// Count returns the total number of orders that match the filter.
+func (s *Store) Count(ctx context.Context, filter orderbus.QueryFilter) (int, error) {
+	data := map[string]any{}
+
+	const q = `
+	SELECT
+		count(1)
+	FROM
+		orders`
+
+	var buf strings.Builder
+	buf.WriteString(q)
+	applyFilter(filter, data, &buf)
+
+	var count struct {
+		Count int `db:"count"`
+	}
+	err := s.read(ctx, func(ctx context.Context, db sqlx.ExtContext) error {
+		return sqldb.NamedQueryStruct(ctx, s.log, db, buf.String(), data, &count)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return count.Count, nil
+}
+
+// QueryByID gets the specified order from the database.
+func (s *Store) QueryByID(ctx context.Context, orderID uuid.UUID) (orderbus.Order, error) {
+	data := struct {
+		ID string `db:"order_id"`
+	}{
+		ID: orderID.String(),
+	}
+
+	const q = `
+	SELECT
+		order_id, tenant_id, user_id, items, status, total_cents, date_created, date_updated
+	FROM
+		orders
+	WHERE
+		order_id = :order_id AND tenant_id = :tenant_id`
+
+	var dbOrd dbOrder
+	err := s.read(ctx, func(ctx context.Context, db sqlx.ExtContext) error {
+		return sqldb.NamedQueryStruct(ctx, s.log, db, q, data, &dbOrd)
+	})
+	if err != nil {
+		if errors.Is(err, sqldb.ErrDBNotFound) {
+			return orderbus.Order{}, fmt.Errorf("namedquerystruct: %w", orderbus.ErrNotFound)
+		}
+		return orderbus.Order{}, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return toBusOrder(dbOrd), nil
+}
+
+// Purge removes the final orders last updated before the specified time,
+// whatever tenant they belong to.
+func (s *Store) Purge(ctx context.Context, before time.Time) (int, error) {
+	data := struct {
+		Before    time.Time `db:"before"`
+		Fulfilled string    `db:"fulfilled"`
+		Cancelled string    `db:"cancelled"`
+	}{
+		Before:    before.UTC(),
+		Fulfilled: orderbus.StatusFulfilled,
+		Cancelled: orderbus.StatusCancelled,
+	}
+
+	const q = `
+	WITH purged AS (
+		DELETE FROM
+			orders
+		WHERE
+			status IN (:fulfilled, :cancelled) AND date_updated < :before
+		RETURNING
+			order_id
+	)
+	SELECT
+		count(1)
+	FROM
+		purged`
+
+	var count struct {
+		Count int `db:"count"`
+	}
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.primary(), q, data, &count); err != nil {
+		return 0, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return count.Count, nil
+}
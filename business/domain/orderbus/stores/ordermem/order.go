@@ -0,0 +1,48 @@
+package ordermem
+
+import (
+	"cmp"
+	"fmt"
+	"strings"
+
+	"lobbyte.com/alkeepy/business/domain/orderbus"
+	"lobbyte.com/alkeepy/business/sdk/order"
+)
+
+// compareFunc returns the function that sorts the orders in the specified
+// order, using the id to break ties.
+func compareFunc(orderBy order.By) (func(a, b orderbus.Order) int, error) {
+	var byField func(a, b orderbus.Order) int
+
+	switch orderBy.Field {
+	case orderbus.OrderByID:
+		byField = func(a, b orderbus.Order) int { return 0 }
+	case orderbus.OrderByStatus:
+		byField = func(a, b orderbus.Order) int { return strings.Compare(a.Status, b.Status) }
+	case orderbus.OrderByTotal:
+		byField = func(a, b orderbus.Order) int { return cmp.Compare(a.TotalCents, b.TotalCents) }
+	case orderbus.OrderByDateCreated:
+		byField = func(a, b orderbus.Order) int { return a.DateCreated.Compare(b.DateCreated) }
+	default:
+		return nil, fmt.Errorf("field %q does not exist", orderBy.Field)
+	}
+
+	desc := orderBy.Direction == order.DESC
+
+	f := func(a, b orderbus.Order) int {
+		if c := byField(a, b); c != 0 {
+			if desc {
+				return -c
+			}
+			return c
+		}
+
+		c := strings.Compare(a.ID.String(), b.ID.String())
+		if desc && orderBy.Field == orderbus.OrderByID {
+			return -c
+		}
+		return c
+	}
+
+	return f, nil
+}
@@ -0,0 +1,170 @@
+// Package ordermem contains order related CRUD functionality backed by
+// memory so the business logic can be exercised without a database. The
+// orders are lost when the process exits.
+package ordermem
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"lobbyte.com/alkeepy/business/domain/orderbus"
+	"lobbyte.com/alkeepy/business/sdk/order"
+	"lobbyte.com/alkeepy/business/sdk/page"
+	"lobbyte.com/alkeepy/business/sdk/sqldb"
+	"lobbyte.com/alkeepy/business/sdk/tenant"
+)
+
+// Store manages the set of APIs for order in memory access.
+type Store struct {
+	mu     sync.RWMutex
+	orders map[uuid.UUID]orderbus.Order
+}
+
+// NewStore constructs the api for data access, holding the specified orders.
+func NewStore(orders ...orderbus.Order) *Store {
+	s := Store{
+		orders: make(map[uuid.UUID]orderbus.Order, len(orders)),
+	}
+
+	for _, ord := range orders {
+		s.orders[ord.ID] = clone(ord)
+	}
+
+	return &s
+}
+
+// ExecuteUnderTransaction returns the store itself. Orders kept in memory
+// can't take part in a database transaction, so changes are kept even when
+// the transaction is rolled back.
+func (s *Store) ExecuteUnderTransaction(tx sqldb.CommitRollbacker) (orderbus.Storer, error) {
+	return s, nil
+}
+
+// Create inserts a new order into the tenant in the context.
+func (s *Store) Create(ctx context.Context, ord orderbus.Order) error {
+	tenantID, err := tenant.Get(ctx)
+	if err != nil {
+		return err
+	}
+	ord.TenantID = tenantID
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.orders[ord.ID] = clone(ord)
+
+	return nil
+}
+
+// Update replaces an order.
+func (s *Store) Update(ctx context.Context, ord orderbus.Order) error {
+	tenantID, err := tenant.Get(ctx)
+	if err != nil {
+		return err
+	}
+	ord.TenantID = tenantID
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if o, exists := s.orders[ord.ID]; !exists || o.TenantID != tenantID {
+		return fmt.Errorf("update: %w", orderbus.ErrNotFound)
+	}
+
+	s.orders[ord.ID] = clone(ord)
+
+	return nil
+}
+
+// Query retrieves the page of orders that match the filter in the specified
+// order.
+func (s *Store) Query(ctx context.Context, filter orderbus.QueryFilter, orderBy order.By, pg page.Page) ([]orderbus.Order, error) {
+	tenantID, err := tenant.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cmp, err := compareFunc(orderBy)
+	if err != nil {
+		return nil, err
+	}
+
+	orders := s.match(tenantID, filter)
+	slices.SortFunc(orders, cmp)
+
+	start := min(pg.Offset(), len(orders))
+	end := min(start+pg.RowsPerPage(), len(orders))
+
+	return orders[start:end], nil
+}
+
+// Count returns the total number of orders that match the filter.
+func (s *Store) Count(ctx context.Context, filter orderbus.QueryFilter) (int, error) {
+	tenantID, err := tenant.Get(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(s.match(tenantID, filter)), nil
+}
+
+// QueryByID gets the specified order.
+func (s *Store) QueryByID(ctx context.Context, orderID uuid.UUID) (orderbus.Order, error) {
+	tenantID, err := tenant.Get(ctx)
+	if err != nil {
+		return orderbus.Order{}, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ord, exists := s.orders[orderID]
+	if !exists || ord.TenantID != tenantID {
+		return orderbus.Order{}, fmt.Errorf("query: %w", orderbus.ErrNotFound)
+	}
+
+	return clone(ord), nil
+}
+
+// Purge removes the final orders last updated before the specified time,
+// whatever tenant they belong to.
+func (s *Store) Purge(ctx context.Context, before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var n int
+	for id, ord := range s.orders {
+		if orderbus.Final(ord.Status) && ord.DateUpdated.Before(before) {
+			delete(s.orders, id)
+			n++
+		}
+	}
+
+	return n, nil
+}
+
+// match returns a copy of the orders of the tenant that satisfy the filter.
+func (s *Store) match(tenantID uuid.UUID, filter orderbus.QueryFilter) []orderbus.Order {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var orders []orderbus.Order
+	for _, ord := range s.orders {
+		if ord.TenantID == tenantID && filter.Match(ord) {
+			orders = append(orders, clone(ord))
+		}
+	}
+
+	return orders
+}
+
+// clone copies the items of the order so callers can't change a stored
+// order through them.
+func clone(ord orderbus.Order) orderbus.Order {
+	ord.Items = slices.Clone(ord.Items)
+	return ord
+}
@@ -0,0 +1,58 @@
+package recipebus
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// EventChannel is the channel the database notifies the changes made to
+// the recipes on, whichever instance made them, and the topic they're
+// published on through the outbox.
+const EventChannel = "recipe_changes"
+
+// Set of changes an event reports. EventResync reports that changes may
+// have been missed, so anything derived from the recipes should be
+// refreshed.
+const (
+	EventCreated  = "created"
+	EventUpdated  = "updated"
+	EventDeleted  = "deleted"
+	EventRestored = "restored"
+	EventPurged   = "purged"
+	EventResync   = "resync"
+)
+
+// Event reports a change made to a recipe. A resync event doesn't name a
+// recipe or a tenant.
+type Event struct {
+	Op       string
+	RecipeID uuid.UUID
+	TenantID uuid.UUID
+	Version  int
+}
+
+// ParseEvent decodes the payload the database notifies a change with, which
+// is also the payload of the messages published through the outbox.
+func ParseEvent(payload string) (Event, error) {
+	var p struct {
+		Op       string    `json:"op"`
+		RecipeID uuid.UUID `json:"recipe_id"`
+		TenantID uuid.UUID `json:"tenant_id"`
+		Version  int       `json:"version"`
+	}
+
+	if err := json.Unmarshal([]byte(payload), &p); err != nil {
+		return Event{}, fmt.Errorf("parsing event: %w", err)
+	}
+
+	ev := Event{
+		Op:       p.Op,
+		RecipeID: p.RecipeID,
+		TenantID: p.TenantID,
+		Version:  p.Version,
+	}
+
+	return ev, nil
+}
@@ -0,0 +1,92 @@
+package recipebus
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Set of text search configurations a search can use. The configuration
+// decides how the words are stemmed and which ones are ignored.
+const (
+	SearchSimple  = "simple"
+	SearchEnglish = "english"
+	SearchFrench  = "french"
+	SearchGerman  = "german"
+	SearchItalian = "italian"
+	SearchSpanish = "spanish"
+)
+
+// SearchLanguages lists the text search configurations a search can use.
+var SearchLanguages = []string{SearchSimple, SearchEnglish, SearchFrench, SearchGerman, SearchItalian, SearchSpanish}
+
+// DefaultSearchLanguage is the text search configuration used when the
+// filter doesn't set one.
+const DefaultSearchLanguage = SearchEnglish
+
+// QueryFilter holds the available fields a query can be filtered on.
+// A nil field doesn't restrict the query. Deleted recipes are left out
+// unless IncludeDeleted is set. Search matches the words of the name, the
+// description and the ingredients of the recipe, stemmed the way the
+// SearchLanguage configuration says. Metadata matches the recipes whose
+// metadata contains it.
+type QueryFilter struct {
+	ID               *uuid.UUID
+	UserID           *uuid.UUID
+	Name             *string
+	Tag              *string
+	MaxPrepMinutes   *int
+	StartCreatedDate *time.Time
+	EndCreatedDate   *time.Time
+	Search           *string
+	SearchLanguage   *string
+	Metadata         Metadata
+	IncludeDeleted   bool
+}
+
+// Match reports whether the recipe satisfies the filter. The name matches
+// when it contains the filter value, ignoring case. The search matches when
+// every word of it is found in the name or the description, without the
+// stemming done by the database.
+func (qf QueryFilter) Match(r Recipe) bool {
+	switch {
+	case !qf.IncludeDeleted && r.Deleted():
+		return false
+	case qf.ID != nil && r.ID != *qf.ID:
+		return false
+	case qf.UserID != nil && r.UserID != *qf.UserID:
+		return false
+	case qf.Name != nil && !strings.Contains(strings.ToLower(r.Name), strings.ToLower(*qf.Name)):
+		return false
+	case qf.Tag != nil && !slices.Contains(r.Tags, *qf.Tag):
+		return false
+	case qf.MaxPrepMinutes != nil && r.PrepMinutes > *qf.MaxPrepMinutes:
+		return false
+	case qf.StartCreatedDate != nil && r.DateCreated.Before(*qf.StartCreatedDate):
+		return false
+	case qf.EndCreatedDate != nil && !r.DateCreated.Before(*qf.EndCreatedDate):
+		return false
+	case qf.Search != nil && !matchesWords(r, *qf.Search):
+		return false
+	case qf.Metadata != nil && !r.Metadata.Contains(qf.Metadata):
+		return false
+	}
+
+	return true
+}
+
+// matchesWords reports whether every word of the search is found in the
+// name or the description of the recipe, ignoring case.
+func matchesWords(r Recipe, search string) bool {
+	text := strings.ToLower(r.Name + " " + r.Description)
+
+	for _, word := range strings.Fields(strings.ToLower(search)) {
+		if !strings.Contains(text, word) {
+			return false
+		}
+	}
+
+	return true
+}
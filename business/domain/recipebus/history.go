@@ -0,0 +1,96 @@
+package recipebus
+
+import (
+	"reflect"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Revision is a change made to a recipe, kept in the history of the recipe.
+// Op is the event the change is reported with, Actor who made it, and Old
+// and New the recipe before and after it. Version is the version the change
+// left the recipe at, the same as the version of New.
+type Revision struct {
+	ID          uuid.UUID
+	RecipeID    uuid.UUID
+	TenantID    uuid.UUID
+	Version     int
+	Op          string
+	Actor       string
+	Old         Recipe
+	New         Recipe
+	DateCreated time.Time
+}
+
+// Change is a field of a recipe that a revision changed, with its value
+// before and after.
+type Change struct {
+	Field string
+	Old   any
+	New   any
+}
+
+// Diff returns the fields of the recipe the revision changed, in a fixed
+// order. The deletion of a recipe is a change of its date_deleted field.
+func (r Revision) Diff() []Change {
+	var changes []Change
+	add := func(field string, changed bool, old any, new any) {
+		if changed {
+			changes = append(changes, Change{Field: field, Old: old, New: new})
+		}
+	}
+
+	o, n := r.Old, r.New
+	add("name", o.Name != n.Name, o.Name, n.Name)
+	add("description", o.Description != n.Description, o.Description, n.Description)
+	add("prep_minutes", o.PrepMinutes != n.PrepMinutes, o.PrepMinutes, n.PrepMinutes)
+	add("tags", !slices.Equal(o.Tags, n.Tags), o.Tags, n.Tags)
+	add("metadata", !equalMetadata(o.Metadata, n.Metadata), o.Metadata, n.Metadata)
+	add("date_deleted", !o.DateDeleted.Equal(n.DateDeleted), deletedAt(o), deletedAt(n))
+
+	return changes
+}
+
+// revert returns the changes that bring the fields of a recipe back to how
+// they were in the snapshot.
+func revert(snapshot Recipe, version int) UpdateRecipe {
+	tags := snapshot.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+
+	metadata := snapshot.Metadata
+	if metadata == nil {
+		metadata = Metadata{}
+	}
+
+	return UpdateRecipe{
+		Name:        &snapshot.Name,
+		Description: &snapshot.Description,
+		PrepMinutes: &snapshot.PrepMinutes,
+		Tags:        tags,
+		Metadata:    metadata,
+		Version:     version,
+	}
+}
+
+// equalMetadata reports whether the metadata hold the same values, an empty
+// metadata being the same as none.
+func equalMetadata(m Metadata, other Metadata) bool {
+	if len(m) == 0 && len(other) == 0 {
+		return true
+	}
+
+	return reflect.DeepEqual(m, other)
+}
+
+// deletedAt returns when the recipe was deleted, nil when it isn't.
+func deletedAt(r Recipe) *time.Time {
+	if !r.Deleted() {
+		return nil
+	}
+
+	return &r.DateDeleted
+}
@@ -0,0 +1,165 @@
+package recipebus
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+)
+
+// ErrInvalidMetadata is returned when the metadata of a recipe holds an
+// unknown key or a value of the wrong kind.
+var ErrInvalidMetadata = errors.New("invalid metadata")
+
+// Set of kinds of value a metadata key can hold.
+const (
+	KindString  = "string"
+	KindNumber  = "number"
+	KindBool    = "bool"
+	KindStrings = "strings"
+)
+
+// MetadataKeys lists the keys the metadata of a recipe can hold and the
+// kind of value each one holds. A key is added here instead of a column
+// when it doesn't need one of its own.
+var MetadataKeys = map[string]string{
+	"cuisine":    KindString,
+	"difficulty": KindString,
+	"servings":   KindNumber,
+	"vegan":      KindBool,
+	"vegetarian": KindBool,
+	"allergens":  KindStrings,
+}
+
+// Metadata holds the attributes of a recipe that don't have a column of
+// their own. The values are the ones JSON decodes to, so the accessors
+// should be used to read them.
+type Metadata map[string]any
+
+// Validate checks every key is known and holds a value of its kind.
+func (m Metadata) Validate() error {
+	for _, key := range slices.Sorted(maps.Keys(m)) {
+		kind, exists := MetadataKeys[key]
+		if !exists {
+			return fmt.Errorf("%w: unknown key %q", ErrInvalidMetadata, key)
+		}
+
+		if !m.holds(key, kind) {
+			return fmt.Errorf("%w: key %q must hold a %s value", ErrInvalidMetadata, key, kind)
+		}
+	}
+
+	return nil
+}
+
+// String returns the string value of the key.
+func (m Metadata) String(key string) (string, bool) {
+	v, ok := m[key].(string)
+	return v, ok
+}
+
+// Number returns the numeric value of the key.
+func (m Metadata) Number(key string) (float64, bool) {
+	switch v := m[key].(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	}
+
+	return 0, false
+}
+
+// Bool returns the boolean value of the key.
+func (m Metadata) Bool(key string) (bool, bool) {
+	v, ok := m[key].(bool)
+	return v, ok
+}
+
+// Strings returns the list of strings the key holds.
+func (m Metadata) Strings(key string) ([]string, bool) {
+	switch v := m[key].(type) {
+	case []string:
+		return v, true
+	case []any:
+		s := make([]string, len(v))
+		for i, e := range v {
+			str, ok := e.(string)
+			if !ok {
+				return nil, false
+			}
+			s[i] = str
+		}
+		return s, true
+	}
+
+	return nil, false
+}
+
+// Contains reports whether the metadata holds every key of the other one
+// with the same value, the way the jsonb containment operator does. A list
+// contains another when it holds every string of it.
+func (m Metadata) Contains(other Metadata) bool {
+	for key := range other {
+		switch MetadataKeys[key] {
+		case KindString:
+			v, ok := m.String(key)
+			w, _ := other.String(key)
+			if !ok || v != w {
+				return false
+			}
+
+		case KindNumber:
+			v, ok := m.Number(key)
+			w, _ := other.Number(key)
+			if !ok || v != w {
+				return false
+			}
+
+		case KindBool:
+			v, ok := m.Bool(key)
+			w, _ := other.Bool(key)
+			if !ok || v != w {
+				return false
+			}
+
+		case KindStrings:
+			v, ok := m.Strings(key)
+			w, _ := other.Strings(key)
+			if !ok {
+				return false
+			}
+			for _, s := range w {
+				if !slices.Contains(v, s) {
+					return false
+				}
+			}
+
+		default:
+			if !reflect.DeepEqual(m[key], other[key]) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// holds reports whether the key holds a value of the kind.
+func (m Metadata) holds(key string, kind string) bool {
+	var ok bool
+
+	switch kind {
+	case KindString:
+		_, ok = m.String(key)
+	case KindNumber:
+		_, ok = m.Number(key)
+	case KindBool:
+		_, ok = m.Bool(key)
+	case KindStrings:
+		_, ok = m.Strings(key)
+	}
+
+	return ok
+}
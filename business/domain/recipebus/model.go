@@ -0,0 +1,49 @@
+package recipebus
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Recipe represents information about an individual recipe. TenantID is the
+// bakery the recipe belongs to. Metadata holds the attributes without a
+// column of their own. Version counts
+// the changes made to the recipe so concurrent updates can be detected.
+// DateDeleted is zero unless the recipe has been deleted. Rank and Headline
+// are only set by a query that searches: how well the recipe matches and
+// the part of the description that does, with the matching words marked.
+type Recipe struct {
+	ID          uuid.UUID
+	TenantID    uuid.UUID
+	UserID      uuid.UUID
+	Name        string
+	Description string
+	PrepMinutes int
+	Tags        []string
+	Metadata    Metadata
+	Version     int
+	DateCreated time.Time
+	DateUpdated time.Time
+	DateDeleted time.Time
+	Rank        float64
+	Headline    string
+}
+
+// Deleted reports whether the recipe has been deleted and can still be
+// restored.
+func (r Recipe) Deleted() bool {
+	return !r.DateDeleted.IsZero()
+}
+
+// UpdateRecipe contains information needed to update a recipe. A nil field
+// is left unchanged and the metadata is replaced as a whole. Version is the version of the recipe the changes were
+// made to.
+type UpdateRecipe struct {
+	Name        *string
+	Description *string
+	PrepMinutes *int
+	Tags        []string
+	Metadata    Metadata
+	Version     int
+}
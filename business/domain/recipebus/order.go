@@ -0,0 +1,19 @@
+package recipebus
+
+import "lobbyte.com/alkeepy/business/sdk/order"
+
+// DefaultOrderBy represents the default way we sort.
+var DefaultOrderBy = order.NewBy(OrderByDateCreated, order.DESC)
+
+// SearchOrderBy is the default way we sort the results of a search, the
+// best matches first.
+var SearchOrderBy = order.NewBy(OrderByRank, order.DESC)
+
+// Set of fields that the results can be ordered by.
+const (
+	OrderByID          = "a"
+	OrderByName        = "b"
+	OrderByPrepMinutes = "c"
+	OrderByDateCreated = "d"
+	OrderByRank        = "e"
+)
@@ -0,0 +1,248 @@
+// Package recipebus provides business access to the recipe domain.
+package recipebus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"lobbyte.com/alkeepy/business/sdk/order"
+	"lobbyte.com/alkeepy/business/sdk/page"
+	"lobbyte.com/alkeepy/business/sdk/sqldb"
+)
+
+// Set of error variables for CRUD operations.
+var (
+	ErrNotFound        = errors.New("recipe not found")
+	ErrVersionConflict = errors.New("recipe has been changed since the expected version")
+	ErrCursorOrder     = errors.New("paging by cursor requires ordering by date created")
+	ErrVersionNotFound = errors.New("recipe version not found in the history")
+)
+
+// Storer interface declares the behavior this package needs to persist and
+// retrieve data.
+type Storer interface {
+	ExecuteUnderTransaction(tx sqldb.CommitRollbacker) (Storer, error)
+	Query(ctx context.Context, filter QueryFilter, orderBy order.By, pg page.Page) ([]Recipe, error)
+	Count(ctx context.Context, filter QueryFilter) (int, error)
+	QueryByID(ctx context.Context, recipeID uuid.UUID) (Recipe, error)
+	Update(ctx context.Context, recipe Recipe, version int) error
+	Delete(ctx context.Context, recipe Recipe) error
+	Restore(ctx context.Context, recipeID uuid.UUID) (Recipe, error)
+	Purge(ctx context.Context, before time.Time) (int, error)
+	QueryHistory(ctx context.Context, recipeID uuid.UUID, pg page.Page) ([]Revision, error)
+	CountHistory(ctx context.Context, recipeID uuid.UUID) (int, error)
+	QueryVersion(ctx context.Context, recipeID uuid.UUID, version int) (Recipe, error)
+}
+
+// Business manages the set of APIs for recipe access.
+type Business struct {
+	log    *slog.Logger
+	storer Storer
+}
+
+// NewBusiness constructs a recipe business API for use.
+func NewBusiness(log *slog.Logger, storer Storer) *Business {
+	return &Business{
+		log:    log,
+		storer: storer,
+	}
+}
+
+// ExecuteUnderTransaction constructs a new Business value that will use the
+// specified transaction in any store related calls.
+func (b *Business) ExecuteUnderTransaction(tx sqldb.CommitRollbacker) (*Business, error) {
+	storer, err := b.storer.ExecuteUnderTransaction(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	bus := Business{
+		log:    b.log,
+		storer: storer,
+	}
+
+	return &bus, nil
+}
+
+// Query retrieves the page of recipes that match the filter in the specified
+// order. A page that starts after a cursor requires ordering by the date
+// the recipes were created.
+func (b *Business) Query(ctx context.Context, filter QueryFilter, orderBy order.By, pg page.Page) ([]Recipe, error) {
+	if _, ok := pg.Cursor(); ok && orderBy.Field != OrderByDateCreated {
+		return nil, fmt.Errorf("query: %w", ErrCursorOrder)
+	}
+
+	recipes, err := b.storer.Query(ctx, filter, orderBy, pg)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	return recipes, nil
+}
+
+// Count returns the total number of recipes that match the filter.
+func (b *Business) Count(ctx context.Context, filter QueryFilter) (int, error) {
+	n, err := b.storer.Count(ctx, filter)
+	if err != nil {
+		return 0, fmt.Errorf("count: %w", err)
+	}
+
+	return n, nil
+}
+
+// QueryByID finds the recipe by the specified ID. A deleted recipe isn't
+// found.
+func (b *Business) QueryByID(ctx context.Context, recipeID uuid.UUID) (Recipe, error) {
+	recipe, err := b.storer.QueryByID(ctx, recipeID)
+	if err != nil {
+		return Recipe{}, fmt.Errorf("query: recipeID[%s]: %w", recipeID, err)
+	}
+
+	return recipe, nil
+}
+
+// Update modifies the recipe. The update must be based on the current
+// version of the recipe, otherwise ErrVersionConflict is returned so the
+// changes of someone else aren't overwritten. The version is checked again
+// when the recipe is stored in case it changes in the meantime.
+func (b *Business) Update(ctx context.Context, recipe Recipe, ur UpdateRecipe) (Recipe, error) {
+	if ur.Version != recipe.Version {
+		return Recipe{}, fmt.Errorf("update: recipeID[%s] version[%d]: %w", recipe.ID, ur.Version, ErrVersionConflict)
+	}
+
+	if ur.Name != nil {
+		recipe.Name = *ur.Name
+	}
+
+	if ur.Description != nil {
+		recipe.Description = *ur.Description
+	}
+
+	if ur.PrepMinutes != nil {
+		recipe.PrepMinutes = *ur.PrepMinutes
+	}
+
+	if ur.Tags != nil {
+		recipe.Tags = ur.Tags
+	}
+
+	if ur.Metadata != nil {
+		if err := ur.Metadata.Validate(); err != nil {
+			return Recipe{}, fmt.Errorf("update: recipeID[%s]: %w", recipe.ID, err)
+		}
+		recipe.Metadata = ur.Metadata
+	}
+
+	recipe.Version = ur.Version + 1
+	recipe.DateUpdated = time.Now().UTC()
+
+	if err := b.storer.Update(ctx, recipe, ur.Version); err != nil {
+		return Recipe{}, fmt.Errorf("update: recipeID[%s] version[%d]: %w", recipe.ID, ur.Version, err)
+	}
+
+	return recipe, nil
+}
+
+// Delete marks the recipe as deleted. It's left out of the queries from
+// then on but can be restored until it's purged.
+func (b *Business) Delete(ctx context.Context, recipe Recipe) error {
+	recipe.DateDeleted = time.Now().UTC()
+
+	if err := b.storer.Delete(ctx, recipe); err != nil {
+		return fmt.Errorf("delete: recipeID[%s]: %w", recipe.ID, err)
+	}
+
+	return nil
+}
+
+// Restore brings back the deleted recipe with the specified ID.
+// ErrNotFound is returned when no deleted recipe has the ID.
+func (b *Business) Restore(ctx context.Context, recipeID uuid.UUID) (Recipe, error) {
+	recipe, err := b.storer.Restore(ctx, recipeID)
+	if err != nil {
+		return Recipe{}, fmt.Errorf("restore: recipeID[%s]: %w", recipeID, err)
+	}
+
+	return recipe, nil
+}
+
+// QueryHistory retrieves the page of the changes made to the recipe with the
+// specified ID, newest first.
+func (b *Business) QueryHistory(ctx context.Context, recipeID uuid.UUID, pg page.Page) ([]Revision, error) {
+	revisions, err := b.storer.QueryHistory(ctx, recipeID, pg)
+	if err != nil {
+		return nil, fmt.Errorf("queryhistory: recipeID[%s]: %w", recipeID, err)
+	}
+
+	return revisions, nil
+}
+
+// CountHistory returns the total number of changes made to the recipe with
+// the specified ID.
+func (b *Business) CountHistory(ctx context.Context, recipeID uuid.UUID) (int, error) {
+	n, err := b.storer.CountHistory(ctx, recipeID)
+	if err != nil {
+		return 0, fmt.Errorf("counthistory: recipeID[%s]: %w", recipeID, err)
+	}
+
+	return n, nil
+}
+
+// Revert changes the recipe back to how it was at the specified version of
+// its history. The revert is an update like any other: it must be based on
+// the current version of the recipe and makes a new version, so it's in the
+// history too and can be reverted in turn. ErrVersionNotFound is returned
+// when the history doesn't hold the version.
+func (b *Business) Revert(ctx context.Context, recipe Recipe, version int, expected int) (Recipe, error) {
+	snapshot, err := b.storer.QueryVersion(ctx, recipe.ID, version)
+	if err != nil {
+		return Recipe{}, fmt.Errorf("revert: recipeID[%s] version[%d]: %w", recipe.ID, version, err)
+	}
+
+	recipe, err = b.Update(ctx, recipe, revert(snapshot, expected))
+	if err != nil {
+		return Recipe{}, fmt.Errorf("revert: %w", err)
+	}
+
+	return recipe, nil
+}
+
+// Purge permanently removes the recipes deleted before the specified time,
+// along with their history, and returns how many were removed.
+func (b *Business) Purge(ctx context.Context, before time.Time) (int, error) {
+	n, err := b.storer.Purge(ctx, before)
+	if err != nil {
+		return 0, fmt.Errorf("purge: %w", err)
+	}
+
+	return n, nil
+}
+
+// PurgeEvery purges the recipes deleted longer than the retention ago on
+// every interval until the context is canceled.
+func (b *Business) PurgeEvery(ctx context.Context, interval time.Duration, retention time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		n, err := b.Purge(ctx, time.Now().Add(-retention))
+		switch {
+		case ctx.Err() != nil:
+			return
+		case err != nil:
+			b.log.ErrorContext(ctx, "recipe purge", "msg", err)
+		case n > 0:
+			b.log.InfoContext(ctx, "recipe purge", "status", "purged deleted recipes", "count", n, "retention", retention.String())
+		}
+	}
+}
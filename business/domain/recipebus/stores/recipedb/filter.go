@@ -0,0 +1,101 @@
+package recipedb
+
+import (
+	"strings"
+
+	"lobbyte.com/alkeepy/business/domain/recipebus"
+	"lobbyte.com/alkeepy/business/sdk/sqldb"
+)
+
+// applyFilter adds the WHERE clause for the filter to the query. The values
+// are always bound as named parameters, never written into the query.
+// The query is always scoped to the tenant in the context and deleted
+// recipes are left out unless the filter includes them. The extra
+// conditions are added as they are.
+func applyFilter(filter recipebus.QueryFilter, data map[string]any, buf *strings.Builder, extra ...string) {
+	wc := sqldb.ExcludeDeleted([]string{sqldb.TenantScope}, filter.IncludeDeleted)
+
+	if filter.ID != nil {
+		data["recipe_id"] = *filter.ID
+		wc = append(wc, "recipe_id = :recipe_id")
+	}
+
+	if filter.UserID != nil {
+		data["user_id"] = *filter.UserID
+		wc = append(wc, "user_id = :user_id")
+	}
+
+	if filter.Name != nil {
+		data["name"] = "%" + escapeLike(*filter.Name) + "%"
+		wc = append(wc, "name ILIKE :name")
+	}
+
+	if filter.Tag != nil {
+		data["tag"] = *filter.Tag
+		wc = append(wc, ":tag = ANY(tags)")
+	}
+
+	if filter.MaxPrepMinutes != nil {
+		data["max_prep_minutes"] = *filter.MaxPrepMinutes
+		wc = append(wc, "prep_minutes <= :max_prep_minutes")
+	}
+
+	if filter.StartCreatedDate != nil {
+		data["start_date_created"] = filter.StartCreatedDate.UTC()
+		wc = append(wc, "date_created >= :start_date_created")
+	}
+
+	if filter.EndCreatedDate != nil {
+		data["end_date_created"] = filter.EndCreatedDate.UTC()
+		wc = append(wc, "date_created < :end_date_created")
+	}
+
+	if filter.Metadata != nil {
+		data["metadata"] = sqldb.JSONB[recipebus.Metadata]{V: filter.Metadata}
+		wc = append(wc, "metadata @> CAST(:metadata AS jsonb)")
+	}
+
+	if filter.Search != nil {
+		language := recipebus.DefaultSearchLanguage
+		if filter.SearchLanguage != nil {
+			language = *filter.SearchLanguage
+		}
+
+		data["search"] = *filter.Search
+		data["search_language"] = language
+		wc = append(wc, "search @@ "+searchQuery)
+	}
+
+	for _, c := range extra {
+		if c != "" {
+			wc = append(wc, c)
+		}
+	}
+
+	if len(wc) > 0 {
+		buf.WriteString(" WHERE ")
+		buf.WriteString(strings.Join(wc, " AND "))
+	}
+}
+
+// searchQuery is the text search query of the filter. Its parameters are
+// bound by applyFilter.
+const searchQuery = "websearch_to_tsquery(CAST(:search_language AS regconfig), :search)"
+
+// searchColumns returns the rank and headline columns of the query. They're
+// only computed when the filter searches.
+func searchColumns(filter recipebus.QueryFilter) string {
+	if filter.Search == nil {
+		return "0 AS rank, '' AS headline"
+	}
+
+	return "ts_rank(search, " + searchQuery + ") AS rank, " +
+		"ts_headline(CAST(:search_language AS regconfig), description, " + searchQuery + ", 'StartSel=<mark>, StopSel=</mark>, MaxFragments=2') AS headline"
+}
+
+// escapeLike escapes the characters that have a meaning in a LIKE pattern
+// so the name is matched literally.
+func escapeLike(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return r.Replace(s)
+}
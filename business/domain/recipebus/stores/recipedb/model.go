@@ -0,0 +1,219 @@
+package recipedb
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"lobbyte.com/alkeepy/business/domain/recipebus"
+	"lobbyte.com/alkeepy/business/sdk/sqldb"
+)
+
+type recipe struct {
+	ID          uuid.UUID                       `db:"recipe_id"`
+	TenantID    uuid.UUID                       `db:"tenant_id"`
+	UserID      uuid.UUID                       `db:"user_id"`
+	Name        string                          `db:"name"`
+	Description string                          `db:"description"`
+	PrepMinutes int                             `db:"prep_minutes"`
+	Tags        sqldb.StringArray               `db:"tags"`
+	Metadata    sqldb.JSONB[recipebus.Metadata] `db:"metadata"`
+	Version     int                             `db:"version"`
+	DateCreated time.Time                       `db:"date_created"`
+	DateUpdated time.Time                       `db:"date_updated"`
+	DateDeleted sql.NullTime                    `db:"deleted_at"`
+	Rank        float64                         `db:"rank"`
+	Headline    string                          `db:"headline"`
+}
+
+// recipeUpdate binds the recipe and the version it's expected to be at
+// when it's updated.
+type recipeUpdate struct {
+	recipe
+	ExpectedVersion int `db:"expected_version"`
+}
+
+func toDBRecipe(bus recipebus.Recipe) recipe {
+	tags := bus.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+
+	metadata := bus.Metadata
+	if metadata == nil {
+		metadata = recipebus.Metadata{}
+	}
+
+	db := recipe{
+		ID:          bus.ID,
+		TenantID:    bus.TenantID,
+		UserID:      bus.UserID,
+		Name:        bus.Name,
+		Description: bus.Description,
+		PrepMinutes: bus.PrepMinutes,
+		Tags:        tags,
+		Metadata:    sqldb.JSONB[recipebus.Metadata]{V: metadata},
+		Version:     bus.Version,
+		DateCreated: bus.DateCreated.UTC(),
+		DateUpdated: bus.DateUpdated.UTC(),
+	}
+
+	if bus.Deleted() {
+		db.DateDeleted = sql.NullTime{Time: bus.DateDeleted.UTC(), Valid: true}
+	}
+
+	return db
+}
+
+func toBusRecipe(db recipe) recipebus.Recipe {
+	r := recipebus.Recipe{
+		ID:          db.ID,
+		TenantID:    db.TenantID,
+		UserID:      db.UserID,
+		Name:        db.Name,
+		Description: db.Description,
+		PrepMinutes: db.PrepMinutes,
+		Tags:        db.Tags,
+		Metadata:    db.Metadata.V,
+		Version:     db.Version,
+		DateCreated: db.DateCreated.In(time.Local),
+		DateUpdated: db.DateUpdated.In(time.Local),
+		Rank:        db.Rank,
+		Headline:    db.Headline,
+	}
+
+	if db.DateDeleted.Valid {
+		r.DateDeleted = db.DateDeleted.Time.In(time.Local)
+	}
+
+	return r
+}
+
+func toBusRecipes(dbs []recipe) []recipebus.Recipe {
+	bus := make([]recipebus.Recipe, len(dbs))
+	for i, db := range dbs {
+		bus[i] = toBusRecipe(db)
+	}
+
+	return bus
+}
+
+// =============================================================================
+
+// event is the payload of the events written to the outbox. It matches the
+// payload the database notifies the changes with so recipebus.ParseEvent
+// reads both.
+type event struct {
+	Op       string    `json:"op"`
+	RecipeID uuid.UUID `json:"recipe_id"`
+	TenantID uuid.UUID `json:"tenant_id"`
+	Version  int       `json:"version"`
+}
+
+// =============================================================================
+
+// revision is a row of the recipe history.
+type revision struct {
+	ID          uuid.UUID             `db:"history_id"`
+	RecipeID    uuid.UUID             `db:"recipe_id"`
+	TenantID    uuid.UUID             `db:"tenant_id"`
+	Version     int                   `db:"version"`
+	Op          string                `db:"op"`
+	Actor       string                `db:"actor"`
+	Old         sqldb.JSONB[snapshot] `db:"old_recipe"`
+	New         sqldb.JSONB[snapshot] `db:"new_recipe"`
+	DateCreated time.Time             `db:"date_created"`
+}
+
+// snapshot is a recipe as it's kept in the history. The keys are the names
+// of the columns so the history can be queried like the recipes.
+type snapshot struct {
+	ID          uuid.UUID          `json:"recipe_id"`
+	TenantID    uuid.UUID          `json:"tenant_id"`
+	UserID      uuid.UUID          `json:"user_id"`
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	PrepMinutes int                `json:"prep_minutes"`
+	Tags        []string           `json:"tags"`
+	Metadata    recipebus.Metadata `json:"metadata"`
+	Version     int                `json:"version"`
+	DateCreated time.Time          `json:"date_created"`
+	DateUpdated time.Time          `json:"date_updated"`
+	DateDeleted *time.Time         `json:"deleted_at"`
+}
+
+func toSnapshot(r recipe) snapshot {
+	s := snapshot{
+		ID:          r.ID,
+		TenantID:    r.TenantID,
+		UserID:      r.UserID,
+		Name:        r.Name,
+		Description: r.Description,
+		PrepMinutes: r.PrepMinutes,
+		Tags:        r.Tags,
+		Metadata:    r.Metadata.V,
+		Version:     r.Version,
+		DateCreated: r.DateCreated.UTC(),
+		DateUpdated: r.DateUpdated.UTC(),
+	}
+
+	if s.Tags == nil {
+		s.Tags = []string{}
+	}
+
+	if s.Metadata == nil {
+		s.Metadata = recipebus.Metadata{}
+	}
+
+	if r.DateDeleted.Valid {
+		t := r.DateDeleted.Time.UTC()
+		s.DateDeleted = &t
+	}
+
+	return s
+}
+
+func (s snapshot) toBusRecipe() recipebus.Recipe {
+	r := recipebus.Recipe{
+		ID:          s.ID,
+		TenantID:    s.TenantID,
+		UserID:      s.UserID,
+		Name:        s.Name,
+		Description: s.Description,
+		PrepMinutes: s.PrepMinutes,
+		Tags:        s.Tags,
+		Metadata:    s.Metadata,
+		Version:     s.Version,
+		DateCreated: s.DateCreated.In(time.Local),
+		DateUpdated: s.DateUpdated.In(time.Local),
+	}
+
+	if s.DateDeleted != nil {
+		r.DateDeleted = s.DateDeleted.In(time.Local)
+	}
+
+	return r
+}
+
+func toBusRevision(db revision) recipebus.Revision {
+	return recipebus.Revision{
+		ID:          db.ID,
+		RecipeID:    db.RecipeID,
+		TenantID:    db.TenantID,
+		Version:     db.Version,
+		Op:          db.Op,
+		Actor:       db.Actor,
+		Old:         db.Old.V.toBusRecipe(),
+		New:         db.New.V.toBusRecipe(),
+		DateCreated: db.DateCreated.In(time.Local),
+	}
+}
+
+func toBusRevisions(dbs []revision) []recipebus.Revision {
+	bus := make([]recipebus.Revision, len(dbs))
+	for i, db := range dbs {
+		bus[i] = toBusRevision(db)
+	}
+
+	return bus
+}
@@ -0,0 +1,39 @@
+package recipedb
+
+import (
+	"fmt"
+
+	"lobbyte.com/alkeepy/business/domain/recipebus"
+	"lobbyte.com/alkeepy/business/sdk/order"
+)
+
+// orderByFields is the whitelist of columns a query can be ordered by. Only
+// these values are ever written into the query.
+var orderByFields = map[string]string{
+	recipebus.OrderByID:          "recipe_id",
+	recipebus.OrderByName:        "name",
+	recipebus.OrderByPrepMinutes: "prep_minutes",
+	recipebus.OrderByDateCreated: "date_created",
+	recipebus.OrderByRank:        "rank",
+}
+
+// orderByClause returns the columns of the ORDER BY clause. The id breaks
+// ties in the same direction so the rows keep the same order from one page
+// to the next and a page can start after a cursor.
+func orderByClause(orderBy order.By) (string, error) {
+	by, exists := orderByFields[orderBy.Field]
+	if !exists {
+		return "", fmt.Errorf("field %q does not exist", orderBy.Field)
+	}
+
+	direction := order.ASC
+	if orderBy.Direction == order.DESC {
+		direction = order.DESC
+	}
+
+	if by == "recipe_id" {
+		return by + " " + direction, nil
+	}
+
+	return by + " " + direction + ", recipe_id " + direction, nil
+}
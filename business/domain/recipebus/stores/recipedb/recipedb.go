@@ -0,0 +1,593 @@
+// Package recipedb contains recipe related CRUD functionality.
+package recipedb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"lobbyte.com/alkeepy/business/domain/recipebus"
+	"lobbyte.com/alkeepy/business/sdk/order"
+	"lobbyte.com/alkeepy/business/sdk/outbox"
+	"lobbyte.com/alkeepy/business/sdk/page"
+	"lobbyte.com/alkeepy/business/sdk/sqldb"
+	"lobbyte.com/alkeepy/business/sdk/tenant"
+)
+
+// Store manages the set of APIs for recipe database access.
+type Store struct {
+	log     *slog.Logger
+	cluster *sqldb.Cluster
+	tx      sqlx.ExtContext
+}
+
+// NewStore constructs the api for data access.
+func NewStore(log *slog.Logger, cluster *sqldb.Cluster) *Store {
+	return &Store{
+		log:     log,
+		cluster: cluster,
+	}
+}
+
+// ExecuteUnderTransaction constructs a new Store value that runs every query
+// inside the transaction, reads included.
+func (s *Store) ExecuteUnderTransaction(tx sqldb.CommitRollbacker) (recipebus.Storer, error) {
+	ec, err := sqldb.GetExtContext(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	store := Store{
+		log:     s.log,
+		cluster: s.cluster,
+		tx:      ec,
+	}
+
+	return &store, nil
+}
+
+// read runs a query that only reads data. Outside of a transaction it goes
+// to a replica when one is healthy and is retried after a transient failure,
+// picking the database again on each attempt. It's refused when the context
+// isn't scoped to a tenant.
+func (s *Store) read(ctx context.Context, fn func(ctx context.Context, db sqlx.ExtContext) error) error {
+	if _, err := tenant.Get(ctx); err != nil {
+		return err
+	}
+
+	if s.tx != nil {
+		return fn(ctx, s.tx)
+	}
+
+	return sqldb.Retry(ctx, s.log, sqldb.DefaultBackoff, func(ctx context.Context) error {
+		return fn(ctx, s.cluster.Reader())
+	})
+}
+
+// write runs a change as a single transaction, which lets the change write
+// the event reporting it to the outbox atomically. Inside a transaction it
+// becomes part of it, otherwise it runs in a transaction of its own on the
+// primary. Writes aren't retried since they may have been applied before
+// the failure was reported. It's refused when the context isn't scoped to a
+// tenant.
+func (s *Store) write(ctx context.Context, fn func(ctx context.Context, db sqlx.ExtContext) error) error {
+	if _, err := tenant.Get(ctx); err != nil {
+		return err
+	}
+
+	if s.tx != nil {
+		return fn(ctx, s.tx)
+	}
+
+	tx, err := s.cluster.Primary().BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(ctx, tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+
+	return nil
+}
+
+// primary returns the transaction when there is one, otherwise the primary.
+func (s *Store) primary() sqlx.ExtContext {
+	if s.tx != nil {
+		return s.tx
+	}
+
+	return s.cluster.Primary()
+}
+
+// Query retrieves the page of recipes that match the filter in the specified
+// order. The recipes are ranked and highlighted when the filter searches.
+func (s *Store) Query(ctx context.Context, filter recipebus.QueryFilter, orderBy order.By, pg page.Page) ([]recipebus.Recipe, error) {
+	data := map[string]any{}
+
+	const q = `
+	SELECT
+		recipe_id, tenant_id, user_id, name, description, prep_minutes, tags, metadata, version, date_created, date_updated, deleted_at, %s
+	FROM
+		recipes`
+
+	orderClause, err := orderByClause(orderBy)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor := sqldb.CursorCondition(data, pg, "date_created", "recipe_id", orderBy.Direction == order.DESC)
+
+	var buf strings.Builder
+	fmt.Fprintf(&buf, q, searchColumns(filter))
+	applyFilter(filter, data, &buf, cursor)
+	buf.WriteString(" ORDER BY " + orderClause)
+	sqldb.AddPageClause(&buf, data, pg)
+
+	var dbRecipes []recipe
+	err = s.read(ctx, func(ctx context.Context, db sqlx.ExtContext) error {
+		return sqldb.NamedQuerySlice(ctx, s.log, db, buf.String(), data, &dbRecipes)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return toBusRecipes(dbRecipes), nil
+}
+
+// Count returns the total number of recipes that match the filter.
+func (s *Store) Count(ctx context.Context, filter recipebus.QueryFilter) (int, error) {
+	data := map[string]any{}
+
+	const q = `
+	SELECT
+		count(1)
+	FROM
+		recipes`
+
+	var buf strings.Builder
+	buf.WriteString(q)
+	applyFilter(filter, data, &buf)
+
+	var count struct {
+		Count int `db:"count"`
+	}
+	err := s.read(ctx, func(ctx context.Context, db sqlx.ExtContext) error {
+		return sqldb.NamedQueryStruct(ctx, s.log, db, buf.String(), data, &count)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return count.Count, nil
+}
+
+// QueryByID gets the specified recipe from the database unless it's been
+// deleted.
+func (s *Store) QueryByID(ctx context.Context, recipeID uuid.UUID) (recipebus.Recipe, error) {
+	data := struct {
+		ID string `db:"recipe_id"`
+	}{
+		ID: recipeID.String(),
+	}
+
+	const q = `
+	SELECT
+		recipe_id, tenant_id, user_id, name, description, prep_minutes, tags, metadata, version, date_created, date_updated, deleted_at
+	FROM
+		recipes
+	WHERE
+		recipe_id = :recipe_id AND tenant_id = :tenant_id AND deleted_at IS NULL`
+
+	var dbRecipe recipe
+	err := s.read(ctx, func(ctx context.Context, db sqlx.ExtContext) error {
+		return sqldb.NamedQueryStruct(ctx, s.log, db, q, data, &dbRecipe)
+	})
+	if err != nil {
+		if errors.Is(err, sqldb.ErrDBNotFound) {
+			return recipebus.Recipe{}, fmt.Errorf("namedquerystruct: %w", recipebus.ErrNotFound)
+		}
+		return recipebus.Recipe{}, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return toBusRecipe(dbRecipe), nil
+}
+
+// Update replaces the recipe in the database as long as it's still at the
+// specified version. ErrVersionConflict is returned when it's been changed
+// or deleted since.
+func (s *Store) Update(ctx context.Context, recipe recipebus.Recipe, version int) error {
+	data := recipeUpdate{
+		recipe:          toDBRecipe(recipe),
+		ExpectedVersion: version,
+	}
+
+	const q = `
+	UPDATE
+		recipes
+	SET
+		name = :name,
+		description = :description,
+		prep_minutes = :prep_minutes,
+		tags = :tags,
+		metadata = :metadata,
+		version = :version,
+		date_updated = :date_updated,
+		updated_by = :updated_by
+	WHERE
+		recipe_id = :recipe_id AND tenant_id = :tenant_id AND version = :expected_version AND deleted_at IS NULL
+	RETURNING
+		recipe_id`
+
+	return s.write(ctx, func(ctx context.Context, db sqlx.ExtContext) error {
+		old, err := s.lock(ctx, db, recipe.ID)
+		if err != nil {
+			if errors.Is(err, sqldb.ErrDBNotFound) {
+				return fmt.Errorf("lock: %w", recipebus.ErrVersionConflict)
+			}
+			return fmt.Errorf("lock: %w", err)
+		}
+
+		var updated struct {
+			ID uuid.UUID `db:"recipe_id"`
+		}
+		if err := sqldb.NamedQueryStruct(ctx, s.log, db, q, data, &updated); err != nil {
+			if errors.Is(err, sqldb.ErrDBNotFound) {
+				return fmt.Errorf("namedquerystruct: %w", recipebus.ErrVersionConflict)
+			}
+			return fmt.Errorf("namedquerystruct: %w", err)
+		}
+
+		if err := s.addRevision(ctx, db, recipebus.EventUpdated, old, data.recipe); err != nil {
+			return err
+		}
+
+		return s.addEvent(ctx, db, recipebus.EventUpdated, recipe)
+	})
+}
+
+// Delete marks the recipe as deleted in the database. A recipe that's
+// already deleted keeps its original deletion time.
+func (s *Store) Delete(ctx context.Context, recipe recipebus.Recipe) error {
+	data := struct {
+		ID          string    `db:"recipe_id"`
+		DateDeleted time.Time `db:"deleted_at"`
+	}{
+		ID:          recipe.ID.String(),
+		DateDeleted: recipe.DateDeleted.UTC(),
+	}
+
+	const q = `
+	UPDATE
+		recipes
+	SET
+		deleted_at = :deleted_at
+	WHERE
+		recipe_id = :recipe_id AND tenant_id = :tenant_id AND deleted_at IS NULL
+	RETURNING
+		recipe_id`
+
+	return s.write(ctx, func(ctx context.Context, db sqlx.ExtContext) error {
+		old, err := s.lock(ctx, db, recipe.ID)
+		if err != nil {
+			if errors.Is(err, sqldb.ErrDBNotFound) {
+				return nil
+			}
+			return fmt.Errorf("lock: %w", err)
+		}
+
+		var deleted struct {
+			ID uuid.UUID `db:"recipe_id"`
+		}
+		if err := sqldb.NamedQueryStruct(ctx, s.log, db, q, data, &deleted); err != nil {
+			if errors.Is(err, sqldb.ErrDBNotFound) {
+				return nil
+			}
+			return fmt.Errorf("namedquerystruct: %w", err)
+		}
+
+		updated := old
+		updated.DateDeleted = sql.NullTime{Time: data.DateDeleted, Valid: true}
+
+		if err := s.addRevision(ctx, db, recipebus.EventDeleted, old, updated); err != nil {
+			return err
+		}
+
+		return s.addEvent(ctx, db, recipebus.EventDeleted, recipe)
+	})
+}
+
+// Restore clears the deletion of the specified recipe in the database.
+func (s *Store) Restore(ctx context.Context, recipeID uuid.UUID) (recipebus.Recipe, error) {
+	data := struct {
+		ID string `db:"recipe_id"`
+	}{
+		ID: recipeID.String(),
+	}
+
+	const q = `
+	UPDATE
+		recipes
+	SET
+		deleted_at = NULL
+	WHERE
+		recipe_id = :recipe_id AND tenant_id = :tenant_id AND deleted_at IS NOT NULL
+	RETURNING
+		recipe_id, tenant_id, user_id, name, description, prep_minutes, tags, metadata, version, date_created, date_updated, deleted_at`
+
+	var dbRecipe recipe
+	err := s.write(ctx, func(ctx context.Context, db sqlx.ExtContext) error {
+		old, err := s.lock(ctx, db, recipeID)
+		if err != nil {
+			if errors.Is(err, sqldb.ErrDBNotFound) {
+				return fmt.Errorf("lock: %w", recipebus.ErrNotFound)
+			}
+			return fmt.Errorf("lock: %w", err)
+		}
+
+		if err := sqldb.NamedQueryStruct(ctx, s.log, db, q, data, &dbRecipe); err != nil {
+			if errors.Is(err, sqldb.ErrDBNotFound) {
+				return fmt.Errorf("namedquerystruct: %w", recipebus.ErrNotFound)
+			}
+			return fmt.Errorf("namedquerystruct: %w", err)
+		}
+
+		if err := s.addRevision(ctx, db, recipebus.EventRestored, old, dbRecipe); err != nil {
+			return err
+		}
+
+		return s.addEvent(ctx, db, recipebus.EventRestored, toBusRecipe(dbRecipe))
+	})
+	if err != nil {
+		return recipebus.Recipe{}, err
+	}
+
+	return toBusRecipe(dbRecipe), nil
+}
+
+// Purge removes the recipes deleted before the specified time from the
+// database. It's run by the system on behalf of every tenant so it isn't
+// scoped to one. The event reporting each purged recipe is written to the
+// outbox by the same statement, and the history of the purged recipes goes
+// with them.
+func (s *Store) Purge(ctx context.Context, before time.Time) (int, error) {
+	data := struct {
+		Before time.Time `db:"before"`
+		Topic  string    `db:"topic"`
+		Op     string    `db:"op"`
+		Now    time.Time `db:"now"`
+	}{
+		Before: before.UTC(),
+		Topic:  recipebus.EventChannel,
+		Op:     recipebus.EventPurged,
+		Now:    time.Now().UTC(),
+	}
+
+	const q = `
+	WITH purged AS (
+		DELETE FROM
+			recipes
+		WHERE
+			deleted_at < :before
+		RETURNING
+			recipe_id, tenant_id, version
+	), queued AS (
+		INSERT INTO outbox
+			(outbox_id, tenant_id, topic, key, payload, date_created)
+		SELECT
+			gen_random_uuid(), tenant_id, :topic, CAST(recipe_id AS text),
+			json_build_object('op', CAST(:op AS text), 'recipe_id', recipe_id, 'tenant_id', tenant_id, 'version', version),
+			:now
+		FROM
+			purged
+	)
+	SELECT
+		count(1)
+	FROM
+		purged`
+
+	var count struct {
+		Count int `db:"count"`
+	}
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.primary(), q, data, &count); err != nil {
+		return 0, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return count.Count, nil
+}
+
+// QueryHistory retrieves the page of the changes made to the specified
+// recipe, newest first.
+func (s *Store) QueryHistory(ctx context.Context, recipeID uuid.UUID, pg page.Page) ([]recipebus.Revision, error) {
+	data := map[string]any{
+		"recipe_id": recipeID.String(),
+	}
+
+	const q = `
+	SELECT
+		history_id, recipe_id, tenant_id, version, op, actor, old_recipe, new_recipe, date_created
+	FROM
+		recipe_history
+	WHERE
+		recipe_id = :recipe_id AND tenant_id = :tenant_id
+	ORDER BY
+		date_created DESC, history_id`
+
+	var buf strings.Builder
+	buf.WriteString(q)
+	sqldb.AddPageClause(&buf, data, pg)
+
+	var dbRevisions []revision
+	err := s.read(ctx, func(ctx context.Context, db sqlx.ExtContext) error {
+		return sqldb.NamedQuerySlice(ctx, s.log, db, buf.String(), data, &dbRevisions)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return toBusRevisions(dbRevisions), nil
+}
+
+// CountHistory returns the total number of changes made to the specified
+// recipe.
+func (s *Store) CountHistory(ctx context.Context, recipeID uuid.UUID) (int, error) {
+	data := struct {
+		ID string `db:"recipe_id"`
+	}{
+		ID: recipeID.String(),
+	}
+
+	const q = `
+	SELECT
+		count(1)
+	FROM
+		recipe_history
+	WHERE
+		recipe_id = :recipe_id AND tenant_id = :tenant_id`
+
+	var count struct {
+		Count int `db:"count"`
+	}
+	err := s.read(ctx, func(ctx context.Context, db sqlx.ExtContext) error {
+		return sqldb.NamedQueryStruct(ctx, s.log, db, q, data, &count)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return count.Count, nil
+}
+
+// QueryVersion gets the specified recipe as it was at the version from its
+// history. A version is left by the change that made it and found again
+// before the change that followed, which is the only place the version the
+// recipe started at is kept.
+func (s *Store) QueryVersion(ctx context.Context, recipeID uuid.UUID, version int) (recipebus.Recipe, error) {
+	data := struct {
+		ID      string `db:"recipe_id"`
+		Version int    `db:"version"`
+	}{
+		ID:      recipeID.String(),
+		Version: version,
+	}
+
+	const q = `
+	SELECT
+		recipe
+	FROM (
+		SELECT
+			new_recipe AS recipe, date_created
+		FROM
+			recipe_history
+		WHERE
+			recipe_id = :recipe_id AND tenant_id = :tenant_id AND version = :version
+		UNION ALL
+		SELECT
+			old_recipe, date_created
+		FROM
+			recipe_history
+		WHERE
+			recipe_id = :recipe_id AND tenant_id = :tenant_id AND CAST(old_recipe->>'version' AS INT) = :version
+	) AS versions
+	ORDER BY
+		date_created
+	LIMIT 1`
+
+	var row struct {
+		Recipe sqldb.JSONB[snapshot] `db:"recipe"`
+	}
+	err := s.read(ctx, func(ctx context.Context, db sqlx.ExtContext) error {
+		return sqldb.NamedQueryStruct(ctx, s.log, db, q, data, &row)
+	})
+	if err != nil {
+		if errors.Is(err, sqldb.ErrDBNotFound) {
+			return recipebus.Recipe{}, fmt.Errorf("namedquerystruct: %w", recipebus.ErrVersionNotFound)
+		}
+		return recipebus.Recipe{}, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return row.Recipe.V.toBusRecipe(), nil
+}
+
+// lock gets the specified recipe, deleted or not, and locks it until the
+// end of the transaction so the recipe it's changed from is the one kept in
+// the history.
+func (s *Store) lock(ctx context.Context, db sqlx.ExtContext, recipeID uuid.UUID) (recipe, error) {
+	data := struct {
+		ID string `db:"recipe_id"`
+	}{
+		ID: recipeID.String(),
+	}
+
+	const q = `
+	SELECT
+		recipe_id, tenant_id, user_id, name, description, prep_minutes, tags, metadata, version, date_created, date_updated, deleted_at
+	FROM
+		recipes
+	WHERE
+		recipe_id = :recipe_id AND tenant_id = :tenant_id
+	FOR UPDATE`
+
+	var dbRecipe recipe
+	if err := sqldb.NamedQueryStruct(ctx, s.log, db, q, data, &dbRecipe); err != nil {
+		return recipe{}, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return dbRecipe, nil
+}
+
+// addRevision writes the change made to the recipe to its history, with the
+// recipe before and after it.
+func (s *Store) addRevision(ctx context.Context, db sqlx.ExtContext, op string, old recipe, updated recipe) error {
+	data := revision{
+		ID:          uuid.New(),
+		RecipeID:    updated.ID,
+		TenantID:    updated.TenantID,
+		Version:     updated.Version,
+		Op:          op,
+		Actor:       sqldb.GetActor(ctx),
+		Old:         sqldb.JSONB[snapshot]{V: toSnapshot(old)},
+		New:         sqldb.JSONB[snapshot]{V: toSnapshot(updated)},
+		DateCreated: time.Now().UTC(),
+	}
+
+	const q = `
+	INSERT INTO recipe_history
+		(history_id, recipe_id, tenant_id, version, op, actor, old_recipe, new_recipe, date_created)
+	VALUES
+		(:history_id, :recipe_id, :tenant_id, :version, :op, :actor, :old_recipe, :new_recipe, :date_created)`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, db, q, data); err != nil {
+		return fmt.Errorf("history: namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// addEvent writes the event reporting the change made to the recipe to the
+// outbox, with the payload the database notifies the change with.
+func (s *Store) addEvent(ctx context.Context, db sqlx.ExtContext, op string, r recipebus.Recipe) error {
+	ev := event{
+		Op:       op,
+		RecipeID: r.ID,
+		TenantID: r.TenantID,
+		Version:  r.Version,
+	}
+
+	if err := outbox.Add(ctx, s.log, db, recipebus.EventChannel, r.ID.String(), ev); err != nil {
+		return fmt.Errorf("outbox: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package recipemem
+
+import (
+	"cmp"
+	"fmt"
+	"strings"
+
+	"lobbyte.com/alkeepy/business/domain/recipebus"
+	"lobbyte.com/alkeepy/business/sdk/order"
+)
+
+// compareFunc returns the function that sorts the recipes in the order of
+// the database store, including the id that breaks ties in the same
+// direction.
+func compareFunc(orderBy order.By) (func(a, b recipebus.Recipe) int, error) {
+	var byField func(a, b recipebus.Recipe) int
+
+	switch orderBy.Field {
+	case recipebus.OrderByID:
+		byField = func(a, b recipebus.Recipe) int { return 0 }
+	case recipebus.OrderByName:
+		byField = func(a, b recipebus.Recipe) int { return strings.Compare(a.Name, b.Name) }
+	case recipebus.OrderByPrepMinutes:
+		byField = func(a, b recipebus.Recipe) int { return cmp.Compare(a.PrepMinutes, b.PrepMinutes) }
+	case recipebus.OrderByDateCreated:
+		byField = func(a, b recipebus.Recipe) int { return a.DateCreated.Compare(b.DateCreated) }
+	case recipebus.OrderByRank:
+		byField = func(a, b recipebus.Recipe) int { return cmp.Compare(a.Rank, b.Rank) }
+	default:
+		return nil, fmt.Errorf("field %q does not exist", orderBy.Field)
+	}
+
+	desc := orderBy.Direction == order.DESC
+
+	f := func(a, b recipebus.Recipe) int {
+		if c := byField(a, b); c != 0 {
+			if desc {
+				return -c
+			}
+			return c
+		}
+
+		c := strings.Compare(a.ID.String(), b.ID.String())
+		if desc {
+			return -c
+		}
+		return c
+	}
+
+	return f, nil
+}
@@ -0,0 +1,365 @@
+// Package recipemem contains recipe related CRUD functionality backed by
+// memory so the API can run without a database during development and the
+// business logic can be exercised without one. The recipes are lost when the
+// process exits.
+package recipemem
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"lobbyte.com/alkeepy/business/domain/recipebus"
+	"lobbyte.com/alkeepy/business/sdk/order"
+	"lobbyte.com/alkeepy/business/sdk/page"
+	"lobbyte.com/alkeepy/business/sdk/sqldb"
+	"lobbyte.com/alkeepy/business/sdk/tenant"
+)
+
+// Store manages the set of APIs for recipe in memory access.
+type Store struct {
+	mu      sync.RWMutex
+	recipes map[uuid.UUID]recipebus.Recipe
+	history []recipebus.Revision
+	notify  func(ev recipebus.Event)
+}
+
+// NewStore constructs the api for data access, holding the specified
+// recipes.
+func NewStore(recipes ...recipebus.Recipe) *Store {
+	s := Store{
+		recipes: make(map[uuid.UUID]recipebus.Recipe, len(recipes)),
+	}
+
+	for _, r := range recipes {
+		r.Tags = slices.Clone(r.Tags)
+		r.Metadata = maps.Clone(r.Metadata)
+		s.recipes[r.ID] = r
+	}
+
+	return &s
+}
+
+// OnChange sets the function each change made to the recipes is reported
+// to, like the database notifies them when they're stored there. It's
+// called with the store locked, so it mustn't block or use the store.
+func (s *Store) OnChange(notify func(ev recipebus.Event)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.notify = notify
+}
+
+// ExecuteUnderTransaction returns the store itself. Recipes kept in memory
+// can't take part in a database transaction, so changes are kept even when
+// the transaction is rolled back.
+func (s *Store) ExecuteUnderTransaction(tx sqldb.CommitRollbacker) (recipebus.Storer, error) {
+	return s, nil
+}
+
+// Query retrieves the page of recipes that match the filter in the specified
+// order.
+func (s *Store) Query(ctx context.Context, filter recipebus.QueryFilter, orderBy order.By, pg page.Page) ([]recipebus.Recipe, error) {
+	tenantID, err := tenant.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cmp, err := compareFunc(orderBy)
+	if err != nil {
+		return nil, err
+	}
+
+	recipes := s.match(tenantID, filter)
+	slices.SortFunc(recipes, cmp)
+
+	if c, ok := pg.Cursor(); ok {
+		recipes = slices.DeleteFunc(recipes, func(r recipebus.Recipe) bool {
+			return !after(r, c, orderBy.Direction == order.DESC)
+		})
+	}
+
+	start := min(pg.Offset(), len(recipes))
+	end := min(start+pg.RowsPerPage(), len(recipes))
+
+	return recipes[start:end], nil
+}
+
+// Count returns the total number of recipes that match the filter.
+func (s *Store) Count(ctx context.Context, filter recipebus.QueryFilter) (int, error) {
+	tenantID, err := tenant.Get(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(s.match(tenantID, filter)), nil
+}
+
+// QueryByID gets the specified recipe unless it's been deleted.
+func (s *Store) QueryByID(ctx context.Context, recipeID uuid.UUID) (recipebus.Recipe, error) {
+	tenantID, err := tenant.Get(ctx)
+	if err != nil {
+		return recipebus.Recipe{}, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	recipe, exists := s.recipes[recipeID]
+	if !exists || recipe.TenantID != tenantID || recipe.Deleted() {
+		return recipebus.Recipe{}, fmt.Errorf("query: %w", recipebus.ErrNotFound)
+	}
+
+	return recipe, nil
+}
+
+// Update replaces the recipe as long as it's still at the specified
+// version. ErrVersionConflict is returned when it's been changed or deleted
+// since.
+func (s *Store) Update(ctx context.Context, recipe recipebus.Recipe, version int) error {
+	tenantID, err := tenant.Get(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, exists := s.recipes[recipe.ID]
+	if !exists || r.TenantID != tenantID || r.Deleted() || r.Version != version {
+		return fmt.Errorf("update: %w", recipebus.ErrVersionConflict)
+	}
+
+	recipe.TenantID = tenantID
+	recipe.Tags = slices.Clone(recipe.Tags)
+	recipe.Metadata = maps.Clone(recipe.Metadata)
+	s.recipes[recipe.ID] = recipe
+	s.addRevision(ctx, recipebus.EventUpdated, r, recipe)
+
+	return nil
+}
+
+// Delete marks the recipe as deleted. A recipe that's already deleted keeps
+// its original deletion time.
+func (s *Store) Delete(ctx context.Context, recipe recipebus.Recipe) error {
+	tenantID, err := tenant.Get(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, exists := s.recipes[recipe.ID]
+	if !exists || r.TenantID != tenantID || r.Deleted() {
+		return nil
+	}
+
+	old := r
+	r.DateDeleted = recipe.DateDeleted
+	s.recipes[r.ID] = r
+	s.addRevision(ctx, recipebus.EventDeleted, old, r)
+
+	return nil
+}
+
+// Restore clears the deletion of the specified recipe.
+func (s *Store) Restore(ctx context.Context, recipeID uuid.UUID) (recipebus.Recipe, error) {
+	tenantID, err := tenant.Get(ctx)
+	if err != nil {
+		return recipebus.Recipe{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, exists := s.recipes[recipeID]
+	if !exists || r.TenantID != tenantID || !r.Deleted() {
+		return recipebus.Recipe{}, fmt.Errorf("restore: %w", recipebus.ErrNotFound)
+	}
+
+	old := r
+	r.DateDeleted = time.Time{}
+	s.recipes[r.ID] = r
+	s.addRevision(ctx, recipebus.EventRestored, old, r)
+
+	return r, nil
+}
+
+// Purge removes the recipes deleted before the specified time, whatever
+// tenant they belong to, along with their history.
+func (s *Store) Purge(ctx context.Context, before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var n int
+	for id, r := range s.recipes {
+		if r.Deleted() && r.DateDeleted.Before(before) {
+			delete(s.recipes, id)
+			s.report(recipebus.EventPurged, r)
+			n++
+		}
+	}
+
+	s.history = slices.DeleteFunc(s.history, func(rev recipebus.Revision) bool {
+		_, exists := s.recipes[rev.RecipeID]
+		return !exists
+	})
+
+	return n, nil
+}
+
+// QueryHistory retrieves the page of the changes made to the specified
+// recipe, newest first.
+func (s *Store) QueryHistory(ctx context.Context, recipeID uuid.UUID, pg page.Page) ([]recipebus.Revision, error) {
+	revisions, err := s.revisions(ctx, recipeID)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.Reverse(revisions)
+
+	start := min(pg.Offset(), len(revisions))
+	end := min(start+pg.RowsPerPage(), len(revisions))
+
+	return revisions[start:end], nil
+}
+
+// CountHistory returns the total number of changes made to the specified
+// recipe.
+func (s *Store) CountHistory(ctx context.Context, recipeID uuid.UUID) (int, error) {
+	revisions, err := s.revisions(ctx, recipeID)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(revisions), nil
+}
+
+// QueryVersion gets the specified recipe as it was at the version from its
+// history, either as the change that made the version left it or as the
+// change that followed found it.
+func (s *Store) QueryVersion(ctx context.Context, recipeID uuid.UUID, version int) (recipebus.Recipe, error) {
+	revisions, err := s.revisions(ctx, recipeID)
+	if err != nil {
+		return recipebus.Recipe{}, err
+	}
+
+	for _, rev := range revisions {
+		switch version {
+		case rev.New.Version:
+			return rev.New, nil
+		case rev.Old.Version:
+			return rev.Old, nil
+		}
+	}
+
+	return recipebus.Recipe{}, fmt.Errorf("queryversion: %w", recipebus.ErrVersionNotFound)
+}
+
+// revisions returns a copy of the changes made to the recipe of the tenant,
+// oldest first.
+func (s *Store) revisions(ctx context.Context, recipeID uuid.UUID) ([]recipebus.Revision, error) {
+	tenantID, err := tenant.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var revisions []recipebus.Revision
+	for _, rev := range s.history {
+		if rev.RecipeID == recipeID && rev.TenantID == tenantID {
+			revisions = append(revisions, rev)
+		}
+	}
+
+	return revisions, nil
+}
+
+// addRevision adds the change made to the recipe to its history and reports
+// it. It must be called with the lock held.
+func (s *Store) addRevision(ctx context.Context, op string, old recipebus.Recipe, updated recipebus.Recipe) {
+	s.history = append(s.history, recipebus.Revision{
+		ID:          uuid.New(),
+		RecipeID:    updated.ID,
+		TenantID:    updated.TenantID,
+		Version:     updated.Version,
+		Op:          op,
+		Actor:       sqldb.GetActor(ctx),
+		Old:         old,
+		New:         updated,
+		DateCreated: time.Now(),
+	})
+
+	s.report(op, updated)
+}
+
+// report notifies the change made to the recipe when a function is set. It
+// must be called with the lock held.
+func (s *Store) report(op string, r recipebus.Recipe) {
+	if s.notify == nil {
+		return
+	}
+
+	s.notify(recipebus.Event{
+		Op:       op,
+		RecipeID: r.ID,
+		TenantID: r.TenantID,
+		Version:  r.Version,
+	})
+}
+
+// match returns a copy of the recipes of the tenant that satisfy the filter,
+// ranked when the filter searches.
+func (s *Store) match(tenantID uuid.UUID, filter recipebus.QueryFilter) []recipebus.Recipe {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var recipes []recipebus.Recipe
+	for _, r := range s.recipes {
+		if r.TenantID == tenantID && filter.Match(r) {
+			if filter.Search != nil {
+				r.Rank = rank(r, *filter.Search)
+			}
+			recipes = append(recipes, r)
+		}
+	}
+
+	return recipes
+}
+
+// after reports whether the recipe comes after the cursor in the order of
+// the date created and id.
+func after(r recipebus.Recipe, c page.Cursor, desc bool) bool {
+	v := r.DateCreated.Compare(c.DateCreated)
+	if v == 0 {
+		v = strings.Compare(r.ID.String(), c.ID.String())
+	}
+
+	if desc {
+		return v < 0
+	}
+	return v > 0
+}
+
+// rank scores how well the recipe matches the search. Like the weights the
+// database gives them, a word in the name counts more than one in the
+// description.
+func rank(r recipebus.Recipe, search string) float64 {
+	name := strings.ToLower(r.Name)
+	description := strings.ToLower(r.Description)
+
+	var score float64
+	for _, word := range strings.Fields(strings.ToLower(search)) {
+		score += float64(strings.Count(name, word)) + 0.4*float64(strings.Count(description, word))
+	}
+
+	return score
+}
@@ -0,0 +1,97 @@
+package recipemem_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"lobbyte.com/alkeepy/business/domain/recipebus"
+	"lobbyte.com/alkeepy/business/domain/recipebus/stores/recipemem"
+	"lobbyte.com/alkeepy/business/sdk/order"
+	"lobbyte.com/alkeepy/business/sdk/page"
+	"lobbyte.com/alkeepy/business/sdk/tenant"
+)
+
+func Test_QueryCursor(t *testing.T) {
+	tenantID := uuid.MustParse("e7a1c2d4-5b6f-4a8e-9c0d-1f2a3b4c5d6e")
+	ctx := tenant.With(context.Background(), tenantID)
+
+	// Pairs of recipes are created at the same time so the id has to break
+	// the ties.
+	start := time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC)
+
+	var recipes []recipebus.Recipe
+	for i := range 7 {
+		recipes = append(recipes, recipebus.Recipe{
+			ID:          uuid.New(),
+			TenantID:    tenantID,
+			Name:        fmt.Sprintf("recipe %d", i),
+			DateCreated: start.Add(time.Duration(i/2) * time.Minute),
+		})
+	}
+
+	tests := []struct {
+		name    string
+		orderBy order.By
+	}{
+		{name: "ascending", orderBy: order.NewBy(recipebus.OrderByDateCreated, order.ASC)},
+		{name: "descending", orderBy: order.NewBy(recipebus.OrderByDateCreated, order.DESC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := recipemem.NewStore(recipes...)
+
+			all, err := store.Query(ctx, recipebus.QueryFilter{}, tt.orderBy, page.MustParse("1", "100"))
+			if err != nil {
+				t.Fatalf("Should query the recipes: %s", err)
+			}
+
+			pg, err := page.Parse("", "3")
+			if err != nil {
+				t.Fatalf("Should parse the first page: %s", err)
+			}
+
+			var walked []recipebus.Recipe
+			for {
+				got, err := store.Query(ctx, recipebus.QueryFilter{}, tt.orderBy, pg)
+				if err != nil {
+					t.Fatalf("Should query the page %s: %s", pg, err)
+				}
+
+				walked = append(walked, got...)
+				if len(got) < 3 {
+					break
+				}
+
+				// Removing a recipe of a page already walked doesn't shift
+				// the pages that follow.
+				if len(walked) == 3 {
+					if err := store.Delete(ctx, walked[0]); err != nil {
+						t.Fatalf("Should delete a recipe: %s", err)
+					}
+				}
+
+				last := got[len(got)-1]
+				c := page.Cursor{DateCreated: last.DateCreated, ID: last.ID}
+
+				pg, err = page.ParseCursor(c.Encode(), "3")
+				if err != nil {
+					t.Fatalf("Should parse the page after %s: %s", c, err)
+				}
+			}
+
+			if len(walked) != len(all) {
+				t.Fatalf("Should walk %d recipes, got %d", len(all), len(walked))
+			}
+
+			for i, r := range all {
+				if walked[i].ID != r.ID {
+					t.Fatalf("Should walk the recipes in order, got %s at %d, want %s", walked[i].Name, i, r.Name)
+				}
+			}
+		})
+	}
+}
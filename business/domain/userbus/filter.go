@@ -0,0 +1,38 @@
+package userbus
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// QueryFilter holds the available fields a query can be filtered on.
+// A nil field doesn't restrict the query.
+type QueryFilter struct {
+	ID               *uuid.UUID
+	Name             *string
+	Email            *string
+	StartCreatedDate *time.Time
+	EndCreatedDate   *time.Time
+}
+
+// Match reports whether the user satisfies the filter. The name matches
+// when it contains the filter value, ignoring case, and the email must be
+// equal ignoring case.
+func (qf QueryFilter) Match(u User) bool {
+	switch {
+	case qf.ID != nil && u.ID != *qf.ID:
+		return false
+	case qf.Name != nil && !strings.Contains(strings.ToLower(u.Name), strings.ToLower(*qf.Name)):
+		return false
+	case qf.Email != nil && !strings.EqualFold(u.Email, *qf.Email):
+		return false
+	case qf.StartCreatedDate != nil && u.DateCreated.Before(*qf.StartCreatedDate):
+		return false
+	case qf.EndCreatedDate != nil && !u.DateCreated.Before(*qf.EndCreatedDate):
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,52 @@
+package userbus
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Set of roles a user can be given.
+const (
+	RoleAdmin = "ADMIN"
+	RoleUser  = "USER"
+)
+
+// User represents information about an individual user. TenantID is the
+// bakery the user belongs to. Phone and Address are sensitive and are
+// encrypted by the stores.
+type User struct {
+	ID           uuid.UUID
+	TenantID     uuid.UUID
+	Name         string
+	Email        string
+	Phone        string
+	Address      string
+	Roles        []string
+	PasswordHash []byte
+	Enabled      bool
+	DateCreated  time.Time
+	DateUpdated  time.Time
+}
+
+// NewUser contains information needed to create a new user.
+type NewUser struct {
+	Name     string
+	Email    string
+	Phone    string
+	Address  string
+	Roles    []string
+	Password string
+}
+
+// UpdateUser contains information needed to update a user. A nil field is
+// left unchanged.
+type UpdateUser struct {
+	Name     *string
+	Email    *string
+	Phone    *string
+	Address  *string
+	Roles    []string
+	Password *string
+	Enabled  *bool
+}
@@ -0,0 +1,51 @@
+package userdb
+
+import (
+	"strings"
+
+	"lobbyte.com/alkeepy/business/domain/userbus"
+	"lobbyte.com/alkeepy/business/sdk/sqldb"
+)
+
+// applyFilter adds the WHERE clause for the filter to the query. The values
+// are always bound as named parameters, never written into the query.
+// The query is always scoped to the tenant in the context. The name and the
+// email are matched ignoring case like the memory store does.
+func applyFilter(filter userbus.QueryFilter, data map[string]any, buf *strings.Builder) {
+	wc := []string{sqldb.TenantScope}
+
+	if filter.ID != nil {
+		data["user_id"] = *filter.ID
+		wc = append(wc, "user_id = :user_id")
+	}
+
+	if filter.Name != nil {
+		data["name"] = "%" + escapeLike(*filter.Name) + "%"
+		wc = append(wc, "name ILIKE :name")
+	}
+
+	if filter.Email != nil {
+		data["email"] = *filter.Email
+		wc = append(wc, "lower(email) = lower(:email)")
+	}
+
+	if filter.StartCreatedDate != nil {
+		data["start_date_created"] = filter.StartCreatedDate.UTC()
+		wc = append(wc, "date_created >= :start_date_created")
+	}
+
+	if filter.EndCreatedDate != nil {
+		data["end_date_created"] = filter.EndCreatedDate.UTC()
+		wc = append(wc, "date_created < :end_date_created")
+	}
+
+	buf.WriteString(" WHERE ")
+	buf.WriteString(strings.Join(wc, " AND "))
+}
+
+// escapeLike escapes the characters that have a meaning in a LIKE pattern
+// so the name is matched literally.
+func escapeLike(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return r.Replace(s)
+}
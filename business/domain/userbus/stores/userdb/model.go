@@ -0,0 +1,129 @@
+package userdb
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"lobbyte.com/alkeepy/business/domain/userbus"
+	"lobbyte.com/alkeepy/business/sdk/sqldb"
+	"lobbyte.com/alkeepy/foundation/envelope"
+)
+
+type user struct {
+	ID           uuid.UUID         `db:"user_id"`
+	TenantID     uuid.UUID         `db:"tenant_id"`
+	Name         string            `db:"name"`
+	Email        string            `db:"email"`
+	Phone        string            `db:"phone"`
+	Address      string            `db:"address"`
+	Roles        sqldb.StringArray `db:"roles"`
+	PasswordHash []byte            `db:"password_hash"`
+	Enabled      bool              `db:"enabled"`
+	DateCreated  time.Time         `db:"date_created"`
+	DateUpdated  time.Time         `db:"date_updated"`
+}
+
+// toDBUser converts the user to the row to store, with the sensitive fields
+// sealed by the keyring.
+func toDBUser(keys *envelope.Keyring, bus userbus.User) (user, error) {
+	roles := bus.Roles
+	if roles == nil {
+		roles = []string{}
+	}
+
+	db := user{
+		ID:           bus.ID,
+		TenantID:     bus.TenantID,
+		Name:         bus.Name,
+		Email:        bus.Email,
+		Phone:        bus.Phone,
+		Address:      bus.Address,
+		Roles:        roles,
+		PasswordHash: bus.PasswordHash,
+		Enabled:      bus.Enabled,
+		DateCreated:  bus.DateCreated.UTC(),
+		DateUpdated:  bus.DateUpdated.UTC(),
+	}
+
+	for _, f := range sensitive(&db) {
+		if *f.value == "" {
+			continue
+		}
+
+		sealed, err := keys.Seal([]byte(*f.value), additionalData(db.ID, f.name))
+		if err != nil {
+			return user{}, fmt.Errorf("sealing %s: %w", f.name, err)
+		}
+		*f.value = sealed
+	}
+
+	return db, nil
+}
+
+// toBusUser converts the stored row to a user, with the sensitive fields
+// opened by the keyring.
+func toBusUser(keys *envelope.Keyring, db user) (userbus.User, error) {
+	for _, f := range sensitive(&db) {
+		if *f.value == "" {
+			continue
+		}
+
+		plaintext, err := keys.Open(*f.value, additionalData(db.ID, f.name))
+		if err != nil {
+			return userbus.User{}, fmt.Errorf("opening %s of user %s: %w", f.name, db.ID, err)
+		}
+		*f.value = string(plaintext)
+	}
+
+	usr := userbus.User{
+		ID:           db.ID,
+		TenantID:     db.TenantID,
+		Name:         db.Name,
+		Email:        db.Email,
+		Phone:        db.Phone,
+		Address:      db.Address,
+		Roles:        db.Roles,
+		PasswordHash: db.PasswordHash,
+		Enabled:      db.Enabled,
+		DateCreated:  db.DateCreated.In(time.Local),
+		DateUpdated:  db.DateUpdated.In(time.Local),
+	}
+
+	return usr, nil
+}
+
+func toBusUsers(keys *envelope.Keyring, dbs []user) ([]userbus.User, error) {
+	bus := make([]userbus.User, len(dbs))
+
+	for i, db := range dbs {
+		usr, err := toBusUser(keys, db)
+		if err != nil {
+			return nil, err
+		}
+		bus[i] = usr
+	}
+
+	return bus, nil
+}
+
+// field is a sensitive field of a user by the name of its column.
+type field struct {
+	name  string
+	value *string
+}
+
+// sensitive returns the fields of the row that are stored encrypted.
+func sensitive(db *user) []field {
+	return []field{
+		{name: "phone", value: &db.Phone},
+		{name: "address", value: &db.Address},
+	}
+}
+
+// additionalData ties a sealed value to the field of the user it was
+// sealed for. It's the same as the memory store's so the values are sealed
+// the same way wherever they're kept.
+func additionalData(userID uuid.UUID, name string) []byte {
+	return []byte("users." + name + ":" + userID.String())
+}
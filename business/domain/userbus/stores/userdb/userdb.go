@@ -0,0 +1,397 @@
+// Package userdb contains user related CRUD functionality. The phone and
+// address of the users are sealed by the keyring before they're written and
+// opened when they're read, so the database only ever holds them encrypted.
+package userdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"lobbyte.com/alkeepy/business/domain/userbus"
+	"lobbyte.com/alkeepy/business/sdk/order"
+	"lobbyte.com/alkeepy/business/sdk/page"
+	"lobbyte.com/alkeepy/business/sdk/sqldb"
+	"lobbyte.com/alkeepy/business/sdk/tenant"
+	"lobbyte.com/alkeepy/foundation/envelope"
+)
+
+// Store manages the set of APIs for user database access.
+type Store struct {
+	log     *slog.Logger
+	cluster *sqldb.Cluster
+	keys    *envelope.Keyring
+	tx      sqlx.ExtContext
+}
+
+// NewStore constructs the api for data access, sealing the sensitive fields
+// of the users with the keyring.
+func NewStore(log *slog.Logger, cluster *sqldb.Cluster, keys *envelope.Keyring) *Store {
+	return &Store{
+		log:     log,
+		cluster: cluster,
+		keys:    keys,
+	}
+}
+
+// ExecuteUnderTransaction constructs a new Store value that runs every query
+// inside the transaction, reads included.
+func (s *Store) ExecuteUnderTransaction(tx sqldb.CommitRollbacker) (userbus.Storer, error) {
+	ec, err := sqldb.GetExtContext(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	store := Store{
+		log:     s.log,
+		cluster: s.cluster,
+		keys:    s.keys,
+		tx:      ec,
+	}
+
+	return &store, nil
+}
+
+// read runs a query that only reads data. Outside of a transaction it goes
+// to a replica when one is healthy and is retried after a transient failure,
+// picking the database again on each attempt. It's refused when the context
+// isn't scoped to a tenant.
+func (s *Store) read(ctx context.Context, fn func(ctx context.Context, db sqlx.ExtContext) error) error {
+	if _, err := tenant.Get(ctx); err != nil {
+		return err
+	}
+
+	if s.tx != nil {
+		return fn(ctx, s.tx)
+	}
+
+	return sqldb.Retry(ctx, s.log, sqldb.DefaultBackoff, func(ctx context.Context) error {
+		return fn(ctx, s.cluster.Reader())
+	})
+}
+
+// primary returns the transaction when there is one, otherwise the primary.
+func (s *Store) primary() sqlx.ExtContext {
+	if s.tx != nil {
+		return s.tx
+	}
+
+	return s.cluster.Primary()
+}
+
+// Create inserts a new user into the tenant in the context.
+func (s *Store) Create(ctx context.Context, usr userbus.User) error {
+	if _, err := tenant.Get(ctx); err != nil {
+		return err
+	}
+
+	dbUsr, err := toDBUser(s.keys, usr)
+	if err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+
+	const q = `
+	INSERT INTO users
+		(user_id, tenant_id, name, email, phone, address, roles, password_hash, enabled, date_created, date_updated, created_by, updated_by)
+	VALUES
+		(:user_id, :tenant_id, :name, :email, :phone, :address, :roles, :password_hash, :enabled, :date_created, :date_updated, :created_by, :updated_by)`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.primary(), q, dbUsr); err != nil {
+		if errors.Is(err, sqldb.ErrDBDuplicatedEntry) {
+			return fmt.Errorf("namedexeccontext: %w", userbus.ErrUniqueEmail)
+		}
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// Update replaces a user in the database.
+func (s *Store) Update(ctx context.Context, usr userbus.User) error {
+	if _, err := tenant.Get(ctx); err != nil {
+		return err
+	}
+
+	dbUsr, err := toDBUser(s.keys, usr)
+	if err != nil {
+		return fmt.Errorf("update: %w", err)
+	}
+
+	const q = `
+	UPDATE
+		users
+	SET
+		name = :name,
+		email = :email,
+		phone = :phone,
+		address = :address,
+		roles = :roles,
+		password_hash = :password_hash,
+		enabled = :enabled,
+		date_updated = :date_updated,
+		updated_by = :updated_by
+	WHERE
+		user_id = :user_id AND tenant_id = :tenant_id
+	RETURNING
+		user_id`
+
+	var updated struct {
+		ID uuid.UUID `db:"user_id"`
+	}
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.primary(), q, dbUsr, &updated); err != nil {
+		switch {
+		case errors.Is(err, sqldb.ErrDBNotFound):
+			return fmt.Errorf("namedquerystruct: %w", userbus.ErrNotFound)
+		case errors.Is(err, sqldb.ErrDBDuplicatedEntry):
+			return fmt.Errorf("namedquerystruct: %w", userbus.ErrUniqueEmail)
+		}
+		return fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return nil
+}
+
+// Delete removes a user from the database. Deleting a user that doesn't
+// exist isn't an error.
+func (s *Store) Delete(ctx context.Context, usr userbus.User) error {
+	if _, err := tenant.Get(ctx); err != nil {
+		return err
+	}
+
+	data := struct {
+		ID string `db:"user_id"`
+	}{
+		ID: usr.ID.String(),
+	}
+
+	const q = `
+	DELETE FROM
+		users
+	WHERE
+		user_id = :user_id AND tenant_id = :tenant_id`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.primary(), q, data); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// Query retrieves the page of users that match the filter in the specified
+// order.
+func (s *Store) Query(ctx context.Context, filter userbus.QueryFilter, orderBy order.By, pg page.Page) ([]userbus.User, error) {
+	data := map[string]any{}
+
+	const q = `
+	SELECT
+		user_id, tenant_id, name, email, phone, address, roles, password_hash, enabled, date_created, date_updated
+	FROM
+		users`
+
+	orderClause, err := orderByClause(orderBy)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf strings.Builder
+	buf.WriteString(q)
+	applyFilter(filter, data, &buf)
+	buf.WriteString(" ORDER BY " + orderClause)
+	sqldb.AddPageClause(&buf, data, pg)
+
+	var dbUsers []user
+	err = s.read(ctx, func(ctx context.Context, db sqlx.ExtContext) error {
+		return sqldb.NamedQuerySlice(ctx, s.log, db, buf.String(), data, &dbUsers)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return toBusUsers(s.keys, dbUsers)
+}
+
+// Count returns the total number of users that match the filter.
+func (s *Store) Count(ctx context.Context, filter userbus.QueryFilter) (int, error) {
+	data := map[string]any{}
+
+	const q = `
+	SELECT
+		count(1)
+	FROM
+		users`
+
+	var buf strings.Builder
+	buf.WriteString(q)
+	applyFilter(filter, data, &buf)
+
+	var count struct {
+		Count int `db:"count"`
+	}
+	err := s.read(ctx, func(ctx context.Context, db sqlx.ExtContext) error {
+		return sqldb.NamedQueryStruct(ctx, s.log, db, buf.String(), data, &count)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return count.Count, nil
+}
+
+// QueryByID gets the specified user from the database.
+func (s *Store) QueryByID(ctx context.Context, userID uuid.UUID) (userbus.User, error) {
+	data := struct {
+		ID string `db:"user_id"`
+	}{
+		ID: userID.String(),
+	}
+
+	const q = `
+	SELECT
+		user_id, tenant_id, name, email, phone, address, roles, password_hash, enabled, date_created, date_updated
+	FROM
+		users
+	WHERE
+		user_id = :user_id AND tenant_id = :tenant_id`
+
+	return s.queryOne(ctx, q, data)
+}
+
+// QueryByEmail gets the user of the tenant with the specified email,
+// ignoring case.
+func (s *Store) QueryByEmail(ctx context.Context, email string) (userbus.User, error) {
+	data := struct {
+		Email string `db:"email"`
+	}{
+		Email: email,
+	}
+
+	const q = `
+	SELECT
+		user_id, tenant_id, name, email, phone, address, roles, password_hash, enabled, date_created, date_updated
+	FROM
+		users
+	WHERE
+		lower(email) = lower(:email) AND tenant_id = :tenant_id`
+
+	return s.queryOne(ctx, q, data)
+}
+
+// Rewrap seals the fields of up to batchSize users sealed with a key that
+// isn't the primary key again with the primary key. It's run by the system
+// on behalf of every tenant so it isn't scoped to one. The rows are locked
+// while they're rewrapped and the rows locked by another instance are
+// skipped, so every instance can run it.
+func (s *Store) Rewrap(ctx context.Context, batchSize int) (int, error) {
+	data := struct {
+		Primary string `db:"primary_key"`
+		Limit   int    `db:"limit"`
+	}{
+		Primary: s.keys.Primary(),
+		Limit:   batchSize,
+	}
+
+	// The id of the key is the second part of a sealed value. The colon is
+	// doubled so it isn't taken for a named parameter.
+	const q = `
+	SELECT
+		user_id, phone, address
+	FROM
+		users
+	WHERE
+		(phone <> '' AND split_part(phone, '::', 2) <> :primary_key) OR
+		(address <> '' AND split_part(address, '::', 2) <> :primary_key)
+	LIMIT :limit
+	FOR UPDATE SKIP LOCKED`
+
+	const u = `
+	UPDATE
+		users
+	SET
+		phone = :phone,
+		address = :address
+	WHERE
+		user_id = :user_id`
+
+	var n int
+	err := s.transact(ctx, func(ctx context.Context, db sqlx.ExtContext) error {
+		var dbUsers []user
+		if err := sqldb.NamedQuerySlice(ctx, s.log, db, q, data, &dbUsers); err != nil {
+			return fmt.Errorf("namedqueryslice: %w", err)
+		}
+
+		for _, dbUsr := range dbUsers {
+			for _, f := range sensitive(&dbUsr) {
+				if *f.value == "" {
+					continue
+				}
+
+				rewrapped, err := s.keys.Rewrap(*f.value)
+				if err != nil {
+					return fmt.Errorf("rewrapping %s of user %s: %w", f.name, dbUsr.ID, err)
+				}
+				*f.value = rewrapped
+			}
+
+			if err := sqldb.NamedExecContext(ctx, s.log, db, u, dbUsr); err != nil {
+				return fmt.Errorf("namedexeccontext: %w", err)
+			}
+		}
+
+		n = len(dbUsers)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+// transact runs the function in the transaction when there is one,
+// otherwise in a transaction of its own on the primary.
+func (s *Store) transact(ctx context.Context, fn func(ctx context.Context, db sqlx.ExtContext) error) error {
+	if s.tx != nil {
+		return fn(ctx, s.tx)
+	}
+
+	tx, err := s.cluster.Primary().BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(ctx, tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+
+	return nil
+}
+
+// queryOne gets the single user the query selects.
+func (s *Store) queryOne(ctx context.Context, q string, data any) (userbus.User, error) {
+	var dbUsr user
+	err := s.read(ctx, func(ctx context.Context, db sqlx.ExtContext) error {
+		return sqldb.NamedQueryStruct(ctx, s.log, db, q, data, &dbUsr)
+	})
+	if err != nil {
+		if errors.Is(err, sqldb.ErrDBNotFound) {
+			return userbus.User{}, fmt.Errorf("namedquerystruct: %w", userbus.ErrNotFound)
+		}
+		return userbus.User{}, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	usr, err := toBusUser(s.keys, dbUsr)
+	if err != nil {
+		return userbus.User{}, fmt.Errorf("query: %w", err)
+	}
+
+	return usr, nil
+}
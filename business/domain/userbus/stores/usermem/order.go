@@ -0,0 +1,47 @@
+package usermem
+
+import (
+	"fmt"
+	"strings"
+
+	"lobbyte.com/alkeepy/business/domain/userbus"
+	"lobbyte.com/alkeepy/business/sdk/order"
+)
+
+// compareFunc returns the function that sorts the users in the specified
+// order, using the id to break ties.
+func compareFunc(orderBy order.By) (func(a, b userbus.User) int, error) {
+	var byField func(a, b userbus.User) int
+
+	switch orderBy.Field {
+	case userbus.OrderByID:
+		byField = func(a, b userbus.User) int { return 0 }
+	case userbus.OrderByName:
+		byField = func(a, b userbus.User) int { return strings.Compare(a.Name, b.Name) }
+	case userbus.OrderByEmail:
+		byField = func(a, b userbus.User) int { return strings.Compare(a.Email, b.Email) }
+	case userbus.OrderByDateCreated:
+		byField = func(a, b userbus.User) int { return a.DateCreated.Compare(b.DateCreated) }
+	default:
+		return nil, fmt.Errorf("field %q does not exist", orderBy.Field)
+	}
+
+	desc := orderBy.Direction == order.DESC
+
+	f := func(a, b userbus.User) int {
+		if c := byField(a, b); c != 0 {
+			if desc {
+				return -c
+			}
+			return c
+		}
+
+		c := strings.Compare(a.ID.String(), b.ID.String())
+		if desc && orderBy.Field == userbus.OrderByID {
+			return -c
+		}
+		return c
+	}
+
+	return f, nil
+}
@@ -0,0 +1,366 @@
+// Package usermem contains user related CRUD functionality backed by memory
+// so the business logic can be exercised without a database. The users are
+// lost when the process exits. Like they would be in the database, the
+// phone and address of the users are kept encrypted.
+package usermem
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"lobbyte.com/alkeepy/business/domain/userbus"
+	"lobbyte.com/alkeepy/business/sdk/order"
+	"lobbyte.com/alkeepy/business/sdk/page"
+	"lobbyte.com/alkeepy/business/sdk/sqldb"
+	"lobbyte.com/alkeepy/business/sdk/tenant"
+	"lobbyte.com/alkeepy/foundation/envelope"
+)
+
+// Store manages the set of APIs for user in memory access. The users are
+// held with their sensitive fields sealed by the keyring.
+type Store struct {
+	keys  *envelope.Keyring
+	mu    sync.RWMutex
+	users map[uuid.UUID]userbus.User
+}
+
+// NewStore constructs the api for data access, holding the specified users.
+func NewStore(keys *envelope.Keyring, users ...userbus.User) (*Store, error) {
+	s := Store{
+		keys:  keys,
+		users: make(map[uuid.UUID]userbus.User, len(users)),
+	}
+
+	for _, usr := range users {
+		sealed, err := s.seal(usr)
+		if err != nil {
+			return nil, err
+		}
+		s.users[usr.ID] = sealed
+	}
+
+	return &s, nil
+}
+
+// ExecuteUnderTransaction returns the store itself. Users kept in memory
+// can't take part in a database transaction, so changes are kept even when
+// the transaction is rolled back.
+func (s *Store) ExecuteUnderTransaction(tx sqldb.CommitRollbacker) (userbus.Storer, error) {
+	return s, nil
+}
+
+// Create inserts a new user into the tenant in the context. Emails are
+// unique within a tenant ignoring case, like the database constraint.
+func (s *Store) Create(ctx context.Context, usr userbus.User) error {
+	tenantID, err := tenant.Get(ctx)
+	if err != nil {
+		return err
+	}
+	usr.TenantID = tenantID
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.emailTaken(usr) {
+		return fmt.Errorf("create: %w", userbus.ErrUniqueEmail)
+	}
+
+	sealed, err := s.seal(usr)
+	if err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+
+	s.users[usr.ID] = sealed
+
+	return nil
+}
+
+// Update replaces a user.
+func (s *Store) Update(ctx context.Context, usr userbus.User) error {
+	tenantID, err := tenant.Get(ctx)
+	if err != nil {
+		return err
+	}
+	usr.TenantID = tenantID
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if u, exists := s.users[usr.ID]; !exists || u.TenantID != tenantID {
+		return fmt.Errorf("update: %w", userbus.ErrNotFound)
+	}
+
+	if s.emailTaken(usr) {
+		return fmt.Errorf("update: %w", userbus.ErrUniqueEmail)
+	}
+
+	sealed, err := s.seal(usr)
+	if err != nil {
+		return fmt.Errorf("update: %w", err)
+	}
+
+	s.users[usr.ID] = sealed
+
+	return nil
+}
+
+// Delete removes a user. Deleting a user that doesn't exist isn't an error.
+func (s *Store) Delete(ctx context.Context, usr userbus.User) error {
+	tenantID, err := tenant.Get(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if u, exists := s.users[usr.ID]; exists && u.TenantID == tenantID {
+		delete(s.users, usr.ID)
+	}
+
+	return nil
+}
+
+// Query retrieves the page of users that match the filter in the specified
+// order.
+func (s *Store) Query(ctx context.Context, filter userbus.QueryFilter, orderBy order.By, pg page.Page) ([]userbus.User, error) {
+	tenantID, err := tenant.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cmp, err := compareFunc(orderBy)
+	if err != nil {
+		return nil, err
+	}
+
+	users, err := s.match(tenantID, filter)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	slices.SortFunc(users, cmp)
+
+	start := min(pg.Offset(), len(users))
+	end := min(start+pg.RowsPerPage(), len(users))
+
+	return users[start:end], nil
+}
+
+// Count returns the total number of users that match the filter.
+func (s *Store) Count(ctx context.Context, filter userbus.QueryFilter) (int, error) {
+	tenantID, err := tenant.Get(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	users, err := s.match(tenantID, filter)
+	if err != nil {
+		return 0, fmt.Errorf("count: %w", err)
+	}
+
+	return len(users), nil
+}
+
+// QueryByID gets the specified user.
+func (s *Store) QueryByID(ctx context.Context, userID uuid.UUID) (userbus.User, error) {
+	tenantID, err := tenant.Get(ctx)
+	if err != nil {
+		return userbus.User{}, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	usr, exists := s.users[userID]
+	if !exists || usr.TenantID != tenantID {
+		return userbus.User{}, fmt.Errorf("query: %w", userbus.ErrNotFound)
+	}
+
+	usr, err = s.open(usr)
+	if err != nil {
+		return userbus.User{}, fmt.Errorf("query: %w", err)
+	}
+
+	return usr, nil
+}
+
+// QueryByEmail gets the user of the tenant with the specified email,
+// ignoring case.
+func (s *Store) QueryByEmail(ctx context.Context, email string) (userbus.User, error) {
+	tenantID, err := tenant.Get(ctx)
+	if err != nil {
+		return userbus.User{}, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, usr := range s.users {
+		if usr.TenantID == tenantID && strings.EqualFold(usr.Email, email) {
+			usr, err := s.open(usr)
+			if err != nil {
+				return userbus.User{}, fmt.Errorf("query: %w", err)
+			}
+			return usr, nil
+		}
+	}
+
+	return userbus.User{}, fmt.Errorf("query: %w", userbus.ErrNotFound)
+}
+
+// Rewrap seals the fields of up to batchSize users sealed with a key that
+// isn't the primary key again with the primary key. Like in the database it
+// covers the users of every tenant.
+func (s *Store) Rewrap(ctx context.Context, batchSize int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var n int
+	for id, usr := range s.users {
+		if n >= batchSize {
+			break
+		}
+
+		rewrapped, changed, err := s.rewrap(usr)
+		if err != nil {
+			return n, fmt.Errorf("rewrap: %w", err)
+		}
+
+		if changed {
+			s.users[id] = rewrapped
+			n++
+		}
+	}
+
+	return n, nil
+}
+
+// match returns a copy of the users of the tenant that satisfy the filter.
+// The filter doesn't look at the sealed fields, so only the users that
+// match are opened.
+func (s *Store) match(tenantID uuid.UUID, filter userbus.QueryFilter) ([]userbus.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var users []userbus.User
+	for _, usr := range s.users {
+		if usr.TenantID == tenantID && filter.Match(usr) {
+			usr, err := s.open(usr)
+			if err != nil {
+				return nil, err
+			}
+			users = append(users, usr)
+		}
+	}
+
+	return users, nil
+}
+
+// emailTaken reports whether another user of the same tenant already has
+// the email of the specified user. The caller must hold the lock.
+func (s *Store) emailTaken(usr userbus.User) bool {
+	for _, u := range s.users {
+		if u.ID != usr.ID && u.TenantID == usr.TenantID && strings.EqualFold(u.Email, usr.Email) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// seal returns a copy of the user to store with the sensitive fields
+// encrypted. Each value is tied to the user and the field it belongs to.
+func (s *Store) seal(usr userbus.User) (userbus.User, error) {
+	usr = clone(usr)
+
+	for _, f := range sensitive(&usr) {
+		if *f.value == "" {
+			continue
+		}
+
+		sealed, err := s.keys.Seal([]byte(*f.value), additionalData(usr.ID, f.name))
+		if err != nil {
+			return userbus.User{}, fmt.Errorf("sealing %s: %w", f.name, err)
+		}
+		*f.value = sealed
+	}
+
+	return usr, nil
+}
+
+// open returns a copy of the stored user with the sensitive fields
+// decrypted.
+func (s *Store) open(usr userbus.User) (userbus.User, error) {
+	usr = clone(usr)
+
+	for _, f := range sensitive(&usr) {
+		if *f.value == "" {
+			continue
+		}
+
+		plaintext, err := s.keys.Open(*f.value, additionalData(usr.ID, f.name))
+		if err != nil {
+			return userbus.User{}, fmt.Errorf("opening %s of user %s: %w", f.name, usr.ID, err)
+		}
+		*f.value = string(plaintext)
+	}
+
+	return usr, nil
+}
+
+// rewrap returns a copy of the stored user with the sensitive fields sealed
+// with the primary key, and whether any of them wasn't.
+func (s *Store) rewrap(usr userbus.User) (userbus.User, bool, error) {
+	usr = clone(usr)
+
+	var changed bool
+	for _, f := range sensitive(&usr) {
+		if *f.value == "" {
+			continue
+		}
+
+		rewrapped, err := s.keys.Rewrap(*f.value)
+		if err != nil {
+			return userbus.User{}, false, fmt.Errorf("rewrapping %s of user %s: %w", f.name, usr.ID, err)
+		}
+
+		if rewrapped != *f.value {
+			*f.value = rewrapped
+			changed = true
+		}
+	}
+
+	return usr, changed, nil
+}
+
+// field is a sensitive field of a user by the name of its column.
+type field struct {
+	name  string
+	value *string
+}
+
+// sensitive returns the fields of the user that are stored encrypted.
+func sensitive(usr *userbus.User) []field {
+	return []field{
+		{name: "phone", value: &usr.Phone},
+		{name: "address", value: &usr.Address},
+	}
+}
+
+// additionalData ties a sealed value to the field of the user it was
+// sealed for.
+func additionalData(userID uuid.UUID, name string) []byte {
+	return []byte("users." + name + ":" + userID.String())
+}
+
+// clone copies the slices of the user so callers can't change a stored user
+// through them.
+func clone(usr userbus.User) userbus.User {
+	usr.Roles = slices.Clone(usr.Roles)
+	usr.PasswordHash = slices.Clone(usr.PasswordHash)
+	return usr
+}
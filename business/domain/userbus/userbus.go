@@ -0,0 +1,340 @@
+// Package userbus provides business access to the user domain.
+package userbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"lobbyte.com/alkeepy/business/domain/auditbus"
+	"lobbyte.com/alkeepy/business/sdk/order"
+	"lobbyte.com/alkeepy/business/sdk/page"
+	"lobbyte.com/alkeepy/business/sdk/sqldb"
+	"lobbyte.com/alkeepy/business/sdk/tenant"
+)
+
+// Set of error variables for CRUD operations.
+var (
+	ErrNotFound              = errors.New("user not found")
+	ErrUniqueEmail           = errors.New("email is not unique")
+	ErrAuthenticationFailure = errors.New("authentication failed")
+)
+
+// Storer interface declares the behavior this package needs to persist and
+// retrieve data.
+type Storer interface {
+	ExecuteUnderTransaction(tx sqldb.CommitRollbacker) (Storer, error)
+	Create(ctx context.Context, usr User) error
+	Update(ctx context.Context, usr User) error
+	Delete(ctx context.Context, usr User) error
+	Query(ctx context.Context, filter QueryFilter, orderBy order.By, pg page.Page) ([]User, error)
+	Count(ctx context.Context, filter QueryFilter) (int, error)
+	QueryByID(ctx context.Context, userID uuid.UUID) (User, error)
+	QueryByEmail(ctx context.Context, email string) (User, error)
+	Rewrap(ctx context.Context, batchSize int) (int, error)
+}
+
+// Business manages the set of APIs for user access.
+type Business struct {
+	log      *slog.Logger
+	auditBus *auditbus.Business
+	storer   Storer
+}
+
+// NewBusiness constructs a user business API for use. The users created and
+// deleted, the changes to their roles and their logins are recorded with the
+// audit business API.
+func NewBusiness(log *slog.Logger, auditBus *auditbus.Business, storer Storer) *Business {
+	return &Business{
+		log:      log,
+		auditBus: auditBus,
+		storer:   storer,
+	}
+}
+
+// ExecuteUnderTransaction constructs a new Business value that will use the
+// specified transaction in any store related calls.
+func (b *Business) ExecuteUnderTransaction(tx sqldb.CommitRollbacker) (*Business, error) {
+	storer, err := b.storer.ExecuteUnderTransaction(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	auditBus, err := b.auditBus.ExecuteUnderTransaction(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	bus := Business{
+		log:      b.log,
+		auditBus: auditBus,
+		storer:   storer,
+	}
+
+	return &bus, nil
+}
+
+// Create adds a new user to the tenant in the context. The password is
+// stored as a bcrypt hash and the user starts enabled. The creation is
+// recorded in the audit log.
+func (b *Business) Create(ctx context.Context, nu NewUser) (User, error) {
+	tenantID, err := tenant.Get(ctx)
+	if err != nil {
+		return User{}, err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(nu.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return User{}, fmt.Errorf("generatefrompassword: %w", err)
+	}
+
+	now := time.Now().UTC()
+
+	usr := User{
+		ID:           uuid.New(),
+		TenantID:     tenantID,
+		Name:         nu.Name,
+		Email:        nu.Email,
+		Phone:        nu.Phone,
+		Address:      nu.Address,
+		Roles:        nu.Roles,
+		PasswordHash: hash,
+		Enabled:      true,
+		DateCreated:  now,
+		DateUpdated:  now,
+	}
+
+	if err := b.storer.Create(ctx, usr); err != nil {
+		return User{}, fmt.Errorf("create: %w", err)
+	}
+
+	msg := fmt.Sprintf("created with roles [%s]", strings.Join(usr.Roles, ", "))
+	if err := b.audit(ctx, sqldb.GetActor(ctx), auditbus.ActionCreate, usr, msg); err != nil {
+		return User{}, err
+	}
+
+	return usr, nil
+}
+
+// Update modifies information about a user. A change of the roles of the
+// user is recorded in the audit log, so it should be run under a transaction
+// for the change and its record to be kept together.
+func (b *Business) Update(ctx context.Context, usr User, uu UpdateUser) (User, error) {
+	roles := usr.Roles
+
+	if uu.Name != nil {
+		usr.Name = *uu.Name
+	}
+
+	if uu.Email != nil {
+		usr.Email = *uu.Email
+	}
+
+	if uu.Phone != nil {
+		usr.Phone = *uu.Phone
+	}
+
+	if uu.Address != nil {
+		usr.Address = *uu.Address
+	}
+
+	if uu.Roles != nil {
+		usr.Roles = uu.Roles
+	}
+
+	if uu.Password != nil {
+		hash, err := bcrypt.GenerateFromPassword([]byte(*uu.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return User{}, fmt.Errorf("generatefrompassword: %w", err)
+		}
+		usr.PasswordHash = hash
+	}
+
+	if uu.Enabled != nil {
+		usr.Enabled = *uu.Enabled
+	}
+
+	usr.DateUpdated = time.Now().UTC()
+
+	if err := b.storer.Update(ctx, usr); err != nil {
+		return User{}, fmt.Errorf("update: %w", err)
+	}
+
+	if !sameRoles(roles, usr.Roles) {
+		if err := b.auditRoleChange(ctx, usr, roles); err != nil {
+			return User{}, err
+		}
+	}
+
+	return usr, nil
+}
+
+// Delete removes the specified user and records it in the audit log.
+func (b *Business) Delete(ctx context.Context, usr User) error {
+	if err := b.storer.Delete(ctx, usr); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+
+	if err := b.audit(ctx, sqldb.GetActor(ctx), auditbus.ActionDelete, usr, "deleted"); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Query retrieves the page of users that match the filter in the specified
+// order.
+func (b *Business) Query(ctx context.Context, filter QueryFilter, orderBy order.By, pg page.Page) ([]User, error) {
+	users, err := b.storer.Query(ctx, filter, orderBy, pg)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	return users, nil
+}
+
+// Count returns the total number of users that match the filter.
+func (b *Business) Count(ctx context.Context, filter QueryFilter) (int, error) {
+	n, err := b.storer.Count(ctx, filter)
+	if err != nil {
+		return 0, fmt.Errorf("count: %w", err)
+	}
+
+	return n, nil
+}
+
+// QueryByID finds the user by the specified ID.
+func (b *Business) QueryByID(ctx context.Context, userID uuid.UUID) (User, error) {
+	usr, err := b.storer.QueryByID(ctx, userID)
+	if err != nil {
+		return User{}, fmt.Errorf("query: userID[%s]: %w", userID, err)
+	}
+
+	return usr, nil
+}
+
+// QueryByEmail finds the user by the specified email.
+func (b *Business) QueryByEmail(ctx context.Context, email string) (User, error) {
+	usr, err := b.storer.QueryByEmail(ctx, email)
+	if err != nil {
+		return User{}, fmt.Errorf("query: email[%s]: %w", email, err)
+	}
+
+	return usr, nil
+}
+
+// Authenticate finds the enabled user with the specified email and checks
+// the password. ErrAuthenticationFailure is returned for an unknown email, a
+// disabled user and a wrong password alike so the caller can't tell them
+// apart. The user that logs in is recorded in the audit log as the actor,
+// and a login that can't be recorded fails.
+func (b *Business) Authenticate(ctx context.Context, email string, password string) (User, error) {
+	usr, err := b.storer.QueryByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return User{}, ErrAuthenticationFailure
+		}
+		return User{}, fmt.Errorf("query: email[%s]: %w", email, err)
+	}
+
+	if !usr.Enabled {
+		return User{}, ErrAuthenticationFailure
+	}
+
+	if err := bcrypt.CompareHashAndPassword(usr.PasswordHash, []byte(password)); err != nil {
+		return User{}, ErrAuthenticationFailure
+	}
+
+	if err := b.audit(ctx, usr.ID.String(), auditbus.ActionLogin, usr, "logged in"); err != nil {
+		return User{}, err
+	}
+
+	return usr, nil
+}
+
+// Rewrap seals the sensitive fields of the users sealed with a key that's no
+// longer the primary key of the keyring again with the primary key, in
+// batches, and returns the number of users rewrapped. It's run by the system
+// on behalf of every tenant, so once it returns 0 the keys that aren't
+// primary can be removed.
+func (b *Business) Rewrap(ctx context.Context, batchSize int) (int, error) {
+	var total int
+
+	for {
+		n, err := b.storer.Rewrap(ctx, batchSize)
+		total += n
+		if err != nil {
+			return total, fmt.Errorf("rewrap: %w", err)
+		}
+
+		if n < batchSize {
+			return total, nil
+		}
+	}
+}
+
+// RewrapEvery rewraps the users sealed with a key that's no longer primary
+// on every interval until the context is canceled.
+func (b *Business) RewrapEvery(ctx context.Context, interval time.Duration, batchSize int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		n, err := b.Rewrap(ctx, batchSize)
+		switch {
+		case ctx.Err() != nil:
+			return
+		case err != nil:
+			b.log.ErrorContext(ctx, "user rewrap", "msg", err, "count", n)
+		case n > 0:
+			b.log.InfoContext(ctx, "user rewrap", "status", "sealed users with the primary key", "count", n)
+		}
+	}
+}
+
+// auditRoleChange records the change of the roles of the user.
+func (b *Business) auditRoleChange(ctx context.Context, usr User, old []string) error {
+	msg := fmt.Sprintf("roles changed from [%s] to [%s]", strings.Join(old, ", "), strings.Join(usr.Roles, ", "))
+
+	return b.audit(ctx, sqldb.GetActor(ctx), auditbus.ActionRoleChange, usr, msg)
+}
+
+// audit records the action the actor took on the user. A record that can't
+// be stored fails the action, which is rolled back with it when the business
+// runs under a transaction.
+func (b *Business) audit(ctx context.Context, actor string, action string, usr User, message string) error {
+	na := auditbus.NewAudit{
+		Actor:        actor,
+		Action:       action,
+		ResourceType: "user",
+		ResourceID:   usr.ID.String(),
+		Message:      message,
+	}
+
+	if _, err := b.auditBus.Create(ctx, na); err != nil {
+		return fmt.Errorf("audit: %w", err)
+	}
+
+	return nil
+}
+
+// sameRoles reports whether the lists hold the same roles in any order.
+func sameRoles(roles []string, other []string) bool {
+	a, b := slices.Clone(roles), slices.Clone(other)
+	slices.Sort(a)
+	slices.Sort(b)
+
+	return slices.Equal(slices.Compact(a), slices.Compact(b))
+}
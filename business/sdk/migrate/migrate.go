@@ -0,0 +1,415 @@
+// Package migrate contains the database schema and seed data, and the support
+// for bringing a database up to date with them. Both are embedded in the
+// binary so a schema change ships with the code that needs it.
+package migrate
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	_ "embed" // Calls init function.
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"lobbyte.com/alkeepy/business/sdk/sqldb"
+)
+
+var (
+	//go:embed sql/migrate.sql
+	migrateDoc string
+
+	//go:embed sql/seed.sql
+	seedDoc string
+)
+
+// lockID is the key of the advisory lock held while migrating so instances
+// started together don't apply the same migration twice.
+const lockID = 7207592042
+
+// Migration is a single versioned change to the schema.
+type Migration struct {
+	Version     float64
+	Description string
+	Script      string
+}
+
+// Checksum identifies the script so a migration that's changed after it was
+// applied can be detected.
+func (m Migration) Checksum() string {
+	sum := sha256.Sum256([]byte(m.Script))
+	return hex.EncodeToString(sum[:])
+}
+
+// Migrations returns the migrations defined in this package in the order
+// they're applied.
+func Migrations() ([]Migration, error) {
+	return Parse(migrateDoc)
+}
+
+// Parse splits a document into its migrations. Each migration starts with a
+// "-- Version:" line, may be followed by a "-- Description:" line, and runs
+// until the next version. Versions must be increasing.
+func Parse(doc string) ([]Migration, error) {
+	var migrations []Migration
+
+	for _, line := range strings.Split(doc, "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case strings.HasPrefix(trimmed, "-- Version:"):
+			v := strings.TrimSpace(strings.TrimPrefix(trimmed, "-- Version:"))
+
+			version, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil, fmt.Errorf("parsing version %q: %w", v, err)
+			}
+
+			if n := len(migrations); n > 0 && version <= migrations[n-1].Version {
+				return nil, fmt.Errorf("version %v must be greater than %v", version, migrations[n-1].Version)
+			}
+
+			migrations = append(migrations, Migration{Version: version})
+
+		case strings.HasPrefix(trimmed, "-- Description:"):
+			if len(migrations) == 0 {
+				return nil, errors.New("description found before the first version")
+			}
+			migrations[len(migrations)-1].Description = strings.TrimSpace(strings.TrimPrefix(trimmed, "-- Description:"))
+
+		default:
+			if len(migrations) == 0 {
+				if trimmed != "" {
+					return nil, errors.New("statement found before the first version")
+				}
+				continue
+			}
+			migrations[len(migrations)-1].Script += line + "\n"
+		}
+	}
+
+	for i := range migrations {
+		migrations[i].Script = strings.TrimSpace(migrations[i].Script)
+	}
+
+	return migrations, nil
+}
+
+// Set of states a migration can be in.
+const (
+	StateApplied = "applied"
+	StatePending = "pending"
+	StateChanged = "changed"
+	StateUnknown = "unknown"
+)
+
+// MigrationStatus describes the state of a single migration in a database.
+// A changed migration has been edited since it was applied, and an unknown
+// one was applied but is no longer defined in this package, usually because
+// the database was migrated by a newer build. Migrate refuses to run while
+// either is found.
+type MigrationStatus struct {
+	Version       float64    `json:"version"`
+	Description   string     `json:"description"`
+	State         string     `json:"state"`
+	AppliedAt     *time.Time `json:"appliedAt,omitempty"`
+	ExecutionTime string     `json:"executionTime,omitempty"`
+}
+
+// Status describes the state of the schema of a database. Current is the
+// latest version applied to it.
+type Status struct {
+	Current    float64           `json:"current"`
+	Applied    int               `json:"applied"`
+	Pending    int               `json:"pending"`
+	Drifted    int               `json:"drifted"`
+	Migrations []MigrationStatus `json:"migrations"`
+}
+
+// Report compares the migrations applied to the database with the ones
+// defined in this package, in version order. It only reads, so it's safe to
+// run against a database in use and before the first migration.
+func Report(ctx context.Context, db *sqlx.DB) (Status, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return Status{}, fmt.Errorf("parsing migrations: %w", err)
+	}
+
+	records, err := appliedRecords(ctx, db)
+	if err != nil {
+		return Status{}, err
+	}
+
+	status := Status{
+		Migrations: make([]MigrationStatus, 0, len(migrations)),
+	}
+
+	for _, m := range migrations {
+		ms := MigrationStatus{
+			Version:     m.Version,
+			Description: m.Description,
+			State:       StatePending,
+		}
+
+		if rec, ok := records[m.Version]; ok {
+			ms.State = StateApplied
+			if rec.checksum != m.Checksum() {
+				ms.State = StateChanged
+			}
+			ms.AppliedAt = &rec.appliedAt
+			ms.ExecutionTime = rec.executionTime.String()
+
+			delete(records, m.Version)
+		}
+
+		status.Migrations = append(status.Migrations, ms)
+	}
+
+	for version, rec := range records {
+		status.Migrations = append(status.Migrations, MigrationStatus{
+			Version:       version,
+			Description:   rec.description,
+			State:         StateUnknown,
+			AppliedAt:     &rec.appliedAt,
+			ExecutionTime: rec.executionTime.String(),
+		})
+	}
+
+	slices.SortFunc(status.Migrations, func(a, b MigrationStatus) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+
+	for _, ms := range status.Migrations {
+		switch ms.State {
+		case StatePending:
+			status.Pending++
+			continue
+		case StateApplied:
+			status.Applied++
+		default:
+			status.Drifted++
+		}
+		status.Current = max(status.Current, ms.Version)
+	}
+
+	return status, nil
+}
+
+// Migrate attempts to bring the database up to date with the migrations
+// defined in this package. Every pending migration is applied in a single
+// transaction, so either all of them are applied or none are.
+func Migrate(ctx context.Context, log *slog.Logger, db *sqlx.DB) error {
+	if err := sqldb.StatusCheck(ctx, db); err != nil {
+		return fmt.Errorf("status check database: %w", err)
+	}
+
+	migrations, err := Migrations()
+	if err != nil {
+		return fmt.Errorf("parsing migrations: %w", err)
+	}
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, lockID); err != nil {
+		return fmt.Errorf("acquiring lock: %w", err)
+	}
+
+	if err := createTable(ctx, tx); err != nil {
+		return err
+	}
+
+	applied, err := appliedMigrations(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	if err := verify(migrations, applied); err != nil {
+		return err
+	}
+
+	var count int
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+
+		start := time.Now()
+
+		if _, err := tx.ExecContext(ctx, m.Script); err != nil {
+			return fmt.Errorf("applying version %v %q: %w", m.Version, m.Description, err)
+		}
+
+		const q = `
+		INSERT INTO schema_migrations
+			(version, description, checksum, applied_at, execution_time)
+		VALUES
+			($1, $2, $3, $4, $5)`
+
+		if _, err := tx.ExecContext(ctx, q, m.Version, m.Description, m.Checksum(), time.Now().UTC(), time.Since(start).Milliseconds()); err != nil {
+			return fmt.Errorf("recording version %v: %w", m.Version, err)
+		}
+
+		log.InfoContext(ctx, "migrate", "status", "applied", "version", m.Version, "description", m.Description, "took", time.Since(start).String())
+		count++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+
+	log.InfoContext(ctx, "migrate", "status", "complete", "applied", count, "total", len(migrations))
+
+	return nil
+}
+
+// Seed loads the baseline dataset used by development and demo environments.
+// Rows that already exist are left untouched, so it's safe to run again.
+func Seed(ctx context.Context, log *slog.Logger, db *sqlx.DB) error {
+	if err := sqldb.StatusCheck(ctx, db); err != nil {
+		return fmt.Errorf("status check database: %w", err)
+	}
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, seedDoc); err != nil {
+		return fmt.Errorf("loading seed data: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+
+	log.InfoContext(ctx, "seed", "status", "complete")
+
+	return nil
+}
+
+// =============================================================================
+
+// createTable creates the table that records the applied migrations.
+func createTable(ctx context.Context, tx *sqlx.Tx) error {
+	const q = `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version        DOUBLE PRECISION NOT NULL,
+		description    TEXT             NOT NULL,
+		checksum       TEXT             NOT NULL,
+		applied_at     TIMESTAMP        NOT NULL,
+		execution_time BIGINT           NOT NULL,
+
+		PRIMARY KEY (version)
+	)`
+
+	if _, err := tx.ExecContext(ctx, q); err != nil {
+		return fmt.Errorf("creating migrations table: %w", err)
+	}
+
+	return nil
+}
+
+// appliedMigrations returns the checksum of every applied migration by
+// version.
+func appliedMigrations(ctx context.Context, tx *sqlx.Tx) (map[float64]string, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT version, checksum FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("querying applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[float64]string)
+	for rows.Next() {
+		var version float64
+		var checksum string
+		if err := rows.Scan(&version, &checksum); err != nil {
+			return nil, fmt.Errorf("scanning applied migration: %w", err)
+		}
+		applied[version] = checksum
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("querying applied migrations: %w", err)
+	}
+
+	return applied, nil
+}
+
+// record is a row of the table of applied migrations.
+type record struct {
+	description   string
+	checksum      string
+	appliedAt     time.Time
+	executionTime time.Duration
+}
+
+// appliedRecords returns the applied migrations by version without creating
+// the table that records them, which doesn't exist before the first
+// migration.
+func appliedRecords(ctx context.Context, db *sqlx.DB) (map[float64]record, error) {
+	var exists bool
+	if err := db.QueryRowContext(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("looking for migrations table: %w", err)
+	}
+
+	records := make(map[float64]record)
+	if !exists {
+		return records, nil
+	}
+
+	rows, err := db.QueryContext(ctx, `SELECT version, description, checksum, applied_at, execution_time FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("querying applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var version float64
+		var rec record
+		var ms int64
+		if err := rows.Scan(&version, &rec.description, &rec.checksum, &rec.appliedAt, &ms); err != nil {
+			return nil, fmt.Errorf("scanning applied migration: %w", err)
+		}
+		rec.executionTime = time.Duration(ms) * time.Millisecond
+		records[version] = rec
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("querying applied migrations: %w", err)
+	}
+
+	return records, nil
+}
+
+// verify makes sure the applied migrations are still defined and haven't
+// been changed since they were applied.
+func verify(migrations []Migration, applied map[float64]string) error {
+	defined := make(map[float64]Migration, len(migrations))
+	for _, m := range migrations {
+		defined[m.Version] = m
+	}
+
+	for version, checksum := range applied {
+		m, ok := defined[version]
+		if !ok {
+			return fmt.Errorf("applied version %v is no longer defined", version)
+		}
+
+		if m.Checksum() != checksum {
+			return fmt.Errorf("applied version %v %q has been changed", version, m.Description)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package migrate_test
+
+import (
+	"reflect"
+	"testing"
+
+	"lobbyte.com/alkeepy/business/sdk/migrate"
+)
+
+func Test_Parse(t *testing.T) {
+	tests := []struct {
+		name    string
+		doc     string
+		want    []migrate.Migration
+		wantErr bool
+	}{
+		{
+			name: "migrations",
+			doc: `
+-- Version: 1.01
+-- Description: Create table users
+CREATE TABLE users (
+	user_id UUID NOT NULL
+);
+
+-- Version: 1.02
+CREATE INDEX users_idx ON users (user_id);
+`,
+			want: []migrate.Migration{
+				{Version: 1.01, Description: "Create table users", Script: "CREATE TABLE users (\n\tuser_id UUID NOT NULL\n);"},
+				{Version: 1.02, Script: "CREATE INDEX users_idx ON users (user_id);"},
+			},
+		},
+		{name: "empty", doc: "\n\n"},
+		{name: "bad version", doc: "-- Version: one\nSELECT 1;", wantErr: true},
+		{name: "version not increasing", doc: "-- Version: 1.02\nSELECT 1;\n-- Version: 1.01\nSELECT 2;", wantErr: true},
+		{name: "repeated version", doc: "-- Version: 1.01\nSELECT 1;\n-- Version: 1.01\nSELECT 2;", wantErr: true},
+		{name: "description before the first version", doc: "-- Description: Orphan\n-- Version: 1.01\nSELECT 1;", wantErr: true},
+		{name: "statement before the first version", doc: "SELECT 1;\n-- Version: 1.01\nSELECT 2;", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := migrate.Parse(tt.doc)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Should refuse the document, got %v", got)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Should parse the document: %s", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Should split the document into its migrations:\ngot  %#v\nwant %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_Migrations(t *testing.T) {
+	migrations, err := migrate.Migrations()
+	if err != nil {
+		t.Fatalf("Should parse the embedded migrations: %s", err)
+	}
+
+	if len(migrations) == 0 {
+		t.Fatalf("Should define migrations")
+	}
+
+	for _, m := range migrations {
+		if m.Script == "" {
+			t.Fatalf("Should give version %v a script", m.Version)
+		}
+	}
+}
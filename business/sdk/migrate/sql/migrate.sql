@@ -0,0 +1,329 @@
+-- Version: 1.01
+-- Description: Create table users
+CREATE TABLE users (
+	user_id       UUID        NOT NULL,
+	name          TEXT        NOT NULL,
+	email         TEXT UNIQUE NOT NULL,
+	roles         TEXT[]      NOT NULL,
+	password_hash TEXT        NOT NULL,
+	enabled       BOOLEAN     NOT NULL,
+	date_created  TIMESTAMP   NOT NULL,
+	date_updated  TIMESTAMP   NOT NULL,
+
+	PRIMARY KEY (user_id)
+);
+
+-- Version: 1.02
+-- Description: Create table ingredients
+CREATE TABLE ingredients (
+	ingredient_id UUID        NOT NULL,
+	name          TEXT UNIQUE NOT NULL,
+	unit          TEXT        NOT NULL,
+	date_created  TIMESTAMP   NOT NULL,
+	date_updated  TIMESTAMP   NOT NULL,
+
+	PRIMARY KEY (ingredient_id)
+);
+
+-- Version: 1.03
+-- Description: Create table recipes
+CREATE TABLE recipes (
+	recipe_id    UUID      NOT NULL,
+	user_id      UUID      NOT NULL,
+	name         TEXT      NOT NULL,
+	description  TEXT      NOT NULL,
+	prep_minutes INT       NOT NULL,
+	tags         TEXT[]    NOT NULL,
+	date_created TIMESTAMP NOT NULL,
+	date_updated TIMESTAMP NOT NULL,
+
+	PRIMARY KEY (recipe_id),
+	FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
+);
+
+-- Version: 1.04
+-- Description: Create table recipe_ingredients
+CREATE TABLE recipe_ingredients (
+	recipe_id     UUID    NOT NULL,
+	ingredient_id UUID    NOT NULL,
+	quantity      NUMERIC NOT NULL,
+
+	PRIMARY KEY (recipe_id, ingredient_id),
+	FOREIGN KEY (recipe_id) REFERENCES recipes(recipe_id) ON DELETE CASCADE,
+	FOREIGN KEY (ingredient_id) REFERENCES ingredients(ingredient_id) ON DELETE RESTRICT
+);
+
+-- Version: 1.05
+-- Description: Create table pastries
+CREATE TABLE pastries (
+	pastry_id    UUID      NOT NULL,
+	recipe_id    UUID      NULL,
+	name         TEXT      NOT NULL,
+	category     TEXT      NOT NULL,
+	price_cents  INT       NOT NULL,
+	available    BOOLEAN   NOT NULL,
+	date_created TIMESTAMP NOT NULL,
+	date_updated TIMESTAMP NOT NULL,
+
+	PRIMARY KEY (pastry_id),
+	FOREIGN KEY (recipe_id) REFERENCES recipes(recipe_id) ON DELETE SET NULL
+);
+
+-- Version: 1.06
+-- Description: Create table audits
+CREATE TABLE audits (
+	audit_id      UUID      NOT NULL,
+	actor         TEXT      NOT NULL,
+	action        TEXT      NOT NULL,
+	resource_type TEXT      NOT NULL,
+	resource_id   TEXT      NOT NULL,
+	message       TEXT      NOT NULL,
+	timestamp     TIMESTAMP NOT NULL,
+
+	PRIMARY KEY (audit_id)
+);
+
+CREATE INDEX audits_timestamp_idx ON audits (timestamp DESC);
+
+-- Version: 1.07
+-- Description: Add soft delete to recipes
+ALTER TABLE recipes ADD COLUMN deleted_at TIMESTAMP NULL;
+
+CREATE INDEX recipes_deleted_at_idx ON recipes (deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- Version: 1.08
+-- Description: Add a version to recipes for optimistic concurrency
+ALTER TABLE recipes ADD COLUMN version INT NOT NULL DEFAULT 1;
+
+-- Version: 1.09
+-- Description: Add the columns recording who created and last updated a row
+ALTER TABLE users
+	ADD COLUMN created_by TEXT NOT NULL DEFAULT 'system',
+	ADD COLUMN updated_by TEXT NOT NULL DEFAULT 'system';
+
+ALTER TABLE ingredients
+	ADD COLUMN created_by TEXT NOT NULL DEFAULT 'system',
+	ADD COLUMN updated_by TEXT NOT NULL DEFAULT 'system';
+
+ALTER TABLE recipes
+	ADD COLUMN created_by TEXT NOT NULL DEFAULT 'system',
+	ADD COLUMN updated_by TEXT NOT NULL DEFAULT 'system';
+
+ALTER TABLE pastries
+	ADD COLUMN created_by TEXT NOT NULL DEFAULT 'system',
+	ADD COLUMN updated_by TEXT NOT NULL DEFAULT 'system';
+
+-- Version: 1.10
+-- Description: Scope the data to tenants so several bakeries can share a deployment
+CREATE TABLE tenants (
+	tenant_id    UUID      NOT NULL,
+	name         TEXT      NOT NULL,
+	date_created TIMESTAMP NOT NULL,
+	date_updated TIMESTAMP NOT NULL,
+
+	PRIMARY KEY (tenant_id)
+);
+
+INSERT INTO tenants (tenant_id, name, date_created, date_updated) VALUES
+	('e7a1c2d4-5b6f-4a8e-9c0d-1f2a3b4c5d6e', 'Default', now() AT TIME ZONE 'utc', now() AT TIME ZONE 'utc');
+
+ALTER TABLE users ADD COLUMN tenant_id UUID NOT NULL DEFAULT 'e7a1c2d4-5b6f-4a8e-9c0d-1f2a3b4c5d6e' REFERENCES tenants(tenant_id);
+ALTER TABLE users ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE users DROP CONSTRAINT users_email_key;
+ALTER TABLE users ADD CONSTRAINT users_tenant_id_email_key UNIQUE (tenant_id, email);
+
+ALTER TABLE ingredients ADD COLUMN tenant_id UUID NOT NULL DEFAULT 'e7a1c2d4-5b6f-4a8e-9c0d-1f2a3b4c5d6e' REFERENCES tenants(tenant_id);
+ALTER TABLE ingredients ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE ingredients DROP CONSTRAINT ingredients_name_key;
+ALTER TABLE ingredients ADD CONSTRAINT ingredients_tenant_id_name_key UNIQUE (tenant_id, name);
+
+ALTER TABLE recipes ADD COLUMN tenant_id UUID NOT NULL DEFAULT 'e7a1c2d4-5b6f-4a8e-9c0d-1f2a3b4c5d6e' REFERENCES tenants(tenant_id);
+ALTER TABLE recipes ALTER COLUMN tenant_id DROP DEFAULT;
+CREATE INDEX recipes_tenant_id_idx ON recipes (tenant_id);
+
+ALTER TABLE pastries ADD COLUMN tenant_id UUID NOT NULL DEFAULT 'e7a1c2d4-5b6f-4a8e-9c0d-1f2a3b4c5d6e' REFERENCES tenants(tenant_id);
+ALTER TABLE pastries ALTER COLUMN tenant_id DROP DEFAULT;
+CREATE INDEX pastries_tenant_id_idx ON pastries (tenant_id);
+
+-- Version: 1.11
+-- Description: Add full text search over the name, description and ingredients of recipes
+ALTER TABLE recipes
+	ADD COLUMN search_language REGCONFIG NOT NULL DEFAULT 'english',
+	ADD COLUMN search          TSVECTOR  NOT NULL DEFAULT '';
+
+-- recipe_search builds the search document of a recipe. The name weighs the
+-- most, then the description, then the names of the ingredients.
+CREATE FUNCTION recipe_search(r recipes) RETURNS TSVECTOR AS $$
+	SELECT
+		setweight(to_tsvector(r.search_language, r.name), 'A') ||
+		setweight(to_tsvector(r.search_language, r.description), 'B') ||
+		setweight(to_tsvector(r.search_language, coalesce(string_agg(i.name, ' '), '')), 'C')
+	FROM
+		recipe_ingredients ri
+	JOIN
+		ingredients i ON i.ingredient_id = ri.ingredient_id
+	WHERE
+		ri.recipe_id = r.recipe_id
+$$ LANGUAGE sql STABLE;
+
+CREATE FUNCTION recipes_search_trigger() RETURNS TRIGGER AS $$
+BEGIN
+	NEW.search := recipe_search(NEW);
+	RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER recipes_search_update
+	BEFORE INSERT OR UPDATE OF name, description, search_language ON recipes
+	FOR EACH ROW EXECUTE FUNCTION recipes_search_trigger();
+
+CREATE FUNCTION recipe_ingredients_search_trigger() RETURNS TRIGGER AS $$
+BEGIN
+	UPDATE recipes r SET search = recipe_search(r) WHERE r.recipe_id IN (OLD.recipe_id, NEW.recipe_id);
+	RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER recipe_ingredients_search_update
+	AFTER INSERT OR UPDATE OR DELETE ON recipe_ingredients
+	FOR EACH ROW EXECUTE FUNCTION recipe_ingredients_search_trigger();
+
+CREATE FUNCTION ingredients_search_trigger() RETURNS TRIGGER AS $$
+BEGIN
+	UPDATE recipes r SET search = recipe_search(r)
+	WHERE r.recipe_id IN (SELECT recipe_id FROM recipe_ingredients WHERE ingredient_id = NEW.ingredient_id);
+	RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER ingredients_search_update
+	AFTER UPDATE OF name ON ingredients
+	FOR EACH ROW EXECUTE FUNCTION ingredients_search_trigger();
+
+UPDATE recipes r SET search = recipe_search(r);
+
+CREATE INDEX recipes_search_idx ON recipes USING GIN (search);
+
+-- Version: 1.12
+-- Description: Add metadata to recipes for the attributes without a column of their own
+ALTER TABLE recipes ADD COLUMN metadata JSONB NOT NULL DEFAULT '{}';
+
+CREATE INDEX recipes_metadata_idx ON recipes USING GIN (metadata jsonb_path_ops);
+
+-- Version: 1.13
+-- Description: Notify the changes made to recipes so every instance can follow them
+CREATE FUNCTION recipes_notify_trigger() RETURNS TRIGGER AS $$
+DECLARE
+	r  recipes;
+	op TEXT;
+BEGIN
+	CASE
+		WHEN TG_OP = 'INSERT' THEN
+			r := NEW; op := 'created';
+		WHEN TG_OP = 'DELETE' THEN
+			r := OLD; op := 'purged';
+		WHEN NEW.deleted_at IS NOT NULL AND OLD.deleted_at IS NULL THEN
+			r := NEW; op := 'deleted';
+		WHEN NEW.deleted_at IS NULL AND OLD.deleted_at IS NOT NULL THEN
+			r := NEW; op := 'restored';
+		ELSE
+			r := NEW; op := 'updated';
+	END CASE;
+
+	PERFORM pg_notify('recipe_changes', json_build_object(
+		'op', op,
+		'recipe_id', r.recipe_id,
+		'tenant_id', r.tenant_id,
+		'version', r.version
+	)::text);
+
+	RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER recipes_notify_insert_delete
+	AFTER INSERT OR DELETE ON recipes
+	FOR EACH ROW EXECUTE FUNCTION recipes_notify_trigger();
+
+-- Rebuilding the search document isn't a change to report.
+CREATE TRIGGER recipes_notify_update
+	AFTER UPDATE ON recipes
+	FOR EACH ROW
+	WHEN (OLD.version IS DISTINCT FROM NEW.version OR OLD.deleted_at IS DISTINCT FROM NEW.deleted_at)
+	EXECUTE FUNCTION recipes_notify_trigger();
+
+-- Version: 1.14
+-- Description: Add the encrypted contact details of users
+-- The values are sealed by the application and start with the id of the
+-- key their data key is encrypted with, so the rows left to rotate are the
+-- ones that don't start with the primary key, like NOT LIKE 'v1:key-2:%'.
+ALTER TABLE users
+	ADD COLUMN phone   TEXT NOT NULL DEFAULT '',
+	ADD COLUMN address TEXT NOT NULL DEFAULT '';
+
+-- Version: 1.15
+-- Description: Create table outbox
+CREATE TABLE outbox (
+	outbox_id      UUID      NOT NULL,
+	tenant_id      UUID      NOT NULL REFERENCES tenants(tenant_id),
+	topic          TEXT      NOT NULL,
+	key            TEXT      NOT NULL,
+	payload        JSONB     NOT NULL,
+	attempts       INT       NOT NULL DEFAULT 0,
+	last_error     TEXT      NULL,
+	date_created   TIMESTAMP NOT NULL,
+	date_published TIMESTAMP NULL,
+
+	PRIMARY KEY (outbox_id)
+);
+
+-- The relay only ever looks for the messages waiting to be published.
+CREATE INDEX outbox_pending_idx ON outbox (date_created, outbox_id) WHERE date_published IS NULL;
+
+-- Version: 1.16
+-- Description: Archive old audits and index the published outbox messages
+-- Rows are moved with INSERT ... SELECT *, so a column added to audits must
+-- be added to audits_archive in the same position.
+CREATE TABLE audits_archive (LIKE audits INCLUDING ALL);
+
+CREATE INDEX outbox_published_idx ON outbox (date_published) WHERE date_published IS NOT NULL;
+
+-- Version: 1.17
+-- Description: Create table recipe_history
+-- Each row is a change made to a recipe with the recipe as it was before and
+-- after it. The version is the one the change left the recipe at.
+CREATE TABLE recipe_history (
+	history_id   UUID      NOT NULL,
+	recipe_id    UUID      NOT NULL REFERENCES recipes(recipe_id) ON DELETE CASCADE,
+	tenant_id    UUID      NOT NULL REFERENCES tenants(tenant_id),
+	version      INT       NOT NULL,
+	op           TEXT      NOT NULL,
+	actor        TEXT      NOT NULL,
+	old_recipe   JSONB     NOT NULL,
+	new_recipe   JSONB     NOT NULL,
+	date_created TIMESTAMP NOT NULL,
+
+	PRIMARY KEY (history_id)
+);
+
+CREATE INDEX recipe_history_recipe_id_idx ON recipe_history (recipe_id, date_created DESC);
+
+-- Version: 1.18
+-- Description: Create table orders
+-- The items are kept with the order since they're only ever read and written
+-- along with it.
+CREATE TABLE orders (
+	order_id     UUID      NOT NULL,
+	tenant_id    UUID      NOT NULL REFERENCES tenants(tenant_id),
+	user_id      UUID      NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+	items        JSONB     NOT NULL,
+	status       TEXT      NOT NULL,
+	total_cents  INT       NOT NULL,
+	date_created TIMESTAMP NOT NULL,
+	date_updated TIMESTAMP NOT NULL,
+
+	PRIMARY KEY (order_id)
+);
+
+CREATE INDEX orders_tenant_id_idx ON orders (tenant_id, date_created DESC);
+CREATE INDEX orders_status_idx ON orders (status, date_updated);
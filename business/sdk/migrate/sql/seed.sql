@@ -0,0 +1,52 @@
+-- The baseline dataset for development and demo environments. Every row has
+-- a fixed id and conflicts are ignored, so the seed can be loaded any number
+-- of times. The password of every user is "gophers". Everything belongs to
+-- the default tenant.
+
+INSERT INTO users (user_id, tenant_id, name, email, roles, password_hash, enabled, date_created, date_updated) VALUES
+	('5cf37266-3473-4006-984f-9325122678b7', 'e7a1c2d4-5b6f-4a8e-9c0d-1f2a3b4c5d6e', 'Admin Gopher', 'admin@example.com', '{ADMIN}', '$2a$10$rnsDxkP8FcuZYitw9Qzjtewsb6hzbcOMM5MDYGNoBXlFklxavGzgm', true, '2025-01-01 00:00:00', '2025-01-01 00:00:00'),
+	('45b5fbd3-755f-4379-8f07-a58d4a30fa2f', 'e7a1c2d4-5b6f-4a8e-9c0d-1f2a3b4c5d6e', 'Baker Gopher', 'baker@example.com', '{USER}', '$2a$10$rnsDxkP8FcuZYitw9Qzjtewsb6hzbcOMM5MDYGNoBXlFklxavGzgm', true, '2025-01-01 00:00:00', '2025-01-01 00:00:00')
+	ON CONFLICT DO NOTHING;
+
+INSERT INTO ingredients (ingredient_id, tenant_id, name, unit, date_created, date_updated) VALUES
+	('a2b0639f-2cc6-44b8-b97b-15d69dbb511e', 'e7a1c2d4-5b6f-4a8e-9c0d-1f2a3b4c5d6e', 'Flour', 'g', '2025-01-01 00:00:00', '2025-01-01 00:00:00'),
+	('72f8b983-3eb4-48db-9ed0-e45cc6bd716b', 'e7a1c2d4-5b6f-4a8e-9c0d-1f2a3b4c5d6e', 'Butter', 'g', '2025-01-01 00:00:00', '2025-01-01 00:00:00'),
+	('98b6d4b8-f04b-4c79-8c2e-a0aef46854b7', 'e7a1c2d4-5b6f-4a8e-9c0d-1f2a3b4c5d6e', 'Sugar', 'g', '2025-01-01 00:00:00', '2025-01-01 00:00:00'),
+	('85f6fb09-eb05-4874-ae39-82d1a30fe0d7', 'e7a1c2d4-5b6f-4a8e-9c0d-1f2a3b4c5d6e', 'Milk', 'ml', '2025-01-01 00:00:00', '2025-01-01 00:00:00'),
+	('a235be9e-ab5d-44e6-a987-fa1c749264c7', 'e7a1c2d4-5b6f-4a8e-9c0d-1f2a3b4c5d6e', 'Egg', 'unit', '2025-01-01 00:00:00', '2025-01-01 00:00:00'),
+	('1b8103a5-4ae5-4c3f-8a8a-2d1cc1c28e0a', 'e7a1c2d4-5b6f-4a8e-9c0d-1f2a3b4c5d6e', 'Yeast', 'g', '2025-01-01 00:00:00', '2025-01-01 00:00:00'),
+	('d9a4e2b6-0b3f-4a53-a3a6-3f43f03fa1c9', 'e7a1c2d4-5b6f-4a8e-9c0d-1f2a3b4c5d6e', 'Dark Chocolate', 'g', '2025-01-01 00:00:00', '2025-01-01 00:00:00'),
+	('c4f1f4a0-6c63-4f0e-9a0b-5e7e0b8a1d24', 'e7a1c2d4-5b6f-4a8e-9c0d-1f2a3b4c5d6e', 'Salt', 'g', '2025-01-01 00:00:00', '2025-01-01 00:00:00')
+	ON CONFLICT DO NOTHING;
+
+INSERT INTO recipes (recipe_id, tenant_id, user_id, name, description, prep_minutes, tags, date_created, date_updated) VALUES
+	('98b6d4b8-f04b-4c79-8c2e-a0aef46854b8', 'e7a1c2d4-5b6f-4a8e-9c0d-1f2a3b4c5d6e', '45b5fbd3-755f-4379-8f07-a58d4a30fa2f', 'Croissant', 'Laminated yeast dough rolled into crescents.', 180, '{viennoiserie,breakfast}', '2025-01-01 00:00:00', '2025-01-01 00:00:00'),
+	('85f6fb09-eb05-4874-ae39-82d1a30fe0d8', 'e7a1c2d4-5b6f-4a8e-9c0d-1f2a3b4c5d6e', '45b5fbd3-755f-4379-8f07-a58d4a30fa2f', 'Pain au Chocolat', 'Croissant dough folded around two bars of dark chocolate.', 190, '{viennoiserie,chocolate}', '2025-01-01 00:00:00', '2025-01-01 00:00:00'),
+	('a235be9e-ab5d-44e6-a987-fa1c749264c8', 'e7a1c2d4-5b6f-4a8e-9c0d-1f2a3b4c5d6e', '5cf37266-3473-4006-984f-9325122678b7', 'Sablé Cookies', 'Crumbly butter cookies.', 45, '{cookie,quick}', '2025-01-01 00:00:00', '2025-01-01 00:00:00')
+	ON CONFLICT DO NOTHING;
+
+INSERT INTO recipe_ingredients (recipe_id, ingredient_id, quantity) VALUES
+	('98b6d4b8-f04b-4c79-8c2e-a0aef46854b8', 'a2b0639f-2cc6-44b8-b97b-15d69dbb511e', 500),
+	('98b6d4b8-f04b-4c79-8c2e-a0aef46854b8', '72f8b983-3eb4-48db-9ed0-e45cc6bd716b', 280),
+	('98b6d4b8-f04b-4c79-8c2e-a0aef46854b8', '98b6d4b8-f04b-4c79-8c2e-a0aef46854b7', 55),
+	('98b6d4b8-f04b-4c79-8c2e-a0aef46854b8', '85f6fb09-eb05-4874-ae39-82d1a30fe0d7', 140),
+	('98b6d4b8-f04b-4c79-8c2e-a0aef46854b8', '1b8103a5-4ae5-4c3f-8a8a-2d1cc1c28e0a', 10),
+	('98b6d4b8-f04b-4c79-8c2e-a0aef46854b8', 'c4f1f4a0-6c63-4f0e-9a0b-5e7e0b8a1d24', 10),
+	('85f6fb09-eb05-4874-ae39-82d1a30fe0d8', 'a2b0639f-2cc6-44b8-b97b-15d69dbb511e', 500),
+	('85f6fb09-eb05-4874-ae39-82d1a30fe0d8', '72f8b983-3eb4-48db-9ed0-e45cc6bd716b', 280),
+	('85f6fb09-eb05-4874-ae39-82d1a30fe0d8', '98b6d4b8-f04b-4c79-8c2e-a0aef46854b7', 55),
+	('85f6fb09-eb05-4874-ae39-82d1a30fe0d8', '85f6fb09-eb05-4874-ae39-82d1a30fe0d7', 140),
+	('85f6fb09-eb05-4874-ae39-82d1a30fe0d8', '1b8103a5-4ae5-4c3f-8a8a-2d1cc1c28e0a', 10),
+	('85f6fb09-eb05-4874-ae39-82d1a30fe0d8', 'd9a4e2b6-0b3f-4a53-a3a6-3f43f03fa1c9', 120),
+	('a235be9e-ab5d-44e6-a987-fa1c749264c8', 'a2b0639f-2cc6-44b8-b97b-15d69dbb511e', 250),
+	('a235be9e-ab5d-44e6-a987-fa1c749264c8', '72f8b983-3eb4-48db-9ed0-e45cc6bd716b', 200),
+	('a235be9e-ab5d-44e6-a987-fa1c749264c8', '98b6d4b8-f04b-4c79-8c2e-a0aef46854b7', 100),
+	('a235be9e-ab5d-44e6-a987-fa1c749264c8', 'a235be9e-ab5d-44e6-a987-fa1c749264c7', 1)
+	ON CONFLICT DO NOTHING;
+
+INSERT INTO pastries (pastry_id, tenant_id, recipe_id, name, category, price_cents, available, date_created, date_updated) VALUES
+	('0f3d9c8e-5b1a-4e2f-9c7d-6a4b3e2d1c01', 'e7a1c2d4-5b6f-4a8e-9c0d-1f2a3b4c5d6e', '98b6d4b8-f04b-4c79-8c2e-a0aef46854b8', 'Croissant', 'viennoiserie', 250, true, '2025-01-01 00:00:00', '2025-01-01 00:00:00'),
+	('0f3d9c8e-5b1a-4e2f-9c7d-6a4b3e2d1c02', 'e7a1c2d4-5b6f-4a8e-9c0d-1f2a3b4c5d6e', '85f6fb09-eb05-4874-ae39-82d1a30fe0d8', 'Pain au Chocolat', 'viennoiserie', 290, true, '2025-01-01 00:00:00', '2025-01-01 00:00:00'),
+	('0f3d9c8e-5b1a-4e2f-9c7d-6a4b3e2d1c03', 'e7a1c2d4-5b6f-4a8e-9c0d-1f2a3b4c5d6e', 'a235be9e-ab5d-44e6-a987-fa1c749264c8', 'Sablé Cookies (box of 6)', 'cookie', 650, true, '2025-01-01 00:00:00', '2025-01-01 00:00:00'),
+	('0f3d9c8e-5b1a-4e2f-9c7d-6a4b3e2d1c04', 'e7a1c2d4-5b6f-4a8e-9c0d-1f2a3b4c5d6e', NULL, 'Seasonal Tart', 'tart', 480, false, '2025-01-01 00:00:00', '2025-01-01 00:00:00')
+	ON CONFLICT DO NOTHING;
@@ -0,0 +1,69 @@
+// Package order provides support for describing the ordering of data.
+package order
+
+import (
+	"fmt"
+	"strings"
+
+	"lobbyte.com/alkeepy/foundation/validate"
+)
+
+// Set of directions for data ordering.
+const (
+	ASC  = "ASC"
+	DESC = "DESC"
+)
+
+var directions = map[string]string{
+	ASC:  "ASC",
+	DESC: "DESC",
+}
+
+// By represents a field used to order by and direction.
+type By struct {
+	Field     string
+	Direction string
+}
+
+// NewBy constructs a new By value with no checks.
+func NewBy(field string, direction string) By {
+	return By{
+		Field:     field,
+		Direction: direction,
+	}
+}
+
+// Parse constructs a By value by parsing a string in the form of
+// "field,direction" ie "name,desc". The field must be one of the keys of the
+// mappings, which translates it into the field known by the business layer,
+// and the direction defaults to ascending. An empty string selects the
+// default order. The error is a validate.FieldErrors for the orderBy field.
+func Parse(fieldMappings map[string]string, orderBy string, defaultOrder By) (By, error) {
+	if orderBy == "" {
+		return defaultOrder, nil
+	}
+
+	orderParts := strings.Split(orderBy, ",")
+
+	orgFieldName := strings.TrimSpace(orderParts[0])
+	fieldName, exists := fieldMappings[orgFieldName]
+	if !exists {
+		return By{}, validate.FieldErrors{{Field: "orderBy", Err: fmt.Sprintf("unknown order field %q", orgFieldName)}}
+	}
+
+	switch len(orderParts) {
+	case 1:
+		return NewBy(fieldName, ASC), nil
+
+	case 2:
+		direction := strings.ToUpper(strings.TrimSpace(orderParts[1]))
+		if _, exists := directions[direction]; !exists {
+			return By{}, validate.FieldErrors{{Field: "orderBy", Err: fmt.Sprintf("unknown direction %q", orderParts[1])}}
+		}
+
+		return NewBy(fieldName, direction), nil
+
+	default:
+		return By{}, validate.FieldErrors{{Field: "orderBy", Err: fmt.Sprintf("unknown order %q, expected field,direction", orderBy)}}
+	}
+}
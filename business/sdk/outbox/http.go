@@ -0,0 +1,75 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// HTTPPublisher publishes the messages to an HTTP endpoint of the message
+// bus. Each message is posted as JSON with its id as the Idempotency-Key
+// header, and any 2xx status means the bus has accepted it.
+type HTTPPublisher struct {
+	url    string
+	client *http.Client
+}
+
+// NewHTTPPublisher constructs a publisher posting the messages to the url
+// with the client.
+func NewHTTPPublisher(url string, client *http.Client) *HTTPPublisher {
+	return &HTTPPublisher{
+		url:    url,
+		client: client,
+	}
+}
+
+// Publish implements the Publisher interface.
+func (p *HTTPPublisher) Publish(ctx context.Context, msg Message) error {
+	body := struct {
+		ID          string          `json:"id"`
+		TenantID    string          `json:"tenantID"`
+		Topic       string          `json:"topic"`
+		Key         string          `json:"key"`
+		Payload     json.RawMessage `json:"payload"`
+		DateCreated string          `json:"dateCreated"`
+	}{
+		ID:          msg.ID.String(),
+		TenantID:    msg.TenantID.String(),
+		Topic:       msg.Topic,
+		Key:         msg.Key,
+		Payload:     msg.Payload,
+		DateCreated: msg.DateCreated.Format(time.RFC3339Nano),
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("new request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", msg.ID.String())
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("post: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// The body is drained so the connection can be reused.
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("post: unexpected status %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,273 @@
+// Package outbox provides support for publishing events reliably. An event is
+// written to the outbox table in the same transaction as the change it
+// reports, so it's recorded if and only if the change is committed, and a
+// relay publishes the recorded events to the message bus afterwards.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"lobbyte.com/alkeepy/business/sdk/sqldb"
+)
+
+// Set of counters of the messages handled by the relays.
+var (
+	published = expvar.NewInt("outbox_published")
+	failed    = expvar.NewInt("outbox_failed")
+)
+
+// DefaultBatchSize is the number of messages a relay publishes in a
+// transaction when the batch size isn't set.
+const DefaultBatchSize = 100
+
+// Message is an event waiting in the outbox. The id is the same every time
+// the message is published, so the consumers can use it to discard the
+// duplicates. Key identifies what the event is about, like the id of a
+// recipe, so a bus can keep the events about the same thing in order.
+type Message struct {
+	ID          uuid.UUID
+	TenantID    uuid.UUID
+	Topic       string
+	Key         string
+	Payload     json.RawMessage
+	Attempts    int
+	DateCreated time.Time
+}
+
+// Publisher represents the message bus the relay publishes to. Publish must
+// only return once the bus has accepted the message.
+type Publisher interface {
+	Publish(ctx context.Context, msg Message) error
+}
+
+// Add writes a message about the tenant in the context to the outbox. It
+// must be called with the transaction making the change the message
+// reports.
+func Add(ctx context.Context, log *slog.Logger, db sqlx.ExtContext, topic string, key string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("marshal payload: %w", err)
+	}
+
+	msg := message{
+		ID:          uuid.New(),
+		Topic:       topic,
+		Key:         key,
+		Payload:     data,
+		DateCreated: time.Now().UTC(),
+	}
+
+	const q = `
+	INSERT INTO outbox
+		(outbox_id, tenant_id, topic, key, payload, date_created)
+	VALUES
+		(:outbox_id, :tenant_id, :topic, :key, :payload, :date_created)`
+
+	if err := sqldb.NamedExecContext(ctx, log, db, q, msg); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// =============================================================================
+
+// Relay publishes the messages waiting in the outbox in the order they were
+// written. A message is marked published only once the bus has accepted it,
+// so it's delivered at least once: a relay that stops between the two
+// publishes it again. Several relays can run against the same database,
+// each one skips the messages another is publishing.
+type Relay struct {
+	log       *slog.Logger
+	db        *sqlx.DB
+	publisher Publisher
+	batchSize int
+}
+
+// NewRelay constructs a relay publishing the messages of the database.
+func NewRelay(log *slog.Logger, db *sqlx.DB, publisher Publisher, batchSize int) *Relay {
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+
+	return &Relay{
+		log:       log,
+		db:        db,
+		publisher: publisher,
+		batchSize: batchSize,
+	}
+}
+
+// Run publishes the waiting messages on every interval until the context
+// is canceled. Full batches are followed by the next one right away so a
+// backlog drains quickly once the bus is back.
+func (r *Relay) Run(ctx context.Context, interval time.Duration) {
+	for {
+		n, err := r.Publish(ctx)
+		switch {
+		case ctx.Err() != nil:
+			return
+		case err != nil:
+			r.log.ErrorContext(ctx, "outbox relay", "msg", err)
+		case n == r.batchSize:
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// Publish publishes a batch of the waiting messages and returns how many
+// were published. It stops at the first message the bus refuses, which is
+// tried again by the next batch, so the messages after it aren't published
+// ahead of it.
+func (r *Relay) Publish(ctx context.Context) (int, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	data := struct {
+		Limit int `db:"limit"`
+	}{
+		Limit: r.batchSize,
+	}
+
+	const q = `
+	SELECT
+		outbox_id, tenant_id, topic, key, payload, attempts, date_created
+	FROM
+		outbox
+	WHERE
+		date_published IS NULL
+	ORDER BY
+		date_created, outbox_id
+	LIMIT :limit
+	FOR UPDATE SKIP LOCKED`
+
+	var msgs []message
+	if err := sqldb.NamedQuerySlice(ctx, r.log, tx, q, data, &msgs); err != nil {
+		return 0, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	var count int
+	var pubErr error
+	for _, m := range msgs {
+		if pubErr = r.publisher.Publish(ctx, m.toMessage()); pubErr != nil {
+			failed.Add(1)
+
+			if err := r.markFailed(ctx, tx, m, pubErr); err != nil {
+				return 0, err
+			}
+			break
+		}
+
+		if err := r.markPublished(ctx, tx, m); err != nil {
+			return 0, err
+		}
+		count++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit: %w", err)
+	}
+
+	published.Add(int64(count))
+
+	if pubErr != nil {
+		return count, fmt.Errorf("publishing message %s: %w", msgs[count].ID, pubErr)
+	}
+
+	return count, nil
+}
+
+// markPublished records that the bus has accepted the message. A message
+// that's already marked keeps the time it was first published.
+func (r *Relay) markPublished(ctx context.Context, tx *sqlx.Tx, m message) error {
+	data := struct {
+		ID            uuid.UUID `db:"outbox_id"`
+		DatePublished time.Time `db:"date_published"`
+	}{
+		ID:            m.ID,
+		DatePublished: time.Now().UTC(),
+	}
+
+	const q = `
+	UPDATE
+		outbox
+	SET
+		date_published = :date_published,
+		attempts = attempts + 1,
+		last_error = NULL
+	WHERE
+		outbox_id = :outbox_id AND date_published IS NULL`
+
+	if err := sqldb.NamedExecContext(ctx, r.log, tx, q, data); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// markFailed records the failed attempt to publish the message.
+func (r *Relay) markFailed(ctx context.Context, tx *sqlx.Tx, m message, pubErr error) error {
+	data := struct {
+		ID        uuid.UUID `db:"outbox_id"`
+		LastError string    `db:"last_error"`
+	}{
+		ID:        m.ID,
+		LastError: pubErr.Error(),
+	}
+
+	const q = `
+	UPDATE
+		outbox
+	SET
+		attempts = attempts + 1,
+		last_error = :last_error
+	WHERE
+		outbox_id = :outbox_id`
+
+	if err := sqldb.NamedExecContext(ctx, r.log, tx, q, data); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// =============================================================================
+
+// message is a row of the outbox.
+type message struct {
+	ID          uuid.UUID `db:"outbox_id"`
+	TenantID    uuid.UUID `db:"tenant_id"`
+	Topic       string    `db:"topic"`
+	Key         string    `db:"key"`
+	Payload     []byte    `db:"payload"`
+	Attempts    int       `db:"attempts"`
+	DateCreated time.Time `db:"date_created"`
+}
+
+func (m message) toMessage() Message {
+	return Message{
+		ID:          m.ID,
+		TenantID:    m.TenantID,
+		Topic:       m.Topic,
+		Key:         m.Key,
+		Payload:     m.Payload,
+		Attempts:    m.Attempts,
+		DateCreated: m.DateCreated.In(time.Local),
+	}
+}
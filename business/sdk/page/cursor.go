@@ -0,0 +1,92 @@
+package page
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"lobbyte.com/alkeepy/foundation/validate"
+)
+
+// Cursor marks the last row of a page so the next one can start right
+// after it. Rows are ordered by the time they were created and the id
+// breaks ties, so a page is found with an index seek however deep it is
+// and rows added meanwhile don't shift the pages.
+type Cursor struct {
+	DateCreated time.Time
+	ID          uuid.UUID
+}
+
+// Encode returns the opaque string clients send back for the next page.
+func (c Cursor) Encode() string {
+	s := c.DateCreated.UTC().Format(time.RFC3339Nano) + "|" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(s))
+}
+
+// String implements the stringer interface.
+func (c Cursor) String() string {
+	return fmt.Sprintf("after: %s %s", c.DateCreated.UTC().Format(time.RFC3339Nano), c.ID)
+}
+
+// DecodeCursor parses a string returned by Encode.
+func DecodeCursor(s string) (Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, errors.New("malformed cursor")
+	}
+
+	created, id, found := strings.Cut(string(b), "|")
+	if !found {
+		return Cursor{}, errors.New("malformed cursor")
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, created)
+	if err != nil {
+		return Cursor{}, errors.New("malformed cursor")
+	}
+
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		return Cursor{}, errors.New("malformed cursor")
+	}
+
+	return Cursor{DateCreated: t, ID: uid}, nil
+}
+
+// ParseCursor parses the strings of a page that starts after the cursor
+// and validates the values are in reason. The error is a
+// validate.FieldErrors naming the cursor and rows fields at fault.
+func ParseCursor(cursor string, rowsPerPage string) (Page, error) {
+	var fieldErrors validate.FieldErrors
+
+	c, err := DecodeCursor(cursor)
+	if err != nil {
+		fieldErrors = append(fieldErrors, validate.FieldError{Field: "cursor", Err: err.Error()})
+	}
+
+	pg, err := Parse("", rowsPerPage)
+	if err != nil {
+		fieldErrors = append(fieldErrors, validate.GetFieldErrors(err)...)
+	}
+
+	if fieldErrors != nil {
+		return Page{}, fieldErrors
+	}
+
+	pg.cursor = &c
+
+	return pg, nil
+}
+
+// Cursor returns the cursor the page starts after, if it's paged by
+// cursor.
+func (p Page) Cursor() (Cursor, bool) {
+	if p.cursor == nil {
+		return Cursor{}, false
+	}
+
+	return *p.cursor, true
+}
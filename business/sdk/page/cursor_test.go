@@ -0,0 +1,87 @@
+package page_test
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"lobbyte.com/alkeepy/business/sdk/page"
+	"lobbyte.com/alkeepy/foundation/validate"
+)
+
+func Test_CursorEncode(t *testing.T) {
+	c := page.Cursor{
+		DateCreated: time.Date(2026, 3, 4, 5, 6, 7, 890123456, time.FixedZone("", 3600)),
+		ID:          uuid.MustParse("e7a1c2d4-5b6f-4a8e-9c0d-1f2a3b4c5d6e"),
+	}
+
+	got, err := page.DecodeCursor(c.Encode())
+	if err != nil {
+		t.Fatalf("Should decode an encoded cursor: %s", err)
+	}
+
+	if !got.DateCreated.Equal(c.DateCreated) || got.ID != c.ID {
+		t.Fatalf("Should decode the cursor that was encoded, got %s, want %s", got, c)
+	}
+}
+
+func Test_DecodeCursorMalformed(t *testing.T) {
+	encode := func(s string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(s))
+	}
+
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{name: "not base64", cursor: "not a cursor!"},
+		{name: "no separator", cursor: encode("2026-03-04T05:06:07Z")},
+		{name: "bad time", cursor: encode("yesterday|e7a1c2d4-5b6f-4a8e-9c0d-1f2a3b4c5d6e")},
+		{name: "bad id", cursor: encode("2026-03-04T05:06:07Z|42")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := page.DecodeCursor(tt.cursor); err == nil {
+				t.Fatalf("Should refuse the cursor")
+			}
+		})
+	}
+}
+
+func Test_ParseCursor(t *testing.T) {
+	c := page.Cursor{
+		DateCreated: time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC),
+		ID:          uuid.MustParse("e7a1c2d4-5b6f-4a8e-9c0d-1f2a3b4c5d6e"),
+	}
+
+	pg, err := page.ParseCursor(c.Encode(), "20")
+	if err != nil {
+		t.Fatalf("Should parse the page: %s", err)
+	}
+
+	got, ok := pg.Cursor()
+	if !ok || !got.DateCreated.Equal(c.DateCreated) || got.ID != c.ID {
+		t.Fatalf("Should start the page after the cursor %s, got %s", c, got)
+	}
+
+	if pg.RowsPerPage() != 20 || pg.Number() != 0 || pg.Offset() != 0 {
+		t.Fatalf("Should select the rows by the cursor alone, got %s offset %d", pg, pg.Offset())
+	}
+
+	if _, ok := page.MustParse("3", "20").Cursor(); ok {
+		t.Fatalf("Should have no cursor for a page parsed by number")
+	}
+
+	_, err = page.ParseCursor("bad", "0")
+
+	fields := map[string]bool{}
+	for _, fe := range validate.GetFieldErrors(err) {
+		fields[fe.Field] = true
+	}
+
+	if !fields["cursor"] || !fields["rows"] {
+		t.Fatalf("Should report the cursor and rows fields, got %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+// Package page provides support for paging the results of list queries.
+package page
+
+import (
+	"fmt"
+	"strconv"
+
+	"lobbyte.com/alkeepy/foundation/validate"
+)
+
+// Set of bounds for the number of rows in a page.
+const (
+	DefaultRows = 10
+	MaxRows     = 100
+)
+
+// Page represents the requested page and rows per page. A page parsed by
+// ParseCursor starts after a cursor instead and has no number.
+type Page struct {
+	number int
+	rows   int
+	cursor *Cursor
+}
+
+// Parse parses the strings and validates the values are in reason. An empty
+// string selects the first page and the default number of rows. The error
+// is a validate.FieldErrors naming the page and rows fields at fault.
+func Parse(page string, rowsPerPage string) (Page, error) {
+	var fieldErrors validate.FieldErrors
+
+	number := 1
+	if page != "" {
+		n, err := strconv.Atoi(page)
+		switch {
+		case err != nil:
+			fieldErrors = append(fieldErrors, validate.FieldError{Field: "page", Err: err.Error()})
+		case n <= 0:
+			fieldErrors = append(fieldErrors, validate.FieldError{Field: "page", Err: "must be larger than 0"})
+		default:
+			number = n
+		}
+	}
+
+	rows := DefaultRows
+	if rowsPerPage != "" {
+		n, err := strconv.Atoi(rowsPerPage)
+		switch {
+		case err != nil:
+			fieldErrors = append(fieldErrors, validate.FieldError{Field: "rows", Err: err.Error()})
+		case n <= 0 || n > MaxRows:
+			fieldErrors = append(fieldErrors, validate.FieldError{Field: "rows", Err: fmt.Sprintf("must be between 1 and %d", MaxRows)})
+		default:
+			rows = n
+		}
+	}
+
+	if fieldErrors != nil {
+		return Page{}, fieldErrors
+	}
+
+	p := Page{
+		number: number,
+		rows:   rows,
+	}
+
+	return p, nil
+}
+
+// MustParse creates a paging value for testing.
+func MustParse(page string, rowsPerPage string) Page {
+	pg, err := Parse(page, rowsPerPage)
+	if err != nil {
+		panic(err)
+	}
+
+	return pg
+}
+
+// String implements the stringer interface.
+func (p Page) String() string {
+	if p.cursor != nil {
+		return fmt.Sprintf("%s rows: %d", p.cursor, p.rows)
+	}
+
+	return fmt.Sprintf("page: %d rows: %d", p.number, p.rows)
+}
+
+// Number returns the page number, zero for a page that starts after a
+// cursor.
+func (p Page) Number() int {
+	if p.cursor != nil {
+		return 0
+	}
+
+	return p.number
+}
+
+// RowsPerPage returns the rows per page.
+func (p Page) RowsPerPage() int {
+	return p.rows
+}
+
+// Offset returns the number of rows that come before the page. It's zero
+// for a page that starts after a cursor since the cursor selects its rows.
+func (p Page) Offset() int {
+	if p.cursor != nil {
+		return 0
+	}
+
+	return (p.number - 1) * p.rows
+}
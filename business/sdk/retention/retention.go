@@ -0,0 +1,150 @@
+// Package retention provides support for removing the rows that are kept
+// for a limited time, so the primary tables only hold the data still in
+// use. Rows are either purged or archived into a table of their own.
+package retention
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"lobbyte.com/alkeepy/business/sdk/sqldb"
+)
+
+// rows counts the rows removed by each policy.
+var rows = expvar.NewMap("retention_rows")
+
+// DefaultBatchSize is the number of rows removed by each statement when the
+// batch size of a table isn't set.
+const DefaultBatchSize = 1000
+
+// Policy removes what's older than the retention. Remove is called with the
+// time before which everything goes and returns how much was removed.
+type Policy struct {
+	Name      string
+	Retention time.Duration
+	Remove    func(ctx context.Context, before time.Time) (int, error)
+}
+
+// Run applies every policy once. A policy that fails doesn't keep the
+// others from being applied.
+func Run(ctx context.Context, log *slog.Logger, policies []Policy) {
+	for _, p := range policies {
+		start := time.Now()
+
+		n, err := p.Remove(ctx, start.Add(-p.Retention))
+		rows.Add(p.Name, int64(n))
+
+		switch {
+		case ctx.Err() != nil:
+			return
+		case err != nil:
+			log.ErrorContext(ctx, "retention", "policy", p.Name, "removed", n, "msg", err)
+		case n > 0:
+			log.InfoContext(ctx, "retention", "status", "removed expired rows", "policy", p.Name, "count", n, "retention", p.Retention.String(), "took", time.Since(start).String())
+		}
+	}
+}
+
+// Every applies the policies on every interval until the context is
+// canceled.
+func Every(ctx context.Context, log *slog.Logger, interval time.Duration, policies []Policy) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		Run(ctx, log, policies)
+	}
+}
+
+// =============================================================================
+
+// Table describes the expired rows of a table. A row expires once the time
+// in Column is older than the retention and it satisfies Where, when set.
+// The rows are archived into ArchiveTo, a table with the same columns in
+// the same order, or purged when it isn't set.
+type Table struct {
+	Name      string
+	Column    string
+	Where     string
+	ArchiveTo string
+	BatchSize int
+}
+
+// Rows returns the function removing the expired rows of the table for a
+// policy. The rows are removed in batches, each one a statement of its own,
+// so the table is never locked for long and other instances running the
+// same policy skip the rows being removed.
+func Rows(log *slog.Logger, db *sqlx.DB, t Table) func(ctx context.Context, before time.Time) (int, error) {
+	batchSize := t.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+
+	query := removeQuery(t)
+
+	f := func(ctx context.Context, before time.Time) (int, error) {
+		data := struct {
+			Before time.Time `db:"before"`
+			Limit  int       `db:"limit"`
+		}{
+			Before: before.UTC(),
+			Limit:  batchSize,
+		}
+
+		var total int
+		for {
+			var count struct {
+				Count int `db:"count"`
+			}
+			if err := sqldb.NamedQueryStruct(ctx, log, db, query, data, &count); err != nil {
+				return total, fmt.Errorf("namedquerystruct: %s: %w", t.Name, err)
+			}
+			total += count.Count
+
+			if count.Count < batchSize || ctx.Err() != nil {
+				return total, nil
+			}
+		}
+	}
+
+	return f
+}
+
+// removeQuery builds the statement removing a batch of the expired rows of
+// the table.
+func removeQuery(t Table) string {
+	where := t.Column + " < :before"
+	if t.Where != "" {
+		where += " AND (" + t.Where + ")"
+	}
+
+	var buf strings.Builder
+	fmt.Fprintf(&buf, `
+	WITH batch AS (
+		SELECT ctid FROM %[1]s WHERE %[2]s LIMIT :limit FOR UPDATE SKIP LOCKED
+	), removed AS (
+		DELETE FROM %[1]s WHERE ctid IN (SELECT ctid FROM batch) RETURNING *
+	)`, t.Name, where)
+
+	if t.ArchiveTo != "" {
+		fmt.Fprintf(&buf, `, archived AS (
+		INSERT INTO %s SELECT * FROM removed
+	)`, t.ArchiveTo)
+	}
+
+	buf.WriteString(`
+	SELECT count(1) FROM removed`)
+
+	return buf.String()
+}
@@ -0,0 +1,175 @@
+package retention_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"lobbyte.com/alkeepy/business/sdk/retention"
+)
+
+// recorder is a database that keeps the statements it's sent and answers
+// each one with the next of the counts.
+type recorder struct {
+	counts  []int64
+	queries []string
+	args    [][]driver.NamedValue
+}
+
+func (r *recorder) Connect(context.Context) (driver.Conn, error) { return conn{r}, nil }
+func (r *recorder) Driver() driver.Driver                        { return nil }
+
+type conn struct {
+	r *recorder
+}
+
+func (c conn) Prepare(string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (c conn) Close() error                        { return nil }
+func (c conn) Begin() (driver.Tx, error)           { return nil, driver.ErrSkip }
+
+func (c conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.r.queries = append(c.r.queries, query)
+	c.r.args = append(c.r.args, args)
+
+	count := c.r.counts[0]
+	c.r.counts = c.r.counts[1:]
+
+	return &rows{count: count}, nil
+}
+
+type rows struct {
+	count int64
+	done  bool
+}
+
+func (r *rows) Columns() []string { return []string{"count"} }
+func (r *rows) Close() error      { return nil }
+
+func (r *rows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = r.count
+	return nil
+}
+
+func Test_Rows(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	before := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		table     retention.Table
+		counts    []int64
+		want      int
+		wantQuery []string
+		wantLimit int64
+	}{
+		{
+			name:      "purges in batches until one isn't full",
+			table:     retention.Table{Name: "outbox", Column: "date_created", BatchSize: 2},
+			counts:    []int64{2, 2, 1},
+			want:      5,
+			wantQuery: []string{"FROM outbox WHERE date_created < $1 LIMIT $2", "DELETE FROM outbox"},
+			wantLimit: 2,
+		},
+		{
+			name:      "default batch size",
+			table:     retention.Table{Name: "outbox", Column: "date_created"},
+			counts:    []int64{0},
+			want:      0,
+			wantLimit: retention.DefaultBatchSize,
+		},
+		{
+			name:      "where",
+			table:     retention.Table{Name: "outbox", Column: "date_created", Where: "sent OR attempts > 5"},
+			counts:    []int64{3},
+			want:      3,
+			wantQuery: []string{"WHERE date_created < $1 AND (sent OR attempts > 5) LIMIT $2"},
+			wantLimit: retention.DefaultBatchSize,
+		},
+		{
+			name:      "archives",
+			table:     retention.Table{Name: "audits", Column: "timestamp", ArchiveTo: "audits_archive"},
+			counts:    []int64{1},
+			want:      1,
+			wantQuery: []string{"DELETE FROM audits", "INSERT INTO audits_archive SELECT * FROM removed"},
+			wantLimit: retention.DefaultBatchSize,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := recorder{counts: tt.counts}
+			db := sqlx.NewDb(sql.OpenDB(&rec), "pgx")
+			t.Cleanup(func() { db.Close() })
+
+			n, err := retention.Rows(log, db, tt.table)(context.Background(), before)
+			if err != nil {
+				t.Fatalf("Should remove the rows: %s", err)
+			}
+
+			if n != tt.want {
+				t.Fatalf("Should remove %d rows, got %d", tt.want, n)
+			}
+
+			if len(rec.queries) != len(tt.counts) {
+				t.Fatalf("Should run %d statements, got %d", len(tt.counts), len(rec.queries))
+			}
+
+			for _, want := range tt.wantQuery {
+				if !strings.Contains(rec.queries[0], want) {
+					t.Fatalf("Should run a statement containing %q, got %s", want, rec.queries[0])
+				}
+			}
+
+			if tt.table.ArchiveTo == "" && strings.Contains(rec.queries[0], "INSERT") {
+				t.Fatalf("Should purge the rows without archiving them, got %s", rec.queries[0])
+			}
+
+			args := rec.args[0]
+			if got, ok := args[0].Value.(time.Time); !ok || !got.Equal(before) {
+				t.Fatalf("Should remove the rows before %s, got %v", before, args[0].Value)
+			}
+
+			if got := args[1].Value; got != tt.wantLimit {
+				t.Fatalf("Should remove %d rows by statement, got %v", tt.wantLimit, got)
+			}
+		})
+	}
+}
+
+func Test_Run(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	var got []string
+	policy := func(name string, err error) retention.Policy {
+		return retention.Policy{
+			Name:      name,
+			Retention: time.Hour,
+			Remove: func(ctx context.Context, before time.Time) (int, error) {
+				if d := time.Since(before); d < time.Hour || d > time.Hour+time.Minute {
+					t.Errorf("Should remove what's older than the retention, got %s", d)
+				}
+				got = append(got, name)
+				return 1, err
+			},
+		}
+	}
+
+	retention.Run(context.Background(), log, []retention.Policy{
+		policy("first", io.ErrUnexpectedEOF),
+		policy("second", nil),
+	})
+
+	if strings.Join(got, ",") != "first,second" {
+		t.Fatalf("Should apply every policy even when one fails, got %v", got)
+	}
+}
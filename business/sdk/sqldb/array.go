@@ -0,0 +1,26 @@
+package sqldb
+
+import (
+	"sync"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// typeMap decodes the text representation of Postgres arrays. A map isn't
+// safe for concurrent use so access is serialized.
+var (
+	typeMapMu sync.Mutex
+	typeMap   = pgtype.NewMap()
+)
+
+// StringArray represents a Postgres text array column in the db models of
+// the stores. The driver encodes it directly when it's used as an argument.
+type StringArray []string
+
+// Scan implements the sql.Scanner interface.
+func (a *StringArray) Scan(src any) error {
+	typeMapMu.Lock()
+	defer typeMapMu.Unlock()
+
+	return typeMap.SQLScanner((*[]string)(a)).Scan(src)
+}
@@ -0,0 +1,113 @@
+package sqldb
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx/reflectx"
+)
+
+// SystemActor is recorded as the actor of the changes made outside of a
+// request, like those of the background jobs.
+const SystemActor = "system"
+
+// auditParams are the named parameters the named query helpers bind for the
+// audit columns.
+var auditParams = []string{":created_by", ":updated_by", ":date_created", ":date_updated"}
+
+// mapper maps the fields of the data values to their parameter names the
+// same way sqlx does.
+var mapper = reflectx.NewMapperFunc("db", strings.ToLower)
+
+type actorKey struct{}
+
+// WithActor returns a context recording who is making the changes so the
+// audit columns of the rows they touch can be set.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// GetActor returns who is making the changes, or SystemActor when the
+// context doesn't say.
+func GetActor(ctx context.Context) string {
+	v, ok := ctx.Value(actorKey{}).(string)
+	if !ok || v == "" {
+		return SystemActor
+	}
+
+	return v
+}
+
+// withAudit returns the data to bind for a named query. When the query uses
+// any of the audit parameters the data is turned into a map of its fields
+// plus those parameters: created_by and updated_by are always the actor in
+// the context, date_created and date_updated are the current time unless the
+// data sets them. Stores only have to name the columns in their queries.
+func withAudit(ctx context.Context, query string, data any) (any, error) {
+	if !usesAuditParams(query) {
+		return data, nil
+	}
+
+	m, err := toMap(data)
+	if err != nil {
+		return nil, err
+	}
+
+	actor := GetActor(ctx)
+	m["created_by"] = actor
+	m["updated_by"] = actor
+
+	now := time.Now().UTC()
+	for _, key := range []string{"date_created", "date_updated"} {
+		if t, ok := m[key].(time.Time); !ok || t.IsZero() {
+			m[key] = now
+		}
+	}
+
+	return m, nil
+}
+
+// usesAuditParams reports whether the query binds any of the audit
+// parameters.
+func usesAuditParams(query string) bool {
+	for _, p := range auditParams {
+		if usesParam(query, p) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// toMap copies the parameters of the data into a map. The data must be a
+// map or a struct, like sqlx requires.
+func toMap(data any) (map[string]any, error) {
+	if m, ok := data.(map[string]any); ok {
+		return maps.Clone(m), nil
+	}
+
+	v := reflect.Indirect(reflect.ValueOf(data))
+	if v.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("unsupported data type %T", data)
+	}
+
+	// The mapper may need to allocate embedded pointers so it works on an
+	// addressable copy.
+	pv := reflect.New(v.Type())
+	pv.Elem().Set(v)
+
+	fields := mapper.FieldMap(pv)
+
+	m := make(map[string]any, len(fields)+len(auditParams))
+	for name, fv := range fields {
+		if fv.IsValid() && fv.CanInterface() {
+			m[name] = fv.Interface()
+		}
+	}
+
+	return m, nil
+}
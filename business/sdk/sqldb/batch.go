@@ -0,0 +1,54 @@
+package sqldb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/jmoiron/sqlx"
+)
+
+// ExecBatch sends the queued statements to the database in a single round
+// trip and returns the first error reported for them. The statements aren't
+// run in a transaction unless the batch begins and commits one. It works
+// with either driver since both hand out pgx connections.
+func ExecBatch(ctx context.Context, log *slog.Logger, db *sqlx.DB, batch *pgx.Batch) (err error) {
+	name := queryName()
+
+	queries := make([]string, len(batch.QueuedQueries))
+	for i, qq := range batch.QueuedQueries {
+		queries[i] = qq.SQL
+	}
+	query := strings.Join(queries, "; ")
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	ctx, span := startSpan(ctx, name, query)
+	defer func() { endSpan(span, err) }()
+	defer observe(ctx, log, name, query, nil, time.Now())
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("acquiring connection: %w", err)
+	}
+	defer conn.Close()
+
+	return conn.Raw(func(driverConn any) error {
+		c, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return errors.New("batches require the pgx driver")
+		}
+
+		if err := c.Conn().SendBatch(ctx, batch).Close(); err != nil {
+			return fmt.Errorf("sending batch: %w", err)
+		}
+
+		return nil
+	})
+}
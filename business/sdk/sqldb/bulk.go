@@ -0,0 +1,219 @@
+package sqldb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/jmoiron/sqlx"
+	"lobbyte.com/alkeepy/business/sdk/tenant"
+)
+
+// DefaultChunkSize is the number of rows inserted by each statement of a
+// bulk insert when the chunk size isn't set.
+const DefaultChunkSize = 500
+
+// maxParams is the most parameters Postgres accepts in a statement.
+const maxParams = 65535
+
+// BulkInsert describes the rows to insert with multi-row VALUES statements.
+// Every row holds a value for each column. ChunkSize caps the rows sent in a
+// statement and is lowered when the rows would need more parameters than
+// Postgres accepts. Suffix is appended to each statement, like an
+// ON CONFLICT clause.
+type BulkInsert struct {
+	Table     string
+	Columns   []string
+	Rows      [][]any
+	ChunkSize int
+	Suffix    string
+}
+
+// InsertRows inserts the rows in chunks and returns how many were inserted.
+// It works inside a transaction, so a failed chunk can take the ones before
+// it with it. Like the named query helpers, the tenant_id, created_by and
+// updated_by columns are bound from the context whatever the rows say.
+func InsertRows(ctx context.Context, log *slog.Logger, db sqlx.ExtContext, bi BulkInsert) (int64, error) {
+	name := queryName()
+
+	if len(bi.Columns) == 0 {
+		return 0, errors.New("bulk insert requires columns")
+	}
+
+	bound, err := contextColumns(ctx, bi.Columns)
+	if err != nil {
+		return 0, fmt.Errorf("binding %s: %w", name, err)
+	}
+
+	chunkSize := bi.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+	chunkSize = min(chunkSize, maxParams/len(bi.Columns))
+
+	var total int64
+	for chunk := range slices.Chunk(bi.Rows, chunkSize) {
+		query, args, err := valuesQuery(bi, chunk, bound)
+		if err != nil {
+			return total, fmt.Errorf("binding %s: %w", name, err)
+		}
+
+		n, err := insertChunk(ctx, log, db, name, query, args)
+		if err != nil {
+			return total, err
+		}
+		total += n
+	}
+
+	return total, nil
+}
+
+// CopyRows inserts the rows into the table with the COPY protocol, the
+// fastest way to load a large number of rows. Every row holds a value for
+// each column. It needs a connection of its own so it can't take part in a
+// transaction, and it fails as a whole. The tenant_id, created_by and
+// updated_by columns are bound from the context like they are by
+// InsertRows.
+func CopyRows(ctx context.Context, log *slog.Logger, db *sqlx.DB, table string, columns []string, rows [][]any) (n int64, err error) {
+	name := queryName()
+	query := fmt.Sprintf("COPY %s (%s) FROM STDIN", table, strings.Join(columns, ", "))
+
+	bound, err := contextColumns(ctx, columns)
+	if err != nil {
+		return 0, fmt.Errorf("binding %s: %w", name, err)
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	ctx, span := startSpan(ctx, name, query)
+	defer func() { endSpan(span, err) }()
+	defer observe(ctx, log, name, query, nil, time.Now())
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("acquiring connection: %w", err)
+	}
+	defer conn.Close()
+
+	src := pgx.CopyFromSlice(len(rows), func(i int) ([]any, error) {
+		return withBound(rows[i], len(columns), bound)
+	})
+
+	err = conn.Raw(func(driverConn any) error {
+		c, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return errors.New("copy requires the pgx driver")
+		}
+
+		n, err = c.Conn().CopyFrom(ctx, pgx.Identifier(strings.Split(table, ".")), columns, src)
+		if err != nil {
+			return fmt.Errorf("copying rows: %w", err)
+		}
+
+		return nil
+	})
+
+	return n, err
+}
+
+// =============================================================================
+
+// insertChunk runs a single statement of a bulk insert.
+func insertChunk(ctx context.Context, log *slog.Logger, db sqlx.ExtContext, name string, query string, args []any) (n int64, err error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	ctx, span := startSpan(ctx, name, query)
+	defer func() { endSpan(span, err) }()
+	defer observe(ctx, log, name, query, args, time.Now())
+
+	res, err := db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+// valuesQuery builds the multi-row VALUES statement inserting the rows.
+func valuesQuery(bi BulkInsert, rows [][]any, bound map[int]any) (string, []any, error) {
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "INSERT INTO %s (%s) VALUES ", bi.Table, strings.Join(bi.Columns, ", "))
+
+	args := make([]any, 0, len(rows)*len(bi.Columns))
+	for i, row := range rows {
+		values, err := withBound(row, len(bi.Columns), bound)
+		if err != nil {
+			return "", nil, err
+		}
+
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString("(")
+		for j := range values {
+			if j > 0 {
+				buf.WriteString(", ")
+			}
+			buf.WriteString("$" + strconv.Itoa(len(args)+j+1))
+		}
+		buf.WriteString(")")
+
+		args = append(args, values...)
+	}
+
+	if bi.Suffix != "" {
+		buf.WriteString(" " + bi.Suffix)
+	}
+
+	return buf.String(), args, nil
+}
+
+// contextColumns returns the values bound from the context by the position
+// of their column. A tenant_id column requires a tenant in the context.
+func contextColumns(ctx context.Context, columns []string) (map[int]any, error) {
+	bound := make(map[int]any)
+
+	for i, col := range columns {
+		switch col {
+		case "tenant_id":
+			tenantID, err := tenant.Get(ctx)
+			if err != nil {
+				return nil, err
+			}
+			bound[i] = tenantID
+
+		case "created_by", "updated_by":
+			bound[i] = GetActor(ctx)
+		}
+	}
+
+	return bound, nil
+}
+
+// withBound returns a copy of the row with the values bound from the
+// context, leaving the caller's row untouched.
+func withBound(row []any, columns int, bound map[int]any) ([]any, error) {
+	if len(row) != columns {
+		return nil, fmt.Errorf("row has %d values for %d columns", len(row), columns)
+	}
+
+	if len(bound) == 0 {
+		return row, nil
+	}
+
+	values := slices.Clone(row)
+	for i, v := range bound {
+		values[i] = v
+	}
+
+	return values, nil
+}
@@ -0,0 +1,84 @@
+package sqldb
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Cluster holds the primary database and an optional read replica. Writes
+// always go to the primary. Reads go to the replica while it's healthy so
+// heavy browsing doesn't contend with writes, and fall back to the primary
+// when it isn't. A replica may lag behind the primary, so a read that must
+// see a write made by the same request belongs in a transaction.
+type Cluster struct {
+	primary *sqlx.DB
+	replica *sqlx.DB
+	healthy atomic.Bool
+}
+
+// NewCluster constructs a cluster for the primary. The replica may be nil.
+// It isn't used for reads until Monitor has found it healthy.
+func NewCluster(primary *sqlx.DB, replica *sqlx.DB) *Cluster {
+	return &Cluster{
+		primary: primary,
+		replica: replica,
+	}
+}
+
+// Primary returns the database that accepts writes.
+func (c *Cluster) Primary() *sqlx.DB {
+	return c.primary
+}
+
+// Begin implements the Beginner interface, starting the transaction on the
+// primary since a transaction may write.
+func (c *Cluster) Begin() (CommitRollbacker, error) {
+	return c.primary.Beginx()
+}
+
+// Reader returns the database read queries should use.
+func (c *Cluster) Reader() *sqlx.DB {
+	if c.replica != nil && c.healthy.Load() {
+		return c.replica
+	}
+
+	return c.primary
+}
+
+// Monitor checks the health of the replica at the interval until the context
+// is canceled, routing reads back to the replica once it recovers.
+func (c *Cluster) Monitor(ctx context.Context, log *slog.Logger, interval time.Duration) {
+	if c.replica == nil {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for checked := false; ; checked = true {
+		err := StatusCheck(ctx, c.replica)
+		if ctx.Err() != nil {
+			return
+		}
+
+		healthy := err == nil
+		if c.healthy.Swap(healthy) != healthy || !checked {
+			switch healthy {
+			case true:
+				log.InfoContext(ctx, "replica", "status", "healthy, reads routed to the replica")
+			default:
+				log.WarnContext(ctx, "replica", "status", "unhealthy, reads routed to the primary", "msg", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package sqldb
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// sslModes are the values of the sslmode parameter Postgres accepts.
+var sslModes = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+
+// Set of drivers that can pool the connections.
+const (
+	DriverStdlib  = "stdlib"
+	DriverPgxPool = "pgxpool"
+)
+
+// drivers are the values of Config.Driver Open accepts.
+var drivers = []string{DriverStdlib, DriverPgxPool}
+
+// queryExecModes are the values of the default_query_exec_mode parameter pgx
+// accepts.
+var queryExecModes = []string{"cache_statement", "cache_describe", "describe_exec", "exec", "simple_protocol"}
+
+// Config is the required properties to use the database.
+type Config struct {
+	User         string
+	Password     string
+	Host         string
+	Port         int
+	Name         string
+	SSLMode      string
+	DisableTLS   bool
+	MaxIdleConns int
+	MaxOpenConns int
+
+	// Driver selects the pool behind the *sqlx.DB. DriverStdlib, the default,
+	// uses the database/sql pool. DriverPgxPool uses the native pgx pool,
+	// which manages its own idle connections so MaxIdleConns doesn't apply.
+	Driver string
+
+	// QueryExecMode and StatementCacheCapacity control how pgx prepares and
+	// caches the statements on each connection. The zero values keep the pgx
+	// defaults of cache_statement and 512.
+	QueryExecMode          string
+	StatementCacheCapacity int
+
+	// Credentials, when set, is called for every new connection so the user
+	// and password can be rotated without reopening the pool.
+	Credentials func() (user, password string)
+}
+
+// DSN returns the Postgres connection URL for the configuration. DisableTLS
+// takes precedence over the SSL mode, which defaults to require.
+func (cfg Config) DSN() string {
+	sslMode := cfg.SSLMode
+	switch {
+	case cfg.DisableTLS:
+		sslMode = "disable"
+	case sslMode == "":
+		sslMode = "require"
+	}
+
+	q := make(url.Values)
+	q.Set("sslmode", sslMode)
+	q.Set("timezone", "utc")
+	if cfg.QueryExecMode != "" {
+		q.Set("default_query_exec_mode", cfg.QueryExecMode)
+	}
+	if cfg.StatementCacheCapacity > 0 {
+		q.Set("statement_cache_capacity", strconv.Itoa(cfg.StatementCacheCapacity))
+	}
+
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(cfg.User, cfg.Password),
+		Host:     net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)),
+		Path:     cfg.Name,
+		RawQuery: q.Encode(),
+	}
+
+	return u.String()
+}
+
+// Validate returns an error describing every property that can't be used
+// to connect.
+func (cfg Config) Validate() error {
+	var problems []string
+
+	if cfg.Host == "" {
+		problems = append(problems, "host is required")
+	}
+	if cfg.Port < 1 || cfg.Port > 65535 {
+		problems = append(problems, fmt.Sprintf("port %d is out of range", cfg.Port))
+	}
+	if cfg.Name == "" {
+		problems = append(problems, "name is required")
+	}
+	if cfg.User == "" {
+		problems = append(problems, "user is required")
+	}
+	if cfg.SSLMode != "" && !slices.Contains(sslModes, cfg.SSLMode) {
+		problems = append(problems, fmt.Sprintf("ssl mode %q must be one of %s", cfg.SSLMode, strings.Join(sslModes, ", ")))
+	}
+	if cfg.Driver != "" && !slices.Contains(drivers, cfg.Driver) {
+		problems = append(problems, fmt.Sprintf("driver %q must be one of %s", cfg.Driver, strings.Join(drivers, ", ")))
+	}
+	if cfg.QueryExecMode != "" && !slices.Contains(queryExecModes, cfg.QueryExecMode) {
+		problems = append(problems, fmt.Sprintf("query exec mode %q must be one of %s", cfg.QueryExecMode, strings.Join(queryExecModes, ", ")))
+	}
+	if cfg.StatementCacheCapacity < 0 {
+		problems = append(problems, "statement cache capacity must not be negative")
+	}
+
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, ", "))
+	}
+
+	return nil
+}
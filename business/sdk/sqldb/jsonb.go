@@ -0,0 +1,47 @@
+package sqldb
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// JSONB represents a Postgres jsonb column in the db models of the stores.
+// The value is marshaled to JSON when it's written and unmarshaled into V
+// when it's read, so the stores work with a typed value.
+type JSONB[T any] struct {
+	V T
+}
+
+// Scan implements the sql.Scanner interface.
+func (j *JSONB[T]) Scan(src any) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		var zero T
+		j.V = zero
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("scanning jsonb: unsupported type %T", src)
+	}
+
+	if err := json.Unmarshal(data, &j.V); err != nil {
+		return fmt.Errorf("scanning jsonb: %w", err)
+	}
+
+	return nil
+}
+
+// Value implements the driver.Valuer interface.
+func (j JSONB[T]) Value() (driver.Value, error) {
+	data, err := json.Marshal(j.V)
+	if err != nil {
+		return nil, fmt.Errorf("encoding jsonb: %w", err)
+	}
+
+	return string(data), nil
+}
@@ -0,0 +1,119 @@
+package sqldb
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jmoiron/sqlx"
+)
+
+// notifications counts the notifications received by the listeners.
+var notifications = expvar.NewInt("db_notifications")
+
+// Notification is a message sent with NOTIFY on a channel a listener is
+// subscribed to.
+type Notification struct {
+	Channel string
+	Payload string
+}
+
+// listenBackoff spaces the attempts to connect the listener again after its
+// connection is lost.
+var listenBackoff = Backoff{
+	Initial: time.Second,
+	Max:     30 * time.Second,
+}
+
+// Listen subscribes to the channels and calls the function for every
+// notification sent on them until the context is done. It holds a
+// connection of its own outside of the pool, opened again whenever it's
+// lost, so notifications sent while it's reconnecting are missed and the
+// function is called with a nil notification once it's listening again for
+// the caller to resynchronize.
+func Listen(ctx context.Context, log *slog.Logger, cfg Config, channels []string, fn func(ctx context.Context, n *Notification)) {
+	delay := listenBackoff.Initial
+
+	for {
+		err := listen(ctx, log, cfg, channels, func(ctx context.Context, n *Notification) {
+			delay = listenBackoff.Initial
+			fn(ctx, n)
+		})
+		if ctx.Err() != nil {
+			return
+		}
+
+		wait := delay/2 + rand.N(delay/2)
+		log.WarnContext(ctx, "database.listen", "status", "connection lost", "channels", channels, "wait", wait.String(), "msg", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+
+		delay = min(delay*2, listenBackoff.Max)
+	}
+}
+
+// Notify sends the payload on the channel. Inside a transaction it's only
+// delivered once the transaction commits, and not at all when it's rolled
+// back.
+func Notify(ctx context.Context, log *slog.Logger, db sqlx.ExtContext, channel string, payload string) error {
+	const q = `SELECT pg_notify($1, $2)`
+
+	return execContext(ctx, log, db, queryName(), q, []any{channel, payload})
+}
+
+// =============================================================================
+
+// listen runs a single connection of the listener until it fails or the
+// context is done.
+func listen(ctx context.Context, log *slog.Logger, cfg Config, channels []string, fn func(ctx context.Context, n *Notification)) error {
+	connCfg, err := pgx.ParseConfig(cfg.DSN())
+	if err != nil {
+		return fmt.Errorf("parsing dsn: %w", err)
+	}
+
+	if cfg.Credentials != nil {
+		connCfg.User, connCfg.Password = cfg.Credentials()
+	}
+
+	conn, err := pgx.ConnectConfig(ctx, connCfg)
+	if err != nil {
+		return fmt.Errorf("connecting: %w", err)
+	}
+	defer conn.Close(context.WithoutCancel(ctx))
+
+	for _, ch := range channels {
+		if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{ch}.Sanitize()); err != nil {
+			return fmt.Errorf("listening on %s: %w", ch, err)
+		}
+	}
+
+	log.InfoContext(ctx, "database.listen", "status", "listening", "channels", channels)
+
+	fn(ctx, nil)
+
+	for {
+		pn, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			if errors.Is(err, context.Canceled) {
+				return nil
+			}
+			return fmt.Errorf("waiting for notification: %w", err)
+		}
+
+		notifications.Add(1)
+
+		fn(ctx, &Notification{
+			Channel: pn.Channel,
+			Payload: pn.Payload,
+		})
+	}
+}
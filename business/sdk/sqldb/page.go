@@ -0,0 +1,39 @@
+package sqldb
+
+import (
+	"strings"
+
+	"lobbyte.com/alkeepy/business/sdk/page"
+)
+
+// AddPageClause appends the clause that selects the rows of the page to a
+// list query, so every store pages the same way. The values are bound to the
+// offset and rows_per_page named parameters.
+func AddPageClause(buf *strings.Builder, data map[string]any, pg page.Page) {
+	data["offset"] = pg.Offset()
+	data["rows_per_page"] = pg.RowsPerPage()
+
+	buf.WriteString(" LIMIT :rows_per_page OFFSET :offset")
+}
+
+// CursorCondition returns the condition that selects the rows after the
+// cursor of the page, or an empty string when it isn't paged by cursor. The
+// rows must be ordered by the created and id columns, both in the same
+// direction. The values are bound to the cursor_created and cursor_id named
+// parameters.
+func CursorCondition(data map[string]any, pg page.Page, createdColumn string, idColumn string, desc bool) string {
+	c, ok := pg.Cursor()
+	if !ok {
+		return ""
+	}
+
+	data["cursor_created"] = c.DateCreated.UTC()
+	data["cursor_id"] = c.ID
+
+	op := ">"
+	if desc {
+		op = "<"
+	}
+
+	return "(" + createdColumn + ", " + idColumn + ") " + op + " (:cursor_created, :cursor_id)"
+}
@@ -0,0 +1,313 @@
+package sqldb
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jmoiron/sqlx"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"lobbyte.com/alkeepy/foundation/otel"
+)
+
+// closureSuffix matches the part of a function name added for closures.
+var closureSuffix = regexp.MustCompile(`(\.func\d+)+(\.\d+)*$`)
+
+// slowQueries counts the queries that took longer than the threshold.
+var slowQueries = expvar.NewInt("slow_queries")
+
+// slowThreshold is the duration, in nanoseconds, above which a query is
+// logged as slow. A threshold of 0 disables slow query logging.
+var slowThreshold atomic.Int64
+
+// SetSlowQueryThreshold sets the duration above which a query is logged as
+// slow. It's safe to call while queries are running.
+func SetSlowQueryThreshold(d time.Duration) {
+	slowThreshold.Store(int64(d))
+}
+
+// ExecContext is a helper function to execute a CUD operation with
+// logging.
+func ExecContext(ctx context.Context, log *slog.Logger, db sqlx.ExtContext, query string, args ...any) error {
+	return execContext(ctx, log, db, queryName(), query, args)
+}
+
+// NamedExecContext is a helper function to execute a CUD operation with
+// logging. The named parameters in the query are bound to the fields of the
+// data struct using their db tags. The audit columns are bound for the
+// actor in the context. ErrDBDuplicatedEntry is returned when the change
+// breaks a unique constraint.
+func NamedExecContext(ctx context.Context, log *slog.Logger, db sqlx.ExtContext, query string, data any) error {
+	name := queryName()
+
+	q, args, err := bindNamed(ctx, db, name, query, data)
+	if err != nil {
+		return err
+	}
+
+	return execContext(ctx, log, db, name, q, args)
+}
+
+// QueryStruct is a helper function for executing queries that return a
+// single value to be unmarshalled into a struct type. ErrDBNotFound is
+// returned when no row matches.
+func QueryStruct(ctx context.Context, log *slog.Logger, db sqlx.ExtContext, query string, dest any, args ...any) error {
+	return queryStruct(ctx, log, db, queryName(), query, args, dest)
+}
+
+// NamedQueryStruct is a helper function for executing queries that return a
+// single value to be unmarshalled into a struct type, binding the named
+// parameters in the query to the fields of the data struct. ErrDBNotFound is
+// returned when no row matches.
+func NamedQueryStruct(ctx context.Context, log *slog.Logger, db sqlx.ExtContext, query string, data any, dest any) error {
+	name := queryName()
+
+	q, args, err := bindNamed(ctx, db, name, query, data)
+	if err != nil {
+		return err
+	}
+
+	return queryStruct(ctx, log, db, name, q, args, dest)
+}
+
+// QuerySlice is a helper function for executing queries that return a
+// collection of data to be unmarshalled into a slice.
+func QuerySlice[T any](ctx context.Context, log *slog.Logger, db sqlx.ExtContext, query string, dest *[]T, args ...any) error {
+	return querySlice(ctx, log, db, queryName(), query, args, dest)
+}
+
+// NamedQuerySlice is a helper function for executing queries that return a
+// collection of data to be unmarshalled into a slice, binding the named
+// parameters in the query to the fields of the data struct.
+func NamedQuerySlice[T any](ctx context.Context, log *slog.Logger, db sqlx.ExtContext, query string, data any, dest *[]T) error {
+	name := queryName()
+
+	q, args, err := bindNamed(ctx, db, name, query, data)
+	if err != nil {
+		return err
+	}
+
+	return querySlice(ctx, log, db, name, q, args, dest)
+}
+
+// =============================================================================
+
+// bindNamed binds the named parameters of the query to the data and the
+// audit and tenant parameters the query uses.
+func bindNamed(ctx context.Context, db sqlx.ExtContext, name string, query string, data any) (string, []any, error) {
+	data, err := withAudit(ctx, query, data)
+	if err != nil {
+		return "", nil, fmt.Errorf("binding %s: %w", name, err)
+	}
+
+	data, err = withTenant(ctx, query, data)
+	if err != nil {
+		return "", nil, fmt.Errorf("binding %s: %w", name, err)
+	}
+
+	q, args, err := db.BindNamed(query, data)
+	if err != nil {
+		return "", nil, fmt.Errorf("binding %s: %w", name, err)
+	}
+
+	return q, args, nil
+}
+
+func execContext(ctx context.Context, log *slog.Logger, db sqlx.ExtContext, name string, query string, args []any) (err error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	ctx, span := startSpan(ctx, name, query)
+	defer func() { endSpan(span, err) }()
+	defer observe(ctx, log, name, query, args, time.Now())
+
+	if _, err := db.ExecContext(ctx, query, args...); err != nil {
+		return duplicated(err)
+	}
+
+	return nil
+}
+
+func queryStruct(ctx context.Context, log *slog.Logger, db sqlx.ExtContext, name string, query string, args []any, dest any) (err error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	ctx, span := startSpan(ctx, name, query)
+	defer func() { endSpan(span, err) }()
+	defer observe(ctx, log, name, query, args, time.Now())
+
+	rows, err := db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return duplicated(err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return duplicated(err)
+		}
+		return ErrDBNotFound
+	}
+
+	if err := rows.StructScan(dest); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func querySlice[T any](ctx context.Context, log *slog.Logger, db sqlx.ExtContext, name string, query string, args []any, dest *[]T) (err error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	ctx, span := startSpan(ctx, name, query)
+	defer func() { endSpan(span, err) }()
+	defer observe(ctx, log, name, query, args, time.Now())
+
+	rows, err := db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var slice []T
+	for rows.Next() {
+		v := new(T)
+		if err := rows.StructScan(v); err != nil {
+			return err
+		}
+		slice = append(slice, *v)
+	}
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	*dest = slice
+
+	return nil
+}
+
+// startSpan starts a span for the query named after the store function that
+// ran it.
+func startSpan(ctx context.Context, name string, query string) (context.Context, trace.Span) {
+	return otel.AddSpan(ctx, "database.query",
+		attribute.String("db.system", "postgresql"),
+		attribute.String("db.operation.name", name),
+		attribute.String("db.query.text", strings.Join(strings.Fields(query), " ")),
+	)
+}
+
+// endSpan records the outcome of the query. Not finding a row is an expected
+// outcome so it isn't reported as an error.
+func endSpan(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, ErrDBNotFound) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+// =============================================================================
+
+// Set of error variables for the outcomes of a query the stores handle.
+var (
+	ErrDBNotFound        = errors.New("not found")
+	ErrDBDuplicatedEntry = errors.New("duplicated entry")
+)
+
+// uniqueViolation is the code of the error reported when an insert or an
+// update breaks a unique constraint.
+const uniqueViolation = "23505"
+
+// duplicated returns ErrDBDuplicatedEntry when the error reports a broken
+// unique constraint, so stores can tell it apart without knowing the codes
+// of the database. Any other error is returned as is.
+func duplicated(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return ErrDBDuplicatedEntry
+	}
+
+	return err
+}
+
+// observe logs the query at debug level and as a warning when it took longer
+// than the slow query threshold. The bound arguments may contain personal
+// data so only their number is logged.
+func observe(ctx context.Context, log *slog.Logger, name string, query string, args []any, start time.Time) {
+	since := time.Since(start)
+	q := strings.Join(strings.Fields(query), " ")
+
+	threshold := time.Duration(slowThreshold.Load())
+	if threshold > 0 && since > threshold {
+		slowQueries.Add(1)
+		log.WarnContext(ctx, "slow query", "name", name, "query", q, "args", redact(args), "since", since.String(), "threshold", threshold.String())
+		return
+	}
+
+	log.DebugContext(ctx, "database.query", "name", name, "query", q, "since", since.String())
+}
+
+// redact replaces the bound arguments with a placeholder.
+func redact(args []any) string {
+	if len(args) == 0 {
+		return "[]"
+	}
+
+	return fmt.Sprintf("[%d redacted]", len(args))
+}
+
+// queryName returns the name of the function that called the query helper
+// so the query can be identified in the logs.
+func queryName() string {
+	pc, _, _, ok := runtime.Caller(2)
+	if !ok {
+		return "unknown"
+	}
+
+	name := runtime.FuncForPC(pc).Name()
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+
+	// A query run from a closure, like the one given to Retry, is named
+	// after the function that declared it.
+	name = closureSuffix.ReplaceAllString(name, "")
+
+	return name
+}
+
+// usesParam reports whether the query binds the named parameter, given with
+// its colon. The name must end where the parameter does, so :tenant_ids
+// doesn't bind :tenant_id.
+func usesParam(query string, param string) bool {
+	for {
+		i := strings.Index(query, param)
+		if i < 0 {
+			return false
+		}
+
+		query = query[i+len(param):]
+		if query == "" || !isParamChar(query[0]) {
+			return true
+		}
+	}
+}
+
+// isParamChar reports whether the character can be part of the name of a
+// parameter, like sqlx parses them.
+func isParamChar(c byte) bool {
+	return c == '_' || c == '.' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
@@ -0,0 +1,120 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"expvar"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// retries counts the operations run again after a transient failure.
+var retries = expvar.NewInt("db_retries")
+
+// transientCodes are the Postgres error codes, by class or full code, of
+// failures that are expected to succeed when the operation is run again.
+var transientCodes = []string{
+	"08",    // connection exception
+	"40001", // serialization_failure
+	"40P01", // deadlock_detected
+	"53300", // too_many_connections
+	"57P01", // admin_shutdown
+	"57P02", // crash_shutdown
+	"57P03", // cannot_connect_now
+	"25006", // read_only_sql_transaction, the primary has been demoted
+}
+
+// Backoff describes how the attempts of an operation are spaced. The delay
+// doubles after each attempt up to the maximum, and a random part of it is
+// used so clients don't retry in lockstep.
+type Backoff struct {
+	Attempts int
+	Initial  time.Duration
+	Max      time.Duration
+}
+
+// DefaultBackoff rides out a failover or a burst of serialization errors
+// without holding the request for long.
+var DefaultBackoff = Backoff{
+	Attempts: 4,
+	Initial:  50 * time.Millisecond,
+	Max:      time.Second,
+}
+
+// Retry runs the operation until it succeeds, fails with an error that isn't
+// transient, or runs out of attempts. It gives up early when the context is
+// done or its deadline would pass before the next attempt. Only idempotent
+// operations may be retried, and never a single statement of a transaction
+// since the failure aborts the whole transaction.
+func Retry(ctx context.Context, log *slog.Logger, b Backoff, fn func(ctx context.Context) error) error {
+	delay := b.Initial
+
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil || attempt >= b.Attempts || !IsTransient(err) {
+			return err
+		}
+
+		var wait time.Duration
+		if delay > 0 {
+			wait = rand.N(delay)
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return err
+		}
+
+		log.WarnContext(ctx, "database.retry", "attempt", attempt, "wait", wait.String(), "msg", err)
+		retries.Add(1)
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+
+		delay = min(delay*2, b.Max)
+	}
+}
+
+// IsTransient reports whether the error is a failure that is expected to go
+// away, like a serialization error, a connection reset or a failover.
+func IsTransient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		for _, code := range transientCodes {
+			if strings.HasPrefix(pgErr.Code, code) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var connectErr *pgconn.ConnectError
+	var netErr net.Error
+
+	switch {
+	case pgconn.SafeToRetry(err),
+		errors.As(err, &connectErr),
+		errors.Is(err, driver.ErrBadConn),
+		errors.Is(err, io.EOF),
+		errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, syscall.ECONNRESET),
+		errors.Is(err, syscall.ECONNREFUSED),
+		errors.As(err, &netErr):
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,17 @@
+package sqldb
+
+// NotDeleted is the condition that leaves out the soft deleted rows. Tables
+// that support soft delete record when a row was deleted in a nullable
+// deleted_at column, which is cleared when the row is restored. The row is
+// only removed for good when it's purged.
+const NotDeleted = "deleted_at IS NULL"
+
+// ExcludeDeleted appends NotDeleted to the conditions of a WHERE clause
+// unless the soft deleted rows were asked for.
+func ExcludeDeleted(wc []string, includeDeleted bool) []string {
+	if includeDeleted {
+		return wc
+	}
+
+	return append(wc, NotDeleted)
+}
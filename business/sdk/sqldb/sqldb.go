@@ -0,0 +1,144 @@
+// Package sqldb provides support for access the database.
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/jmoiron/sqlx"
+)
+
+// Open knows how to open a database connection based on the configuration.
+// No connection is made until the pool is used, so StatusCheck should be
+// called to know the database can be reached.
+func Open(cfg Config) (*sqlx.DB, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	if cfg.Driver == DriverPgxPool {
+		return openPool(cfg)
+	}
+
+	connCfg, err := pgx.ParseConfig(cfg.DSN())
+	if err != nil {
+		return nil, fmt.Errorf("parsing dsn: %w", err)
+	}
+
+	var opts []stdlib.OptionOpenDB
+	if cfg.Credentials != nil {
+		opts = append(opts, stdlib.OptionBeforeConnect(func(ctx context.Context, cc *pgx.ConnConfig) error {
+			cc.User, cc.Password = cfg.Credentials()
+			return nil
+		}))
+
+		// Like the pgx pool does before acquiring them, connections opened
+		// before the credentials were rotated are discarded as they're reused
+		// instead of being handed out again.
+		opts = append(opts, stdlib.OptionResetSession(func(ctx context.Context, conn *pgx.Conn) error {
+			if user, _ := cfg.Credentials(); conn.Config().User != user {
+				return driver.ErrBadConn
+			}
+			return nil
+		}))
+	}
+
+	db := sqlx.NewDb(stdlib.OpenDB(*connCfg, opts...), "pgx")
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+
+	return db, nil
+}
+
+// openPool opens a native pgx pool and wraps it in a *sqlx.DB so the stores
+// work the same with either driver.
+func openPool(cfg Config) (*sqlx.DB, error) {
+	poolCfg, err := pgxpool.ParseConfig(cfg.DSN())
+	if err != nil {
+		return nil, fmt.Errorf("parsing dsn: %w", err)
+	}
+
+	if cfg.MaxOpenConns > 0 {
+		poolCfg.MaxConns = int32(cfg.MaxOpenConns)
+	}
+
+	if cfg.Credentials != nil {
+		poolCfg.BeforeConnect = func(ctx context.Context, cc *pgx.ConnConfig) error {
+			cc.User, cc.Password = cfg.Credentials()
+			return nil
+		}
+
+		// Connections opened before the credentials were rotated are closed
+		// as they're acquired instead of being handed out again.
+		poolCfg.BeforeAcquire = func(ctx context.Context, conn *pgx.Conn) bool {
+			user, _ := cfg.Credentials()
+			return conn.Config().User == user
+		}
+	}
+
+	pool, err := pgxpool.NewWithConfig(context.Background(), poolCfg)
+	if err != nil {
+		return nil, fmt.Errorf("creating pool: %w", err)
+	}
+
+	// The idle connections are kept by the pool. Keeping them in database/sql
+	// as well would hold pool connections nothing else could acquire.
+	db := sql.OpenDB(poolConnector{
+		Connector: stdlib.GetPoolConnector(pool),
+		pool:      pool,
+	})
+	db.SetMaxIdleConns(0)
+
+	return sqlx.NewDb(db, "pgx"), nil
+}
+
+// poolConnector closes the pgx pool when the *sql.DB using it is closed.
+type poolConnector struct {
+	driver.Connector
+	pool *pgxpool.Pool
+}
+
+// Close implements io.Closer, which database/sql calls from DB.Close.
+func (c poolConnector) Close() error {
+	c.pool.Close()
+	return nil
+}
+
+// StatusCheck returns nil if it can successfully talk to the database. It
+// returns a non-nil error otherwise.
+func StatusCheck(ctx context.Context, db *sqlx.DB) error {
+	// If the user doesn't give us a deadline set 1 second.
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Second)
+		defer cancel()
+	}
+
+	for attempts := 1; ; attempts++ {
+		if err := db.PingContext(ctx); err == nil {
+			break
+		}
+
+		time.Sleep(time.Duration(attempts) * 100 * time.Millisecond)
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	// Run a simple query to determine connectivity.
+	// Running this query forces a round trip through the database.
+	const q = `SELECT TRUE`
+	var tmp bool
+	return db.QueryRowContext(ctx, q).Scan(&tmp)
+}
@@ -0,0 +1,35 @@
+package sqldb
+
+import (
+	"context"
+
+	"lobbyte.com/alkeepy/business/sdk/tenant"
+)
+
+// TenantScope is the condition scoping a query to the tenant in the
+// context. The named query helpers bind its parameter.
+const TenantScope = "tenant_id = :tenant_id"
+
+// withTenant returns the data to bind for a named query. When the query uses
+// the tenant_id parameter it's always bound to the tenant in the context,
+// whatever the data says, so a caller can't reach the rows of another tenant.
+// A query using it without a tenant in the context is refused.
+func withTenant(ctx context.Context, query string, data any) (any, error) {
+	if !usesParam(query, ":tenant_id") {
+		return data, nil
+	}
+
+	tenantID, err := tenant.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := toMap(data)
+	if err != nil {
+		return nil, err
+	}
+
+	m["tenant_id"] = tenantID
+
+	return m, nil
+}
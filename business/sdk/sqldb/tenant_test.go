@@ -0,0 +1,113 @@
+package sqldb_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"lobbyte.com/alkeepy/business/sdk/sqldb"
+	"lobbyte.com/alkeepy/business/sdk/tenant"
+)
+
+// recorder keeps the arguments a statement is run with instead of running
+// it.
+type recorder struct {
+	sqlx.ExtContext
+	args []any
+}
+
+func (r *recorder) BindNamed(query string, arg any) (string, []any, error) {
+	return sqlx.BindNamed(sqlx.DOLLAR, query, arg)
+}
+
+func (r *recorder) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	r.args = args
+	return driver.RowsAffected(1), nil
+}
+
+func Test_NamedExecContextTenant(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	scoped := uuid.MustParse("e7a1c2d4-5b6f-4a8e-9c0d-1f2a3b4c5d6e")
+	other := uuid.MustParse("0b6c1a4e-2f3d-4c5b-8a9e-7d6f5e4c3b2a")
+
+	tests := []struct {
+		name     string
+		tenantID uuid.UUID
+		query    string
+		data     map[string]any
+		wantArgs []any
+		wantErr  bool
+	}{
+		{
+			name:     "binds the tenant of the context",
+			tenantID: scoped,
+			query:    "DELETE FROM recipes WHERE recipe_id = :recipe_id AND tenant_id = :tenant_id",
+			data:     map[string]any{"recipe_id": "r1"},
+			wantArgs: []any{"r1", scoped},
+		},
+		{
+			name:     "overrides the tenant of the data",
+			tenantID: scoped,
+			query:    "DELETE FROM recipes WHERE tenant_id = :tenant_id",
+			data:     map[string]any{"tenant_id": other},
+			wantArgs: []any{scoped},
+		},
+		{
+			name:    "refuses a scoped query without a tenant",
+			query:   "DELETE FROM recipes WHERE tenant_id = :tenant_id",
+			data:    map[string]any{"tenant_id": other},
+			wantErr: true,
+		},
+		{
+			name:     "leaves a parameter that only starts like the tenant",
+			query:    "DELETE FROM recipes WHERE tenant_id = ANY(:tenant_ids)",
+			data:     map[string]any{"tenant_ids": "{t1,t2}"},
+			wantArgs: []any{"{t1,t2}"},
+		},
+		{
+			name:     "leaves an unscoped query",
+			query:    "DELETE FROM recipes WHERE deleted_at < :before",
+			data:     map[string]any{"before": "yesterday"},
+			wantArgs: []any{"yesterday"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.tenantID != uuid.Nil {
+				ctx = tenant.With(ctx, tt.tenantID)
+			}
+
+			var db recorder
+			err := sqldb.NamedExecContext(ctx, log, &db, tt.query, tt.data)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Should refuse the query")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Should run the query: %s", err)
+			}
+
+			if len(db.args) != len(tt.wantArgs) {
+				t.Fatalf("Should bind %v, got %v", tt.wantArgs, db.args)
+			}
+
+			for i := range tt.wantArgs {
+				if db.args[i] != tt.wantArgs[i] {
+					t.Fatalf("Should bind %v, got %v", tt.wantArgs, db.args)
+				}
+			}
+		})
+	}
+}
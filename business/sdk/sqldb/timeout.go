@@ -0,0 +1,47 @@
+package sqldb
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// queryTimeout is the duration, in nanoseconds, a query may run for when the
+// context doesn't end sooner. A timeout of 0 leaves the queries bounded by
+// their context only.
+var queryTimeout atomic.Int64
+
+// SetQueryTimeout sets how long a query may run for when its context doesn't
+// end sooner, so a slow query can't outlive the request or the shutdown that
+// started it. It's safe to call while queries are running.
+func SetQueryTimeout(d time.Duration) {
+	queryTimeout.Store(int64(d))
+}
+
+type timeoutKey struct{}
+
+// WithQueryTimeout returns a context whose queries may run for the duration
+// instead of the default timeout, for the work known to need longer like an
+// export. A duration of 0 bounds them by the context only.
+func WithQueryTimeout(ctx context.Context, d time.Duration) context.Context {
+	return context.WithValue(ctx, timeoutKey{}, d)
+}
+
+// withQueryTimeout returns the context a single query runs with. The timeout
+// only shortens the deadline of the context, it never extends it.
+func withQueryTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	d := time.Duration(queryTimeout.Load())
+	if v, ok := ctx.Value(timeoutKey{}).(time.Duration); ok {
+		d = v
+	}
+
+	if d <= 0 {
+		return ctx, func() {}
+	}
+
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= d {
+		return ctx, func() {}
+	}
+
+	return context.WithTimeout(ctx, d)
+}
@@ -0,0 +1,50 @@
+package sqldb
+
+import (
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Beginner represents a value that can begin a transaction.
+type Beginner interface {
+	Begin() (CommitRollbacker, error)
+}
+
+// CommitRollbacker represents a value that can commit or rollback a
+// transaction.
+type CommitRollbacker interface {
+	Commit() error
+	Rollback() error
+}
+
+// =============================================================================
+
+// dbBeginner implements the Beginner interface.
+type dbBeginner struct {
+	sqlxDB *sqlx.DB
+}
+
+// NewBeginner constructs a value that implements the beginner interface.
+func NewBeginner(sqlxDB *sqlx.DB) Beginner {
+	return &dbBeginner{
+		sqlxDB: sqlxDB,
+	}
+}
+
+// Begin implements the Beginner interface and returns a concrete value that
+// implements the CommitRollbacker interface.
+func (db *dbBeginner) Begin() (CommitRollbacker, error) {
+	return db.sqlxDB.Beginx()
+}
+
+// GetExtContext is a helper function that extracts the sqlx value from the
+// transaction so a store can run its queries as part of it.
+func GetExtContext(tx CommitRollbacker) (sqlx.ExtContext, error) {
+	ec, ok := tx.(sqlx.ExtContext)
+	if !ok {
+		return nil, errors.New("transaction is not a database transaction")
+	}
+
+	return ec, nil
+}
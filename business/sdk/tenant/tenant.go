@@ -0,0 +1,36 @@
+// Package tenant carries the bakery a request acts for so the data layer
+// can scope every query to it. Several bakeries share a deployment and none
+// of them can see the rows of another.
+package tenant
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+// ErrMissing is returned when data is accessed without a tenant.
+var ErrMissing = errors.New("tenant is required")
+
+// Default is the tenant the rows created before tenancy was introduced
+// belong to. It's the tenant of single bakery deployments.
+var Default = uuid.MustParse("e7a1c2d4-5b6f-4a8e-9c0d-1f2a3b4c5d6e")
+
+type ctxKey struct{}
+
+// With returns a context scoped to the specified tenant.
+func With(ctx context.Context, tenantID uuid.UUID) context.Context {
+	return context.WithValue(ctx, ctxKey{}, tenantID)
+}
+
+// Get returns the tenant the context is scoped to. ErrMissing is returned
+// when it isn't scoped to one.
+func Get(ctx context.Context) (uuid.UUID, error) {
+	v, ok := ctx.Value(ctxKey{}).(uuid.UUID)
+	if !ok || v == uuid.Nil {
+		return uuid.Nil, ErrMissing
+	}
+
+	return v, nil
+}
@@ -0,0 +1,64 @@
+// Package buildinfo provides support for identifying the build of the running
+// binary.
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Info describes the build of the running binary.
+type Info struct {
+	Build     string `json:"build"`
+	Revision  string `json:"revision,omitempty"`
+	Time      string `json:"time,omitempty"`
+	Modified  bool   `json:"modified,omitempty"`
+	GoVersion string `json:"goVersion"`
+}
+
+// Read merges the build set with ldflags with the version control details
+// the Go toolchain embeds in the binary. When the binary wasn't built with a
+// build value, the short revision is used instead so the commit is always
+// known.
+func Read(build string) Info {
+	info := Info{
+		Build:     build,
+		GoVersion: runtime.Version(),
+	}
+
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			info.Revision = s.Value
+		case "vcs.time":
+			info.Time = s.Value
+		case "vcs.modified":
+			info.Modified = s.Value == "true"
+		}
+	}
+
+	if (build == "" || build == "develop") && info.Revision != "" {
+		info.Build = short(info.Revision)
+		if info.Modified {
+			info.Build += "-dirty"
+		}
+	}
+
+	return info
+}
+
+// short returns the abbreviated form of a revision.
+func short(revision string) string {
+	const length = 12
+
+	if len(revision) > length {
+		return revision[:length]
+	}
+
+	return revision
+}
@@ -0,0 +1,133 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+)
+
+// The schemes of the values stored in AWS.
+const (
+	SchemeSecretsManager = "aws-sm"
+	SchemeParameterStore = "aws-ssm"
+)
+
+// AWS resolves values stored in AWS Secrets Manager and SSM Parameter Store.
+// The credentials and region are taken from the default chain, so the task
+// or pod role is used on ECS and EKS. Nothing is loaded until the first
+// value is resolved.
+type AWS struct {
+	once sync.Once
+	cfg  aws.Config
+	err  error
+}
+
+// Resolvers returns the resolvers for the AWS schemes to be used with
+// Resolve.
+func (a *AWS) Resolvers() map[string]ResolveFunc {
+	return map[string]ResolveFunc{
+		SchemeSecretsManager: a.SecretsManager,
+		SchemeParameterStore: a.ParameterStore,
+	}
+}
+
+// SecretsManager returns the secret named by ref. A JSON secret holding
+// several values can be addressed by key, e.g. wasfa/db#password.
+func (a *AWS) SecretsManager(ctx context.Context, ref string) (string, error) {
+	cfg, err := a.config(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	id, key, hasKey := strings.Cut(ref, "#")
+
+	out, err := secretsmanager.NewFromConfig(cfg).GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(id),
+	})
+	if err != nil {
+		return "", fmt.Errorf("getting secret %s: %w", id, err)
+	}
+
+	value := aws.ToString(out.SecretString)
+	if !hasKey {
+		return value, nil
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(value), &fields); err != nil {
+		return "", fmt.Errorf("decoding secret %s: %w", id, err)
+	}
+
+	field, exists := fields[key]
+	if !exists {
+		return "", fmt.Errorf("secret %s has no key %q", id, key)
+	}
+
+	if s, ok := field.(string); ok {
+		return s, nil
+	}
+
+	return fmt.Sprint(field), nil
+}
+
+// ParameterStore returns the parameter named by ref, decrypting it when it's
+// a SecureString. Names of hierarchical parameters get their leading slash
+// back, so aws-ssm://wasfa/db/password reads /wasfa/db/password.
+func (a *AWS) ParameterStore(ctx context.Context, ref string) (string, error) {
+	cfg, err := a.config(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	name := ref
+	if strings.Contains(name, "/") && !strings.HasPrefix(name, "/") {
+		name = "/" + name
+	}
+
+	out, err := ssm.NewFromConfig(cfg).GetParameter(ctx, &ssm.GetParameterInput{
+		Name:           aws.String(name),
+		WithDecryption: aws.Bool(true),
+	})
+	if err != nil {
+		return "", fmt.Errorf("getting parameter %s: %w", name, err)
+	}
+
+	return aws.ToString(out.Parameter.Value), nil
+}
+
+// KMSDecrypt returns the plaintext of a ciphertext blob encrypted with a
+// symmetric KMS key.
+func (a *AWS) KMSDecrypt(ctx context.Context, ciphertext []byte) (string, error) {
+	cfg, err := a.config(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	out, err := kms.NewFromConfig(cfg).Decrypt(ctx, &kms.DecryptInput{
+		CiphertextBlob: ciphertext,
+	})
+	if err != nil {
+		return "", fmt.Errorf("kms decrypt: %w", err)
+	}
+
+	return string(out.Plaintext), nil
+}
+
+func (a *AWS) config(ctx context.Context) (aws.Config, error) {
+	a.once.Do(func() {
+		a.cfg, a.err = awsconfig.LoadDefaultConfig(ctx)
+		if a.err != nil {
+			a.err = fmt.Errorf("loading aws config: %w", a.err)
+		}
+	})
+
+	return a.cfg, a.err
+}
@@ -0,0 +1,118 @@
+// Package config provides support for loading the configuration from a file
+// in addition to the environment and command line flags.
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ardanlabs/conf/v3"
+	"github.com/ardanlabs/conf/v3/yaml"
+)
+
+// resolveTimeout bounds the time spent fetching values from remote sources.
+const resolveTimeout = 30 * time.Second
+
+// Parse parses the configuration from the profile, the file named by Path,
+// the _FILE secret variants, the environment and the flags, in increasing
+// order of precedence. The profile can be nil. Values referencing AWS
+// Secrets Manager or SSM Parameter Store are then resolved and enc:// values
+// are decrypted. It can be called again to reload the configuration into a
+// zero value of the same type.
+func Parse(ctx context.Context, prefix string, cfg any, profile Profile) (string, error) {
+	var parsers []conf.Parsers
+
+	if len(profile) > 0 {
+		parsers = append(parsers, profileParser{profile: profile})
+	}
+
+	fileParser, err := File(Path(os.Args[1:]))
+	if err != nil {
+		return "", fmt.Errorf("loading config file: %w", err)
+	}
+	if fileParser != nil {
+		parsers = append(parsers, fileParser)
+	}
+
+	parsers = append(parsers, SecretFiles{})
+
+	help, err := conf.Parse(prefix, cfg, parsers...)
+	if err != nil {
+		return help, err
+	}
+
+	masterKey, err := MasterKey()
+	if err != nil {
+		return "", err
+	}
+
+	var aws AWS
+	resolvers := aws.Resolvers()
+	resolvers[SchemeEncrypted] = Encrypted{MasterKey: masterKey, AWS: &aws}.Decrypt
+
+	// The remote sources shouldn't be able to hang the startup.
+	ctx, cancel := context.WithTimeout(ctx, resolveTimeout)
+	defer cancel()
+
+	if err := Resolve(ctx, cfg, resolvers); err != nil {
+		return "", err
+	}
+
+	return "", nil
+}
+
+// Path returns the location of the configuration file from the --config
+// flag, or the CONFIG_FILE environment variable when the flag isn't set. The
+// flag has to be found before the configuration is parsed since the file is
+// one of the sources.
+func Path(args []string) string {
+	return lookup(args, "config", "CONFIG_FILE")
+}
+
+// lookup returns the value of the flag in the arguments, or the environment
+// variable when the flag isn't set.
+func lookup(args []string, flag string, env string) string {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		if v, ok := strings.CutPrefix(arg, "--"+flag+"="); ok {
+			return v
+		}
+
+		if arg == "--"+flag && i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+
+	return os.Getenv(env)
+}
+
+// File returns the parser for the configuration file based on its extension.
+// YAML and TOML files are supported. The file is applied before the
+// environment and flags so they take precedence over it. A nil parser is
+// returned when the path is empty.
+func File(path string) (conf.Parsers, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		return yaml.WithData(data), nil
+
+	case ".toml":
+		return tomlParser{data: data}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported config file type %q", ext)
+	}
+}
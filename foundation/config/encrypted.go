@@ -0,0 +1,123 @@
+package config
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// SchemeEncrypted is the scheme of values encrypted with the master key or
+// an AWS KMS key.
+const SchemeEncrypted = "enc"
+
+// Encrypted decrypts enc:// values so semi sensitive values can be kept in a
+// config file that's committed. The ciphertext is base64 encoded. When a
+// master key is set the value is decrypted locally with AES-256-GCM,
+// otherwise it's a KMS ciphertext blob, which names the key that encrypted
+// it.
+type Encrypted struct {
+	MasterKey []byte
+	AWS       *AWS
+}
+
+// Decrypt returns the plaintext of the base64 encoded ciphertext.
+func (e Encrypted) Decrypt(ctx context.Context, ref string) (string, error) {
+	ciphertext, err := base64.StdEncoding.DecodeString(ref)
+	if err != nil {
+		return "", fmt.Errorf("decoding ciphertext: %w", err)
+	}
+
+	if e.MasterKey == nil {
+		if e.AWS == nil {
+			return "", errors.New("no master key or KMS configured")
+		}
+		return e.AWS.KMSDecrypt(ctx, ciphertext)
+	}
+
+	gcm, err := newGCM(e.MasterKey)
+	if err != nil {
+		return "", err
+	}
+
+	if len(ciphertext) < gcm.NonceSize() {
+		return "", errors.New("ciphertext is too short")
+	}
+
+	nonce, sealed := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+
+	plaintext, err := gcm.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return "", fmt.Errorf("decrypting: %w", err)
+	}
+
+	return string(plaintext), nil
+}
+
+// Encrypt returns the enc:// value of the plaintext encrypted with the
+// master key.
+func Encrypt(masterKey []byte, plaintext string) (string, error) {
+	gcm, err := newGCM(masterKey)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("generating nonce: %w", err)
+	}
+
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+
+	return SchemeEncrypted + "://" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// MasterKey returns the base64 encoded 32 byte key in the CONFIG_MASTER_KEY
+// variable, or in the file named by CONFIG_MASTER_KEY_FILE. A nil key is
+// returned when neither is set.
+func MasterKey() ([]byte, error) {
+	encoded := os.Getenv("CONFIG_MASTER_KEY")
+
+	if path := os.Getenv("CONFIG_MASTER_KEY_FILE"); encoded == "" && path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading CONFIG_MASTER_KEY_FILE: %w", err)
+		}
+		encoded = string(data)
+	}
+
+	encoded = strings.TrimSpace(encoded)
+	if encoded == "" {
+		return nil, nil
+	}
+
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("decoding master key: %w", err)
+	}
+
+	if len(key) != 32 {
+		return nil, fmt.Errorf("master key must be 32 bytes, got %d", len(key))
+	}
+
+	return key, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("creating cipher: %w", err)
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("creating gcm: %w", err)
+	}
+
+	return gcm, nil
+}
@@ -0,0 +1,161 @@
+package config_test
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"lobbyte.com/alkeepy/foundation/config"
+)
+
+func Test_Decrypt(t *testing.T) {
+	key, other := newMasterKey(t), newMasterKey(t)
+
+	value, err := config.Encrypt(key, "s3cr3t")
+	if err != nil {
+		t.Fatalf("Should encrypt the value: %s", err)
+	}
+
+	scheme, ref, _ := strings.Cut(value, "://")
+	if scheme != config.SchemeEncrypted {
+		t.Fatalf("Should use the %s scheme, got %q", config.SchemeEncrypted, scheme)
+	}
+
+	ciphertext, err := base64.StdEncoding.DecodeString(ref)
+	if err != nil {
+		t.Fatalf("Should be base64 encoded: %s", err)
+	}
+	ciphertext[len(ciphertext)-1] ^= 0xff
+	tampered := base64.StdEncoding.EncodeToString(ciphertext)
+
+	tests := []struct {
+		name  string
+		key   []byte
+		ref   string
+		valid bool
+	}{
+		{name: "valid", key: key, ref: ref, valid: true},
+		{name: "other key", key: other, ref: ref},
+		{name: "short key", key: key[:10], ref: ref},
+		{name: "tampered", key: key, ref: tampered},
+		{name: "too short", key: key, ref: base64.StdEncoding.EncodeToString([]byte("short"))},
+		{name: "not base64", key: key, ref: "not base64!"},
+		{name: "no key or KMS", ref: ref},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := config.Encrypted{MasterKey: tt.key}.Decrypt(context.Background(), tt.ref)
+
+			if !tt.valid {
+				if err == nil {
+					t.Fatalf("Should refuse to decrypt the value, got %q", got)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Should decrypt the value: %s", err)
+			}
+
+			if got != "s3cr3t" {
+				t.Fatalf("Should get the plaintext back, got %q", got)
+			}
+		})
+	}
+}
+
+func Test_ResolveEncrypted(t *testing.T) {
+	key := newMasterKey(t)
+
+	value, err := config.Encrypt(key, "postgres-password")
+	if err != nil {
+		t.Fatalf("Should encrypt the value: %s", err)
+	}
+
+	cfg := struct {
+		DB struct {
+			User     string
+			Password string
+		}
+	}{}
+	cfg.DB.User = "postgres"
+	cfg.DB.Password = value
+
+	resolvers := map[string]config.ResolveFunc{
+		config.SchemeEncrypted: config.Encrypted{MasterKey: key}.Decrypt,
+	}
+
+	if err := config.Resolve(context.Background(), &cfg, resolvers); err != nil {
+		t.Fatalf("Should resolve the configuration: %s", err)
+	}
+
+	if cfg.DB.Password != "postgres-password" || cfg.DB.User != "postgres" {
+		t.Fatalf("Should only replace the encrypted value, got %+v", cfg.DB)
+	}
+}
+
+func Test_MasterKey(t *testing.T) {
+	key := newMasterKey(t)
+	encoded := base64.StdEncoding.EncodeToString(key)
+
+	file := filepath.Join(t.TempDir(), "master.key")
+	if err := os.WriteFile(file, []byte(encoded+"\n"), 0o600); err != nil {
+		t.Fatalf("Should write the key file: %s", err)
+	}
+
+	tests := []struct {
+		name  string
+		env   string
+		file  string
+		want  []byte
+		valid bool
+	}{
+		{name: "none", valid: true},
+		{name: "env", env: encoded, want: key, valid: true},
+		{name: "file", file: file, want: key, valid: true},
+		{name: "env over file", env: encoded, file: filepath.Join(t.TempDir(), "missing"), want: key, valid: true},
+		{name: "missing file", file: filepath.Join(t.TempDir(), "missing")},
+		{name: "not base64", env: "not base64!"},
+		{name: "wrong size", env: base64.StdEncoding.EncodeToString(key[:16])},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("CONFIG_MASTER_KEY", tt.env)
+			t.Setenv("CONFIG_MASTER_KEY_FILE", tt.file)
+
+			got, err := config.MasterKey()
+
+			if !tt.valid {
+				if err == nil {
+					t.Fatalf("Should refuse the master key")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Should read the master key: %s", err)
+			}
+
+			if string(got) != string(tt.want) {
+				t.Fatalf("Should get the master key, got %x", got)
+			}
+		})
+	}
+}
+
+// =============================================================================
+
+func newMasterKey(t *testing.T) []byte {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatalf("Should generate a key: %s", err)
+	}
+
+	return key
+}
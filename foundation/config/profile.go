@@ -0,0 +1,73 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/ardanlabs/conf/v3"
+)
+
+// Profile holds the defaults for a deployment environment keyed by the name
+// of their environment variable.
+type Profile map[string]string
+
+// Environment returns the name of the selected profile from the
+// --environment flag, or the ENVIRONMENT variable when the flag isn't set.
+// Like the config file, the profile has to be known before the
+// configuration is parsed.
+func Environment(args []string) string {
+	return lookup(args, "environment", "ENVIRONMENT")
+}
+
+// SelectProfile returns the named profile of the set.
+func SelectProfile(profiles map[string]Profile, name string) (Profile, error) {
+	profile, exists := profiles[name]
+	if !exists {
+		return nil, fmt.Errorf("unknown environment %q, expected one of %s", name, strings.Join(slices.Sorted(maps.Keys(profiles)), ", "))
+	}
+
+	return profile, nil
+}
+
+// profileParser applies a profile before the config file is, so the file,
+// the environment and the flags all take precedence over the profile and
+// the profile over the defaults of the fields. Since conf applies a default
+// to any field left at its zero value, a field a profile may set to its zero
+// value must not have a default of its own.
+type profileParser struct {
+	profile Profile
+}
+
+// Process implements the conf.Parsers interface. The variables of the
+// profile that aren't set are only set while conf parses them into the
+// configuration, so the file parsed after can still replace them.
+func (p profileParser) Process(prefix string, cfg any) error {
+	var set []string
+	defer func() {
+		for _, key := range set {
+			os.Unsetenv(key)
+		}
+	}()
+
+	for key, value := range p.profile {
+		if _, exists := os.LookupEnv(key); exists {
+			continue
+		}
+
+		if err := os.Setenv(key, value); err != nil {
+			return fmt.Errorf("setting %s: %w", key, err)
+		}
+		set = append(set, key)
+	}
+
+	// The help and version flags are answered by the parse that follows.
+	if _, err := conf.Parse(prefix, cfg); err != nil && !errors.Is(err, conf.ErrHelpWanted) {
+		return fmt.Errorf("applying profile: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ResolveFunc returns the value referenced by ref, the part of a config
+// value following its scheme.
+type ResolveFunc func(ctx context.Context, ref string) (string, error)
+
+// Resolve replaces every string value of the parsed configuration that starts
+// with one of the schemes, e.g. aws-sm://wasfa/db-password, with the value
+// returned by the resolver of the scheme. It runs after parsing so references
+// can come from any source.
+func Resolve(ctx context.Context, cfg any, resolvers map[string]ResolveFunc) error {
+	v := reflect.ValueOf(cfg)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("resolve: expected a pointer to a struct, got %T", cfg)
+	}
+
+	return resolveValue(ctx, "", v.Elem(), resolvers)
+}
+
+func resolveValue(ctx context.Context, name string, v reflect.Value, resolvers map[string]ResolveFunc) error {
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := range v.NumField() {
+			f := v.Type().Field(i)
+			if !f.IsExported() {
+				continue
+			}
+
+			if err := resolveValue(ctx, join(name, f.Name), v.Field(i), resolvers); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return nil
+		}
+
+		for i := range v.Len() {
+			if err := resolveValue(ctx, fmt.Sprintf("%s[%d]", name, i), v.Index(i), resolvers); err != nil {
+				return err
+			}
+		}
+
+	case reflect.String:
+		scheme, ref, ok := strings.Cut(v.String(), "://")
+		if !ok {
+			return nil
+		}
+
+		fn, exists := resolvers[scheme]
+		if !exists {
+			return nil
+		}
+
+		value, err := fn(ctx, ref)
+		if err != nil {
+			return fmt.Errorf("resolving %s: %w", name, err)
+		}
+
+		v.SetString(value)
+	}
+
+	return nil
+}
+
+func join(prefix string, name string) string {
+	if prefix == "" {
+		return name
+	}
+
+	return prefix + "." + name
+}
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/ardanlabs/conf/v3"
+)
+
+// envKey matches the environment variable names in the conf usage output.
+var envKey = regexp.MustCompile(`\$([A-Z0-9_]+)`)
+
+// SecretFiles is a parser that supports a _FILE variant of every environment
+// variable, e.g. DB_PASSWORD_FILE=/run/secrets/db_password. When the variable
+// itself isn't set, the content of the file is used as its value so secrets
+// mounted by Docker or Kubernetes don't have to be copied into the
+// environment. It must be the last parser so the environment still takes
+// precedence over a config file.
+type SecretFiles struct{}
+
+// Process implements the conf.Parsers interface. Like for a profile, the
+// variables are only set while conf parses them into the configuration, so
+// a reload reads the files again instead of finding the variables set.
+func (SecretFiles) Process(prefix string, cfg any) error {
+	var set []string
+	defer func() {
+		for _, key := range set {
+			os.Unsetenv(key)
+		}
+	}()
+
+	// The usage output is used to find the variable names so they are
+	// derived from the fields exactly as conf does it.
+	usage, err := conf.UsageInfo(prefix, cfg)
+	if err != nil {
+		return fmt.Errorf("collecting env names: %w", err)
+	}
+
+	for _, match := range envKey.FindAllStringSubmatch(usage, -1) {
+		key := match[1]
+
+		if _, exists := os.LookupEnv(key); exists {
+			continue
+		}
+
+		path := os.Getenv(key + "_FILE")
+		if path == "" {
+			continue
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("reading %s_FILE: %w", key, err)
+		}
+
+		if err := os.Setenv(key, strings.TrimRight(string(data), "\r\n")); err != nil {
+			return fmt.Errorf("setting %s: %w", key, err)
+		}
+		set = append(set, key)
+	}
+
+	if len(set) == 0 {
+		return nil
+	}
+
+	// The help and version flags are answered by the parse that follows.
+	if _, err := conf.Parse(prefix, cfg); err != nil && !errors.Is(err, conf.ErrHelpWanted) {
+		return fmt.Errorf("applying secret files: %w", err)
+	}
+
+	return nil
+}
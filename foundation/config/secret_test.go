@@ -0,0 +1,58 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ardanlabs/conf/v3"
+	"lobbyte.com/alkeepy/foundation/config"
+)
+
+func Test_SecretFilesReload(t *testing.T) {
+	// conf parses the flags of the process, which are the ones of the test.
+	args := os.Args
+	os.Args = []string{args[0]}
+	t.Cleanup(func() { os.Args = args })
+
+	path := filepath.Join(t.TempDir(), "db_password")
+	t.Setenv("TEST_DB_PASSWORD_FILE", path)
+
+	type settings struct {
+		DB struct {
+			Password string `conf:"mask"`
+		}
+	}
+
+	parse := func(secret string) string {
+		t.Helper()
+
+		if err := os.WriteFile(path, []byte(secret+"\n"), 0o600); err != nil {
+			t.Fatalf("Should write the secret: %s", err)
+		}
+
+		var cfg settings
+		if _, err := conf.Parse("TEST", &cfg, config.SecretFiles{}); err != nil {
+			t.Fatalf("Should parse the configuration: %s", err)
+		}
+
+		return cfg.DB.Password
+	}
+
+	if got := parse("first"); got != "first" {
+		t.Fatalf("Should read the secret from the file, got %q", got)
+	}
+
+	if _, exists := os.LookupEnv("TEST_DB_PASSWORD"); exists {
+		t.Fatalf("Should not leave the variable set")
+	}
+
+	if got := parse("rotated"); got != "rotated" {
+		t.Fatalf("Should read the file again on reload, got %q", got)
+	}
+
+	t.Setenv("TEST_DB_PASSWORD", "from-env")
+	if got := parse("ignored"); got != "from-env" {
+		t.Fatalf("Should let the environment take precedence, got %q", got)
+	}
+}
@@ -0,0 +1,22 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/BurntSushi/toml"
+)
+
+// tomlParser provides support for unmarshaling TOML into the configuration.
+// Keys are matched to the field names ignoring case.
+type tomlParser struct {
+	data []byte
+}
+
+// Process implements the conf.Parsers interface.
+func (t tomlParser) Process(prefix string, cfg any) error {
+	if err := toml.Unmarshal(t.data, cfg); err != nil {
+		return fmt.Errorf("unmarshal toml: %w", err)
+	}
+
+	return nil
+}
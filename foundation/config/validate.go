@@ -0,0 +1,38 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Violations collects the problems found in the configuration so they are
+// all reported at startup instead of one at a time.
+type Violations []string
+
+// Check records the violation when the condition doesn't hold.
+func (v *Violations) Check(ok bool, field string, format string, args ...any) {
+	if !ok {
+		v.Add(field, format, args...)
+	}
+}
+
+// CheckErr records the error as a violation of the field when it isn't nil.
+func (v *Violations) CheckErr(err error, field string) {
+	if err != nil {
+		v.Add(field, "%s", err)
+	}
+}
+
+// Add records a violation of the field.
+func (v *Violations) Add(field string, format string, args ...any) {
+	*v = append(*v, field+": "+fmt.Sprintf(format, args...))
+}
+
+// Err returns an error listing every violation, or nil when there are none.
+func (v Violations) Err() error {
+	if len(v) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("invalid configuration: %s", strings.Join(v, "; "))
+}
@@ -0,0 +1,243 @@
+// Package envelope provides envelope encryption for the fields that must not
+// be stored in the clear. Every value is encrypted with a data key of its
+// own and the data key is encrypted with a key of the keyring. The id of
+// that key is stored with the ciphertext, so a key can be rotated by
+// encrypting the data keys again without touching the values.
+package envelope
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Set of error variables for decrypting values.
+var (
+	ErrUnknownKey = errors.New("unknown key")
+	ErrMalformed  = errors.New("malformed ciphertext")
+)
+
+// KeySize is the size in bytes of the keys of a keyring and of the data
+// keys, which makes them AES-256 keys.
+const KeySize = 32
+
+// version leads every sealed value so the format can change later.
+const version = "v1"
+
+// Keyring holds the keys that encrypt the data keys, by id. New values are
+// sealed with the primary key and the other keys are kept to open the
+// values sealed before it was made primary.
+type Keyring struct {
+	primary string
+	keys    map[string]cipher.AEAD
+}
+
+// New constructs a keyring from the keys by id. The primary key must be one
+// of them.
+func New(primary string, keys map[string][]byte) (*Keyring, error) {
+	if _, exists := keys[primary]; !exists {
+		return nil, fmt.Errorf("primary key %q: %w", primary, ErrUnknownKey)
+	}
+
+	kr := Keyring{
+		primary: primary,
+		keys:    make(map[string]cipher.AEAD, len(keys)),
+	}
+
+	for id, key := range keys {
+		if id == "" || strings.Contains(id, ":") {
+			return nil, fmt.Errorf("key id %q must be set and can't contain a colon", id)
+		}
+
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", id, err)
+		}
+
+		kr.keys[id] = aead
+	}
+
+	return &kr, nil
+}
+
+// Primary returns the id of the key new values are sealed with.
+func (kr *Keyring) Primary() string {
+	return kr.primary
+}
+
+// Seal encrypts the plaintext with a new data key and returns it with the
+// data key encrypted by the primary key. The additional data isn't stored
+// but must be passed again to open the value, which ties the value to
+// where it's stored, like the id of its row, so it can't be moved to
+// another one.
+func (kr *Keyring) Seal(plaintext []byte, additionalData []byte) (string, error) {
+	dataKey := make([]byte, KeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", fmt.Errorf("generating data key: %w", err)
+	}
+
+	data, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+
+	ciphertext, err := seal(data, plaintext, additionalData)
+	if err != nil {
+		return "", err
+	}
+
+	wrapped, err := seal(kr.keys[kr.primary], dataKey, []byte(kr.primary))
+	if err != nil {
+		return "", err
+	}
+
+	return format(kr.primary, wrapped, ciphertext), nil
+}
+
+// Open decrypts a value sealed with any key of the keyring. The additional
+// data must be the one the value was sealed with.
+func (kr *Keyring) Open(sealed string, additionalData []byte) ([]byte, error) {
+	keyID, wrapped, ciphertext, err := parse(sealed)
+	if err != nil {
+		return nil, err
+	}
+
+	dataKey, err := kr.unwrap(keyID, wrapped)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := open(data, ciphertext, additionalData)
+	if err != nil {
+		return nil, fmt.Errorf("decrypting value: %w", err)
+	}
+
+	return plaintext, nil
+}
+
+// Rewrap encrypts the data key of the value again with the primary key. The
+// value itself isn't decrypted, so a key is rotated by rewrapping every
+// value sealed with it before it's removed from the keyring. A value
+// already sealed with the primary key is returned as is.
+func (kr *Keyring) Rewrap(sealed string) (string, error) {
+	keyID, wrapped, ciphertext, err := parse(sealed)
+	if err != nil {
+		return "", err
+	}
+
+	if keyID == kr.primary {
+		return sealed, nil
+	}
+
+	dataKey, err := kr.unwrap(keyID, wrapped)
+	if err != nil {
+		return "", err
+	}
+
+	wrapped, err = seal(kr.keys[kr.primary], dataKey, []byte(kr.primary))
+	if err != nil {
+		return "", err
+	}
+
+	return format(kr.primary, wrapped, ciphertext), nil
+}
+
+// KeyID returns the id of the key the value was sealed with.
+func KeyID(sealed string) (string, error) {
+	keyID, _, _, err := parse(sealed)
+	if err != nil {
+		return "", err
+	}
+
+	return keyID, nil
+}
+
+// =============================================================================
+
+// unwrap decrypts the data key with the key it was sealed with.
+func (kr *Keyring) unwrap(keyID string, wrapped []byte) ([]byte, error) {
+	key, exists := kr.keys[keyID]
+	if !exists {
+		return nil, fmt.Errorf("key %q: %w", keyID, ErrUnknownKey)
+	}
+
+	dataKey, err := open(key, wrapped, []byte(keyID))
+	if err != nil {
+		return nil, fmt.Errorf("decrypting data key: %w", err)
+	}
+
+	return dataKey, nil
+}
+
+// newAEAD returns AES-GCM with the key.
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("key must be %d bytes, got %d", KeySize, len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("creating cipher: %w", err)
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts the plaintext with a random nonce placed before it.
+func seal(aead cipher.AEAD, plaintext []byte, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("generating nonce: %w", err)
+	}
+
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// open decrypts a ciphertext produced by seal.
+func open(aead cipher.AEAD, ciphertext []byte, additionalData []byte) ([]byte, error) {
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, ErrMalformed
+	}
+
+	nonce, ciphertext := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+
+	return aead.Open(nil, nonce, ciphertext, additionalData)
+}
+
+// format lays out a sealed value as the version, the key id, the wrapped
+// data key and the ciphertext separated by colons. The key id is kept in
+// the clear so the values sealed with a key can be found.
+func format(keyID string, wrapped []byte, ciphertext []byte) string {
+	enc := base64.RawURLEncoding
+
+	return strings.Join([]string{version, keyID, enc.EncodeToString(wrapped), enc.EncodeToString(ciphertext)}, ":")
+}
+
+// parse splits a sealed value laid out by format.
+func parse(sealed string) (keyID string, wrapped []byte, ciphertext []byte, err error) {
+	parts := strings.Split(sealed, ":")
+	if len(parts) != 4 || parts[0] != version || parts[1] == "" {
+		return "", nil, nil, ErrMalformed
+	}
+
+	enc := base64.RawURLEncoding
+
+	if wrapped, err = enc.DecodeString(parts[2]); err != nil {
+		return "", nil, nil, ErrMalformed
+	}
+
+	if ciphertext, err = enc.DecodeString(parts[3]); err != nil {
+		return "", nil, nil, ErrMalformed
+	}
+
+	return parts[1], wrapped, ciphertext, nil
+}
@@ -0,0 +1,189 @@
+package envelope_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+
+	"lobbyte.com/alkeepy/foundation/envelope"
+)
+
+func Test_New(t *testing.T) {
+	key := newKey(t)
+
+	tests := []struct {
+		name    string
+		primary string
+		keys    map[string][]byte
+		valid   bool
+	}{
+		{name: "valid", primary: "k1", keys: map[string][]byte{"k1": key, "k2": newKey(t)}, valid: true},
+		{name: "unknown primary", primary: "k3", keys: map[string][]byte{"k1": key}},
+		{name: "short key", primary: "k1", keys: map[string][]byte{"k1": key[:16]}},
+		{name: "empty id", primary: "k1", keys: map[string][]byte{"k1": key, "": newKey(t)}},
+		{name: "id with a colon", primary: "k:1", keys: map[string][]byte{"k:1": key}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := envelope.New(tt.primary, tt.keys)
+
+			if tt.valid && err != nil {
+				t.Fatalf("Should construct the keyring: %s", err)
+			}
+
+			if !tt.valid && err == nil {
+				t.Fatalf("Should refuse the keys")
+			}
+		})
+	}
+}
+
+func Test_Open(t *testing.T) {
+	kr, err := envelope.New("k1", map[string][]byte{"k1": newKey(t)})
+	if err != nil {
+		t.Fatalf("Should construct the keyring: %s", err)
+	}
+
+	other, err := envelope.New("k1", map[string][]byte{"k1": newKey(t)})
+	if err != nil {
+		t.Fatalf("Should construct the keyring: %s", err)
+	}
+
+	plaintext := []byte("+966 55 123 4567")
+	ad := []byte("5cf37266-3473-4006-984f-9325122678b7")
+
+	sealed, err := kr.Seal(plaintext, ad)
+	if err != nil {
+		t.Fatalf("Should seal the value: %s", err)
+	}
+
+	parts := strings.Split(sealed, ":")
+
+	tests := []struct {
+		name    string
+		keyring *envelope.Keyring
+		sealed  string
+		ad      []byte
+		valid   bool
+		wantErr error
+	}{
+		{name: "valid", keyring: kr, sealed: sealed, ad: ad, valid: true},
+		{name: "other additional data", keyring: kr, sealed: sealed, ad: []byte("another row")},
+		{name: "no additional data", keyring: kr, sealed: sealed},
+		{name: "other key with the same id", keyring: other, sealed: sealed, ad: ad},
+		{name: "unknown key", keyring: kr, sealed: join(parts[0], "k9", parts[2], parts[3]), ad: ad, wantErr: envelope.ErrUnknownKey},
+		{name: "tampered ciphertext", keyring: kr, sealed: join(parts[0], parts[1], parts[2], flip(t, parts[3])), ad: ad},
+		{name: "tampered data key", keyring: kr, sealed: join(parts[0], parts[1], flip(t, parts[2]), parts[3]), ad: ad},
+		{name: "truncated ciphertext", keyring: kr, sealed: join(parts[0], parts[1], parts[2], "AAAA"), ad: ad, wantErr: envelope.ErrMalformed},
+		{name: "other version", keyring: kr, sealed: join("v0", parts[1], parts[2], parts[3]), ad: ad, wantErr: envelope.ErrMalformed},
+		{name: "missing part", keyring: kr, sealed: join(parts[0], parts[1], parts[2]), ad: ad, wantErr: envelope.ErrMalformed},
+		{name: "not base64", keyring: kr, sealed: join(parts[0], parts[1], parts[2], "!!"), ad: ad, wantErr: envelope.ErrMalformed},
+		{name: "plaintext", keyring: kr, sealed: string(plaintext), ad: ad, wantErr: envelope.ErrMalformed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.keyring.Open(tt.sealed, tt.ad)
+
+			if tt.valid {
+				if err != nil {
+					t.Fatalf("Should open the value: %s", err)
+				}
+				if !bytes.Equal(got, plaintext) {
+					t.Fatalf("Should get the plaintext back, got %q", got)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatalf("Should refuse to open the value")
+			}
+
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Should fail with %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func Test_Rewrap(t *testing.T) {
+	k1, k2 := newKey(t), newKey(t)
+
+	old, err := envelope.New("k1", map[string][]byte{"k1": k1})
+	if err != nil {
+		t.Fatalf("Should construct the keyring: %s", err)
+	}
+
+	sealed, err := old.Seal([]byte("12 Baker Street"), []byte("row"))
+	if err != nil {
+		t.Fatalf("Should seal the value: %s", err)
+	}
+
+	rotated, err := envelope.New("k2", map[string][]byte{"k1": k1, "k2": k2})
+	if err != nil {
+		t.Fatalf("Should construct the keyring: %s", err)
+	}
+
+	rewrapped, err := rotated.Rewrap(sealed)
+	if err != nil {
+		t.Fatalf("Should rewrap the value: %s", err)
+	}
+
+	if id, _ := envelope.KeyID(rewrapped); id != "k2" {
+		t.Fatalf("Should be sealed with the primary key, got %q", id)
+	}
+
+	again, err := rotated.Rewrap(rewrapped)
+	if err != nil || again != rewrapped {
+		t.Fatalf("Should leave a value sealed with the primary key as is: %v", err)
+	}
+
+	retired, err := envelope.New("k2", map[string][]byte{"k2": k2})
+	if err != nil {
+		t.Fatalf("Should construct the keyring: %s", err)
+	}
+
+	got, err := retired.Open(rewrapped, []byte("row"))
+	if err != nil {
+		t.Fatalf("Should open the value once the old key is removed: %s", err)
+	}
+
+	if string(got) != "12 Baker Street" {
+		t.Fatalf("Should get the plaintext back, got %q", got)
+	}
+
+	if _, err := retired.Open(sealed, []byte("row")); !errors.Is(err, envelope.ErrUnknownKey) {
+		t.Fatalf("Should refuse a value sealed with a removed key, got %v", err)
+	}
+}
+
+// =============================================================================
+
+func newKey(t *testing.T) []byte {
+	key := make([]byte, envelope.KeySize)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatalf("Should generate a key: %s", err)
+	}
+
+	return key
+}
+
+func join(parts ...string) string {
+	return strings.Join(parts, ":")
+}
+
+// flip changes the last byte of the base64 encoded part.
+func flip(t *testing.T, part string) string {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		t.Fatalf("Should decode the part: %s", err)
+	}
+
+	data[len(data)-1] ^= 0xff
+
+	return base64.RawURLEncoding.EncodeToString(data)
+}
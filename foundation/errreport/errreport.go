@@ -0,0 +1,91 @@
+// Package errreport ships unexpected errors and panics to Sentry or any
+// service implementing the Sentry protocol, like GlitchTip.
+package errreport
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// Config defines the information needed to report errors.
+type Config struct {
+	DSN         string
+	Environment string
+	Release     string
+	SampleRate  float64
+}
+
+// Reporter sends error events. A nil Reporter is valid and discards every
+// event so callers don't need to check if reporting is enabled.
+type Reporter struct {
+	client *sentry.Client
+}
+
+// New constructs a Reporter. A nil Reporter is returned when no DSN is
+// configured.
+func New(cfg Config) (*Reporter, error) {
+	if cfg.DSN == "" {
+		return nil, nil
+	}
+
+	client, err := sentry.NewClient(sentry.ClientOptions{
+		Dsn:              cfg.DSN,
+		Environment:      cfg.Environment,
+		Release:          cfg.Release,
+		SampleRate:       cfg.SampleRate,
+		AttachStacktrace: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating client: %w", err)
+	}
+
+	return &Reporter{
+		client: client,
+	}, nil
+}
+
+// Report sends the error along with the request details and tags. The stack
+// trace is taken from where Report is called.
+func (rp *Reporter) Report(ctx context.Context, err error, r *http.Request, tags map[string]string) {
+	if rp == nil {
+		return
+	}
+
+	rp.hub(r, tags).CaptureException(err)
+}
+
+// ReportPanic sends the value recovered from a panic along with the request
+// details and tags. It must be called from the deferred function that
+// recovered the panic so the stack trace points at the panic.
+func (rp *Reporter) ReportPanic(ctx context.Context, rec any, r *http.Request, tags map[string]string) {
+	if rp == nil {
+		return
+	}
+
+	rp.hub(r, tags).RecoverWithContext(ctx, rec)
+}
+
+// Flush waits until the queued events are sent or the timeout is reached.
+func (rp *Reporter) Flush(timeout time.Duration) bool {
+	if rp == nil {
+		return true
+	}
+
+	return rp.client.Flush(timeout)
+}
+
+// hub constructs a hub with its own scope so the request details of
+// concurrent requests don't mix.
+func (rp *Reporter) hub(r *http.Request, tags map[string]string) *sentry.Hub {
+	scope := sentry.NewScope()
+	if r != nil {
+		scope.SetRequest(r)
+	}
+	scope.SetTags(tags)
+
+	return sentry.NewHub(rp.client, scope)
+}
@@ -0,0 +1,188 @@
+// Package health provides support for aggregating the health of the
+// dependencies of the service.
+package health
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// Set of statuses reported for a check.
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// CheckFunc reports the health of a dependency. A non-nil error means the
+// dependency is unhealthy.
+type CheckFunc func(ctx context.Context) error
+
+// Result is the outcome of a single check.
+type Result struct {
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// Report is the outcome of running every registered check.
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks,omitempty"`
+}
+
+// Healthy reports whether every check passed.
+func (r Report) Healthy() bool {
+	return r.Status == StatusOK
+}
+
+// Print writes the report as a table with a line per check in sorted order.
+func (r Report) Print(w io.Writer) error {
+	names := make([]string, 0, len(r.Checks))
+	for name := range r.Checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	for _, name := range names {
+		result := r.Checks[name]
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", name, result.Status, result.Duration, result.Error)
+	}
+	fmt.Fprintf(tw, "overall\t%s\t\t\n", r.Status)
+
+	return tw.Flush()
+}
+
+type check struct {
+	name    string
+	timeout time.Duration
+	fn      CheckFunc
+}
+
+// Registry holds the set of named checks.
+type Registry struct {
+	mu             sync.RWMutex
+	defaultTimeout time.Duration
+	checks         map[string]check
+}
+
+// New constructs a Registry. The default timeout is used for checks that are
+// registered without their own timeout.
+func New(defaultTimeout time.Duration) *Registry {
+	return &Registry{
+		defaultTimeout: defaultTimeout,
+		checks:         make(map[string]check),
+	}
+}
+
+// Register adds a named check. Registering a name a second time replaces the
+// existing check. A timeout of 0 uses the default timeout.
+func (r *Registry) Register(name string, timeout time.Duration, fn CheckFunc) {
+	if timeout <= 0 {
+		timeout = r.defaultTimeout
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.checks[name] = check{
+		name:    name,
+		timeout: timeout,
+		fn:      fn,
+	}
+}
+
+// Names returns the names of the registered checks in sorted order.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.checks))
+	for name := range r.checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Run executes every check concurrently, each with its own timeout, and
+// reports which of them failed.
+func (r *Registry) Run(ctx context.Context) Report {
+	r.mu.RLock()
+	checks := make([]check, 0, len(r.checks))
+	for _, c := range r.checks {
+		checks = append(checks, c)
+	}
+	r.mu.RUnlock()
+
+	report := Report{
+		Status: StatusOK,
+		Checks: make(map[string]Result, len(checks)),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	wg.Add(len(checks))
+
+	for _, c := range checks {
+		go func() {
+			defer wg.Done()
+
+			result := c.run(ctx)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			report.Checks[c.name] = result
+			if result.Status != StatusOK {
+				report.Status = StatusFail
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	return report
+}
+
+// run executes the check and waits no longer than the timeout for it to
+// complete.
+func (c check) run(ctx context.Context) Result {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+
+	// The check runs in its own goroutine so a check that ignores the context
+	// can't hold up the report.
+	ch := make(chan error, 1)
+	go func() {
+		ch <- c.fn(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-ch:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result := Result{
+		Status:   StatusOK,
+		Duration: time.Since(start).String(),
+	}
+
+	if err != nil {
+		result.Status = StatusFail
+		result.Error = err.Error()
+	}
+
+	return result
+}
@@ -0,0 +1,72 @@
+// Package limits fits the Go runtime to the CPU and memory limits of the
+// container the service is running in.
+package limits
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/KimMachineGun/automemlimit/memlimit"
+	"go.uber.org/automaxprocs/maxprocs"
+)
+
+// Config defines the overrides of the limits read from the cgroup. A zero
+// MaxProcs or MemLimitMB uses the limit of the container.
+type Config struct {
+	MaxProcs      int
+	MemLimitMB    int
+	MemLimitRatio float64
+}
+
+// Set adjusts GOMAXPROCS to the CPU quota and GOMEMLIMIT to a ratio of the
+// memory limit of the container, leaving headroom for memory the runtime
+// doesn't manage. The GOMAXPROCS and GOMEMLIMIT environment variables still
+// take precedence over the limits of the container. Without a cgroup the
+// memory limit is logged as unknown and left as it is.
+func Set(log *slog.Logger, cfg Config) error {
+	switch {
+	case cfg.MaxProcs > 0:
+		runtime.GOMAXPROCS(cfg.MaxProcs)
+
+	default:
+		printf := func(format string, args ...any) {
+			log.Info("limits", "maxprocs", fmt.Sprintf(format, args...))
+		}
+
+		if _, err := maxprocs.Set(maxprocs.Logger(printf)); err != nil {
+			return fmt.Errorf("setting GOMAXPROCS: %w", err)
+		}
+	}
+
+	switch {
+	case cfg.MemLimitMB > 0:
+		debug.SetMemoryLimit(int64(cfg.MemLimitMB) << 20)
+
+	default:
+		_, err := memlimit.SetGoMemLimitWithOpts(
+			memlimit.WithRatio(cfg.MemLimitRatio),
+			memlimit.WithProvider(memlimit.FromCgroup),
+			memlimit.WithLogger(log.With("limits", "memlimit")),
+		)
+
+		// Outside of a container, like on a laptop running macOS, there's no
+		// cgroup to read the limit from and the runtime keeps its default.
+		switch {
+		case errors.Is(err, memlimit.ErrCgroupsNotSupported), errors.Is(err, memlimit.ErrNoCgroup):
+			log.Info("limits", "memlimit", "no cgroup, GOMEMLIMIT left unchanged", "msg", err)
+
+		case err != nil:
+			return fmt.Errorf("setting GOMEMLIMIT: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// MemLimit returns the current soft memory limit of the runtime in bytes.
+func MemLimit() int64 {
+	return debug.SetMemoryLimit(-1)
+}
@@ -0,0 +1,46 @@
+package logger
+
+import (
+	"io"
+	"os"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// FileConfig defines how logs are written to a file.
+type FileConfig struct {
+	Path       string
+	MaxSizeMB  int
+	MaxAgeDays int
+	MaxBackups int
+	Compress   bool
+}
+
+// NewFile constructs a writer for the log file that rotates the file once it
+// reaches the maximum size. Rotated files are removed once they are older
+// than the maximum age or there are more than the maximum number of backups.
+func NewFile(cfg FileConfig) io.WriteCloser {
+	return &lumberjack.Logger{
+		Filename:   cfg.Path,
+		MaxSize:    cfg.MaxSizeMB,
+		MaxAge:     cfg.MaxAgeDays,
+		MaxBackups: cfg.MaxBackups,
+		Compress:   cfg.Compress,
+	}
+}
+
+// isTerminal reports whether the writer is a terminal so color is only used
+// when a person is reading the output.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	return fi.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,36 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+)
+
+// TraceIDFn represents a function that returns the trace id for the request
+// in the context, or an empty string when there isn't one.
+type TraceIDFn func(ctx context.Context) string
+
+// traceIDHandler adds the trace id from the context to every record so
+// records logged with a context correlate with the request and its trace.
+type traceIDHandler struct {
+	slog.Handler
+	traceIDFn TraceIDFn
+}
+
+// Handle adds the trace id before passing the record on.
+func (h traceIDHandler) Handle(ctx context.Context, r slog.Record) error {
+	if traceID := h.traceIDFn(ctx); traceID != "" {
+		r.AddAttrs(slog.String("trace_id", traceID))
+	}
+
+	return h.Handler.Handle(ctx, r)
+}
+
+// WithAttrs returns a handler that keeps adding the trace id.
+func (h traceIDHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return traceIDHandler{h.Handler.WithAttrs(attrs), h.traceIDFn}
+}
+
+// WithGroup returns a handler that keeps adding the trace id.
+func (h traceIDHandler) WithGroup(name string) slog.Handler {
+	return traceIDHandler{h.Handler.WithGroup(name), h.traceIDFn}
+}
@@ -0,0 +1,51 @@
+// Package logger provides support for constructing the application logger.
+package logger
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/lmittmann/tint"
+)
+
+// Set of formats the logger can write.
+const (
+	FormatTint = "tint"
+	FormatJSON = "json"
+)
+
+// New constructs a logger writing in the specified format. The tint format
+// is colorized for reading in a terminal during development while the json
+// format is meant for log collectors in production. Color is turned off when
+// the writer isn't a terminal. When a trace id function is provided the trace
+// id of the request is added to every record logged with a context.
+func New(w io.Writer, format string, level slog.Leveler, service string, traceIDFn TraceIDFn) (*slog.Logger, error) {
+	var h slog.Handler
+
+	switch format {
+	case FormatTint:
+		h = tint.NewHandler(w, &tint.Options{
+			AddSource:  true,
+			Level:      level,
+			TimeFormat: time.DateTime,
+			NoColor:    !isTerminal(w),
+		})
+
+	case FormatJSON:
+		h = slog.NewJSONHandler(w, &slog.HandlerOptions{
+			AddSource: true,
+			Level:     level,
+		})
+
+	default:
+		return nil, fmt.Errorf("unknown log format %q", format)
+	}
+
+	if traceIDFn != nil {
+		h = traceIDHandler{h, traceIDFn}
+	}
+
+	return slog.New(h).With("service", service), nil
+}
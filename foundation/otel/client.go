@@ -0,0 +1,72 @@
+package otel
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Transport is a http.RoundTripper that starts a client span for every
+// outbound request and injects the W3C trace context headers so the called
+// service continues the trace.
+type Transport struct {
+	base http.RoundTripper
+}
+
+// NewTransport wraps the specified transport. When base is nil the
+// http.DefaultTransport is used.
+func NewTransport(base http.RoundTripper) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return &Transport{
+		base: base,
+	}
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (t *Transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	ctx, span := otel.Tracer(tracerName).Start(r.Context(), fmt.Sprintf("HTTP %s", r.Method),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.request.method", r.Method),
+			attribute.String("url.full", r.URL.Redacted()),
+		),
+	)
+	defer span.End()
+
+	// The request must not be modified by a RoundTripper so the headers are
+	// written to a clone.
+	r = r.Clone(ctx)
+	Inject(ctx, r.Header)
+
+	resp, err := t.base.RoundTrip(r)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(resp.StatusCode))
+	}
+
+	return resp, nil
+}
+
+// NewClient constructs a http.Client that propagates the trace of the
+// context used to make each request.
+func NewClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Transport: NewTransport(nil),
+		Timeout:   timeout,
+	}
+}
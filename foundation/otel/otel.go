@@ -0,0 +1,124 @@
+// Package otel provides otel support.
+package otel
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// tracerName is the instrumentation name used for spans started by this
+// package.
+const tracerName = "lobbyte.com/alkeepy"
+
+// Config defines the information needed to init tracing.
+type Config struct {
+	ServiceName string
+	Host        string
+	Probability float64
+}
+
+// InitTracing configures open telemetry to be used with the service. When no
+// host is configured a no-op provider is returned so spans cost nothing. The
+// returned function flushes and stops the exporter and must be called during
+// shutdown.
+func InitTracing(log *slog.Logger, cfg Config) (trace.TracerProvider, func(ctx context.Context), error) {
+
+	// W3C trace context is honored even when tracing is disabled so the
+	// trace id of an inbound request is carried to outbound requests.
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	if cfg.Host == "" {
+		log.Info("OTEL", "tracer", "NOOP")
+
+		tp := noop.NewTracerProvider()
+		otel.SetTracerProvider(tp)
+
+		return tp, func(ctx context.Context) {}, nil
+	}
+
+	exporter, err := otlptrace.New(
+		context.Background(),
+		otlptracegrpc.NewClient(
+			otlptracegrpc.WithInsecure(),
+			otlptracegrpc.WithEndpoint(cfg.Host),
+		),
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("creating new exporter: %w", err)
+	}
+
+	log.Info("OTEL", "tracer", cfg.Host, "probability", cfg.Probability)
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.Probability))),
+		sdktrace.WithBatcher(exporter,
+			sdktrace.WithMaxExportBatchSize(sdktrace.DefaultMaxExportBatchSize),
+			sdktrace.WithBatchTimeout(sdktrace.DefaultScheduleDelay),
+		),
+		sdktrace.WithResource(
+			resource.NewWithAttributes(
+				semconv.SchemaURL,
+				semconv.ServiceNameKey.String(cfg.ServiceName),
+			),
+		),
+	)
+
+	// We must set this provider as the global provider for things to work,
+	// but we pass this provider around the program where needed to collect
+	// our traces.
+	otel.SetTracerProvider(tp)
+
+	teardown := func(ctx context.Context) {
+		tp.Shutdown(ctx)
+	}
+
+	return tp, teardown, nil
+}
+
+// AddSpan adds an otel span to the existing trace in the context so business
+// and data layers can time the work they do on behalf of a request.
+func AddSpan(ctx context.Context, spanName string, keyValues ...attribute.KeyValue) (context.Context, trace.Span) {
+	ctx, span := otel.Tracer(tracerName).Start(ctx, spanName)
+	span.SetAttributes(keyValues...)
+
+	return ctx, span
+}
+
+// Extract returns a context containing the remote span described by the
+// traceparent and tracestate headers of the request, if any.
+func Extract(ctx context.Context, h http.Header) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(h))
+}
+
+// Inject writes the traceparent and tracestate headers for the span in the
+// context into the specified headers.
+func Inject(ctx context.Context, h http.Header) {
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(h))
+}
+
+// GetTraceID returns the trace id of the span in the context, or an empty
+// string when the context doesn't contain a recording span.
+func GetTraceID(ctx context.Context) string {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.HasTraceID() {
+		return ""
+	}
+
+	return sc.TraceID().String()
+}
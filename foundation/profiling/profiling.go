@@ -0,0 +1,78 @@
+// Package profiling provides support for pushing profiles to a continuous
+// profiling backend that speaks the Pyroscope protocol.
+package profiling
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/grafana/pyroscope-go"
+)
+
+// Config defines the information needed to push profiles.
+type Config struct {
+	ServerAddress     string
+	ApplicationName   string
+	BasicAuthUser     string
+	BasicAuthPassword string
+	TenantID          string
+	UploadRate        time.Duration
+	Tags              map[string]string
+}
+
+// Start begins collecting CPU, heap and goroutine profiles and pushing them
+// every upload period. The CPU profiler runs the whole time so on demand CPU
+// profiles from /debug/pprof/profile fail while it's running. When no server
+// address is configured nothing is started. The returned function stops the
+// profiler and flushes the pending profiles.
+func Start(log *slog.Logger, cfg Config) (func() error, error) {
+	if cfg.ServerAddress == "" {
+		log.Info("profiling", "status", "disabled")
+		return func() error { return nil }, nil
+	}
+
+	profiler, err := pyroscope.Start(pyroscope.Config{
+		ApplicationName:   cfg.ApplicationName,
+		ServerAddress:     cfg.ServerAddress,
+		BasicAuthUser:     cfg.BasicAuthUser,
+		BasicAuthPassword: cfg.BasicAuthPassword,
+		TenantID:          cfg.TenantID,
+		UploadRate:        cfg.UploadRate,
+		Tags:              cfg.Tags,
+		Logger:            logger{log},
+		ProfileTypes: []pyroscope.ProfileType{
+			pyroscope.ProfileCPU,
+			pyroscope.ProfileAllocObjects,
+			pyroscope.ProfileAllocSpace,
+			pyroscope.ProfileInuseObjects,
+			pyroscope.ProfileInuseSpace,
+			pyroscope.ProfileGoroutines,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("starting profiler: %w", err)
+	}
+
+	log.Info("profiling", "status", "started", "server", cfg.ServerAddress, "rate", cfg.UploadRate)
+
+	return profiler.Stop, nil
+}
+
+// logger adapts slog to the logger the profiler expects.
+type logger struct {
+	log *slog.Logger
+}
+
+func (l logger) Infof(format string, args ...any) {
+	l.log.InfoContext(context.Background(), "profiling", "msg", fmt.Sprintf(format, args...))
+}
+
+func (l logger) Debugf(format string, args ...any) {
+	l.log.DebugContext(context.Background(), "profiling", "msg", fmt.Sprintf(format, args...))
+}
+
+func (l logger) Errorf(format string, args ...any) {
+	l.log.ErrorContext(context.Background(), "profiling", "msg", fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,77 @@
+// Package pubsub provides support for fanning messages out to the
+// subscribers inside the process, like the streams of the clients watching
+// for changes.
+package pubsub
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Broker delivers every published message to each of its subscribers.
+type Broker[T any] struct {
+	mu      sync.Mutex
+	subs    map[chan T]struct{}
+	dropped atomic.Int64
+}
+
+// New constructs a broker without subscribers.
+func New[T any]() *Broker[T] {
+	return &Broker[T]{
+		subs: make(map[chan T]struct{}),
+	}
+}
+
+// Subscribe returns a channel receiving the messages published from now on
+// and the function that ends the subscription and closes the channel. The
+// channel buffers up to size messages for a subscriber that falls behind,
+// further ones are dropped for it.
+func (b *Broker[T]) Subscribe(size int) (<-chan T, func()) {
+	ch := make(chan T, size)
+
+	b.mu.Lock()
+	b.subs[ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subs, ch)
+			b.mu.Unlock()
+
+			close(ch)
+		})
+	}
+
+	return ch, unsubscribe
+}
+
+// Publish delivers the message to every subscriber without waiting for
+// the ones that fell behind.
+func (b *Broker[T]) Publish(msg T) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subs {
+		select {
+		case ch <- msg:
+		default:
+			b.dropped.Add(1)
+		}
+	}
+}
+
+// Subscribers returns the number of subscribers.
+func (b *Broker[T]) Subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return len(b.subs)
+}
+
+// Dropped returns the number of messages dropped for the subscribers that
+// fell behind.
+func (b *Broker[T]) Dropped() int64 {
+	return b.dropped.Load()
+}
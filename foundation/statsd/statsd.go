@@ -0,0 +1,159 @@
+// Package statsd provides a client for sending metrics to a StatsD server,
+// including the tag extension used by the Datadog agent.
+package statsd
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxPacketSize keeps packets below the MTU of most networks.
+const maxPacketSize = 1432
+
+// Config defines the information needed to send metrics.
+type Config struct {
+	Host          string
+	Prefix        string
+	Tags          []string
+	FlushInterval time.Duration
+}
+
+// Client sends metrics over UDP. Metrics are buffered and sent every flush
+// interval or when the buffer fills a packet. Sending never blocks the caller
+// and errors are dropped since metrics are best effort.
+type Client struct {
+	conn   net.Conn
+	prefix string
+	tags   string
+
+	mu  sync.Mutex
+	buf []byte
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New constructs a Client for the configured host.
+func New(cfg Config) (*Client, error) {
+	conn, err := net.Dial("udp", cfg.Host)
+	if err != nil {
+		return nil, fmt.Errorf("dialing statsd: %w", err)
+	}
+
+	prefix := cfg.Prefix
+	if prefix != "" && !strings.HasSuffix(prefix, ".") {
+		prefix += "."
+	}
+
+	interval := cfg.FlushInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	c := Client{
+		conn:   conn,
+		prefix: prefix,
+		tags:   strings.Join(cfg.Tags, ","),
+		done:   make(chan struct{}),
+	}
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				c.Flush()
+			case <-c.done:
+				return
+			}
+		}
+	}()
+
+	return &c, nil
+}
+
+// Count adds the value to a counter.
+func (c *Client) Count(name string, value int64, tags ...string) {
+	c.send(name, strconv.FormatInt(value, 10), "c", tags)
+}
+
+// Gauge sets a gauge to the value.
+func (c *Client) Gauge(name string, value float64, tags ...string) {
+	c.send(name, strconv.FormatFloat(value, 'f', -1, 64), "g", tags)
+}
+
+// Timing records a duration in milliseconds.
+func (c *Client) Timing(name string, d time.Duration, tags ...string) {
+	c.send(name, strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', -1, 64), "ms", tags)
+}
+
+// Flush sends the buffered metrics.
+func (c *Client) Flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.flush()
+}
+
+// Close sends the buffered metrics and closes the connection.
+func (c *Client) Close() error {
+	close(c.done)
+	c.wg.Wait()
+
+	c.Flush()
+
+	return c.conn.Close()
+}
+
+// send formats the metric and appends it to the buffer.
+func (c *Client) send(name string, value string, kind string, tags []string) {
+	var b strings.Builder
+	b.WriteString(c.prefix)
+	b.WriteString(name)
+	b.WriteByte(':')
+	b.WriteString(value)
+	b.WriteByte('|')
+	b.WriteString(kind)
+
+	if c.tags != "" || len(tags) > 0 {
+		b.WriteString("|#")
+		b.WriteString(c.tags)
+		if c.tags != "" && len(tags) > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strings.Join(tags, ","))
+	}
+
+	line := b.String()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.buf)+len(line)+1 > maxPacketSize {
+		c.flush()
+	}
+
+	if len(c.buf) > 0 {
+		c.buf = append(c.buf, '\n')
+	}
+	c.buf = append(c.buf, line...)
+}
+
+// flush writes the buffer as a single packet. The lock must be held.
+func (c *Client) flush() {
+	if len(c.buf) == 0 {
+		return
+	}
+
+	c.conn.Write(c.buf)
+	c.buf = c.buf[:0]
+}
@@ -0,0 +1,175 @@
+// Package tlscert provides support for serving TLS with certificates that can
+// be reloaded while the server is running.
+package tlscert
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// Config represents the files needed to serve TLS. When RequireClientCert is
+// set every client must present a certificate signed by the client CA.
+type Config struct {
+	CertFile          string
+	KeyFile           string
+	ClientCAFile      string
+	RequireClientCert bool
+}
+
+// Store holds the current TLS configuration and replaces it when the
+// certificate files are reloaded.
+type Store struct {
+	cfg     Config
+	mu      sync.RWMutex
+	tlsCfg  *tls.Config
+	modTime time.Time
+}
+
+// New constructs a Store and performs the initial load of the certificates.
+func New(cfg Config) (*Store, error) {
+	if cfg.CertFile == "" || cfg.KeyFile == "" {
+		return nil, errors.New("cert and key files are required")
+	}
+
+	if cfg.RequireClientCert && cfg.ClientCAFile == "" {
+		return nil, errors.New("client ca file is required to verify client certificates")
+	}
+
+	s := Store{
+		cfg: cfg,
+	}
+
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+// Reload reads the certificate files from disk and replaces the configuration
+// used for new connections. Existing connections are not affected.
+func (s *Store) Reload() error {
+	cert, err := tls.LoadX509KeyPair(s.cfg.CertFile, s.cfg.KeyFile)
+	if err != nil {
+		return fmt.Errorf("loading key pair: %w", err)
+	}
+
+	tlsCfg := tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+		NextProtos:   []string{"h2", "http/1.1"},
+	}
+
+	if s.cfg.ClientCAFile != "" {
+		pem, err := os.ReadFile(s.cfg.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("reading client ca: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return errors.New("client ca file contains no certificates")
+		}
+
+		tlsCfg.ClientCAs = pool
+		tlsCfg.ClientAuth = tls.VerifyClientCertIfGiven
+
+		if s.cfg.RequireClientCert {
+			tlsCfg.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	}
+
+	modTime, err := s.latestModTime()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tlsCfg = &tlsCfg
+	s.modTime = modTime
+
+	return nil
+}
+
+// TLSConfig returns the configuration to use with a http.Server. Every new
+// connection picks up the most recently loaded certificates.
+func (s *Store) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		NextProtos: []string{"h2", "http/1.1"},
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			s.mu.RLock()
+			defer s.mu.RUnlock()
+
+			return s.tlsCfg, nil
+		},
+	}
+}
+
+// Watch polls the certificate files at the specified interval and reloads them
+// when they change. Watch blocks until the context is cancelled.
+func (s *Store) Watch(ctx context.Context, log *slog.Logger, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-ticker.C:
+			modTime, err := s.latestModTime()
+			if err != nil {
+				log.ErrorContext(ctx, "tlscert", "status", "checking certificates", "msg", err)
+				continue
+			}
+
+			s.mu.RLock()
+			changed := modTime.After(s.modTime)
+			s.mu.RUnlock()
+
+			if !changed {
+				continue
+			}
+
+			if err := s.Reload(); err != nil {
+				log.ErrorContext(ctx, "tlscert", "status", "reloading certificates", "msg", err)
+				continue
+			}
+
+			log.InfoContext(ctx, "tlscert", "status", "certificates reloaded")
+		}
+	}
+}
+
+// latestModTime returns the most recent modification time of the
+// certificate files.
+func (s *Store) latestModTime() (time.Time, error) {
+	var latest time.Time
+
+	for _, file := range []string{s.cfg.CertFile, s.cfg.KeyFile, s.cfg.ClientCAFile} {
+		if file == "" {
+			continue
+		}
+
+		info, err := os.Stat(file)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("stat %s: %w", file, err)
+		}
+
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+
+	return latest, nil
+}
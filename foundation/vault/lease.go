@@ -0,0 +1,79 @@
+package vault
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// retryDelay is the minimum time between two attempts to renew or replace
+// the credentials.
+const retryDelay = 5 * time.Second
+
+// RotateFunc is called with new credentials before the old ones expire. An
+// error keeps the old credentials in use and the rotation is retried.
+type RotateFunc func(ctx context.Context, creds Credentials) error
+
+// Keep renews the lease of the credentials until the context is canceled.
+// A renewal is attempted once two thirds of the lease has elapsed. When the
+// lease can't be renewed, or Vault grants less than a third of the original
+// duration because the maximum TTL is near, new credentials are generated and
+// passed to rotate so the caller can rebuild its connection pool before the
+// old user expires. It's meant to be run in its own goroutine.
+func (c *Client) Keep(ctx context.Context, log *slog.Logger, creds Credentials, rotate RotateFunc) {
+	if creds.LeaseDuration <= 0 {
+		return
+	}
+
+	ttl := creds.LeaseDuration
+	expires := time.Now().Add(ttl)
+
+	for {
+		wait := max(time.Until(expires)*2/3, retryDelay)
+
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-time.After(wait):
+		}
+
+		if creds.Renewable {
+			granted, err := c.Renew(ctx, creds.LeaseID, ttl)
+			switch {
+			case err != nil:
+				log.WarnContext(ctx, "vault", "status", "renewing lease", "lease_id", creds.LeaseID, "msg", err)
+
+			case granted >= ttl/3:
+				expires = time.Now().Add(granted)
+				log.InfoContext(ctx, "vault", "status", "lease renewed", "lease_id", creds.LeaseID, "duration", granted)
+				continue
+
+			default:
+				expires = time.Now().Add(granted)
+				log.InfoContext(ctx, "vault", "status", "lease near max ttl", "lease_id", creds.LeaseID, "duration", granted)
+			}
+		}
+
+		fresh, err := c.Credentials(ctx)
+		if err != nil {
+			log.ErrorContext(ctx, "vault", "status", "generating credentials", "msg", err)
+			continue
+		}
+
+		if err := rotate(ctx, fresh); err != nil {
+			log.ErrorContext(ctx, "vault", "status", "rotating credentials", "lease_id", fresh.LeaseID, "msg", err)
+			continue
+		}
+
+		log.InfoContext(ctx, "vault", "status", "credentials rotated", "from", creds.LeaseID, "to", fresh.LeaseID, "duration", fresh.LeaseDuration)
+
+		creds = fresh
+		ttl = fresh.LeaseDuration
+		expires = time.Now().Add(ttl)
+
+		if ttl <= 0 {
+			return
+		}
+	}
+}
@@ -0,0 +1,188 @@
+// Package vault fetches and renews dynamic database credentials from
+// HashiCorp Vault using its HTTP API.
+package vault
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Config defines the information needed to talk to Vault.
+type Config struct {
+	Address   string
+	Token     string
+	Namespace string
+	Mount     string
+	Role      string
+	Client    *http.Client
+}
+
+// Credentials represents a database user created by Vault for a lease.
+type Credentials struct {
+	Username      string
+	Password      string
+	LeaseID       string
+	LeaseDuration time.Duration
+	Renewable     bool
+}
+
+// Client provides access to the database secrets engine of a Vault server.
+type Client struct {
+	address   string
+	token     string
+	namespace string
+	mount     string
+	role      string
+	client    *http.Client
+}
+
+// New constructs a Client for the specified Vault server. The mount defaults
+// to "database".
+func New(cfg Config) (*Client, error) {
+	if _, err := url.ParseRequestURI(cfg.Address); err != nil {
+		return nil, fmt.Errorf("parsing address: %w", err)
+	}
+
+	if cfg.Role == "" {
+		return nil, fmt.Errorf("role is required")
+	}
+
+	mount := strings.Trim(cfg.Mount, "/")
+	if mount == "" {
+		mount = "database"
+	}
+
+	client := cfg.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	c := Client{
+		address:   strings.TrimRight(cfg.Address, "/"),
+		token:     cfg.Token,
+		namespace: cfg.Namespace,
+		mount:     mount,
+		role:      cfg.Role,
+		client:    client,
+	}
+
+	return &c, nil
+}
+
+// secret is the part of a Vault response describing a lease.
+type secret struct {
+	LeaseID       string `json:"lease_id"`
+	LeaseDuration int64  `json:"lease_duration"`
+	Renewable     bool   `json:"renewable"`
+	Data          struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	} `json:"data"`
+}
+
+// Credentials generates a new database user for the configured role.
+func (c *Client) Credentials(ctx context.Context) (Credentials, error) {
+	var s secret
+	if err := c.do(ctx, http.MethodGet, "/v1/"+c.mount+"/creds/"+c.role, nil, &s); err != nil {
+		return Credentials{}, fmt.Errorf("creds: %w", err)
+	}
+
+	creds := Credentials{
+		Username:      s.Data.Username,
+		Password:      s.Data.Password,
+		LeaseID:       s.LeaseID,
+		LeaseDuration: time.Duration(s.LeaseDuration) * time.Second,
+		Renewable:     s.Renewable,
+	}
+
+	return creds, nil
+}
+
+// Check verifies the server can be reached and the token is valid without
+// generating credentials.
+func (c *Client) Check(ctx context.Context) error {
+	var resp struct {
+		Data struct {
+			TTL int64 `json:"ttl"`
+		} `json:"data"`
+	}
+
+	if err := c.do(ctx, http.MethodGet, "/v1/auth/token/lookup-self", nil, &resp); err != nil {
+		return fmt.Errorf("lookup token: %w", err)
+	}
+
+	return nil
+}
+
+// Renew extends the lease by the specified increment and returns the
+// duration Vault granted, which is shorter than the increment once the lease
+// approaches its maximum TTL.
+func (c *Client) Renew(ctx context.Context, leaseID string, increment time.Duration) (time.Duration, error) {
+	body := struct {
+		LeaseID   string `json:"lease_id"`
+		Increment int64  `json:"increment"`
+	}{
+		LeaseID:   leaseID,
+		Increment: int64(increment / time.Second),
+	}
+
+	var s secret
+	if err := c.do(ctx, http.MethodPut, "/v1/sys/leases/renew", body, &s); err != nil {
+		return 0, fmt.Errorf("renew: %w", err)
+	}
+
+	return time.Duration(s.LeaseDuration) * time.Second, nil
+}
+
+func (c *Client) do(ctx context.Context, method string, path string, body any, dst any) error {
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("marshal: %w", err)
+		}
+		r = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.address+path, r)
+	if err != nil {
+		return fmt.Errorf("new request: %w", err)
+	}
+
+	req.Header.Set("X-Vault-Token", c.token)
+	req.Header.Set("X-Vault-Request", "true")
+	if c.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", c.namespace)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("do: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		var e struct {
+			Errors []string `json:"errors"`
+		}
+		json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&e)
+
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.Join(e.Errors, "; "))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(dst); err != nil {
+		return fmt.Errorf("decode: %w", err)
+	}
+
+	return nil
+}
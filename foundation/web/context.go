@@ -0,0 +1,95 @@
+package web
+
+import (
+	"context"
+	"time"
+)
+
+type ctxKey int
+
+const key ctxKey = 1
+
+// Values represent state for each request.
+type Values struct {
+	TraceID    string
+	Now        time.Time
+	StatusCode int
+}
+
+// GetValues returns the values from the context.
+func GetValues(ctx context.Context) *Values {
+	v, ok := ctx.Value(key).(*Values)
+	if !ok {
+		return &Values{
+			TraceID: "00000000-0000-0000-0000-000000000000",
+			Now:     time.Now(),
+		}
+	}
+
+	return v
+}
+
+// GetTraceID returns the trace id from the context.
+func GetTraceID(ctx context.Context) string {
+	v, ok := ctx.Value(key).(*Values)
+	if !ok {
+		return "00000000-0000-0000-0000-000000000000"
+	}
+
+	return v.TraceID
+}
+
+// LookupTraceID returns the trace id from the context. The second value is
+// false when the context doesn't belong to a request.
+func LookupTraceID(ctx context.Context) (string, bool) {
+	v, ok := ctx.Value(key).(*Values)
+	if !ok {
+		return "", false
+	}
+
+	return v.TraceID, true
+}
+
+// SetTraceID replaces the trace id for the request.
+func SetTraceID(ctx context.Context, traceID string) {
+	v, ok := ctx.Value(key).(*Values)
+	if !ok {
+		return
+	}
+
+	v.TraceID = traceID
+}
+
+// GetTime returns the time from the context.
+func GetTime(ctx context.Context) time.Time {
+	v, ok := ctx.Value(key).(*Values)
+	if !ok {
+		return time.Now()
+	}
+
+	return v.Now
+}
+
+// GetStatusCode returns the status code recorded for the response.
+func GetStatusCode(ctx context.Context) int {
+	v, ok := ctx.Value(key).(*Values)
+	if !ok {
+		return 0
+	}
+
+	return v.StatusCode
+}
+
+// SetStatusCode sets the status code back into the context.
+func SetStatusCode(ctx context.Context, statusCode int) {
+	v, ok := ctx.Value(key).(*Values)
+	if !ok {
+		return
+	}
+
+	v.StatusCode = statusCode
+}
+
+func setValues(ctx context.Context, v *Values) context.Context {
+	return context.WithValue(ctx, key, v)
+}
@@ -0,0 +1,76 @@
+package web
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ETag computes a strong entity tag for the provided response body.
+func ETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + base64.RawURLEncoding.EncodeToString(sum[:16]) + `"`
+}
+
+// ETagFor computes the entity tag for the JSON representation of the value,
+// matching the tag produced when the value is sent with Respond.
+func ETagFor(data any) (string, error) {
+	body, err := json.Marshal(data)
+	if err != nil {
+		return "", fmt.Errorf("etag: marshal: %w", err)
+	}
+
+	return ETag(body), nil
+}
+
+// IfNoneMatch reports whether the request's If-None-Match header matches the
+// entity tag, meaning the client already has the current representation.
+func IfNoneMatch(r *http.Request, etag string) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+
+	return matchETag(header, etag, true)
+}
+
+// IfMatch reports whether the request's If-Match precondition is satisfied
+// for the entity tag. Requests that don't carry the header are allowed.
+func IfMatch(r *http.Request, etag string) bool {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		return true
+	}
+
+	return matchETag(header, etag, false)
+}
+
+// matchETag compares the entity tag against the list of tags in the header.
+// Weak comparison ignores the weak indicator as required for If-None-Match.
+func matchETag(header string, etag string, weak bool) bool {
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+
+		if weak {
+			tag = strings.TrimPrefix(tag, "W/")
+			etag = strings.TrimPrefix(etag, "W/")
+		}
+
+		if strings.HasPrefix(tag, "W/") {
+			continue
+		}
+
+		if tag == etag {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,58 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"strings"
+)
+
+// unixScheme is the prefix used to identify a unix domain socket address.
+const unixScheme = "unix://"
+
+// ValidateAddr reports whether the address can be used with Listen. The
+// port of a TCP address may be empty or a service name like Listen allows.
+func ValidateAddr(addr string) error {
+	if path, ok := strings.CutPrefix(addr, unixScheme); ok {
+		if path == "" {
+			return errors.New("unix socket path is empty")
+		}
+		return nil
+	}
+
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Listen announces on the specified address. An address with the unix://
+// scheme listens on a unix domain socket at that path, any other address is
+// treated as a TCP host:port.
+func Listen(addr string) (net.Listener, error) {
+	path, ok := strings.CutPrefix(addr, unixScheme)
+	if !ok {
+		return net.Listen("tcp", addr)
+	}
+
+	if path == "" {
+		return nil, errors.New("listen: unix socket path is empty")
+	}
+
+	// Remove a socket file left behind by a previous process. Anything that
+	// isn't a socket is left alone.
+	if info, err := os.Stat(path); err == nil {
+		if info.Mode().Type() != fs.ModeSocket {
+			return nil, fmt.Errorf("listen: %s exists and is not a socket", path)
+		}
+
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("listen: removing stale socket: %w", err)
+		}
+	}
+
+	return net.Listen("unix", path)
+}
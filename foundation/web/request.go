@@ -0,0 +1,87 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"lobbyte.com/alkeepy/foundation/validate"
+)
+
+// Param returns the web call parameters from the request.
+func Param(r *http.Request, key string) string {
+	return r.PathValue(key)
+}
+
+// DefaultMaxBodyBytes is the size of the largest body Decode reads unless
+// the MaxBytes option says otherwise.
+const DefaultMaxBodyBytes = 1 << 20
+
+// DecodeOption represents an option that changes how Decode behaves.
+type DecodeOption func(*decodeOptions)
+
+type decodeOptions struct {
+	strict   bool
+	maxBytes int64
+}
+
+// Strict rejects documents containing fields the value doesn't declare or
+// values of the wrong type. The offending fields are reported by their full
+// path so clients can find typos.
+func Strict() DecodeOption {
+	return func(opts *decodeOptions) {
+		opts.strict = true
+	}
+}
+
+// MaxBytes limits the body Decode reads to the specified number of bytes in
+// place of DefaultMaxBodyBytes. A limit that isn't positive keeps the default.
+func MaxBytes(n int64) DecodeOption {
+	return func(opts *decodeOptions) {
+		if n > 0 {
+			opts.maxBytes = n
+		}
+	}
+}
+
+// Decode reads the body of an HTTP request looking for a JSON document. The
+// body is decoded into the provided value and the value is checked for
+// validation tags. Bodies larger than the limit fail with an
+// http.MaxBytesError, the same error http.MaxBytesReader returns, so a limit
+// set by the server is reported the same way.
+func Decode(r *http.Request, val any, opts ...DecodeOption) error {
+	options := decodeOptions{
+		maxBytes: DefaultMaxBodyBytes,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r.Body, options.maxBytes+1))
+	if err != nil {
+		return fmt.Errorf("request: unable to read payload: %w", err)
+	}
+
+	if int64(len(data)) > options.maxBytes {
+		return fmt.Errorf("request: unable to read payload: %w", &http.MaxBytesError{Limit: options.maxBytes})
+	}
+
+	if options.strict {
+		if err := decodeStrict(data, val); err != nil {
+			return err
+		}
+
+		return validate.Check(val)
+	}
+
+	if err := json.Unmarshal(data, val); err != nil {
+		return fmt.Errorf("request: unable to decode payload: %w", err)
+	}
+
+	if err := validate.Check(val); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package web_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"lobbyte.com/alkeepy/foundation/web"
+)
+
+func Test_DecodeLimit(t *testing.T) {
+	type doc struct {
+		Name string `json:"name"`
+	}
+
+	body := `{"name":"` + strings.Repeat("a", 100) + `"}`
+
+	tests := []struct {
+		name      string
+		opts      []web.DecodeOption
+		wantLimit int64
+	}{
+		{name: "default limit", opts: nil},
+		{name: "larger limit", opts: []web.DecodeOption{web.MaxBytes(int64(len(body)))}},
+		{name: "smaller limit", opts: []web.DecodeOption{web.MaxBytes(64)}, wantLimit: 64},
+		{name: "non positive limit keeps the default", opts: []web.DecodeOption{web.MaxBytes(0)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+
+			var d doc
+			err := web.Decode(r, &d, tt.opts...)
+
+			if tt.wantLimit == 0 {
+				if err != nil {
+					t.Fatalf("Should decode the body: %s", err)
+				}
+				return
+			}
+
+			var maxBytesErr *http.MaxBytesError
+			if !errors.As(err, &maxBytesErr) {
+				t.Fatalf("Should fail with a http.MaxBytesError, got %v", err)
+			}
+
+			if maxBytesErr.Limit != tt.wantLimit {
+				t.Fatalf("Should report the %d byte limit, got %d", tt.wantLimit, maxBytesErr.Limit)
+			}
+		})
+	}
+}
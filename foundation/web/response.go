@@ -0,0 +1,51 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrorResponse is the envelope used for API responses from failures in
+// the API.
+type ErrorResponse struct {
+	Error any `json:"error"`
+}
+
+// Respond converts a Go value to JSON and sends it to the client.
+func Respond(ctx context.Context, w http.ResponseWriter, data any, statusCode int) error {
+	if err := ctx.Err(); err != nil {
+		if errors.Is(err, context.Canceled) {
+			return errors.New("client disconnected, do not send response")
+		}
+	}
+
+	SetStatusCode(ctx, statusCode)
+
+	if statusCode == http.StatusNoContent || data == nil {
+		w.WriteHeader(statusCode)
+		return nil
+	}
+
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("respond: marshal: %w", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	if _, err := w.Write(jsonData); err != nil {
+		return fmt.Errorf("respond: write: %w", err)
+	}
+
+	return nil
+}
+
+// RespondError sends an error response back to the client wrapped in the
+// error envelope.
+func RespondError(ctx context.Context, w http.ResponseWriter, data any, statusCode int) error {
+	return Respond(ctx, w, ErrorResponse{Error: data}, statusCode)
+}
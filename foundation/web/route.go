@@ -0,0 +1,78 @@
+package web
+
+import (
+	"cmp"
+	"encoding/json"
+	"slices"
+	"sync"
+	"time"
+)
+
+// RouteMeta describes the policies that apply to a route so they can be
+// introspected by operators and tooling. Auth requires an authenticated
+// caller holding any of the Roles, or any role when there are none. Timeout
+// bounds how long the route runs, the default of the App when it's zero.
+// They're enforced by the Policy of the App.
+type RouteMeta struct {
+	Summary   string        `json:"summary,omitempty"`
+	Auth      bool          `json:"auth"`
+	Roles     []string      `json:"roles,omitempty"`
+	RateLimit string        `json:"rate_limit,omitempty"`
+	Timeout   time.Duration `json:"-"`
+}
+
+// Policy returns the middleware enforcing the policies the metadata of a
+// route describes.
+type Policy func(meta RouteMeta) []Middleware
+
+// Route represents a route bound to the App.
+type Route struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	RouteMeta
+}
+
+// MarshalJSON implements the json.Marshaler interface. The timeout is
+// written as a duration like 30s rather than a number of nanoseconds.
+func (r Route) MarshalJSON() ([]byte, error) {
+	type route Route
+
+	v := struct {
+		route
+		Timeout string `json:"timeout,omitempty"`
+	}{
+		route: route(r),
+	}
+
+	if r.Timeout > 0 {
+		v.Timeout = r.Timeout.String()
+	}
+
+	return json.Marshal(v)
+}
+
+// routeRegistry holds the set of routes bound to an App.
+type routeRegistry struct {
+	mu     sync.RWMutex
+	routes []Route
+}
+
+func (rr *routeRegistry) add(route Route) {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+
+	rr.routes = append(rr.routes, route)
+}
+
+// list returns a copy of the routes ordered by path and method.
+func (rr *routeRegistry) list() []Route {
+	rr.mu.RLock()
+	defer rr.mu.RUnlock()
+
+	routes := slices.Clone(rr.routes)
+	slices.SortFunc(routes, func(a, b Route) int {
+		return cmp.Or(cmp.Compare(a.Path, b.Path), cmp.Compare(a.Method, b.Method))
+	})
+
+	return routes
+}
@@ -0,0 +1,234 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Event represents a single server sent event.
+type Event struct {
+	ID    string
+	Event string
+	Data  any
+	Retry time.Duration
+}
+
+// SSE provides support for streaming server sent events to a client.
+type SSE struct {
+	w  http.ResponseWriter
+	rc *http.ResponseController
+	mu sync.Mutex
+}
+
+// NewSSE prepares the response for streaming events and sends the headers to
+// the client. The write deadline set by the server is removed since the
+// stream is expected to outlive it.
+func NewSSE(ctx context.Context, w http.ResponseWriter) (*SSE, error) {
+	rc := http.NewResponseController(w)
+
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return nil, fmt.Errorf("sse: clearing write deadline: %w", err)
+	}
+
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+	h.Set("X-Accel-Buffering", "no")
+
+	SetStatusCode(ctx, http.StatusOK)
+	w.WriteHeader(http.StatusOK)
+
+	if err := rc.Flush(); err != nil {
+		return nil, fmt.Errorf("sse: flush: %w", err)
+	}
+
+	sse := SSE{
+		w:  w,
+		rc: rc,
+	}
+
+	return &sse, nil
+}
+
+// EventStream returns a Handler that tracks the handler while it streams
+// events, so it can be ended when the server shuts down. The server waits
+// on a stream like on any request, and a stream only ends when the client
+// goes away, so the context passed to the handler is cancelled by
+// CloseEventStreams, which ends SSE.Stream.
+func (a *App) EventStream(handler Handler) Handler {
+	h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		id := a.streams.add(cancel)
+		defer a.streams.remove(id)
+
+		return handler(ctx, w, r)
+	}
+
+	return h
+}
+
+// CloseEventStreams ends every open event stream and the ones opened after
+// it. It's designed to be registered with http.Server.RegisterOnShutdown.
+func (a *App) CloseEventStreams() {
+	a.streams.closeAll()
+}
+
+// LastEventID returns the id of the last event the client received before it
+// reconnected.
+func LastEventID(r *http.Request) string {
+	return r.Header.Get("Last-Event-ID")
+}
+
+// Send writes the event to the client and flushes it. Data that isn't a
+// string is encoded as JSON.
+func (s *SSE) Send(ev Event) error {
+	var data string
+
+	switch v := ev.Data.(type) {
+	case nil:
+	case string:
+		data = v
+	case []byte:
+		data = string(v)
+	default:
+		d, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("sse: marshal: %w", err)
+		}
+		data = string(d)
+	}
+
+	var b strings.Builder
+
+	if ev.ID != "" {
+		fmt.Fprintf(&b, "id: %s\n", sanitizeField(ev.ID))
+	}
+
+	if ev.Event != "" {
+		fmt.Fprintf(&b, "event: %s\n", sanitizeField(ev.Event))
+	}
+
+	if ev.Retry > 0 {
+		fmt.Fprintf(&b, "retry: %d\n", ev.Retry.Milliseconds())
+	}
+
+	for _, line := range strings.Split(data, "\n") {
+		fmt.Fprintf(&b, "data: %s\n", strings.TrimSuffix(line, "\r"))
+	}
+
+	b.WriteString("\n")
+
+	return s.write(b.String())
+}
+
+// Comment writes a comment line which clients ignore. It's used to keep
+// idle connections open through proxies.
+func (s *SSE) Comment(text string) error {
+	return s.write(": " + sanitizeField(text) + "\n\n")
+}
+
+// Stream sends events from the channel until the channel is closed or the
+// context is cancelled. A keep-alive comment is sent whenever the stream has
+// been idle for the keep-alive interval.
+func (s *SSE) Stream(ctx context.Context, events <-chan Event, keepAlive time.Duration) error {
+	ticker := time.NewTicker(keepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case ev, ok := <-events:
+			if !ok {
+				return nil
+			}
+
+			if err := s.Send(ev); err != nil {
+				return err
+			}
+
+			ticker.Reset(keepAlive)
+
+		case <-ticker.C:
+			if err := s.Comment("keep-alive"); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *SSE) write(data string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.w.Write([]byte(data)); err != nil {
+		return fmt.Errorf("sse: write: %w", err)
+	}
+
+	if err := s.rc.Flush(); err != nil {
+		return fmt.Errorf("sse: flush: %w", err)
+	}
+
+	return nil
+}
+
+// sanitizeField removes line breaks which would terminate the field early.
+func sanitizeField(v string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(v)
+}
+
+// =============================================================================
+
+// streamSet tracks the cancel functions of the open event streams.
+type streamSet struct {
+	mu      sync.Mutex
+	next    uint64
+	closed  bool
+	cancels map[uint64]context.CancelFunc
+}
+
+// add tracks the stream, which is ended right away once the set is closed.
+func (ss *streamSet) add(cancel context.CancelFunc) uint64 {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	if ss.closed {
+		cancel()
+	}
+
+	if ss.cancels == nil {
+		ss.cancels = make(map[uint64]context.CancelFunc)
+	}
+
+	ss.next++
+	ss.cancels[ss.next] = cancel
+
+	return ss.next
+}
+
+func (ss *streamSet) remove(id uint64) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	delete(ss.cancels, id)
+}
+
+func (ss *streamSet) closeAll() {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	ss.closed = true
+	for _, cancel := range ss.cancels {
+		cancel()
+	}
+}
@@ -0,0 +1,189 @@
+package web
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"lobbyte.com/alkeepy/foundation/validate"
+)
+
+// decodeStrict decodes the document into the value, reporting unknown fields
+// and type mismatches as field errors.
+func decodeStrict(data []byte, val any) error {
+	var doc any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("request: unable to decode payload: %w", err)
+	}
+
+	unknown := unknownFields(doc, reflect.TypeOf(val), "")
+	slices.Sort(unknown)
+
+	var fields validate.FieldErrors
+	for _, path := range unknown {
+		fields = append(fields, validate.FieldError{
+			Field: path,
+			Err:   "unknown field",
+		})
+	}
+
+	if len(fields) > 0 {
+		return fields
+	}
+
+	if err := json.Unmarshal(data, val); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) && typeErr.Field != "" {
+			return validate.FieldErrors{
+				{
+					Field: typeErr.Field,
+					Err:   fmt.Sprintf("must be of type %s, got %s", typeErr.Type, typeErr.Value),
+				},
+			}
+		}
+
+		return fmt.Errorf("request: unable to decode payload: %w", err)
+	}
+
+	return nil
+}
+
+var (
+	unmarshalerType     = reflect.TypeFor[json.Unmarshaler]()
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
+
+// unknownFields walks the decoded document alongside the type it will be
+// decoded into and returns the path of every field the type doesn't declare.
+func unknownFields(doc any, typ reflect.Type, path string) []string {
+	for typ != nil && typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+
+	if typ == nil {
+		return nil
+	}
+
+	// Types that decode themselves decide what they accept.
+	if reflect.PointerTo(typ).Implements(unmarshalerType) || reflect.PointerTo(typ).Implements(textUnmarshalerType) {
+		return nil
+	}
+
+	var unknown []string
+
+	switch typ.Kind() {
+	case reflect.Struct:
+		obj, ok := doc.(map[string]any)
+		if !ok {
+			return nil
+		}
+
+		fields := jsonFields(typ)
+
+		for key, value := range obj {
+			fld, ok := fields[key]
+			if !ok {
+				fld, ok = matchFold(fields, key)
+			}
+
+			if !ok {
+				unknown = append(unknown, joinPath(path, key))
+				continue
+			}
+
+			unknown = append(unknown, unknownFields(value, fld, joinPath(path, key))...)
+		}
+
+	case reflect.Slice, reflect.Array:
+		list, ok := doc.([]any)
+		if !ok {
+			return nil
+		}
+
+		for i, value := range list {
+			unknown = append(unknown, unknownFields(value, typ.Elem(), path+"["+strconv.Itoa(i)+"]")...)
+		}
+
+	case reflect.Map:
+		obj, ok := doc.(map[string]any)
+		if !ok {
+			return nil
+		}
+
+		for key, value := range obj {
+			unknown = append(unknown, unknownFields(value, typ.Elem(), joinPath(path, key))...)
+		}
+	}
+
+	return unknown
+}
+
+// jsonFields returns the types of the fields of the struct keyed by their
+// JSON names, following the same rules as encoding/json for embedded structs.
+func jsonFields(typ reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+
+	for i := range typ.NumField() {
+		sf := typ.Field(i)
+
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, _, _ := strings.Cut(tag, ",")
+
+		if sf.Anonymous && name == "" {
+			ft := sf.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+
+			if ft.Kind() == reflect.Struct {
+				for k, v := range jsonFields(ft) {
+					if _, exists := fields[k]; !exists {
+						fields[k] = v
+					}
+				}
+				continue
+			}
+		}
+
+		if !sf.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = sf.Name
+		}
+
+		fields[name] = sf.Type
+	}
+
+	return fields
+}
+
+// matchFold finds a field using the case insensitive matching encoding/json
+// falls back to.
+func matchFold(fields map[string]reflect.Type, key string) (reflect.Type, bool) {
+	for name, typ := range fields {
+		if strings.EqualFold(name, key) {
+			return typ, true
+		}
+	}
+
+	return nil, false
+}
+
+func joinPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+
+	return path + "." + key
+}
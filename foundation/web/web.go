@@ -0,0 +1,264 @@
+// Package web contains a small web framework extension.
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// A Handler is a type that handles a http request within our own little mini
+// framework. Handlers return an error instead of writing status codes
+// directly so error handling can be centralized.
+type Handler func(ctx context.Context, w http.ResponseWriter, r *http.Request) error
+
+// Logger represents a function that will be called to add information
+// to the logs.
+type Logger func(ctx context.Context, msg string, args ...any)
+
+// App is the entrypoint into our application and what configures our context
+// object for each of our http handlers. Feel free to add any configuration
+// data/logic on this App struct.
+type App struct {
+	*http.ServeMux
+	log              Logger
+	shutdown         chan os.Signal
+	mw               []Middleware
+	notFound         http.Handler
+	methodNotAllowed http.Handler
+	websockets       websocketSet
+	streams          streamSet
+	routes           routeRegistry
+	policy           Policy
+	inflight         atomic.Int64
+}
+
+// NewApp creates an App value that handle a set of routes for the application.
+// An application wide set of middleware can be provided and will be executed
+// for every route.
+func NewApp(log Logger, shutdown chan os.Signal, mw ...Middleware) *App {
+	return &App{
+		ServeMux: http.NewServeMux(),
+		log:      log,
+		shutdown: shutdown,
+		mw:       mw,
+	}
+}
+
+// SignalShutdown is used to gracefully shut down the app when an integrity
+// issue is identified.
+func (a *App) SignalShutdown() {
+	a.shutdown <- syscall.SIGTERM
+}
+
+// ServeHTTP implements the http.Handler interface. OPTIONS requests for a
+// bound path are answered with the set of allowed methods. Requests that
+// don't match a route are sent to the NotFound or MethodNotAllowed handlers
+// when they have been provided, otherwise the mux defaults are used.
+func (a *App) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if _, pattern := a.ServeMux.Handler(r); pattern == "" {
+		allowed := a.AllowedMethods(r)
+
+		switch {
+		case len(allowed) == 0 && a.notFound != nil:
+			a.notFound.ServeHTTP(w, r)
+			return
+
+		case len(allowed) > 0 && r.Method == http.MethodOptions:
+			a.httpHandler(wrapMiddleware(a.mw, a.options)).ServeHTTP(w, r)
+			return
+
+		case len(allowed) > 0 && a.methodNotAllowed != nil:
+			w.Header().Set("Allow", strings.Join(append(allowed, http.MethodOptions), ", "))
+			a.methodNotAllowed.ServeHTTP(w, r)
+			return
+		}
+	}
+
+	a.ServeMux.ServeHTTP(w, r)
+}
+
+// SetPolicy sets the policy the routes are bound with from then on, so it
+// must be set before the routes are bound.
+func (a *App) SetPolicy(policy Policy) {
+	a.policy = policy
+}
+
+// NotFound sets the handler used when a request doesn't match any route. The
+// application wide middleware is applied to the handler.
+func (a *App) NotFound(handler Handler) {
+	a.notFound = a.httpHandler(wrapMiddleware(a.mw, handler))
+}
+
+// MethodNotAllowed sets the handler used when a request matches a route but
+// not its method. The Allow header is set before the handler is called. The
+// application wide middleware is applied to the handler.
+func (a *App) MethodNotAllowed(handler Handler) {
+	a.methodNotAllowed = a.httpHandler(wrapMiddleware(a.mw, handler))
+}
+
+// probeMethods are the methods checked when looking for the routes that match
+// a request's path. OPTIONS is answered automatically so it's left out.
+var probeMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+}
+
+// AllowedMethods returns the set of methods that have a route bound for the
+// path of the specified request. A GET route also serves HEAD requests.
+func (a *App) AllowedMethods(r *http.Request) []string {
+	var allowed []string
+
+	for _, method := range probeMethods {
+		probe := r.Clone(r.Context())
+		probe.Method = method
+
+		if _, pattern := a.ServeMux.Handler(probe); pattern != "" {
+			allowed = append(allowed, method)
+		}
+	}
+
+	return allowed
+}
+
+// options answers an OPTIONS request with the methods bound for the path.
+func (a *App) options(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	allowed := append(a.AllowedMethods(r), http.MethodOptions)
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+
+	return Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// Handle sets a handler function for a given HTTP method and path pair
+// to the application server mux. The group is used as a path prefix so
+// routes can be versioned, e.g. a group of "v1" and a path of "/recipes"
+// binds "/v1/recipes". Route specific middleware runs after the application
+// wide middleware.
+func (a *App) Handle(method string, group string, path string, handler Handler, mw ...Middleware) {
+	a.HandleMeta(RouteMeta{}, method, group, path, handler, mw...)
+}
+
+// HandleMeta is like Handle but records the metadata describing the route in
+// the route registry. The middleware the policy derives from the metadata
+// runs ahead of the route specific middleware, so the metadata operators
+// see is what's enforced.
+func (a *App) HandleMeta(meta RouteMeta, method string, group string, path string, handler Handler, mw ...Middleware) {
+	if a.policy != nil {
+		mw = append(a.policy(meta), mw...)
+	}
+
+	handler = wrapMiddleware(mw, handler)
+	handler = wrapMiddleware(a.mw, handler)
+
+	h := a.httpHandler(handler)
+
+	finalPath := path
+	if group != "" {
+		finalPath = "/" + group + path
+	}
+
+	a.routes.add(Route{
+		Method:    method,
+		Path:      finalPath,
+		RouteMeta: meta,
+	})
+
+	a.ServeMux.Handle(fmt.Sprintf("%s %s", method, finalPath), h)
+}
+
+// InFlight returns the number of requests currently being handled.
+func (a *App) InFlight() int64 {
+	return a.inflight.Load()
+}
+
+// Routes returns the set of routes bound to the App.
+func (a *App) Routes() []Route {
+	return a.routes.list()
+}
+
+// httpHandler converts a framework handler into a http.Handler, setting up
+// the request values and handling any error that escapes the middleware.
+func (a *App) httpHandler(handler Handler) http.Handler {
+	h := func(w http.ResponseWriter, r *http.Request) {
+		a.inflight.Add(1)
+		defer a.inflight.Add(-1)
+
+		v := Values{
+			TraceID: uuid.NewString(),
+			Now:     time.Now().UTC(),
+		}
+		ctx := setValues(r.Context(), &v)
+
+		// HEAD requests are served by the GET handler, so the body the
+		// handler writes is discarded.
+		if r.Method == http.MethodHead {
+			w = headWriter{w}
+		}
+
+		if err := handler(ctx, w, r); err != nil {
+			if IsShutdown(err) {
+				a.SignalShutdown()
+				return
+			}
+
+			a.log(ctx, "web", "ERROR", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+	}
+
+	return http.HandlerFunc(h)
+}
+
+// headWriter discards the response body for HEAD requests while keeping the
+// headers and status code.
+type headWriter struct {
+	http.ResponseWriter
+}
+
+// Write discards the data but reports it as written.
+func (hw headWriter) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+// Unwrap returns the underlying writer for use by http.ResponseController.
+func (hw headWriter) Unwrap() http.ResponseWriter {
+	return hw.ResponseWriter
+}
+
+// =============================================================================
+
+// shutdownError is a type used to help with the graceful termination of the
+// service.
+type shutdownError struct {
+	Message string
+}
+
+// NewShutdownError returns an error that causes the framework to signal
+// a graceful shutdown.
+func NewShutdownError(message string) error {
+	return &shutdownError{message}
+}
+
+// Error is the implementation of the error interface.
+func (se *shutdownError) Error() string {
+	return se.Message
+}
+
+// IsShutdown checks to see if the shutdown error is contained
+// in the specified error value.
+func IsShutdown(err error) bool {
+	var se *shutdownError
+	return errors.As(err, &se)
+}
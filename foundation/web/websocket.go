@@ -0,0 +1,104 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/coder/websocket"
+)
+
+// WebSocketHandler handles a connection that has been upgraded to a
+// websocket. The connection is closed once the handler returns.
+type WebSocketHandler func(ctx context.Context, conn *websocket.Conn) error
+
+// Upgrade returns a Handler that upgrades the request to a websocket and
+// passes the connection to the websocket handler. Route middleware such as
+// authentication runs before the upgrade happens. Connections are tracked so
+// they can be closed when the server shuts down.
+func (a *App) Upgrade(handler WebSocketHandler, opts *websocket.AcceptOptions) Handler {
+	h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		conn, err := websocket.Accept(w, r, opts)
+		if err != nil {
+			// Accept has already written the error response to the client.
+			a.log(ctx, "websocket", "status", "upgrade failed", "msg", err)
+			return nil
+		}
+
+		SetStatusCode(ctx, http.StatusSwitchingProtocols)
+
+		// Cancel the context passed to the handler when the server is
+		// shutting down or the connection is done.
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		a.websockets.add(conn, cancel)
+		defer a.websockets.remove(conn)
+
+		// The response can't be used once the connection has been hijacked so
+		// errors are logged here instead of being returned.
+		if err := handler(ctx, conn); err != nil && !errors.Is(err, context.Canceled) {
+			a.log(ctx, "websocket", "status", "handler error", "msg", err)
+			conn.Close(websocket.StatusInternalError, "internal error")
+			return nil
+		}
+
+		conn.Close(websocket.StatusNormalClosure, "")
+
+		return nil
+	}
+
+	return h
+}
+
+// CloseWebSockets closes every open websocket connection with a going away
+// status. It's designed to be registered with http.Server.RegisterOnShutdown
+// since the server doesn't track hijacked connections.
+func (a *App) CloseWebSockets() {
+	a.websockets.closeAll()
+}
+
+// =============================================================================
+
+// websocketSet tracks the set of open websocket connections.
+type websocketSet struct {
+	mu    sync.Mutex
+	conns map[*websocket.Conn]context.CancelFunc
+}
+
+func (ws *websocketSet) add(conn *websocket.Conn, cancel context.CancelFunc) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	if ws.conns == nil {
+		ws.conns = make(map[*websocket.Conn]context.CancelFunc)
+	}
+
+	ws.conns[conn] = cancel
+}
+
+func (ws *websocketSet) remove(conn *websocket.Conn) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	delete(ws.conns, conn)
+}
+
+// closeAll performs the close handshake with every connection concurrently
+// since each handshake can wait on the client to respond.
+func (ws *websocketSet) closeAll() {
+	ws.mu.Lock()
+	conns := make(map[*websocket.Conn]context.CancelFunc, len(ws.conns))
+	for conn, cancel := range ws.conns {
+		conns[conn] = cancel
+	}
+	ws.mu.Unlock()
+
+	for conn, cancel := range conns {
+		go func() {
+			conn.Close(websocket.StatusGoingAway, "server shutting down")
+			cancel()
+		}()
+	}
+}
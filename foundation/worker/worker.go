@@ -0,0 +1,119 @@
+// Package worker provides support for tracking the background goroutines of
+// the service so the ones that don't stop can be reported at shutdown.
+package worker
+
+import (
+	"bytes"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Tracker runs and tracks named background workers.
+type Tracker struct {
+	mu      sync.Mutex
+	running map[string]int
+	wg      sync.WaitGroup
+}
+
+// New constructs a Tracker.
+func New() *Tracker {
+	return &Tracker{
+		running: make(map[string]int),
+	}
+}
+
+// Go runs the function in its own goroutine and tracks it under the name
+// until it returns.
+func (t *Tracker) Go(name string, fn func()) {
+	t.mu.Lock()
+	t.running[name]++
+	t.mu.Unlock()
+
+	t.wg.Add(1)
+
+	go func() {
+		defer func() {
+			t.mu.Lock()
+			t.running[name]--
+			if t.running[name] == 0 {
+				delete(t.running, name)
+			}
+			t.mu.Unlock()
+
+			t.wg.Done()
+		}()
+
+		fn()
+	}()
+}
+
+// Running returns the names of the workers that haven't returned yet, sorted.
+func (t *Tracker) Running() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	names := make([]string, 0, len(t.running))
+	for name := range t.running {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Wait waits for every worker to return or the timeout to expire. It reports
+// whether every worker returned.
+func (t *Tracker) Wait(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		t.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// =============================================================================
+
+// Goroutines returns the number of running goroutines grouped by the function
+// that created them, which is usually enough to spot a leak.
+func Goroutines() map[string]int {
+	buf := make([]byte, 1<<20)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+
+	counts := make(map[string]int)
+
+	for _, stack := range bytes.Split(buf, []byte("\n\n")) {
+		creator := "main"
+
+		lines := strings.Split(string(stack), "\n")
+		for _, line := range lines {
+			if fn, ok := strings.CutPrefix(line, "created by "); ok {
+				if i := strings.Index(fn, " in goroutine"); i >= 0 {
+					fn = fn[:i]
+				}
+				creator = fn
+				break
+			}
+		}
+
+		counts[creator]++
+	}
+
+	return counts
+}